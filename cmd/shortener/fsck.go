@@ -0,0 +1,67 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/alexuryumtsev/go-shortener/config"
+	"github.com/alexuryumtsev/go-shortener/internal/app/storage/file"
+)
+
+// fsckUsage описывает использование подкоманды fsck.
+const fsckUsage = `Usage: shortener fsck [-repair] [FILE]
+
+Verifies checksums of the file storage log FILE (default: -f or FILE_STORAGE_PATH).
+With -repair, drops damaged records and rewrites the log atomically.
+The server must not be running on the same file.
+`
+
+// runFsck выполняет подкоманду fsck с аргументами args.
+func runFsck(args []string, out io.Writer) error {
+	fs := flag.NewFlagSet("fsck", flag.ContinueOnError)
+	fs.SetOutput(out)
+	fs.Usage = func() { fmt.Fprint(out, fsckUsage) }
+	path := fs.String("f", os.Getenv("FILE_STORAGE_PATH"), "Path to file storage or its directory")
+	repair := fs.Bool("repair", false, "Drop damaged records and rewrite the log")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	if fs.NArg() > 0 {
+		*path = fs.Arg(0)
+	}
+	if *path == "" {
+		fs.Usage()
+		return errors.New("file storage path is required")
+	}
+	*path = config.StorageFilePath(*path)
+
+	result, err := file.Check(*path, *repair)
+	if err != nil {
+		return err
+	}
+
+	fmt.Fprintf(out, "%s: %d bytes, %d events, %d URLs\n", *path, result.Size, result.Events, result.URLs)
+	for _, damage := range result.Skipped {
+		fmt.Fprintf(out, "damaged record at offset %d: %v\n", damage.Offset, damage.Err)
+	}
+	if result.TailSize > 0 {
+		fmt.Fprintf(out, "torn tail of %d bytes at offset %d\n", result.TailSize, result.ValidSize)
+	}
+	if result.Unterminated {
+		fmt.Fprintln(out, "last record is not terminated by a newline")
+	}
+
+	switch {
+	case result.Repaired:
+		fmt.Fprintf(out, "Repaired: kept %d events\n", result.Events)
+	case result.Damaged():
+		return errors.New("file storage is damaged: run with -repair to fix it")
+	default:
+		fmt.Fprintln(out, "OK")
+	}
+	return nil
+}
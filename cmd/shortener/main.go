@@ -2,21 +2,43 @@ package main
 
 import (
 	"context"
+	"crypto/tls"
+	"errors"
 	"fmt"
+	"io"
 	"log"
+	"net"
 	"net/http"
 	_ "net/http/pprof"
+	neturl "net/url"
+	"os"
+	"os/signal"
+	"strings"
+	"syscall"
+	"time"
 
 	"github.com/alexuryumtsev/go-shortener/config"
 	"github.com/alexuryumtsev/go-shortener/internal/app/db"
+	"github.com/alexuryumtsev/go-shortener/internal/app/grpcserver"
+	"github.com/alexuryumtsev/go-shortener/internal/app/health"
+	"github.com/alexuryumtsev/go-shortener/internal/app/idgen"
 	"github.com/alexuryumtsev/go-shortener/internal/app/logger"
+	"github.com/alexuryumtsev/go-shortener/internal/app/metrics"
+	"github.com/alexuryumtsev/go-shortener/internal/app/ratelimit"
 	"github.com/alexuryumtsev/go-shortener/internal/app/router"
+	"github.com/alexuryumtsev/go-shortener/internal/app/service/account"
+	"github.com/alexuryumtsev/go-shortener/internal/app/service/apikey"
 	"github.com/alexuryumtsev/go-shortener/internal/app/service/url"
 	"github.com/alexuryumtsev/go-shortener/internal/app/service/user"
 	"github.com/alexuryumtsev/go-shortener/internal/app/storage"
+	"github.com/alexuryumtsev/go-shortener/internal/app/storage/bolt"
 	"github.com/alexuryumtsev/go-shortener/internal/app/storage/file"
 	"github.com/alexuryumtsev/go-shortener/internal/app/storage/memory"
 	"github.com/alexuryumtsev/go-shortener/internal/app/storage/pg"
+	"github.com/alexuryumtsev/go-shortener/internal/app/tlsutil"
+	"github.com/alexuryumtsev/go-shortener/internal/app/tracing"
+	"go.uber.org/zap"
+	"google.golang.org/grpc"
 )
 
 // Информация о сборке приложения.
@@ -51,41 +73,294 @@ func printBuildInfo() {
 }
 
 func main() {
+	// Подкоманда migrate управляет схемой базы данных и не запускает сервер
+	if len(os.Args) > 1 && os.Args[1] == "migrate" {
+		ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGTERM, syscall.SIGINT)
+		err := runMigrate(ctx, os.Args[2:], os.Stdout)
+		stop()
+		if err != nil {
+			log.Fatalf("Migration failed: %v", err)
+		}
+		return
+	}
+
+	// Подкоманда fsck проверяет и восстанавливает файловое хранилище без запуска сервера
+	if len(os.Args) > 1 && os.Args[1] == "fsck" {
+		if err := runFsck(os.Args[2:], os.Stdout); err != nil {
+			log.Fatalf("File storage check failed: %v", err)
+		}
+		return
+	}
+
+	// Подкоманды export и import переносят URL между хранилищами и архивами
+	if len(os.Args) > 1 && (os.Args[1] == "export" || os.Args[1] == "import") {
+		run := runExport
+		if os.Args[1] == "import" {
+			run = runImport
+		}
+		ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGTERM, syscall.SIGINT)
+		err := run(ctx, os.Args[2:], os.Stdin, os.Stdout, os.Stderr)
+		stop()
+		if err != nil {
+			log.Fatalf("Transfer failed: %v", err)
+		}
+		return
+	}
+
 	// Инициализируем конфигурацию
 	cfg, err := config.InitConfig()
 	if err != nil {
 		log.Fatalf("Failed to initialize config: %v", err)
 	}
 
-	// Инициализируем логгер
-	logger.InitLogger()
+	// Режим -print-config выводит итоговую конфигурацию без запуска сервера
+	if cfg.PrintConfig {
+		if err := cfg.Print(os.Stdout); err != nil {
+			log.Fatalf("Failed to print config: %v", err)
+		}
+		return
+	}
 
-	// Подключаемся к базе данных
-	ctx, cancel := context.WithCancel(context.Background())
-	defer cancel()
+	// Инициализируем логгер; вывод пакета log с этого момента идёт через него
+	if err := logger.Init(cfg.LogLevel, cfg.LogFormat); err != nil {
+		log.Fatalf("Failed to initialize logger: %v", err)
+	}
+	defer func() { _ = logger.L().Sync() }()
+
+	// Контекст приложения отменяется при получении сигнала завершения
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGTERM, syscall.SIGINT, syscall.SIGQUIT)
+	defer stop()
+
+	// Настраиваем трассировку до подключения к базе, чтобы запросы миграций попали в трассы
+	shutdownTracing, err := tracing.Setup(ctx, tracing.Options{
+		Exporter:       cfg.TracingExporter,
+		Endpoint:       cfg.TracingEndpoint,
+		File:           cfg.TracingFile,
+		ServiceVersion: buildVer,
+	})
+	if err != nil {
+		logger.L().Fatal("Failed to initialize tracing", zap.Error(err))
+	}
 
+	// Подключаемся к базе данных
 	var repo storage.URLStorage
+	var backend string
 	if cfg.DatabaseDSN != "" {
 		pool, err := db.NewDatabaseConnection(ctx, cfg.DatabaseDSN)
 		if err != nil {
-			log.Fatalf("Failed connect to db: %v", err)
+			logger.L().Fatal("Failed connect to db", zap.Error(err))
+		}
+		if err := metrics.Register(metrics.NewPoolCollector(pool.Pool)); err != nil {
+			logger.L().Fatal("Failed to register pool metrics", zap.Error(err))
+		}
+		repo, backend = pg.NewDatabaseStorage(pool), "postgres"
+	} else if cfg.BoltPath != "" {
+		repo, err = bolt.NewBoltStorage(cfg.BoltPath)
+		if err != nil {
+			logger.L().Fatal("Failed to open bolt storage", zap.Error(err))
 		}
-		defer pool.Close()
-		repo = pg.NewDatabaseStorage(pool)
+		backend = "bolt"
 	} else if cfg.FileStoragePath != "" {
-		repo = file.NewFileStorage(cfg.FileStoragePath)
+		syncPolicy, err := file.ParseSyncPolicy(cfg.FileSync)
+		if err != nil {
+			logger.L().Fatal("Invalid file storage config", zap.Error(err))
+		}
+		repo = file.NewFileStorage(cfg.FileStoragePath,
+			file.WithSync(syncPolicy, cfg.FileSyncInterval),
+			file.WithCompaction(cfg.FileCompactInterval),
+		)
+		backend = "file"
 	} else {
-		repo = memory.NewInMemoryStorage()
+		repo, backend = memory.NewInMemoryStorage(), "memory"
 	}
+	repo = metrics.InstrumentStorage(backend, repo)
 
 	// Инициализируем сервисы
-	userService := user.NewUserService("super-secret-key")
-	urlService := url.NewURLService(repo, cfg.BaseURL, cfg.BatchSize)
+	if cfg.JWTSecret == "" && cfg.JWTKeyFile == "" {
+		logger.L().Warn("JWT signing key is not configured, using a random secret: tokens will not survive restart")
+	}
+	signingKeys, err := user.LoadKeyRing(cfg.JWTSecret, cfg.JWTKeyFile, splitList(cfg.JWTPreviousKeys), cfg.JWTKeyGracePeriod)
+	if err != nil {
+		logger.L().Fatal("Failed to load JWT signing keys", zap.Error(err))
+	}
+	userService := user.NewUserServiceWithKeys(signingKeys)
+	idGenerator, err := idgen.New(cfg.IDStrategy, cfg.IDSalt)
+	if err != nil {
+		logger.L().Fatal("Failed to initialize ID generator", zap.Error(err))
+	}
+	urlService := url.NewTracedURLService(url.NewURLService(repo, cfg.BaseURL, cfg.BatchSize,
+		url.WithDeleteWorkers(cfg.DeleteWorkers),
+		url.WithIDGenerator(idGenerator),
+		url.WithExpiryReaper(cfg.ExpiryReapInterval, cfg.ExpiryArchive),
+		url.WithClickRecorder(cfg.ClickBufferSize, cfg.ClickFlushInterval),
+		url.WithClickSalt(cfg.ClickSalt),
+	))
+	if err := metrics.RegisterDeletionQueue(urlService.DeletionQueueDepth); err != nil {
+		logger.L().Fatal("Failed to register deletion queue metrics", zap.Error(err))
+	}
+
+	// Проверка готовности учитывает состояние хранилища и завершение работы
+	probe := health.NewProbe(repo)
+
+	server := &http.Server{
+		Addr:    cfg.ServerAddress,
+		Handler: router.ShortenerRouter(cfg, repo, userService, urlService, apikey.NewService(repo), account.NewService(repo), ratelimit.NewMemoryLimiter(), probe),
+	}
+
+	grpcServer := grpcserver.NewGRPCServer(urlService, userService, repo)
+
+	// Настраиваем HTTPS и перенаправление с HTTP
+	var redirectServer *http.Server
+	if cfg.EnableHTTPS {
+		server.TLSConfig, err = serverTLSConfig(cfg)
+		if err != nil {
+			logger.L().Fatal("Failed to configure HTTPS", zap.Error(err))
+		}
+		if cfg.HTTPRedirectAddress != "" {
+			redirectServer = &http.Server{
+				Addr:    cfg.HTTPRedirectAddress,
+				Handler: tlsutil.RedirectHandler(cfg.ServerAddress),
+			}
+		}
+	}
+
+	// Запуск серверов
+	serverErr := make(chan error, 3)
+	go func() {
+		var err error
+		if cfg.EnableHTTPS {
+			logger.L().Info("HTTPS server started", zap.String("address", cfg.ServerAddress))
+			err = server.ListenAndServeTLS("", "")
+		} else {
+			logger.L().Info("Server started", zap.String("address", cfg.ServerAddress))
+			err = server.ListenAndServe()
+		}
+		if err != nil && !errors.Is(err, http.ErrServerClosed) {
+			serverErr <- err
+		}
+	}()
+	if redirectServer != nil {
+		go func() {
+			logger.L().Info("HTTP to HTTPS redirect started", zap.String("address", redirectServer.Addr))
+			if err := redirectServer.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+				serverErr <- fmt.Errorf("redirect: %w", err)
+			}
+		}()
+	}
+	go func() {
+		listener, err := net.Listen("tcp", cfg.GRPCAddress)
+		if err != nil {
+			serverErr <- fmt.Errorf("grpc: %w", err)
+			return
+		}
+		logger.L().Info("gRPC server started", zap.String("address", cfg.GRPCAddress))
+		if err := grpcServer.Serve(listener); err != nil {
+			serverErr <- fmt.Errorf("grpc: %w", err)
+		}
+	}()
+
+	select {
+	case err := <-serverErr:
+		logger.L().Fatal("Failed to start server", zap.Error(err))
+	case <-ctx.Done():
+		logger.L().Info("Shutdown signal received")
+	}
+
+	// Повторный сигнал прерывает процесс немедленно
+	stop()
+
+	// Сервис перестаёт быть готовым до остановки серверов, чтобы балансировщик
+	// успел убрать его из ротации
+	probe.SetShuttingDown()
+	if cfg.ShutdownDelay > 0 {
+		logger.L().Info("Waiting before stopping servers", zap.Duration("delay", cfg.ShutdownDelay))
+		time.Sleep(cfg.ShutdownDelay)
+	}
+
+	servers := []*http.Server{server}
+	if redirectServer != nil {
+		servers = append(servers, redirectServer)
+	}
+	if err := shutdown(servers, grpcServer, urlService, repo, shutdownTracing, cfg.ShutdownTimeout); err != nil {
+		logger.L().Error("Graceful shutdown finished with errors", zap.Error(err))
+		return
+	}
+	logger.L().Info("Server stopped gracefully")
+}
+
+// serverTLSConfig возвращает настройки TLS с сертификатом из файлов
+// или с самоподписанным сертификатом для хоста из BaseURL.
+func serverTLSConfig(cfg *config.Config) (*tls.Config, error) {
+	var hosts []string
+	if u, err := neturl.Parse(cfg.BaseURL); err == nil {
+		hosts = append(hosts, u.Hostname())
+	}
 
-	// Запуск сервера
-	fmt.Println("Server started at", cfg.ServerAddress)
-	err = http.ListenAndServe(cfg.ServerAddress, router.ShortenerRouter(cfg, repo, userService, urlService))
+	cert, err := tlsutil.LoadCertificate(cfg.TLSCertFile, cfg.TLSKeyFile, cfg.TLSCacheDir, hosts)
 	if err != nil {
-		log.Fatalf("Failed to start server: %v", err)
+		return nil, err
 	}
+
+	return &tls.Config{
+		Certificates: []tls.Certificate{cert},
+		MinVersion:   tls.VersionTLS12,
+	}, nil
+}
+
+// splitList разбивает список значений, перечисленных через запятую, пропуская пустые.
+func splitList(list string) []string {
+	var items []string
+	for _, item := range strings.Split(list, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
+
+// shutdown последовательно останавливает HTTP- и gRPC-серверы, дожидается фоновых удалений,
+// закрывает хранилище, если фоновые задачи успели завершиться, и отправляет накопленные
+// спаны. Все этапы ограничены общим таймаутом.
+func shutdown(servers []*http.Server, grpcServer *grpc.Server, urlService url.URLService, repo storage.URLStorage, shutdownTracing func(context.Context) error, timeout time.Duration) error {
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	var errs []error
+
+	// Прекращаем приём новых соединений и ждём завершения активных запросов
+	for _, server := range servers {
+		if err := server.Shutdown(ctx); err != nil {
+			errs = append(errs, fmt.Errorf("http server %s shutdown: %w", server.Addr, err))
+		}
+	}
+
+	// gRPC-сервер останавливается принудительно, если активные вызовы не успели завершиться
+	grpcStopped := make(chan struct{})
+	go func() {
+		grpcServer.GracefulStop()
+		close(grpcStopped)
+	}()
+	select {
+	case <-grpcStopped:
+	case <-ctx.Done():
+		grpcServer.Stop()
+		errs = append(errs, fmt.Errorf("grpc server shutdown: %w", ctx.Err()))
+	}
+
+	// Дожидаемся завершения фоновых удалений. Если они не успели завершиться,
+	// хранилище не закрываем: воркеры ещё пишут в него
+	if err := urlService.Shutdown(ctx); err != nil {
+		errs = append(errs, fmt.Errorf("url service shutdown: %w", err))
+	} else if closer, ok := repo.(io.Closer); ok {
+		if err := closer.Close(); err != nil {
+			errs = append(errs, fmt.Errorf("storage close: %w", err))
+		}
+	}
+
+	// Спаны отправляются последними, чтобы в трассы попали этапы остановки
+	if err := shutdownTracing(ctx); err != nil {
+		errs = append(errs, fmt.Errorf("tracing shutdown: %w", err))
+	}
+
+	return errors.Join(errs...)
 }
@@ -0,0 +1,95 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"strconv"
+	"time"
+
+	"github.com/alexuryumtsev/go-shortener/config"
+	"github.com/alexuryumtsev/go-shortener/internal/app/db"
+)
+
+// migrateUsage описывает использование подкоманды migrate.
+const migrateUsage = `Usage: shortener migrate [-c CONFIG] [-d DSN] <command>
+
+The DSN is resolved like the server's: -d, then DATABASE_DSN, then database_dsn
+in the config file given by -c or CONFIG. Other server flags are accepted too.
+
+Commands:
+  up          apply all pending migrations
+  down [N]    roll back the last N applied migrations (default 1)
+  status      print applied and pending migrations
+`
+
+// runMigrate выполняет подкоманду migrate с аргументами args.
+func runMigrate(ctx context.Context, args []string, out io.Writer) error {
+	fs := flag.NewFlagSet("migrate", flag.ContinueOnError)
+	fs.SetOutput(out)
+	fs.Usage = func() { fmt.Fprint(out, migrateUsage) }
+	cfg, err := config.LoadFlagSet(fs, args)
+	if err != nil {
+		return err
+	}
+
+	if fs.NArg() == 0 {
+		fs.Usage()
+		return errors.New("migrate command is required")
+	}
+	if cfg.DatabaseDSN == "" {
+		return errors.New("database DSN is required: use -d, DATABASE_DSN or database_dsn in the config file")
+	}
+
+	database, err := db.Connect(ctx, cfg.DatabaseDSN)
+	if err != nil {
+		return err
+	}
+	defer database.Close()
+
+	migrator, err := db.NewMigrator(database.Pool)
+	if err != nil {
+		return err
+	}
+
+	switch command := fs.Arg(0); command {
+	case "up":
+		applied, err := migrator.Up(ctx)
+		if err != nil {
+			return err
+		}
+		fmt.Fprintf(out, "Applied %d migrations\n", applied)
+	case "down":
+		steps := 1
+		if fs.NArg() > 1 {
+			steps, err = strconv.Atoi(fs.Arg(1))
+			if err != nil || steps <= 0 {
+				return fmt.Errorf("invalid number of steps %q", fs.Arg(1))
+			}
+		}
+		rolledBack, err := migrator.Down(ctx, steps)
+		if err != nil {
+			return err
+		}
+		fmt.Fprintf(out, "Rolled back %d migrations\n", rolledBack)
+	case "status":
+		statuses, err := migrator.Status(ctx)
+		if err != nil {
+			return err
+		}
+		for _, status := range statuses {
+			state := "pending"
+			if status.AppliedAt != nil {
+				state = "applied at " + status.AppliedAt.Format(time.RFC3339)
+			}
+			fmt.Fprintf(out, "%04d_%s\t%s\n", status.Version, status.Name, state)
+		}
+	default:
+		fs.Usage()
+		return fmt.Errorf("unknown migrate command %q", command)
+	}
+
+	return nil
+}
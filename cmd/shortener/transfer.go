@@ -0,0 +1,346 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/alexuryumtsev/go-shortener/internal/app/db"
+	"github.com/alexuryumtsev/go-shortener/internal/app/storage"
+	"github.com/alexuryumtsev/go-shortener/internal/app/storage/bolt"
+	"github.com/alexuryumtsev/go-shortener/internal/app/storage/file"
+	"github.com/alexuryumtsev/go-shortener/internal/app/storage/pg"
+	"github.com/alexuryumtsev/go-shortener/internal/app/storage/transfer"
+)
+
+// transferEndpoints описывает источники и приёмники подкоманд export и import.
+const transferEndpoints = `
+Endpoints:
+  file:PATH              file storage
+  bolt:PATH              bbolt storage
+  postgres://...         PostgreSQL storage (DSN)
+  PATH.ndjson, PATH.jsonl, ndjson:PATH
+                         NDJSON archive, one JSON object per line
+  PATH.csv, csv:PATH     CSV archive with a header row
+  -                      NDJSON archive on standard input or output
+
+The configured storage is taken from DATABASE_DSN, BOLT_PATH or FILE_STORAGE_PATH.
+URLs are copied in ascending short ID order, including user IDs and deleted flags;
+short IDs that already exist in the destination storage are skipped.
+With -progress, the position is saved after every batch: rerun the same command
+to resume an interrupted transfer. The file is removed once the transfer completes.
+The server must not be running on the same file or bolt storage.
+`
+
+// exportUsage описывает использование подкоманды export.
+const exportUsage = `Usage: shortener export [-from SOURCE] -to DESTINATION [-batch N] [-progress FILE]
+
+Copies every URL from SOURCE (default: the configured storage) to DESTINATION.
+` + transferEndpoints
+
+// importUsage описывает использование подкоманды import.
+const importUsage = `Usage: shortener import -from SOURCE [-to DESTINATION] [-batch N] [-progress FILE]
+
+Copies every URL from SOURCE to DESTINATION (default: the configured storage).
+` + transferEndpoints
+
+// endpoint — разобранный источник или приёмник переноса.
+type endpoint struct {
+	// kind — file, bolt, postgres или archive
+	kind string
+	// target — путь к файлу, DSN или "-" для стандартного ввода-вывода
+	target string
+	format transfer.Format
+}
+
+// String возвращает описание endpoint для сообщений, не раскрывая DSN.
+func (e endpoint) String() string {
+	switch e.kind {
+	case "postgres":
+		return "PostgreSQL"
+	case "archive":
+		if e.target == "-" {
+			return "standard stream"
+		}
+		return fmt.Sprintf("%s archive %s", e.format, e.target)
+	default:
+		return fmt.Sprintf("%s storage %s", e.kind, e.target)
+	}
+}
+
+// parseEndpoint разбирает описание источника или приёмника.
+func parseEndpoint(spec string) (endpoint, error) {
+	if spec == "-" {
+		return endpoint{kind: "archive", target: "-", format: transfer.FormatNDJSON}, nil
+	}
+	if strings.HasPrefix(spec, "postgres://") || strings.HasPrefix(spec, "postgresql://") {
+		return endpoint{kind: "postgres", target: spec}, nil
+	}
+	if prefix, path, ok := strings.Cut(spec, ":"); ok && path != "" {
+		switch prefix {
+		case "file", "bolt":
+			return endpoint{kind: prefix, target: path}, nil
+		case "ndjson", "jsonl", "csv":
+			format, err := transfer.ParseFormat(prefix)
+			return endpoint{kind: "archive", target: path, format: format}, err
+		}
+	}
+	if format, ok := transfer.FormatFromPath(spec); ok {
+		return endpoint{kind: "archive", target: spec, format: format}, nil
+	}
+	return endpoint{}, fmt.Errorf("unknown endpoint %q: use file:PATH, bolt:PATH, a postgres:// DSN or an .ndjson/.csv archive", spec)
+}
+
+// configuredStorage возвращает хранилище из переменных окружения сервера
+// с тем же приоритетом, что и при запуске сервера.
+func configuredStorage() string {
+	switch {
+	case os.Getenv("DATABASE_DSN") != "":
+		return os.Getenv("DATABASE_DSN")
+	case os.Getenv("BOLT_PATH") != "":
+		return "bolt:" + os.Getenv("BOLT_PATH")
+	case os.Getenv("FILE_STORAGE_PATH") != "":
+		return "file:" + os.Getenv("FILE_STORAGE_PATH")
+	default:
+		return ""
+	}
+}
+
+// openStorage открывает хранилище endpoint.
+func openStorage(ctx context.Context, e endpoint) (storage.URLStorage, func() error, error) {
+	switch e.kind {
+	case "file":
+		s := file.NewFileStorage(e.target)
+		return s, s.Close, nil
+	case "bolt":
+		s, err := bolt.NewBoltStorage(e.target)
+		if err != nil {
+			return nil, nil, err
+		}
+		return s, s.Close, nil
+	case "postgres":
+		database, err := db.NewDatabaseConnection(ctx, e.target)
+		if err != nil {
+			return nil, nil, err
+		}
+		s := pg.NewDatabaseStorage(database)
+		return s, s.Close, nil
+	default:
+		return nil, nil, fmt.Errorf("%s is not a storage", e)
+	}
+}
+
+// loadProgress читает сохранённую позицию; отсутствие файла означает начало переноса.
+func loadProgress(path string) (transfer.Progress, error) {
+	var progress transfer.Progress
+	if path == "" {
+		return progress, nil
+	}
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return progress, nil
+	}
+	if err != nil {
+		return progress, err
+	}
+	if err := json.Unmarshal(data, &progress); err != nil {
+		return progress, fmt.Errorf("invalid progress file %s: %w", path, err)
+	}
+	return progress, nil
+}
+
+// saveProgress атомарно записывает позицию: сначала во временный файл, затем переименовывает его.
+func saveProgress(path string, progress transfer.Progress) error {
+	data, err := json.Marshal(progress)
+	if err != nil {
+		return err
+	}
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, append(data, '\n'), 0o644); err != nil {
+		return err
+	}
+	return os.Rename(tmp, path)
+}
+
+// runExport выполняет подкоманду export с аргументами args.
+func runExport(ctx context.Context, args []string, stdin io.Reader, stdout, stderr io.Writer) error {
+	return runTransfer(ctx, "export", exportUsage, args, stdin, stdout, stderr)
+}
+
+// runImport выполняет подкоманду import с аргументами args.
+func runImport(ctx context.Context, args []string, stdin io.Reader, stdout, stderr io.Writer) error {
+	return runTransfer(ctx, "import", importUsage, args, stdin, stdout, stderr)
+}
+
+// runTransfer переносит URL между хранилищами и архивами. Export и import
+// отличаются только тем, какая сторона по умолчанию — настроенное хранилище.
+// Сообщения пишутся в stderr, так как stdout может быть приёмником архива.
+func runTransfer(ctx context.Context, name, usage string, args []string, stdin io.Reader, stdout, stderr io.Writer) error {
+	fs := flag.NewFlagSet(name, flag.ContinueOnError)
+	fs.SetOutput(stderr)
+	fs.Usage = func() { fmt.Fprint(stderr, usage) }
+	var defaultFrom, defaultTo string
+	if name == "export" {
+		defaultFrom = configuredStorage()
+	} else {
+		defaultTo = configuredStorage()
+	}
+	fromSpec := fs.String("from", defaultFrom, "Source storage or archive")
+	toSpec := fs.String("to", defaultTo, "Destination storage or archive")
+	batchSize := fs.Int("batch", transfer.DefaultBatchSize, "Number of URLs saved per batch")
+	progressPath := fs.String("progress", "", "File to save progress to and resume from")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	if fs.NArg() > 0 {
+		fs.Usage()
+		return fmt.Errorf("unexpected argument %q", fs.Arg(0))
+	}
+	if *fromSpec == "" || *toSpec == "" {
+		fs.Usage()
+		return errors.New("both source and destination are required: use -from and -to")
+	}
+	if *batchSize <= 0 {
+		return errors.New("batch size must be positive")
+	}
+	from, err := parseEndpoint(*fromSpec)
+	if err != nil {
+		return err
+	}
+	to, err := parseEndpoint(*toSpec)
+	if err != nil {
+		return err
+	}
+	if from.kind == to.kind && from.target == to.target {
+		return errors.New("source and destination must differ")
+	}
+
+	progress, err := loadProgress(*progressPath)
+	if err != nil {
+		return err
+	}
+	if progress.Count > 0 {
+		fmt.Fprintf(stderr, "Resuming after %d URLs\n", progress.Count)
+	}
+
+	// Источник
+	var src transfer.Source
+	if from.kind == "archive" {
+		in := stdin
+		if from.target != "-" {
+			f, err := os.Open(from.target)
+			if err != nil {
+				return err
+			}
+			defer f.Close()
+			in = f
+		}
+		src = transfer.NewReader(in, from.format)
+	} else {
+		repo, closeRepo, err := openStorage(ctx, from)
+		if err != nil {
+			return err
+		}
+		defer closeRepo()
+		src = transfer.FromStorage(repo)
+	}
+
+	// Приёмник. Архив при продолжении обрезается до размера после последнего
+	// сохранённого пакета, чтобы отбросить недописанный.
+	var dst transfer.Sink
+	var archive *os.File
+	closeDst := func() error { return nil }
+	if to.kind == "archive" {
+		if to.target == "-" {
+			if progress.Count > 0 {
+				return errors.New("cannot resume writing to standard output")
+			}
+			dst = transfer.NewWriter(stdout, to.format, true)
+		} else {
+			archive, err = openArchive(to.target, progress)
+			if err != nil {
+				return err
+			}
+			defer archive.Close()
+			dst = transfer.NewWriter(archive, to.format, progress.Count == 0)
+			closeDst = archive.Close
+		}
+	} else {
+		repo, closeRepo, err := openStorage(ctx, to)
+		if err != nil {
+			return err
+		}
+		defer closeRepo()
+		dst = repo
+	}
+
+	checkpoint := func(p transfer.Progress) error {
+		if archive != nil {
+			if err := archive.Sync(); err != nil {
+				return err
+			}
+			offset, err := archive.Seek(0, io.SeekCurrent)
+			if err != nil {
+				return err
+			}
+			p.Offset = offset
+		}
+		if *progressPath == "" {
+			return nil
+		}
+		return saveProgress(*progressPath, p)
+	}
+
+	progress, err = transfer.Copy(ctx, src, dst, progress, transfer.Options{BatchSize: *batchSize, Checkpoint: checkpoint})
+	if err != nil {
+		if *progressPath != "" && progress.Count > 0 {
+			return fmt.Errorf("%w (progress saved to %s after %d URLs, rerun to resume)", err, *progressPath, progress.Count)
+		}
+		return err
+	}
+	if err := closeDst(); err != nil {
+		return err
+	}
+	if *progressPath != "" {
+		if err := os.Remove(*progressPath); err != nil && !errors.Is(err, os.ErrNotExist) {
+			return err
+		}
+	}
+
+	fmt.Fprintf(stderr, "Copied %d URLs from %s to %s\n", progress.Count, from, to)
+	return nil
+}
+
+// openArchive открывает файл архива-приёмника: новый перенос начинает его
+// заново, а продолжение — с размера progress.Offset.
+func openArchive(path string, progress transfer.Progress) (*os.File, error) {
+	if dir := filepath.Dir(path); dir != "." {
+		if err := os.MkdirAll(dir, 0o755); err != nil {
+			return nil, err
+		}
+	}
+	if progress.Count == 0 {
+		return os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0o644)
+	}
+
+	f, err := os.OpenFile(path, os.O_WRONLY, 0o644)
+	if err != nil {
+		return nil, fmt.Errorf("cannot resume: %w", err)
+	}
+	if err := f.Truncate(progress.Offset); err != nil {
+		f.Close()
+		return nil, err
+	}
+	if _, err := f.Seek(progress.Offset, io.SeekStart); err != nil {
+		f.Close()
+		return nil, err
+	}
+	return f, nil
+}
@@ -2,139 +2,447 @@
 package config
 
 import (
+	"encoding/json"
+	"errors"
 	"flag"
+	"fmt"
+	"io"
+	"net"
 	"os"
 	"path/filepath"
-	"strconv"
+	"time"
 
+	"github.com/alexuryumtsev/go-shortener/internal/app/ratelimit"
 	"github.com/alexuryumtsev/go-shortener/internal/app/validator"
 )
 
 // Config содержит настройки конфигурации приложения.
 // Включает параметры сервера, базы данных и другие настройки.
+//
+// Каждый параметр задаётся флагом, переменной окружения или ключом JSON-файла
+// конфигурации (см. options). Приоритет источников одинаков для всех параметров:
+// флаги > переменные окружения > файл конфигурации > значения по умолчанию.
 type Config struct {
 	// ServerAddress определяет адрес запуска HTTP-сервера
 	// По умолчанию: ":8080"
 	ServerAddress string
 
+	// GRPCAddress определяет адрес запуска gRPC-сервера
+	// По умолчанию: ":3200"
+	GRPCAddress string
+
 	// BaseURL определяет базовый адрес для сокращённых URL
-	// По умолчанию: "http://localhost:8080/"
+	// По умолчанию: "http://localhost:8080/" или "https://localhost:8080/" при включённом HTTPS
 	BaseURL string
 
-	// FileStoragePath указывает путь к файлу хранилища
+	// EnableHTTPS включает обслуживание HTTP-сервера по HTTPS
+	// По умолчанию: false
+	EnableHTTPS bool
+
+	// TLSCertFile и TLSKeyFile указывают файлы сертификата и ключа для HTTPS;
+	// если не заданы, используется самоподписанный сертификат из TLSCacheDir
+	// По умолчанию: "" (пустая строка)
+	TLSCertFile string
+	TLSKeyFile  string
+
+	// TLSCacheDir указывает директорию кеша самоподписанного сертификата
+	// По умолчанию: "<os.TempDir>/shortener-tls"
+	TLSCacheDir string
+
+	// HTTPRedirectAddress определяет адрес HTTP-сервера, перенаправляющего запросы на HTTPS;
+	// используется только при включённом HTTPS
+	// По умолчанию: "" (перенаправление отключено)
+	HTTPRedirectAddress string
+
+	// FileStoragePath указывает путь к файлу хранилища. Если путь указывает
+	// на каталог или оканчивается разделителем, используется файл storage.json
+	// в этом каталоге: до появления файла конфигурации флаг -f задавал каталог
 	// По умолчанию: "/tmp/storage.json"
 	FileStoragePath string
 
+	// FileSync определяет, когда журнал файлового хранилища сбрасывается на диск:
+	// always — после каждой записи, interval — раз в FileSyncInterval, never — решает ОС
+	// По умолчанию: "interval"
+	FileSync string
+
+	// FileSyncInterval определяет период сброса журнала на диск для политики interval
+	// По умолчанию: 1s
+	FileSyncInterval time.Duration
+
+	// FileCompactInterval определяет период проверки необходимости сжатия журнала
+	// файлового хранилища; 0 отключает сжатие
+	// По умолчанию: 10m
+	FileCompactInterval time.Duration
+
 	// DatabaseDSN определяет строку подключения к PostgreSQL
 	// По умолчанию: "" (пустая строка)
 	DatabaseDSN string
 
+	// BoltPath указывает путь к файлу встраиваемой базы bbolt.
+	// Если задан, используется вместо файлового хранилища; DatabaseDSN имеет приоритет
+	// По умолчанию: "" (не используется)
+	BoltPath string
+
 	// BatchSize определяет размер батча для пакетных операций
 	// По умолчанию: 10
 	BatchSize int
 
+	// IDStrategy определяет стратегию генерации коротких ID: hash, sequence, random или sqids
+	// По умолчанию: "hash"
+	IDStrategy string
+
+	// IDSalt задаёт соль для стратегий hash (при коллизиях) и sqids (перемешивание алфавита)
+	// По умолчанию: "" (пустая строка)
+	IDSalt string
+
+	// DeleteWorkers определяет количество воркеров очереди удаления URL
+	// По умолчанию: 4
+	DeleteWorkers int
+
+	// ExpiryReapInterval определяет период удаления ссылок с истёкшим сроком действия
+	// По умолчанию: 1m
+	ExpiryReapInterval time.Duration
+
+	// ExpiryArchive включает перенос истёкших ссылок в архив вместо окончательного удаления;
+	// хранилище в памяти архив не ведёт
+	// По умолчанию: false
+	ExpiryArchive bool
+
+	// ClickBufferSize определяет ёмкость буфера событий переходов;
+	// при переполнении новые события отбрасываются
+	// По умолчанию: 4096
+	ClickBufferSize int
+
+	// ClickFlushInterval определяет период записи накопленных событий переходов в хранилище
+	// По умолчанию: 1s
+	ClickFlushInterval time.Duration
+
+	// ClickSalt задаёт соль для хеширования IP-адресов посетителей
+	// По умолчанию: "" (пустая строка)
+	ClickSalt string
+
+	// TrustedSubnet задаёт подсеть в нотации CIDR, из которой разрешён доступ
+	// к внутренним эндпоинтам (/api/internal/stats, /metrics); пустое значение
+	// запрещает доступ всем
+	// По умолчанию: "" (пустая строка)
+	TrustedSubnet string
+
+	// TrustedProxies перечисляет через запятую подсети CIDR или адреса обратных прокси,
+	// заголовкам X-Real-IP и X-Forwarded-For которых можно доверять. Адрес клиента
+	// из этих заголовков используется для ограничения частоты запросов, доступа
+	// по TrustedSubnet и статистики переходов; для остальных соединений
+	// учитывается только адрес соединения
+	// По умолчанию: "" (заголовки не учитываются)
+	TrustedProxies string
+
+	// JWTSecret задаёт секрет подписи токенов HS256
+	// По умолчанию: "" (случайный секрет, токены не переживают перезапуск)
+	JWTSecret string
+
+	// JWTKeyFile указывает файл PEM с закрытым ключом RSA (RS256) или Ed25519 (EdDSA);
+	// если задан, используется вместо JWTSecret
+	// По умолчанию: "" (пустая строка)
+	JWTKeyFile string
+
+	// JWTPreviousKeys перечисляет через запятую предыдущие ключи подписи в формате
+	// "<ключ>@<момент RFC 3339>": секрет HS256 или путь к файлу PEM с префиксом "file:"
+	// и момент, когда ключ перестал быть активным, например
+	// "old-secret@2025-01-31T12:00:00Z"
+	// По умолчанию: "" (пустая строка)
+	JWTPreviousKeys string
+
+	// JWTKeyGracePeriod определяет, сколько после вывода ключа из использования
+	// принимаются токены, подписанные им
+	// По умолчанию: 24h
+	JWTKeyGracePeriod time.Duration
+
+	// RateLimit задаёт ограничение частоты запросов клиента для маршрутов
+	// без собственного ограничения, в формате "<запросы>/<период>", например "100/1m"
+	// По умолчанию: "" (без ограничения)
+	RateLimit string
+
+	// RateLimitRoutes перечисляет через запятую ограничения отдельных маршрутов
+	// в формате "<METHOD> <шаблон маршрута>=<запросы>/<период>"
+	// По умолчанию: "POST /api/shorten/batch=10/1m,GET /{id}=600/1m"
+	RateLimitRoutes string
+
+	// TracingExporter выбирает экспортёр спанов OpenTelemetry:
+	// none, otlp (OTLP/gRPC), otlp-http, stdout или file
+	// По умолчанию: "none" (трассировка отключена)
+	TracingExporter string
+
+	// TracingEndpoint указывает URL коллектора OTLP, например "http://localhost:4317";
+	// схема http отключает TLS
+	// По умолчанию: "" (OTEL_EXPORTER_OTLP_ENDPOINT или адрес по умолчанию экспортёра)
+	TracingEndpoint string
+
+	// TracingFile указывает файл, в который экспортёр file дописывает спаны
+	// По умолчанию: "" (пустая строка)
+	TracingFile string
+
+	// LogLevel задаёт минимальный уровень логов: debug, info, warn или error
+	// По умолчанию: "info"
+	LogLevel string
+
+	// LogFormat задаёт формат логов: json или console
+	// По умолчанию: "json"
+	LogFormat string
+
 	// Debug включает режим отладки
 	// По умолчанию: false
 	Debug bool
+
+	// ShutdownTimeout определяет время на завершение активных запросов
+	// и фоновых задач при остановке сервера
+	// По умолчанию: 10s
+	ShutdownTimeout time.Duration
+
+	// ShutdownDelay определяет паузу между получением сигнала завершения
+	// и остановкой серверов: /readyz уже сообщает о неготовности, и балансировщик
+	// успевает перестать направлять запросы, пока сервер их ещё принимает
+	// По умолчанию: 0 (серверы останавливаются сразу)
+	ShutdownDelay time.Duration
+
+	// PrintConfig требует вывести итоговую конфигурацию и завершить работу.
+	// Задаётся только флагом -print-config
+	PrintConfig bool
 }
 
 // Значения по умолчанию.
 const (
 	defaultServerAddress = ":8080"
+	defaultGRPCAddress   = ":3200"
 	defaultBaseURL       = "http://localhost:8080/"
+	defaultHTTPSBaseURL  = "https://localhost:8080/"
 	defaultStoragePath   = "/tmp/storage.json"
-	defaultDatabaseDSN   = ""
+	storageFileName      = "storage.json"
 	defaultBatchSize     = 10
-	defaultDebug         = false
+	defaultDeleteWorkers = 4
+	defaultIDStrategy    = "hash"
+	defaultRateLimits    = "POST /api/shorten/batch=10/1m,GET /{id}=600/1m"
+	defaultTracing       = "none"
+	defaultLogLevel      = "info"
+	defaultLogFormat     = "json"
+
+	defaultShutdownTimeout    = 10 * time.Second
+	defaultExpiryReapInterval = time.Minute
+	defaultClickBufferSize    = 4096
+	defaultClickFlushInterval = time.Second
+	defaultJWTKeyGracePeriod  = 24 * time.Hour
+
+	defaultFileSync            = "interval"
+	defaultFileSyncInterval    = time.Second
+	defaultFileCompactInterval = 10 * time.Minute
 )
 
+// defaultConfig возвращает конфигурацию со значениями по умолчанию.
+// BaseURL остаётся пустым: его значение по умолчанию зависит от EnableHTTPS.
+func defaultConfig() *Config {
+	return &Config{
+		ServerAddress:       defaultServerAddress,
+		GRPCAddress:         defaultGRPCAddress,
+		TLSCacheDir:         filepath.Join(os.TempDir(), "shortener-tls"),
+		FileStoragePath:     defaultStoragePath,
+		FileSync:            defaultFileSync,
+		FileSyncInterval:    defaultFileSyncInterval,
+		FileCompactInterval: defaultFileCompactInterval,
+		BatchSize:           defaultBatchSize,
+		IDStrategy:          defaultIDStrategy,
+		DeleteWorkers:       defaultDeleteWorkers,
+		ExpiryReapInterval:  defaultExpiryReapInterval,
+		ClickBufferSize:     defaultClickBufferSize,
+		ClickFlushInterval:  defaultClickFlushInterval,
+		ShutdownTimeout:     defaultShutdownTimeout,
+		JWTKeyGracePeriod:   defaultJWTKeyGracePeriod,
+		RateLimitRoutes:     defaultRateLimits,
+		TracingExporter:     defaultTracing,
+		LogLevel:            defaultLogLevel,
+		LogFormat:           defaultLogFormat,
+	}
+}
+
 // InitConfig инициализирует конфигурацию приложения.
-// Читает параметры из переменных окружения и флагов командной строки.
+// Читает параметры из флагов командной строки, переменных окружения
+// и файла конфигурации, путь к которому задаётся флагом -c или переменной CONFIG.
 // Возвращает указатель на Config и ошибку в случае некорректных параметров.
 func InitConfig() (*Config, error) {
-	cfg := &Config{}
+	return load(os.Args[1:], os.LookupEnv)
+}
 
-	// Получаем значения из переменных окружения.
-	envServerAddress := os.Getenv("SERVER_ADDRESS")
-	envBaseURL := os.Getenv("BASE_URL")
-	envPath := os.Getenv("FILE_STORAGE_PATH")
-	envFileStorageName := os.Getenv("FILE_STORAGE_NAME")
-	envDatabaseDSN := os.Getenv("DATABASE_DSN")
-	envBatchSize := os.Getenv("BATCH_SIZE")
-	envDebug := os.Getenv("DEBUG")
+// LoadFlagSet собирает конфигурацию для подкоманды: параметры регистрируются
+// в наборе флагов fs и разбираются из args с тем же приоритетом источников,
+// что и у сервера. Позиционные аргументы остаются доступны через fs.Args.
+func LoadFlagSet(fs *flag.FlagSet, args []string) (*Config, error) {
+	return loadFlagSet(fs, args, os.LookupEnv)
+}
 
-	debug := defaultDebug
-	if envDebug != "" {
-		debug = envDebug == "true"
-	}
+// load собирает конфигурацию из аргументов командной строки args,
+// переменных окружения, доступных через lookupEnv, и файла конфигурации.
+func load(args []string, lookupEnv func(string) (string, bool)) (*Config, error) {
+	fs := flag.NewFlagSet(filepath.Base(os.Args[0]), flag.ContinueOnError)
+	return loadFlagSet(fs, args, lookupEnv)
+}
 
-	// Определяем флаги
-	flag.StringVar(&cfg.ServerAddress, "a", "", "HTTP server address, host:port")
-	flag.StringVar(&cfg.BaseURL, "b", "", "Base URL for shortened links")
-	flag.StringVar(&cfg.FileStoragePath, "f", "", "Path to file storage")
-	flag.StringVar(&cfg.DatabaseDSN, "d", envDatabaseDSN, "Строка подключения к базе данных (DSN)")
-	flag.IntVar(&cfg.BatchSize, "batch", defaultBatchSize, "Batch size for bulk operations")
-	flag.BoolVar(&cfg.Debug, "debug", debug, "Enable debug mode")
+// loadFlagSet собирает конфигурацию, разбирая флаги набором fs.
+func loadFlagSet(fs *flag.FlagSet, args []string, lookupEnv func(string) (string, bool)) (*Config, error) {
+	cfg := defaultConfig()
+	opts := options(cfg)
 
-	// Обрабатываем флаги
-	flag.Parse()
+	// Флаги разбираем первыми, чтобы узнать путь к файлу конфигурации,
+	// но применяем последними, так как у них наивысший приоритет.
+	var configPath string
+	fs.StringVar(&configPath, "c", "", "Path to JSON config file")
+	fs.StringVar(&configPath, "config", "", "Path to JSON config file (same as -c)")
+	fs.BoolVar(&cfg.PrintConfig, "print-config", false, "Print effective config with secrets masked and exit")
 
-	// Проверяем значения флагов и переменных окружения
-	if cfg.ServerAddress == "" {
-		cfg.ServerAddress = envServerAddress
+	flagValues := make(map[string]string)
+	for _, opt := range opts {
+		name := opt.flag
+		record := func(value string) error {
+			flagValues[name] = value
+			return nil
+		}
+		if _, ok := opt.value.(*boolValue); ok {
+			fs.BoolFunc(name, opt.usage, record)
+		} else {
+			fs.Func(name, opt.usage, record)
+		}
+	}
+	if err := fs.Parse(args); err != nil {
+		return nil, err
 	}
 
-	if cfg.ServerAddress == "" {
-		cfg.ServerAddress = defaultServerAddress
+	if configPath == "" {
+		configPath, _ = lookupEnv("CONFIG")
+	}
+	if configPath != "" {
+		if err := applyFile(opts, configPath); err != nil {
+			return nil, err
+		}
 	}
 
-	// Проверка формата host:port
-	err := validator.ValidateServerAddress(cfg.ServerAddress)
-	if err != nil {
+	if err := applyEnv(opts, lookupEnv); err != nil {
 		return nil, err
 	}
 
-	if cfg.BaseURL == "" {
-		cfg.BaseURL = envBaseURL
+	for _, opt := range opts {
+		if value, ok := flagValues[opt.flag]; ok {
+			if err := opt.value.Set(value); err != nil {
+				return nil, fmt.Errorf("invalid value %q for flag -%s: %w", value, opt.flag, err)
+			}
+		}
 	}
 
+	cfg.FileStoragePath = StorageFilePath(cfg.FileStoragePath)
+
 	if cfg.BaseURL == "" {
 		cfg.BaseURL = defaultBaseURL
+		if cfg.EnableHTTPS {
+			cfg.BaseURL = defaultHTTPSBaseURL
+		}
 	}
 
-	if cfg.FileStoragePath != "" {
-		cfg.FileStoragePath = filepath.Join(cfg.FileStoragePath, "storage.json")
+	if err := cfg.validate(); err != nil {
+		return nil, err
 	}
 
-	if cfg.FileStoragePath == "" {
-		cfg.FileStoragePath = filepath.Join(envPath, envFileStorageName)
-	}
+	return cfg, nil
+}
 
-	if cfg.FileStoragePath == "" {
-		cfg.FileStoragePath = defaultStoragePath
+// StorageFilePath возвращает путь к файлу хранилища по значению параметра
+// FileStoragePath: к каталогу и к пути, оканчивающемуся разделителем,
+// дописывается имя storage.json, остальные пути возвращаются как есть.
+func StorageFilePath(path string) string {
+	if path == "" {
+		return path
 	}
+	if os.IsPathSeparator(path[len(path)-1]) {
+		return filepath.Join(path, storageFileName)
+	}
+	if info, err := os.Stat(path); err == nil && info.IsDir() {
+		return filepath.Join(path, storageFileName)
+	}
+	return path
+}
 
-	if cfg.DatabaseDSN == "" {
-		cfg.DatabaseDSN = defaultDatabaseDSN
+// validate проверяет итоговую конфигурацию.
+func (cfg *Config) validate() error {
+	// Проверка формата host:port
+	if err := validator.ValidateServerAddress(cfg.ServerAddress); err != nil {
+		return err
+	}
+	if err := validator.ValidateServerAddress(cfg.GRPCAddress); err != nil {
+		return err
+	}
+	if cfg.HTTPRedirectAddress != "" {
+		if err := validator.ValidateServerAddress(cfg.HTTPRedirectAddress); err != nil {
+			return err
+		}
 	}
 
-	// Установка размера батча из переменной окружения, если указана
-	if envBatchSize != "" {
-		size, parseErr := strconv.Atoi(envBatchSize) // используем другое имя переменной
-		if parseErr == nil {
-			cfg.BatchSize = size
+	if cfg.TrustedSubnet != "" {
+		if _, _, err := net.ParseCIDR(cfg.TrustedSubnet); err != nil {
+			return fmt.Errorf("invalid trusted subnet: %w", err)
 		}
 	}
+	if _, err := validator.ParseSubnets(cfg.TrustedProxies); err != nil {
+		return fmt.Errorf("invalid trusted proxies: %w", err)
+	}
 
-	if cfg.BatchSize <= 0 {
-		cfg.BatchSize = defaultBatchSize
+	if _, err := ratelimit.ParseRules(cfg.RateLimit, cfg.RateLimitRoutes); err != nil {
+		return err
 	}
 
 	// Проверка корректности URL
-	err = validator.ValidateBaseURL(cfg.BaseURL)
-	if err != nil {
-		return nil, err
+	if err := validator.ValidateBaseURL(cfg.BaseURL); err != nil {
+		return err
 	}
 
-	return cfg, nil
+	// При включённом HTTPS короткие ссылки тоже должны вести на HTTPS
+	if cfg.EnableHTTPS {
+		if err := validator.ValidateBaseURLScheme(cfg.BaseURL, "https"); err != nil {
+			return err
+		}
+	}
+
+	var errs []error
+	positive := []struct {
+		name  string
+		value int64
+	}{
+		{"batch_size", int64(cfg.BatchSize)},
+		{"delete_workers", int64(cfg.DeleteWorkers)},
+		{"click_buffer_size", int64(cfg.ClickBufferSize)},
+		{"expiry_reap_interval", int64(cfg.ExpiryReapInterval)},
+		{"click_flush_interval", int64(cfg.ClickFlushInterval)},
+		{"shutdown_timeout", int64(cfg.ShutdownTimeout)},
+		{"file_sync_interval", int64(cfg.FileSyncInterval)},
+		{"jwt_key_grace_period", int64(cfg.JWTKeyGracePeriod)},
+	}
+	for _, p := range positive {
+		if p.value <= 0 {
+			errs = append(errs, fmt.Errorf("%s must be positive", p.name))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// Print выводит итоговую конфигурацию в формате файла конфигурации.
+// Секреты (пароль в DSN, соли) маскируются.
+func (cfg *Config) Print(w io.Writer) error {
+	values := make(map[string]any)
+	for _, opt := range options(cfg) {
+		value := opt.value.get()
+		if opt.secret {
+			value = maskSecret(opt.value.String())
+		}
+		if opt.json == "database_dsn" {
+			value = maskDSN(cfg.DatabaseDSN)
+		}
+		values[opt.json] = value
+	}
+
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(values)
 }
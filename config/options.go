@@ -0,0 +1,210 @@
+package config
+
+import (
+	"bytes"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"net/url"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// option связывает поле Config с флагом, переменной окружения и ключом файла конфигурации.
+type option struct {
+	flag   string
+	env    string
+	json   string
+	usage  string
+	secret bool
+	value  value
+}
+
+// value — значение поля Config, которое можно задать из строки.
+type value interface {
+	flag.Value
+	// get возвращает значение для вывода в формате JSON
+	get() any
+}
+
+// options возвращает описание всех параметров конфигурации cfg.
+func options(cfg *Config) []option {
+	return []option{
+		{"a", "SERVER_ADDRESS", "server_address", "HTTP server address, host:port", false, (*stringValue)(&cfg.ServerAddress)},
+		{"g", "GRPC_ADDRESS", "grpc_address", "gRPC server address, host:port", false, (*stringValue)(&cfg.GRPCAddress)},
+		{"b", "BASE_URL", "base_url", "Base URL for shortened links", false, (*stringValue)(&cfg.BaseURL)},
+		{"s", "ENABLE_HTTPS", "enable_https", "Enable HTTPS", false, (*boolValue)(&cfg.EnableHTTPS)},
+		{"tls-cert", "TLS_CERT_FILE", "tls_cert_file", "Path to TLS certificate file", false, (*stringValue)(&cfg.TLSCertFile)},
+		{"tls-key", "TLS_KEY_FILE", "tls_key_file", "Path to TLS key file", false, (*stringValue)(&cfg.TLSKeyFile)},
+		{"tls-cache-dir", "TLS_CACHE_DIR", "tls_cache_dir", "Directory for the cached self-signed certificate", false, (*stringValue)(&cfg.TLSCacheDir)},
+		{"redirect-addr", "HTTP_REDIRECT_ADDRESS", "http_redirect_address", "HTTP address redirecting to HTTPS, host:port", false, (*stringValue)(&cfg.HTTPRedirectAddress)},
+		{"f", "FILE_STORAGE_PATH", "file_storage_path", "Path to file storage; a directory (as -f meant before) gets storage.json appended", false, (*stringValue)(&cfg.FileStoragePath)},
+		{"file-sync", "FILE_SYNC", "file_sync", "File storage fsync policy: always, interval, never", false, (*stringValue)(&cfg.FileSync)},
+		{"file-sync-interval", "FILE_SYNC_INTERVAL", "file_sync_interval", "File storage fsync interval for the interval policy", false, (*durationValue)(&cfg.FileSyncInterval)},
+		{"file-compact-interval", "FILE_COMPACT_INTERVAL", "file_compact_interval", "Interval of file storage log compaction checks, 0 disables compaction", false, (*durationValue)(&cfg.FileCompactInterval)},
+		{"d", "DATABASE_DSN", "database_dsn", "Database connection string (DSN)", true, (*stringValue)(&cfg.DatabaseDSN)},
+		{"bolt-path", "BOLT_PATH", "bolt_path", "Path to embedded bbolt storage, takes precedence over file storage", false, (*stringValue)(&cfg.BoltPath)},
+		{"batch", "BATCH_SIZE", "batch_size", "Batch size for bulk operations", false, (*intValue)(&cfg.BatchSize)},
+		{"id-strategy", "ID_STRATEGY", "id_strategy", "Short ID strategy: hash, sequence, random, sqids", false, (*stringValue)(&cfg.IDStrategy)},
+		{"id-salt", "ID_SALT", "id_salt", "Salt for short ID generation", true, (*stringValue)(&cfg.IDSalt)},
+		{"delete-workers", "DELETE_WORKERS", "delete_workers", "Number of URL deletion workers", false, (*intValue)(&cfg.DeleteWorkers)},
+		{"expiry-reap-interval", "EXPIRY_REAP_INTERVAL", "expiry_reap_interval", "Interval of expired URLs removal", false, (*durationValue)(&cfg.ExpiryReapInterval)},
+		{"expiry-archive", "EXPIRY_ARCHIVE", "expiry_archive", "Archive expired URLs instead of deleting them", false, (*boolValue)(&cfg.ExpiryArchive)},
+		{"click-buffer", "CLICK_BUFFER_SIZE", "click_buffer_size", "Size of the click events buffer", false, (*intValue)(&cfg.ClickBufferSize)},
+		{"click-flush-interval", "CLICK_FLUSH_INTERVAL", "click_flush_interval", "Interval of click events flush", false, (*durationValue)(&cfg.ClickFlushInterval)},
+		{"click-salt", "CLICK_SALT", "click_salt", "Salt for visitor IP hashing", true, (*stringValue)(&cfg.ClickSalt)},
+		{"t", "TRUSTED_SUBNET", "trusted_subnet", "Trusted subnet (CIDR) for internal endpoints and /metrics", false, (*stringValue)(&cfg.TrustedSubnet)},
+		{"trusted-proxies", "TRUSTED_PROXIES", "trusted_proxies", "Comma-separated CIDRs or IPs of reverse proxies whose X-Real-IP and X-Forwarded-For headers are trusted", false, (*stringValue)(&cfg.TrustedProxies)},
+		{"jwt-secret", "JWT_SECRET", "jwt_secret", "Secret for HS256 token signing", true, (*stringValue)(&cfg.JWTSecret)},
+		{"jwt-key-file", "JWT_KEY_FILE", "jwt_key_file", "Path to RSA or Ed25519 private key (PEM) for token signing", false, (*stringValue)(&cfg.JWTKeyFile)},
+		{"jwt-previous-keys", "JWT_PREVIOUS_KEYS", "jwt_previous_keys", "Comma-separated previous signing keys with retirement time: <secret>@<RFC3339> or file:<path>@<RFC3339>", true, (*stringValue)(&cfg.JWTPreviousKeys)},
+		{"jwt-key-grace-period", "JWT_KEY_GRACE_PERIOD", "jwt_key_grace_period", "How long tokens signed by a previous key are accepted after its retirement time", false, (*durationValue)(&cfg.JWTKeyGracePeriod)},
+		{"rate-limit", "RATE_LIMIT", "rate_limit", "Default per-client rate limit for all routes, <requests>/<period>", false, (*stringValue)(&cfg.RateLimit)},
+		{"rate-limit-routes", "RATE_LIMIT_ROUTES", "rate_limit_routes", "Comma-separated per-route rate limits: <METHOD> <route>=<requests>/<period>", false, (*stringValue)(&cfg.RateLimitRoutes)},
+		{"tracing-exporter", "TRACING_EXPORTER", "tracing_exporter", "Trace exporter: none, otlp, otlp-http, stdout, file", false, (*stringValue)(&cfg.TracingExporter)},
+		{"tracing-endpoint", "TRACING_ENDPOINT", "tracing_endpoint", "OTLP collector endpoint URL, e.g. http://localhost:4317", false, (*stringValue)(&cfg.TracingEndpoint)},
+		{"tracing-file", "TRACING_FILE", "tracing_file", "Path to the trace file for the file exporter", false, (*stringValue)(&cfg.TracingFile)},
+		{"log-level", "LOG_LEVEL", "log_level", "Minimum log level: debug, info, warn, error", false, (*stringValue)(&cfg.LogLevel)},
+		{"log-format", "LOG_FORMAT", "log_format", "Log format: json, console", false, (*stringValue)(&cfg.LogFormat)},
+		{"debug", "DEBUG", "debug", "Enable debug mode", false, (*boolValue)(&cfg.Debug)},
+		{"shutdown-timeout", "SHUTDOWN_TIMEOUT", "shutdown_timeout", "Graceful shutdown timeout", false, (*durationValue)(&cfg.ShutdownTimeout)},
+		{"shutdown-delay", "SHUTDOWN_DELAY", "shutdown_delay", "Delay between reporting not ready and stopping the servers", false, (*durationValue)(&cfg.ShutdownDelay)},
+	}
+}
+
+// applyFile применяет значения из JSON-файла конфигурации.
+// Неизвестные ключи считаются ошибкой, чтобы опечатки не проходили незамеченными.
+func applyFile(opts []option, path string) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("failed to read config file: %w", err)
+	}
+
+	var raw map[string]json.RawMessage
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return fmt.Errorf("failed to parse config file %s: %w", path, err)
+	}
+
+	for _, opt := range opts {
+		message, ok := raw[opt.json]
+		if !ok {
+			continue
+		}
+		delete(raw, opt.json)
+
+		// Строки (в том числе длительности вида "10s") декодируем,
+		// числа и логические значения передаём как есть
+		text := string(bytes.TrimSpace(message))
+		var str string
+		if err := json.Unmarshal(message, &str); err == nil {
+			text = str
+		}
+		if err := opt.value.Set(text); err != nil {
+			return fmt.Errorf("invalid value for %q in config file: %w", opt.json, err)
+		}
+	}
+
+	if len(raw) > 0 {
+		keys := make([]string, 0, len(raw))
+		for key := range raw {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+		return fmt.Errorf("unknown keys in config file: %s", strings.Join(keys, ", "))
+	}
+	return nil
+}
+
+// applyEnv применяет значения из переменных окружения.
+// FILE_STORAGE_NAME, если задана, дописывается к FILE_STORAGE_PATH как имя файла.
+func applyEnv(opts []option, lookupEnv func(string) (string, bool)) error {
+	for _, opt := range opts {
+		// Пустая переменная окружения считается незаданной
+		text, ok := lookupEnv(opt.env)
+		if !ok || text == "" {
+			continue
+		}
+		if opt.env == "FILE_STORAGE_PATH" {
+			if name, ok := lookupEnv("FILE_STORAGE_NAME"); ok {
+				text = filepath.Join(text, name)
+			}
+		}
+		if err := opt.value.Set(text); err != nil {
+			return fmt.Errorf("invalid value for %s: %w", opt.env, err)
+		}
+	}
+	return nil
+}
+
+// maskSecret скрывает непустое значение секрета.
+func maskSecret(secret string) string {
+	if secret == "" {
+		return ""
+	}
+	return "xxxxx"
+}
+
+// dsnPasswordRe находит пароль в DSN формата key=value.
+var dsnPasswordRe = regexp.MustCompile(`(password=)('[^']*'|\S+)`)
+
+// maskDSN скрывает пароль в строке подключения, сохраняя остальные параметры.
+func maskDSN(dsn string) string {
+	if u, err := url.Parse(dsn); err == nil && u.Scheme != "" && u.Host != "" {
+		return u.Redacted()
+	}
+	return dsnPasswordRe.ReplaceAllString(dsn, "${1}xxxxx")
+}
+
+// stringValue — строковое поле Config.
+type stringValue string
+
+func (v *stringValue) Set(s string) error { *v = stringValue(s); return nil }
+func (v *stringValue) String() string     { return string(*v) }
+func (v *stringValue) get() any           { return string(*v) }
+
+// intValue — целочисленное поле Config.
+type intValue int
+
+func (v *intValue) Set(s string) error {
+	n, err := strconv.Atoi(s)
+	if err != nil {
+		return err
+	}
+	*v = intValue(n)
+	return nil
+}
+func (v *intValue) String() string { return strconv.Itoa(int(*v)) }
+func (v *intValue) get() any       { return int(*v) }
+
+// boolValue — логическое поле Config.
+type boolValue bool
+
+func (v *boolValue) Set(s string) error {
+	b, err := strconv.ParseBool(s)
+	if err != nil {
+		return err
+	}
+	*v = boolValue(b)
+	return nil
+}
+func (v *boolValue) String() string { return strconv.FormatBool(bool(*v)) }
+func (v *boolValue) get() any       { return bool(*v) }
+
+// durationValue — поле Config с длительностью в формате time.ParseDuration.
+type durationValue time.Duration
+
+func (v *durationValue) Set(s string) error {
+	d, err := time.ParseDuration(s)
+	if err != nil {
+		return err
+	}
+	*v = durationValue(d)
+	return nil
+}
+func (v *durationValue) String() string { return time.Duration(*v).String() }
+func (v *durationValue) get() any       { return time.Duration(*v).String() }
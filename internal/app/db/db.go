@@ -4,10 +4,11 @@ package db
 import (
 	"context"
 	"fmt"
-	"log"
 	"time"
 
+	"github.com/alexuryumtsev/go-shortener/internal/app/logger"
 	"github.com/jackc/pgx/v5/pgxpool"
+	"go.uber.org/zap"
 )
 
 // Database представляет собой структуру для работы с базой данных PostgreSQL
@@ -16,7 +17,27 @@ type Database struct {
 }
 
 // NewDatabaseConnection создает новое подключение к PostgreSQL
+// и применяет неприменённые миграции схемы.
 func NewDatabaseConnection(ctx context.Context, dsn string) (*Database, error) {
+	db, err := Connect(ctx, dsn)
+	if err != nil {
+		return nil, err
+	}
+
+	// Применяем миграции
+	applied, err := db.Migrate(ctx)
+	if err != nil {
+		db.Pool.Close()
+		return nil, fmt.Errorf("error applying migrations: %w", err)
+	}
+
+	logger.FromContext(ctx).Info("Successfully connected to PostgreSQL", zap.Int("migrations_applied", applied))
+
+	return db, nil
+}
+
+// Connect создает подключение к PostgreSQL без применения миграций
+func Connect(ctx context.Context, dsn string) (*Database, error) {
 	poolConfig, err := pgxpool.ParseConfig(dsn)
 	if err != nil {
 		return nil, fmt.Errorf("error parsing DSN: %w", err)
@@ -25,44 +46,30 @@ func NewDatabaseConnection(ctx context.Context, dsn string) (*Database, error) {
 	// Устанавливаем таймауты
 	poolConfig.ConnConfig.ConnectTimeout = 5 * time.Second
 
+	// Каждый запрос становится спаном текущей трассы
+	poolConfig.ConnConfig.Tracer = newQueryTracer()
+
 	pool, err := pgxpool.NewWithConfig(ctx, poolConfig)
 	if err != nil {
 		return nil, fmt.Errorf("database connection error: %w", err)
 	}
 
-	db := &Database{Pool: pool}
-
-	// Создаем таблицы
-	if err := db.createTables(ctx); err != nil {
-		return nil, fmt.Errorf("error creating tables: %w", err)
-	}
-
-	log.Println("Successfully connected to PostgreSQL and ensured tables exist")
-
-	return db, nil
+	return &Database{Pool: pool}, nil
 }
 
-// createTables создает необходимые таблицы в базе данных
-func (db *Database) createTables(ctx context.Context) error {
-	query := `
-	CREATE TABLE IF NOT EXISTS urls (
-		id SERIAL PRIMARY KEY,
-		user_id VARCHAR(255) NOT NULL,
-		short_url VARCHAR(255) NOT NULL UNIQUE,
-		original_url TEXT NOT NULL,
-		is_deleted BOOLEAN DEFAULT FALSE
-	);
-
-	CREATE INDEX IF NOT EXISTS idx_user_short_url ON urls (user_id, short_url);
-	`
-	_, err := db.Pool.Exec(ctx, query)
-	return err
+// Migrate применяет все неприменённые миграции и возвращает их количество
+func (db *Database) Migrate(ctx context.Context) (int, error) {
+	migrator, err := NewMigrator(db.Pool)
+	if err != nil {
+		return 0, err
+	}
+	return migrator.Up(ctx)
 }
 
 // Close закрывает соединение с базой данных
 func (db *Database) Close() {
 	db.Pool.Close()
-	log.Println("database connection closed")
+	logger.L().Info("Database connection closed")
 }
 
 // Ping проверяет соединение с базой данных
@@ -0,0 +1,53 @@
+package db
+
+import (
+	"io/fs"
+	"testing"
+	"testing/fstest"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLoadMigrations(t *testing.T) {
+	t.Run("Embedded migrations", func(t *testing.T) {
+		sub, err := fs.Sub(migrationsFS, "migrations")
+		require.NoError(t, err)
+
+		migrations, err := loadMigrations(sub)
+		require.NoError(t, err)
+		require.NotEmpty(t, migrations)
+
+		for i, migration := range migrations {
+			assert.Equal(t, i+1, migration.Version, "versions should be sequential")
+			assert.NotEmpty(t, migration.Up)
+			assert.NotEmpty(t, migration.Down)
+		}
+	})
+
+	t.Run("Sorted by version", func(t *testing.T) {
+		fsys := fstest.MapFS{
+			"0010_second.up.sql":  {Data: []byte("SELECT 2")},
+			"0002_first.up.sql":   {Data: []byte("SELECT 1")},
+			"0002_first.down.sql": {Data: []byte("SELECT -1")},
+		}
+
+		migrations, err := loadMigrations(fsys)
+		require.NoError(t, err)
+		require.Len(t, migrations, 2)
+		assert.Equal(t, Migration{Version: 2, Name: "first", Up: "SELECT 1", Down: "SELECT -1"}, migrations[0])
+		assert.Equal(t, Migration{Version: 10, Name: "second", Up: "SELECT 2"}, migrations[1])
+	})
+
+	t.Run("Invalid migrations", func(t *testing.T) {
+		testCases := map[string]fstest.MapFS{
+			"bad name":   {"create.sql": {}},
+			"missing up": {"0001_a.down.sql": {Data: []byte("SELECT 1")}},
+			"name clash": {"0001_a.up.sql": {Data: []byte("SELECT 1")}, "0001_b.down.sql": {Data: []byte("SELECT 1")}},
+		}
+		for name, fsys := range testCases {
+			_, err := loadMigrations(fsys)
+			assert.Error(t, err, name)
+		}
+	})
+}
@@ -0,0 +1,10 @@
+CREATE TABLE IF NOT EXISTS urls (
+	id SERIAL PRIMARY KEY,
+	user_id VARCHAR(255) NOT NULL,
+	short_url VARCHAR(255) NOT NULL UNIQUE,
+	original_url TEXT NOT NULL,
+	is_deleted BOOLEAN DEFAULT FALSE
+);
+
+CREATE INDEX IF NOT EXISTS idx_user_short_url ON urls (user_id, short_url);
+CREATE INDEX IF NOT EXISTS idx_original_url ON urls USING hash (original_url);
@@ -0,0 +1,9 @@
+CREATE TABLE IF NOT EXISTS urls_archive (
+	id SERIAL PRIMARY KEY,
+	user_id VARCHAR(255) NOT NULL,
+	short_url VARCHAR(255) NOT NULL,
+	original_url TEXT NOT NULL,
+	is_deleted BOOLEAN DEFAULT FALSE,
+	expires_at TIMESTAMPTZ,
+	archived_at TIMESTAMPTZ NOT NULL DEFAULT now()
+);
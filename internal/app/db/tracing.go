@@ -0,0 +1,60 @@
+package db
+
+import (
+	"context"
+	"strings"
+
+	"github.com/jackc/pgx/v5"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/codes"
+	semconv "go.opentelemetry.io/otel/semconv/v1.26.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// instrumentation — имя библиотеки инструментирования запросов к PostgreSQL.
+const instrumentation = "github.com/alexuryumtsev/go-shortener/internal/app/db"
+
+// queryTracer создаёт спан для каждого запроса pgx, включая запросы
+// в транзакциях, BEGIN и COMMIT. В спан попадает текст запроса без аргументов,
+// поэтому адреса и идентификаторы пользователей не уходят в трассы.
+type queryTracer struct {
+	tracer trace.Tracer
+}
+
+// newQueryTracer создаёт трассировщик запросов с глобальным TracerProvider.
+func newQueryTracer() *queryTracer {
+	return &queryTracer{tracer: otel.Tracer(instrumentation)}
+}
+
+// TraceQueryStart начинает спан запроса.
+func (t *queryTracer) TraceQueryStart(ctx context.Context, _ *pgx.Conn, data pgx.TraceQueryStartData) context.Context {
+	operation := queryOperation(data.SQL)
+	ctx, _ = t.tracer.Start(ctx, "pg "+operation,
+		trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(
+			semconv.DBSystemPostgreSQL,
+			semconv.DBOperationName(operation),
+			semconv.DBQueryText(data.SQL),
+		),
+	)
+	return ctx
+}
+
+// TraceQueryEnd завершает спан запроса, отмечая ошибку.
+func (t *queryTracer) TraceQueryEnd(ctx context.Context, _ *pgx.Conn, data pgx.TraceQueryEndData) {
+	span := trace.SpanFromContext(ctx)
+	if data.Err != nil {
+		span.RecordError(data.Err)
+		span.SetStatus(codes.Error, data.Err.Error())
+	}
+	span.End()
+}
+
+// queryOperation возвращает первое слово запроса в верхнем регистре, например SELECT.
+func queryOperation(sql string) string {
+	fields := strings.Fields(sql)
+	if len(fields) == 0 {
+		return "QUERY"
+	}
+	return strings.ToUpper(fields[0])
+}
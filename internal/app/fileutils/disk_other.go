@@ -0,0 +1,10 @@
+//go:build !(linux || darwin || freebsd)
+
+package fileutils
+
+import "errors"
+
+// DiskSpace не поддерживается на этой платформе и возвращает errors.ErrUnsupported.
+func DiskSpace(path string) (free, total uint64, err error) {
+	return 0, 0, errors.ErrUnsupported
+}
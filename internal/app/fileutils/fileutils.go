@@ -4,10 +4,16 @@ package fileutils
 
 import (
 	"bufio"
+	"bytes"
 	"encoding/json"
+	"errors"
+	"fmt"
+	"hash/crc32"
 	"io"
 	"os"
 	"path/filepath"
+	"strconv"
+	"time"
 
 	"github.com/alexuryumtsev/go-shortener/internal/app/models"
 )
@@ -33,58 +39,400 @@ func NewFileStorage(filePath string) *FileStorage {
 	return &FileStorage{filePath: filePath}
 }
 
-// SaveRecord сохраняет запись в файл.
-func (fs *FileStorage) SaveRecord(w io.WriteCloser, urlModel models.URLModel) error {
-	defer w.Close()
+// Op — тип события журнала хранилища URL.
+type Op string
 
-	bufferedWriter := bufio.NewWriter(w)
-	defer bufferedWriter.Flush()
-
-	record := struct {
-		UUID        string `json:"uuid"`
-		ShortURL    string `json:"short_url"`
-		OriginalURL string `json:"original_url"`
-		DeletedFlag bool   `json:"is_deleted"`
-	}{
+// Типы событий журнала.
+const (
+	// OpCreate создаёт или заменяет запись URL целиком
+	OpCreate Op = "create"
+	// OpDelete помечает URL удалённым
+	OpDelete Op = "delete"
+	// OpExpire удаляет URL с истёкшим сроком действия
+	OpExpire Op = "expire"
+	// OpOwner передаёт URL другому пользователю (UserID события)
+	OpOwner Op = "owner"
+)
+
+// Event — событие журнала хранилища URL.
+// Для OpDelete и OpExpire значимо только URL.ID, для OpOwner — URL.ID и URL.UserID.
+type Event struct {
+	Op  Op
+	URL models.URLModel
+}
+
+// record — формат записи URL в файле хранилища.
+// Записи без op, сделанные до перехода на журнал событий, считаются OpCreate.
+type record struct {
+	Op          Op         `json:"op,omitempty"`
+	UUID        string     `json:"uuid"`
+	ShortURL    string     `json:"short_url"`
+	OriginalURL string     `json:"original_url"`
+	Deleted     bool       `json:"is_deleted"`
+	ExpiresAt   *time.Time `json:"expires_at,omitempty"`
+}
+
+// newRecord преобразует URL в запись файла.
+func newRecord(op Op, urlModel models.URLModel) record {
+	rec := record{
+		Op:          op,
 		UUID:        urlModel.UserID,
 		ShortURL:    urlModel.ID,
 		OriginalURL: urlModel.URL,
-		DeletedFlag: urlModel.Deleted,
+		Deleted:     urlModel.Deleted,
+	}
+	if !urlModel.ExpiresAt.IsZero() {
+		rec.ExpiresAt = &urlModel.ExpiresAt
 	}
+	return rec
+}
 
-	encoder := json.NewEncoder(bufferedWriter)
-	if err := encoder.Encode(record); err != nil {
+// event преобразует запись файла в событие журнала.
+func (rec record) event() Event {
+	op := rec.Op
+	if op == "" {
+		op = OpCreate
+	}
+	urlModel := models.URLModel{
+		ID:      rec.ShortURL,
+		URL:     rec.OriginalURL,
+		UserID:  rec.UUID,
+		Deleted: rec.Deleted,
+	}
+	if rec.ExpiresAt != nil {
+		urlModel.ExpiresAt = *rec.ExpiresAt
+	}
+	return Event{Op: op, URL: urlModel}
+}
+
+// SaveRecord сохраняет запись в файл.
+// Writer не закрывается, чтобы в один файл можно было записать несколько записей.
+func (fs *FileStorage) SaveRecord(w io.Writer, urlModel models.URLModel) error {
+	var buf bytes.Buffer
+	if err := encodeFrame(&buf, newRecord("", urlModel)); err != nil {
 		return err
 	}
+	_, err := w.Write(buf.Bytes())
+	return err
+}
+
+// AppendEvents дописывает события в журнал одной операцией записи,
+// чтобы при сбое в файл не попала часть событий одной операции хранилища.
+// Возвращает число записанных байт.
+func (fs *FileStorage) AppendEvents(w io.Writer, events []Event) (int, error) {
+	var buf bytes.Buffer
+	for _, event := range events {
+		if err := encodeFrame(&buf, newRecord(event.Op, event.URL)); err != nil {
+			return 0, err
+		}
+	}
+	return w.Write(buf.Bytes())
+}
+
+// ErrCorruptedRecord возвращается, если запись журнала повреждена:
+// не совпадает длина или контрольная сумма либо не разбирается JSON.
+var ErrCorruptedRecord = errors.New("corrupted record")
+
+// Damage описывает повреждённую запись журнала.
+type Damage struct {
+	// Offset — смещение начала записи в байтах
+	Offset int64
+	Err    error
+}
+
+// ReplayReport — результат чтения журнала.
+type ReplayReport struct {
+	// Events — число прочитанных событий
+	Events int
+	// Skipped — повреждённые записи в середине журнала, пропущенные при восстановлении
+	Skipped []Damage
+	// ValidSize — размер журнала без оборванного хвоста
+	ValidSize int64
+	// TailSize — размер оборванного хвоста: недописанной или повреждённой
+	// последней записи, после которой нет целых записей
+	TailSize int64
+	// Unterminated — последняя целая запись не завершена переводом строки
+	// (например, в файле, отредактированном вручную)
+	Unterminated bool
+}
+
+// ReplayEvents читает журнал и передаёт события в apply по порядку.
+// Оборванный хвост журнала, оставшийся после сбоя во время записи, не считается
+// ошибкой и отражается в отчёте; повреждённая запись в середине журнала
+// возвращает ErrCorruptedRecord.
+func (fs *FileStorage) ReplayEvents(r io.Reader, apply func(Event) error) (ReplayReport, error) {
+	return replay(r, false, apply)
+}
+
+// RecoverEvents читает журнал, как ReplayEvents, но пропускает повреждённые
+// записи в середине журнала и перечисляет их в отчёте.
+func (fs *FileStorage) RecoverEvents(r io.Reader, apply func(Event) error) (ReplayReport, error) {
+	return replay(r, true, apply)
+}
+
+// replay читает журнал событий URL.
+func replay(r io.Reader, skipDamaged bool, apply func(Event) error) (ReplayReport, error) {
+	return replayFrames(r, skipDamaged, func(rec record) error {
+		return apply(rec.event())
+	})
+}
+
+// replayFrames читает файл записей в формате encodeFrame построчно, отслеживая
+// смещения записей. Повреждённые записи откладываются до следующей целой записи:
+// если её нет, они относятся к оборванному хвосту.
+func replayFrames[T any](r io.Reader, skipDamaged bool, apply func(T) error) (ReplayReport, error) {
+	var (
+		report  ReplayReport
+		pending []Damage
+		offset  int64
+	)
+	reader := bufio.NewReader(r)
+	for {
+		line, err := reader.ReadBytes('\n')
+		if len(line) > 0 {
+			var rec T
+			if decodeErr := decodeFrame(bytes.TrimSuffix(line, []byte{'\n'}), &rec); decodeErr != nil {
+				pending = append(pending, Damage{Offset: offset, Err: decodeErr})
+			} else {
+				if len(pending) > 0 {
+					if !skipDamaged {
+						return report, fmt.Errorf("%w at offset %d: %w", ErrCorruptedRecord, pending[0].Offset, pending[0].Err)
+					}
+					report.Skipped = append(report.Skipped, pending...)
+					pending = nil
+				}
+				if applyErr := apply(rec); applyErr != nil {
+					return report, fmt.Errorf("record at offset %d: %w", offset, applyErr)
+				}
+				report.Events++
+				report.ValidSize = offset + int64(len(line))
+				report.Unterminated = err != nil
+			}
+		}
+		offset += int64(len(line))
+
+		if err == io.EOF {
+			break
+		} else if err != nil {
+			return report, err
+		}
+	}
+
+	// Всё после последней целой записи — оборванный хвост
+	report.TailSize = offset - report.ValidSize
+	return report, nil
+}
 
+// encodeFrame дописывает в buf запись в формате
+// "<длина JSON, 8 hex> <CRC-32C JSON, 8 hex> <JSON>\n".
+// Длина и контрольная сумма позволяют отличить целую запись от недописанной.
+func encodeFrame(buf *bytes.Buffer, v any) error {
+	payload, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+	fmt.Fprintf(buf, "%08x %08x ", len(payload), crc32.Checksum(payload, crcTable))
+	buf.Write(payload)
+	buf.WriteByte('\n')
 	return nil
 }
 
-// LoadRecords загружает записи из файла.
+// frameHeaderSize — размер заголовка записи: длина, контрольная сумма и два пробела.
+const frameHeaderSize = 18
+
+// crcTable — таблица CRC-32C (Castagnoli), для которой есть аппаратное ускорение.
+var crcTable = crc32.MakeTable(crc32.Castagnoli)
+
+// decodeFrame проверяет запись line без завершающего перевода строки и разбирает её JSON в v.
+// Строки, начинающиеся с "{", записаны до появления контрольных сумм и разбираются как есть.
+func decodeFrame(line []byte, v any) error {
+	payload := line
+	if len(line) == 0 || line[0] != '{' {
+		if len(line) < frameHeaderSize || line[8] != ' ' || line[17] != ' ' {
+			return errors.New("invalid record header")
+		}
+		length, err := strconv.ParseUint(string(line[:8]), 16, 32)
+		if err != nil {
+			return fmt.Errorf("invalid record length: %w", err)
+		}
+		checksum, err := strconv.ParseUint(string(line[9:17]), 16, 32)
+		if err != nil {
+			return fmt.Errorf("invalid record checksum: %w", err)
+		}
+		payload = line[frameHeaderSize:]
+		if uint64(len(payload)) != length {
+			return fmt.Errorf("record length mismatch: header %d, actual %d", length, len(payload))
+		}
+		if crc32.Checksum(payload, crcTable) != uint32(checksum) {
+			return errors.New("record checksum mismatch")
+		}
+	}
+	return json.Unmarshal(payload, v)
+}
+
+// LoadRecords загружает записи из файла, применяя события журнала.
 func (fs *FileStorage) LoadRecords(r io.Reader) (map[string]models.URLModel, error) {
 	data := make(map[string]models.URLModel)
+	_, err := fs.ReplayEvents(r, func(event Event) error {
+		id := event.URL.ID
+		switch event.Op {
+		case OpCreate:
+			data[id] = event.URL
+		case OpDelete:
+			if urlModel, ok := data[id]; ok {
+				urlModel.Deleted = true
+				data[id] = urlModel
+			}
+		case OpExpire:
+			delete(data, id)
+		case OpOwner:
+			if urlModel, ok := data[id]; ok {
+				urlModel.UserID = event.URL.UserID
+				data[id] = urlModel
+			}
+		default:
+			return fmt.Errorf("unknown event %q", event.Op)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return data, nil
+}
+
+// clickRecord — формат записи события перехода в файле статистики.
+type clickRecord struct {
+	ShortURL  string    `json:"short_url"`
+	Timestamp time.Time `json:"ts"`
+	Referrer  string    `json:"referrer,omitempty"`
+	UserAgent string    `json:"user_agent,omitempty"`
+	IPHash    string    `json:"ip_hash"`
+}
+
+// SaveClickRecords дописывает события переходов в файл статистики.
+func (fs *FileStorage) SaveClickRecords(w io.Writer, events []models.ClickEvent) error {
+	bufferedWriter := bufio.NewWriter(w)
+	encoder := json.NewEncoder(bufferedWriter)
+	for _, event := range events {
+		rec := clickRecord{
+			ShortURL:  event.ShortID,
+			Timestamp: event.Timestamp,
+			Referrer:  event.Referrer,
+			UserAgent: event.UserAgent,
+			IPHash:    event.IPHash,
+		}
+		if err := encoder.Encode(rec); err != nil {
+			return err
+		}
+	}
+	return bufferedWriter.Flush()
+}
+
+// LoadClickRecords читает файл статистики и передаёт события переходов в apply по порядку.
+// Возвращает размер файла без оборванного хвоста — последней строки без перевода
+// строки, недописанной при сбое; такая строка пропускается. Повреждённая запись
+// в середине файла возвращает ErrCorruptedRecord.
+func (fs *FileStorage) LoadClickRecords(r io.Reader, apply func(models.ClickEvent)) (int64, error) {
+	var validSize int64
+	reader := bufio.NewReader(r)
+	for {
+		line, err := reader.ReadBytes('\n')
+		if err == io.EOF {
+			return validSize, nil
+		} else if err != nil {
+			return validSize, err
+		}
+
+		var rec clickRecord
+		if decodeErr := json.Unmarshal(line, &rec); decodeErr != nil {
+			return validSize, fmt.Errorf("%w at offset %d: %w", ErrCorruptedRecord, validSize, decodeErr)
+		}
+		apply(models.ClickEvent{
+			ShortID:   rec.ShortURL,
+			Timestamp: rec.Timestamp,
+			Referrer:  rec.Referrer,
+			UserAgent: rec.UserAgent,
+			IPHash:    rec.IPHash,
+		})
+		validSize += int64(len(line))
+	}
+}
+
+// apiKeyRecord — формат записи API-ключа в файле ключей.
+type apiKeyRecord struct {
+	ID         string     `json:"id"`
+	UserID     string     `json:"user_id"`
+	Name       string     `json:"name"`
+	KeyHash    string     `json:"key_hash"`
+	Scopes     []string   `json:"scopes"`
+	CreatedAt  time.Time  `json:"created_at"`
+	LastUsedAt *time.Time `json:"last_used_at,omitempty"`
+}
+
+// SaveAPIKeyRecords записывает API-ключи в файл ключей.
+func (fs *FileStorage) SaveAPIKeyRecords(w io.Writer, keys []models.APIKeyModel) error {
+	bufferedWriter := bufio.NewWriter(w)
+	encoder := json.NewEncoder(bufferedWriter)
+	for _, key := range keys {
+		rec := apiKeyRecord(key)
+		if err := encoder.Encode(rec); err != nil {
+			return err
+		}
+	}
+	return bufferedWriter.Flush()
+}
+
+// LoadAPIKeyRecords загружает API-ключи из файла ключей.
+func (fs *FileStorage) LoadAPIKeyRecords(r io.Reader) (map[string]models.APIKeyModel, error) {
+	keys := make(map[string]models.APIKeyModel)
 	scanner := bufio.NewScanner(r)
 	for scanner.Scan() {
-		var record struct {
-			UUID        string `json:"uuid"`
-			ShortURL    string `json:"short_url"`
-			OriginalURL string `json:"original_url"`
-			Deleted     bool   `json:"is_deleted"`
-		}
-		if err := json.Unmarshal(scanner.Bytes(), &record); err != nil {
+		var rec apiKeyRecord
+		if err := json.Unmarshal(scanner.Bytes(), &rec); err != nil {
 			return nil, err
 		}
-		data[record.ShortURL] = models.URLModel{
-			ID:      record.ShortURL,
-			URL:     record.OriginalURL,
-			UserID:  record.UUID,
-			Deleted: record.Deleted,
-		}
+		keys[rec.ID] = models.APIKeyModel(rec)
 	}
 
 	if err := scanner.Err(); err != nil {
 		return nil, err
 	}
 
-	return data, nil
+	return keys, nil
+}
+
+// userRecord — формат записи пользователя в файле пользователей.
+type userRecord struct {
+	ID           string    `json:"id"`
+	Login        string    `json:"login"`
+	PasswordHash string    `json:"password_hash"`
+	CreatedAt    time.Time `json:"created_at"`
+}
+
+// SaveUserRecord дописывает пользователя в файл пользователей одной записью
+// в формате журнала URL: с длиной и контрольной суммой.
+func (fs *FileStorage) SaveUserRecord(w io.Writer, user models.UserModel) error {
+	var buf bytes.Buffer
+	if err := encodeFrame(&buf, userRecord(user)); err != nil {
+		return err
+	}
+	_, err := w.Write(buf.Bytes())
+	return err
+}
+
+// LoadUserRecords загружает пользователей из файла пользователей, индексируя их по логину.
+// Как и ReplayEvents, не считает ошибкой оборванный хвост файла и отражает его в отчёте.
+// Записи без контрольной суммы, сделанные до её появления, читаются как есть.
+func (fs *FileStorage) LoadUserRecords(r io.Reader) (map[string]models.UserModel, ReplayReport, error) {
+	users := make(map[string]models.UserModel)
+	report, err := replayFrames(r, false, func(rec userRecord) error {
+		users[rec.Login] = models.UserModel(rec)
+		return nil
+	})
+	if err != nil {
+		return nil, report, err
+	}
+	return users, report, nil
 }
@@ -2,8 +2,11 @@ package fileutils
 
 import (
 	"bytes"
+	"fmt"
+	"io"
 	"os"
 	"path/filepath"
+	"strings"
 	"testing"
 
 	"github.com/alexuryumtsev/go-shortener/internal/app/models"
@@ -54,6 +57,152 @@ func TestFileStorage_SaveAndLoadRecords(t *testing.T) {
 	})
 }
 
+func TestFileStorage_Events(t *testing.T) {
+	var buf bytes.Buffer
+	fs := NewFileStorage("test.json")
+
+	_, err := fs.AppendEvents(&buf, []Event{
+		{Op: OpCreate, URL: models.URLModel{ID: "a", URL: "https://a.com", UserID: "anonymous"}},
+		{Op: OpCreate, URL: models.URLModel{ID: "b", URL: "https://b.com", UserID: "anonymous"}},
+		{Op: OpDelete, URL: models.URLModel{ID: "a"}},
+		{Op: OpOwner, URL: models.URLModel{ID: "a", UserID: "account"}},
+		{Op: OpExpire, URL: models.URLModel{ID: "b"}},
+	})
+	require.NoError(t, err)
+
+	data, err := fs.LoadRecords(&buf)
+	require.NoError(t, err)
+	assert.Equal(t, map[string]models.URLModel{
+		"a": {ID: "a", URL: "https://a.com", UserID: "account", Deleted: true},
+	}, data)
+
+	_, err = fs.LoadRecords(bytes.NewBufferString(`{"op":"rename","short_url":"a"}` + "\n"))
+	assert.ErrorContains(t, err, "rename")
+}
+
+func TestFileStorage_Replay(t *testing.T) {
+	fs := NewFileStorage("test.json")
+	frame := func(id string) []byte {
+		var buf bytes.Buffer
+		_, err := fs.AppendEvents(&buf, []Event{{Op: OpCreate, URL: models.URLModel{ID: id, URL: "https://" + id + ".com"}}})
+		require.NoError(t, err)
+		return buf.Bytes()
+	}
+	ids := func(report func(io.Reader, func(Event) error) (ReplayReport, error), log []byte) ([]string, ReplayReport, error) {
+		var got []string
+		rep, err := report(bytes.NewReader(log), func(event Event) error {
+			got = append(got, event.URL.ID)
+			return nil
+		})
+		return got, rep, err
+	}
+
+	a, b, c := frame("a"), frame("b"), frame("c")
+	legacy := []byte(`{"short_url":"old","original_url":"https://old.com"}` + "\n")
+
+	// Целый журнал, в том числе с записями старого формата без контрольной суммы
+	log := bytes.Join([][]byte{legacy, a, b}, nil)
+	got, report, err := ids(fs.ReplayEvents, log)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"old", "a", "b"}, got)
+	assert.Equal(t, ReplayReport{Events: 3, ValidSize: int64(len(log))}, report)
+
+	// Оборванный хвост не мешает чтению
+	torn := bytes.Join([][]byte{a, b, c[:len(c)/2]}, nil)
+	got, report, err = ids(fs.ReplayEvents, torn)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"a", "b"}, got)
+	assert.Equal(t, int64(len(a)+len(b)), report.ValidSize)
+	assert.Equal(t, int64(len(c)/2), report.TailSize)
+
+	// Повреждённая последняя строка целиком тоже считается хвостом
+	flipped := bytes.Clone(c)
+	flipped[len(flipped)-3] ^= 1
+	_, report, err = ids(fs.ReplayEvents, bytes.Join([][]byte{a, flipped}, nil))
+	require.NoError(t, err)
+	assert.Equal(t, int64(len(flipped)), report.TailSize)
+
+	// Повреждение в середине журнала — ошибка при обычном чтении
+	corrupted := bytes.Join([][]byte{a, flipped, b}, nil)
+	_, _, err = ids(fs.ReplayEvents, corrupted)
+	assert.ErrorIs(t, err, ErrCorruptedRecord)
+	assert.ErrorContains(t, err, fmt.Sprintf("offset %d", len(a)))
+
+	// и пропускается при восстановлении
+	got, report, err = ids(fs.RecoverEvents, corrupted)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"a", "b"}, got)
+	if assert.Len(t, report.Skipped, 1) {
+		assert.Equal(t, int64(len(a)), report.Skipped[0].Offset)
+	}
+	assert.Equal(t, int64(len(corrupted)), report.ValidSize)
+
+	// Последняя целая запись без перевода строки читается
+	got, report, err = ids(fs.ReplayEvents, bytes.Join([][]byte{a, b[:len(b)-1]}, nil))
+	require.NoError(t, err)
+	assert.Equal(t, []string{"a", "b"}, got)
+	assert.True(t, report.Unterminated)
+	assert.Zero(t, report.TailSize)
+}
+
+func TestFileStorage_ClickRecords(t *testing.T) {
+	fs := NewFileStorage("test.json")
+	// Строка длиннее буфера bufio.Scanner по умолчанию
+	long := models.ClickEvent{ShortID: "a", UserAgent: strings.Repeat("x", 100*1024), IPHash: "h"}
+	var buf bytes.Buffer
+	require.NoError(t, fs.SaveClickRecords(&buf, []models.ClickEvent{long, {ShortID: "b", IPHash: "h"}}))
+	full := buf.Bytes()
+	require.NoError(t, fs.SaveClickRecords(&buf, []models.ClickEvent{{ShortID: "c", IPHash: "h"}}))
+	torn := buf.Bytes()[:buf.Len()-5]
+
+	load := func(data []byte) ([]string, int64, error) {
+		var got []string
+		size, err := fs.LoadClickRecords(bytes.NewReader(data), func(event models.ClickEvent) {
+			got = append(got, event.ShortID)
+		})
+		return got, size, err
+	}
+
+	got, size, err := load(full)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"a", "b"}, got)
+	assert.Equal(t, int64(len(full)), size)
+
+	// Недописанная последняя строка пропускается
+	got, size, err = load(torn)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"a", "b"}, got)
+	assert.Equal(t, int64(len(full)), size)
+
+	// Повреждение в середине файла — ошибка
+	_, _, err = load(append([]byte("{broken\n"), full...))
+	assert.ErrorIs(t, err, ErrCorruptedRecord)
+}
+
+func TestFileStorage_UserRecords(t *testing.T) {
+	fs := NewFileStorage("test.json")
+	var buf bytes.Buffer
+	// Запись старого формата без контрольной суммы
+	buf.WriteString(`{"id":"1","login":"old","password_hash":"h"}` + "\n")
+	require.NoError(t, fs.SaveUserRecord(&buf, models.UserModel{ID: "2", Login: "alice", PasswordHash: "h"}))
+	full := buf.Len()
+	require.NoError(t, fs.SaveUserRecord(&buf, models.UserModel{ID: "3", Login: "bob", PasswordHash: "h"}))
+
+	users, report, err := fs.LoadUserRecords(bytes.NewReader(buf.Bytes()))
+	require.NoError(t, err)
+	assert.Len(t, users, 3)
+	assert.Equal(t, "2", users["alice"].ID)
+	assert.Zero(t, report.TailSize)
+
+	// Недописанная последняя запись не мешает чтению
+	users, report, err = fs.LoadUserRecords(bytes.NewReader(buf.Bytes()[:buf.Len()-4]))
+	require.NoError(t, err)
+	assert.Len(t, users, 2)
+	assert.NotContains(t, users, "bob")
+	assert.Equal(t, int64(full), report.ValidSize)
+	assert.Equal(t, int64(buf.Len()-4-full), report.TailSize)
+}
+
 // Вспомогательный тип для тестирования
 type nopWriteCloser struct {
 	*bytes.Buffer
@@ -0,0 +1,72 @@
+package grpcserver
+
+import (
+	"context"
+	"strings"
+
+	"github.com/alexuryumtsev/go-shortener/internal/app/service/user"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+// authMetadataKey — ключ метаданных, в котором передаётся JWT.
+const authMetadataKey = "authorization"
+
+// UserIDFromContext возвращает ID пользователя, установленный AuthInterceptor.
+func UserIDFromContext(ctx context.Context) string {
+	identity, _ := user.IdentityFromContext(ctx)
+	return identity.UserID
+}
+
+// AuthInterceptor аутентифицирует вызовы по JWT из метаданных authorization.
+// Как и HTTP-версия, при отсутствии токена создаёт анонимного пользователя и возвращает
+// его токен в заголовке ответа authorization. Некорректный токен отклоняется с Unauthenticated.
+// Личность пользователя помещается в контекст (см. user.IdentityFromContext).
+func AuthInterceptor(userService user.UserService) grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+		var identity user.Identity
+		token := tokenFromMetadata(ctx)
+		if token == "" {
+			// Токен отсутствует, создаем анонимного пользователя
+			var err error
+			identity, token, err = userService.NewAnonymousUser()
+			if err != nil {
+				return nil, status.Error(codes.Internal, "failed to generate token")
+			}
+			if err := grpc.SetHeader(ctx, metadata.Pairs(authMetadataKey, "Bearer "+token)); err != nil {
+				return nil, status.Error(codes.Internal, "failed to set auth header")
+			}
+		} else {
+			var err error
+			identity, err = userService.Authenticate(token)
+			if err != nil {
+				return nil, status.Error(codes.Unauthenticated, "invalid token")
+			}
+		}
+		identity.Method = user.AuthMethodBearer
+
+		return handler(user.ContextWithIdentity(ctx, identity), req)
+	}
+}
+
+// tokenFromMetadata извлекает JWT из входящих метаданных.
+// Допускается значение как с префиксом Bearer, так и без него.
+func tokenFromMetadata(ctx context.Context) string {
+	md, ok := metadata.FromIncomingContext(ctx)
+	if !ok {
+		return ""
+	}
+
+	values := md.Get(authMetadataKey)
+	if len(values) == 0 {
+		return ""
+	}
+
+	token := strings.TrimSpace(values[0])
+	if prefix := "Bearer "; len(token) > len(prefix) && strings.EqualFold(token[:len(prefix)], prefix) {
+		token = strings.TrimSpace(token[len(prefix):])
+	}
+	return token
+}
@@ -0,0 +1,158 @@
+// Package grpcserver реализует gRPC API сервиса сокращения URL.
+// Методы повторяют HTTP-обработчики и используют те же сервисы.
+package grpcserver
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/alexuryumtsev/go-shortener/internal/app/grpcserver/pb"
+	"github.com/alexuryumtsev/go-shortener/internal/app/models"
+	"github.com/alexuryumtsev/go-shortener/internal/app/service/url"
+	"github.com/alexuryumtsev/go-shortener/internal/app/service/user"
+	"github.com/alexuryumtsev/go-shortener/internal/app/storage"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// pinger проверяет доступность хранилища.
+type pinger interface {
+	Ping(ctx context.Context) error
+}
+
+// Server реализует pb.ShortenerServer поверх url.URLService.
+type Server struct {
+	pb.UnimplementedShortenerServer
+
+	urlService url.URLService
+	repo       storage.URLStorage
+}
+
+// NewServer создаёт реализацию gRPC API.
+func NewServer(urlService url.URLService, repo storage.URLStorage) *Server {
+	return &Server{urlService: urlService, repo: repo}
+}
+
+// NewGRPCServer создаёт gRPC-сервер с интерцептором аутентификации
+// и регистрирует на нём сервис Shortener.
+func NewGRPCServer(urlService url.URLService, userService user.UserService, repo storage.URLStorage) *grpc.Server {
+	server := grpc.NewServer(grpc.UnaryInterceptor(AuthInterceptor(userService)))
+	pb.RegisterShortenerServer(server, NewServer(urlService, repo))
+	return server
+}
+
+// Shorten сокращает один URL.
+// Если URL уже сокращён, возвращает существующую ссылку с признаком already_exists.
+func (s *Server) Shorten(ctx context.Context, req *pb.ShortenRequest) (*pb.ShortenResponse, error) {
+	ttl, err := url.TTLFromSeconds(req.GetTtl())
+	if err != nil {
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	}
+	opts := models.ShortenOptions{Alias: req.GetAlias(), TTL: ttl}
+	if req.GetExpiresAt() != "" {
+		expiresAt, err := time.Parse(time.RFC3339, req.GetExpiresAt())
+		if err != nil {
+			return nil, status.Errorf(codes.InvalidArgument, "invalid expires_at: %v", err)
+		}
+		opts.ExpiresAt = expiresAt
+	}
+
+	shortURL, err := s.urlService.ShortenerURLWithOptions(ctx, req.GetUrl(), UserIDFromContext(ctx), opts)
+	if err != nil {
+		if errors.Is(err, url.ErrAliasTaken) {
+			return nil, status.Error(codes.AlreadyExists, err.Error())
+		}
+		if errors.Is(err, storage.ErrConflict) {
+			return &pb.ShortenResponse{ShortUrl: shortURL, AlreadyExists: true}, nil
+		}
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	}
+
+	return &pb.ShortenResponse{ShortUrl: shortURL}, nil
+}
+
+// ShortenBatch сокращает пакет URL.
+func (s *Server) ShortenBatch(ctx context.Context, req *pb.ShortenBatchRequest) (*pb.ShortenBatchResponse, error) {
+	batchModels := make([]models.URLBatchModel, 0, len(req.GetItems()))
+	for _, item := range req.GetItems() {
+		batchModels = append(batchModels, models.URLBatchModel{
+			CorrelationID: item.GetCorrelationId(),
+			OriginalURL:   item.GetOriginalUrl(),
+			Alias:         item.GetAlias(),
+			TTL:           item.GetTtl(),
+		})
+	}
+
+	results, err := s.urlService.SaveBatchShortenerURL(ctx, batchModels, UserIDFromContext(ctx))
+	if err != nil {
+		if errors.Is(err, storage.ErrConflict) {
+			return nil, status.Error(codes.AlreadyExists, err.Error())
+		}
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	}
+
+	resp := &pb.ShortenBatchResponse{Items: make([]*pb.BatchResult, 0, len(results))}
+	for _, result := range results {
+		resp.Items = append(resp.Items, &pb.BatchResult{
+			CorrelationId: result.CorrelationID,
+			ShortUrl:      result.ShortURL,
+		})
+	}
+	return resp, nil
+}
+
+// Get возвращает оригинальный URL по короткому идентификатору.
+func (s *Server) Get(ctx context.Context, req *pb.GetRequest) (*pb.GetResponse, error) {
+	originalURL, exists, err := s.urlService.GetURLByID(ctx, req.GetId())
+	if !exists {
+		return nil, status.Error(codes.NotFound, "URL not found")
+	}
+	if err != nil {
+		return nil, status.Error(codes.NotFound, err.Error())
+	}
+
+	return &pb.GetResponse{OriginalUrl: originalURL}, nil
+}
+
+// ListUserURLs возвращает URL текущего пользователя.
+func (s *Server) ListUserURLs(ctx context.Context, _ *pb.ListUserURLsRequest) (*pb.ListUserURLsResponse, error) {
+	userURLs, err := s.urlService.GetUserURLs(ctx, UserIDFromContext(ctx))
+	if err != nil {
+		return nil, status.Error(codes.Internal, "failed to get user URLs")
+	}
+
+	resp := &pb.ListUserURLsResponse{Urls: make([]*pb.UserURL, 0, len(userURLs))}
+	for _, userURL := range userURLs {
+		item := &pb.UserURL{ShortUrl: userURL.ShortURL, OriginalUrl: userURL.OriginalURL}
+		if userURL.ExpiresAt != nil {
+			item.ExpiresAt = userURL.ExpiresAt.Format(time.RFC3339)
+		}
+		resp.Urls = append(resp.Urls, item)
+	}
+	return resp, nil
+}
+
+// DeleteUserURLs ставит удаление URL текущего пользователя в очередь.
+func (s *Server) DeleteUserURLs(ctx context.Context, req *pb.DeleteUserURLsRequest) (*pb.DeleteUserURLsResponse, error) {
+	jobID, err := s.urlService.EnqueueDeletion(ctx, UserIDFromContext(ctx), req.GetShortUrls())
+	if err != nil {
+		if errors.Is(err, url.ErrDeletionQueueClosed) {
+			return nil, status.Error(codes.Unavailable, "service is shutting down")
+		}
+		return nil, status.Error(codes.Internal, "failed to enqueue deletion")
+	}
+
+	return &pb.DeleteUserURLsResponse{JobId: jobID, Status: url.DeletionStatusPending}, nil
+}
+
+// Ping проверяет доступность хранилища.
+func (s *Server) Ping(ctx context.Context, _ *pb.PingRequest) (*pb.PingResponse, error) {
+	if p, ok := s.repo.(pinger); ok {
+		if err := p.Ping(ctx); err != nil {
+			return nil, status.Error(codes.Unavailable, "storage is unavailable")
+		}
+	}
+	return &pb.PingResponse{}, nil
+}
@@ -0,0 +1,133 @@
+package grpcserver
+
+import (
+	"context"
+	"math"
+	"net"
+	"testing"
+
+	"github.com/alexuryumtsev/go-shortener/internal/app/grpcserver/pb"
+	"github.com/alexuryumtsev/go-shortener/internal/app/service/url"
+	"github.com/alexuryumtsev/go-shortener/internal/app/service/user"
+	"github.com/alexuryumtsev/go-shortener/internal/app/storage/memory"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+)
+
+// newTestClient запускает сервер на bufconn и возвращает клиента к нему.
+func newTestClient(t *testing.T) pb.ShortenerClient {
+	t.Helper()
+
+	repo := memory.NewInMemoryStorage()
+	urlService := url.NewURLService(repo, "http://localhost:8080", 10)
+	server := NewGRPCServer(urlService, user.NewUserService("test-secret"), repo)
+
+	listener := bufconn.Listen(1024 * 1024)
+	go server.Serve(listener)
+
+	conn, err := grpc.NewClient("passthrough:///bufnet",
+		grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) {
+			return listener.DialContext(ctx)
+		}),
+		grpc.WithTransportCredentials(insecure.NewCredentials()),
+	)
+	require.NoError(t, err)
+
+	t.Cleanup(func() {
+		conn.Close()
+		server.Stop()
+		urlService.Shutdown(context.Background())
+	})
+
+	return pb.NewShortenerClient(conn)
+}
+
+func TestServer(t *testing.T) {
+	client := newTestClient(t)
+	ctx := context.Background()
+
+	// Первый вызов без токена: сервер выдаёт новый токен
+	var header metadata.MD
+	shortenResp, err := client.Shorten(ctx, &pb.ShortenRequest{Url: "https://practicum.yandex.ru/"}, grpc.Header(&header))
+	require.NoError(t, err)
+	assert.NotEmpty(t, shortenResp.GetShortUrl())
+	assert.False(t, shortenResp.GetAlreadyExists())
+
+	tokens := header.Get(authMetadataKey)
+	require.Len(t, tokens, 1)
+	authCtx := metadata.AppendToOutgoingContext(ctx, authMetadataKey, tokens[0])
+
+	t.Run("Shorten existing URL", func(t *testing.T) {
+		resp, err := client.Shorten(authCtx, &pb.ShortenRequest{Url: "https://practicum.yandex.ru/"})
+		require.NoError(t, err)
+		assert.True(t, resp.GetAlreadyExists())
+		assert.Equal(t, shortenResp.GetShortUrl(), resp.GetShortUrl())
+	})
+
+	t.Run("Shorten with invalid expires_at", func(t *testing.T) {
+		_, err := client.Shorten(authCtx, &pb.ShortenRequest{Url: "https://example.com", ExpiresAt: "tomorrow"})
+		assert.Equal(t, codes.InvalidArgument, status.Code(err))
+	})
+
+	t.Run("Shorten with out-of-range ttl", func(t *testing.T) {
+		for _, ttl := range []int64{-1, math.MaxInt64} {
+			_, err := client.Shorten(authCtx, &pb.ShortenRequest{Url: "https://example.com", Ttl: ttl})
+			assert.Equal(t, codes.InvalidArgument, status.Code(err), ttl)
+		}
+	})
+
+	t.Run("ShortenBatch", func(t *testing.T) {
+		resp, err := client.ShortenBatch(authCtx, &pb.ShortenBatchRequest{Items: []*pb.BatchItem{
+			{CorrelationId: "1", OriginalUrl: "https://example.com/1"},
+			{CorrelationId: "2", OriginalUrl: "https://example.com/2"},
+		}})
+		require.NoError(t, err)
+		require.Len(t, resp.GetItems(), 2)
+		assert.Equal(t, "1", resp.GetItems()[0].GetCorrelationId())
+	})
+
+	t.Run("Get", func(t *testing.T) {
+		id := shortenResp.GetShortUrl()[len("http://localhost:8080/"):]
+		resp, err := client.Get(authCtx, &pb.GetRequest{Id: id})
+		require.NoError(t, err)
+		assert.Equal(t, "https://practicum.yandex.ru/", resp.GetOriginalUrl())
+
+		_, err = client.Get(authCtx, &pb.GetRequest{Id: "missing"})
+		assert.Equal(t, codes.NotFound, status.Code(err))
+	})
+
+	t.Run("ListUserURLs", func(t *testing.T) {
+		resp, err := client.ListUserURLs(authCtx, &pb.ListUserURLsRequest{})
+		require.NoError(t, err)
+		assert.Len(t, resp.GetUrls(), 3)
+
+		// Другой пользователь не видит чужие URL
+		resp, err = client.ListUserURLs(ctx, &pb.ListUserURLsRequest{})
+		require.NoError(t, err)
+		assert.Empty(t, resp.GetUrls())
+	})
+
+	t.Run("DeleteUserURLs", func(t *testing.T) {
+		resp, err := client.DeleteUserURLs(authCtx, &pb.DeleteUserURLsRequest{ShortUrls: []string{shortenResp.GetShortUrl()}})
+		require.NoError(t, err)
+		assert.NotEmpty(t, resp.GetJobId())
+		assert.Equal(t, url.DeletionStatusPending, resp.GetStatus())
+	})
+
+	t.Run("Ping", func(t *testing.T) {
+		_, err := client.Ping(ctx, &pb.PingRequest{})
+		assert.NoError(t, err)
+	})
+
+	t.Run("Invalid token", func(t *testing.T) {
+		badCtx := metadata.AppendToOutgoingContext(ctx, authMetadataKey, "Bearer invalid")
+		_, err := client.ListUserURLs(badCtx, &pb.ListUserURLsRequest{})
+		assert.Equal(t, codes.Unauthenticated, status.Code(err))
+	})
+}
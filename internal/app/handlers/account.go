@@ -0,0 +1,122 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/alexuryumtsev/go-shortener/internal/app/middleware"
+	"github.com/alexuryumtsev/go-shortener/internal/app/models"
+	"github.com/alexuryumtsev/go-shortener/internal/app/service/account"
+	"github.com/alexuryumtsev/go-shortener/internal/app/service/user"
+)
+
+// RegisterHandler регистрирует пользователя и выполняет вход.
+// Ссылки, созданные текущим анонимным пользователем, переносятся в новый аккаунт.
+//
+// Принимает:
+//   - JSON: {"login": "alice", "password": "..."}
+//
+// Возвращает:
+//   - В случае успеха:
+//     Код: 201 Created
+//     Тело: {"user_id": "...", "login": "alice", "merged_urls": 2}
+//     Токен аккаунта устанавливается в куку auth_token и заголовок Authorization
+//   - В случае ошибки:
+//     Код: 400 Bad Request - если логин или пароль не соответствуют требованиям
+//     Код: 409 Conflict - если логин занят
+func RegisterHandler(accounts account.Service, userService user.UserService) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		var req models.CredentialsRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			http.Error(w, "Invalid request body", http.StatusBadRequest)
+			return
+		}
+
+		ctx, cancel := context.WithTimeout(r.Context(), 5*time.Second)
+		defer cancel()
+
+		acc, merged, err := accounts.Register(ctx, req.Login, req.Password, anonymousUserID(r))
+		if err != nil {
+			switch {
+			case errors.Is(err, account.ErrInvalidLogin), errors.Is(err, account.ErrInvalidPassword):
+				http.Error(w, err.Error(), http.StatusBadRequest)
+			case errors.Is(err, account.ErrLoginTaken):
+				http.Error(w, "Login is already taken", http.StatusConflict)
+			default:
+				http.Error(w, "Failed to register", http.StatusInternalServerError)
+			}
+			return
+		}
+
+		writeAuthResponse(w, userService, acc, merged, http.StatusCreated)
+	}
+}
+
+// LoginHandler выполняет вход по логину и паролю.
+// Ссылки, созданные текущим анонимным пользователем, переносятся в аккаунт.
+//
+// Принимает:
+//   - JSON: {"login": "alice", "password": "..."}
+//
+// Возвращает:
+//   - В случае успеха:
+//     Код: 200 OK
+//     Тело: {"user_id": "...", "login": "alice", "merged_urls": 0}
+//     Токен аккаунта устанавливается в куку auth_token и заголовок Authorization
+//   - В случае ошибки:
+//     Код: 401 Unauthorized - если логин или пароль неверны
+func LoginHandler(accounts account.Service, userService user.UserService) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		var req models.CredentialsRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			http.Error(w, "Invalid request body", http.StatusBadRequest)
+			return
+		}
+
+		ctx, cancel := context.WithTimeout(r.Context(), 5*time.Second)
+		defer cancel()
+
+		acc, merged, err := accounts.Login(ctx, req.Login, req.Password, anonymousUserID(r))
+		if err != nil {
+			if errors.Is(err, account.ErrInvalidCredentials) {
+				http.Error(w, "Invalid login or password", http.StatusUnauthorized)
+				return
+			}
+			http.Error(w, "Failed to log in", http.StatusInternalServerError)
+			return
+		}
+
+		writeAuthResponse(w, userService, acc, merged, http.StatusOK)
+	}
+}
+
+// anonymousUserID возвращает ID текущего анонимного пользователя, ссылки которого
+// переносятся в аккаунт. Для зарегистрированных пользователей и запросов,
+// аутентифицированных API-ключом, перенос не выполняется.
+func anonymousUserID(r *http.Request) string {
+	identity, ok := user.IdentityFromContext(r.Context())
+	if !ok || !identity.Anonymous || identity.Method == user.AuthMethodAPIKey {
+		return ""
+	}
+	return identity.UserID
+}
+
+// writeAuthResponse выдаёт токен аккаунта и отправляет ответ с кодом status.
+func writeAuthResponse(w http.ResponseWriter, userService user.UserService, acc models.UserModel, merged, status int) {
+	token, err := userService.GenerateTokenForUser(acc.ID)
+	if err != nil {
+		http.Error(w, "Failed to generate token", http.StatusInternalServerError)
+		return
+	}
+	middleware.SetAuthCookie(w, token)
+	w.Header().Set("Authorization", "Bearer "+token)
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	if err := json.NewEncoder(w).Encode(models.AuthResponse{UserID: acc.ID, Login: acc.Login, MergedURLs: merged}); err != nil {
+		http.Error(w, "Failed to encode response", http.StatusInternalServerError)
+	}
+}
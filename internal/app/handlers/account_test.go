@@ -0,0 +1,64 @@
+package handlers
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/alexuryumtsev/go-shortener/internal/app/models"
+	"github.com/alexuryumtsev/go-shortener/internal/app/service/account"
+	"github.com/alexuryumtsev/go-shortener/internal/app/service/user"
+	"github.com/alexuryumtsev/go-shortener/internal/app/storage/memory"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAccountHandlers(t *testing.T) {
+	accounts := account.NewService(memory.NewInMemoryStorage())
+	userService := user.NewUserService("test-secret")
+	register := RegisterHandler(accounts, userService)
+	login := LoginHandler(accounts, userService)
+
+	tests := []struct {
+		name    string
+		handler http.HandlerFunc
+		body    string
+		want    int
+	}{
+		{"Register", register, `{"login":"alice","password":"correct horse"}`, http.StatusCreated},
+		{"Register taken login", register, `{"login":"alice","password":"correct horse"}`, http.StatusConflict},
+		{"Register weak password", register, `{"login":"bob","password":"123"}`, http.StatusBadRequest},
+		{"Register invalid body", register, `{`, http.StatusBadRequest},
+		{"Login", login, `{"login":"alice","password":"correct horse"}`, http.StatusOK},
+		{"Login wrong password", login, `{"login":"alice","password":"wrong password"}`, http.StatusUnauthorized},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodPost, "/", bytes.NewBufferString(tt.body))
+			rec := httptest.NewRecorder()
+
+			tt.handler.ServeHTTP(rec, req)
+
+			require.Equal(t, tt.want, rec.Code)
+			if tt.want >= 300 {
+				return
+			}
+
+			var resp models.AuthResponse
+			require.NoError(t, json.NewDecoder(rec.Body).Decode(&resp))
+			assert.Equal(t, "alice", resp.Login)
+
+			// Выданный токен принадлежит аккаунту
+			cookies := rec.Result().Cookies()
+			require.Len(t, cookies, 1)
+			identity, err := userService.Authenticate(cookies[0].Value)
+			require.NoError(t, err)
+			assert.Equal(t, resp.UserID, identity.UserID)
+			assert.True(t, identity.Authenticated())
+			assert.Equal(t, "Bearer "+cookies[0].Value, rec.Header().Get("Authorization"))
+		})
+	}
+}
@@ -0,0 +1,148 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/alexuryumtsev/go-shortener/internal/app/models"
+	"github.com/alexuryumtsev/go-shortener/internal/app/service/apikey"
+	"github.com/alexuryumtsev/go-shortener/internal/app/service/user"
+	"github.com/go-chi/chi/v5"
+)
+
+// keyOwner возвращает ID пользователя, управляющего API-ключами.
+// Управлять ключами с помощью самого API-ключа нельзя, чтобы утёкший ключ
+// не позволял выпустить новые; в этом случае отвечает 403 и возвращает пустую строку.
+func keyOwner(w http.ResponseWriter, r *http.Request) string {
+	identity, ok := user.IdentityFromContext(r.Context())
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return ""
+	}
+	if identity.Method == user.AuthMethodAPIKey {
+		http.Error(w, "API keys cannot manage API keys", http.StatusForbidden)
+		return ""
+	}
+	return identity.UserID
+}
+
+// CreateAPIKeyHandler создаёт API-ключ текущего пользователя.
+//
+// Принимает:
+//   - JSON: {"name": "ci", "scopes": ["urls:read", "urls:write"]}
+//
+// Возвращает:
+//   - В случае успеха:
+//     Код: 201 Created
+//     Тело: {"id": "...", "name": "ci", "scopes": [...], "created_at": "...", "key": "shk_..."}
+//     Значение key показывается только в этом ответе
+//   - В случае ошибки:
+//     Код: 400 Bad Request - если имя пустое или область действия неизвестна
+//     Код: 401 Unauthorized - если пользователь не определён
+//     Код: 403 Forbidden - если запрос аутентифицирован API-ключом
+func CreateAPIKeyHandler(apiKeys apikey.Service) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		userID := keyOwner(w, r)
+		if userID == "" {
+			return
+		}
+
+		var req models.APIKeyRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			http.Error(w, "Invalid request body", http.StatusBadRequest)
+			return
+		}
+
+		ctx, cancel := context.WithTimeout(r.Context(), 5*time.Second)
+		defer cancel()
+
+		apiKey, key, err := apiKeys.Create(ctx, userID, req.Name, req.Scopes)
+		if err != nil {
+			if errors.Is(err, apikey.ErrInvalidName) || errors.Is(err, apikey.ErrInvalidScope) {
+				http.Error(w, err.Error(), http.StatusBadRequest)
+				return
+			}
+			http.Error(w, "Failed to create API key", http.StatusInternalServerError)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusCreated)
+		if err := json.NewEncoder(w).Encode(models.APIKeyResponse{APIKeyModel: apiKey, Key: key}); err != nil {
+			http.Error(w, "Failed to encode response", http.StatusInternalServerError)
+		}
+	}
+}
+
+// ListAPIKeysHandler возвращает API-ключи текущего пользователя без их значений.
+//
+// Возвращает:
+//   - В случае успеха:
+//     Код: 200 OK
+//     Тело: [{"id": "...", "name": "...", "scopes": [...], "created_at": "...", "last_used_at": "..."}]
+//     Код: 204 No Content - если ключей нет
+//   - В случае ошибки:
+//     Код: 401 Unauthorized - если пользователь не определён
+//     Код: 403 Forbidden - если запрос аутентифицирован API-ключом
+func ListAPIKeysHandler(apiKeys apikey.Service) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		userID := keyOwner(w, r)
+		if userID == "" {
+			return
+		}
+
+		ctx, cancel := context.WithTimeout(r.Context(), 5*time.Second)
+		defer cancel()
+
+		keys, err := apiKeys.List(ctx, userID)
+		if err != nil {
+			http.Error(w, "Failed to get API keys", http.StatusInternalServerError)
+			return
+		}
+
+		if len(keys) == 0 {
+			w.WriteHeader(http.StatusNoContent)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		if err := json.NewEncoder(w).Encode(keys); err != nil {
+			http.Error(w, "Failed to encode response", http.StatusInternalServerError)
+		}
+	}
+}
+
+// RevokeAPIKeyHandler отзывает API-ключ текущего пользователя.
+//
+// Возвращает:
+//   - В случае успеха:
+//     Код: 204 No Content
+//   - В случае ошибки:
+//     Код: 401 Unauthorized - если пользователь не определён
+//     Код: 403 Forbidden - если запрос аутентифицирован API-ключом
+//     Код: 404 Not Found - если ключ не найден или принадлежит другому пользователю
+func RevokeAPIKeyHandler(apiKeys apikey.Service) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		userID := keyOwner(w, r)
+		if userID == "" {
+			return
+		}
+
+		ctx, cancel := context.WithTimeout(r.Context(), 5*time.Second)
+		defer cancel()
+
+		if err := apiKeys.Revoke(ctx, userID, chi.URLParam(r, "id")); err != nil {
+			if errors.Is(err, apikey.ErrKeyNotFound) {
+				http.Error(w, "API key not found", http.StatusNotFound)
+				return
+			}
+			http.Error(w, "Failed to revoke API key", http.StatusInternalServerError)
+			return
+		}
+
+		w.WriteHeader(http.StatusNoContent)
+	}
+}
@@ -0,0 +1,91 @@
+package handlers
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/alexuryumtsev/go-shortener/internal/app/models"
+	"github.com/alexuryumtsev/go-shortener/internal/app/service/apikey"
+	"github.com/alexuryumtsev/go-shortener/internal/app/service/user"
+	"github.com/alexuryumtsev/go-shortener/internal/app/storage/memory"
+	"github.com/go-chi/chi/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAPIKeyHandlers(t *testing.T) {
+	apiKeys := apikey.NewService(memory.NewInMemoryStorage())
+
+	r := chi.NewRouter()
+	r.Post("/api/user/keys", withUser("test-user", CreateAPIKeyHandler(apiKeys)))
+	r.Get("/api/user/keys", withUser("test-user", ListAPIKeysHandler(apiKeys)))
+	r.Delete("/api/user/keys/{id}", withUser("test-user", RevokeAPIKeyHandler(apiKeys)))
+	r.Get("/other/keys", withUser("other-user", ListAPIKeysHandler(apiKeys)))
+	r.Get("/anonymous/keys", withUser("", ListAPIKeysHandler(apiKeys)))
+	r.Get("/by-key/keys", func(w http.ResponseWriter, r *http.Request) {
+		identity := user.Identity{UserID: "test-user", Method: user.AuthMethodAPIKey, Scopes: []string{apikey.ScopeURLsRead}}
+		ListAPIKeysHandler(apiKeys)(w, r.WithContext(user.ContextWithIdentity(r.Context(), identity)))
+	})
+
+	// Создание ключа
+	req := httptest.NewRequest(http.MethodPost, "/api/user/keys", bytes.NewBufferString(`{"name":"ci","scopes":["urls:read"]}`))
+	rec := httptest.NewRecorder()
+	r.ServeHTTP(rec, req)
+
+	require.Equal(t, http.StatusCreated, rec.Code)
+	var created models.APIKeyResponse
+	require.NoError(t, json.NewDecoder(rec.Body).Decode(&created))
+	assert.NotEmpty(t, created.ID)
+	assert.NotEmpty(t, created.Key)
+	assert.Equal(t, []string{apikey.ScopeURLsRead}, created.Scopes)
+
+	// Некорректная область действия
+	req = httptest.NewRequest(http.MethodPost, "/api/user/keys", bytes.NewBufferString(`{"name":"ci","scopes":["admin"]}`))
+	rec = httptest.NewRecorder()
+	r.ServeHTTP(rec, req)
+	assert.Equal(t, http.StatusBadRequest, rec.Code)
+
+	// Список ключей не содержит их значений
+	req = httptest.NewRequest(http.MethodGet, "/api/user/keys", nil)
+	rec = httptest.NewRecorder()
+	r.ServeHTTP(rec, req)
+
+	require.Equal(t, http.StatusOK, rec.Code)
+	assert.NotContains(t, rec.Body.String(), created.Key)
+	var keys []models.APIKeyModel
+	require.NoError(t, json.NewDecoder(rec.Body).Decode(&keys))
+	require.Len(t, keys, 1)
+	assert.Equal(t, "ci", keys[0].Name)
+
+	// Другой пользователь не видит чужие ключи
+	req = httptest.NewRequest(http.MethodGet, "/other/keys", nil)
+	rec = httptest.NewRecorder()
+	r.ServeHTTP(rec, req)
+	assert.Equal(t, http.StatusNoContent, rec.Code)
+
+	// Запросы, аутентифицированные API-ключом, не могут управлять ключами
+	req = httptest.NewRequest(http.MethodGet, "/by-key/keys", nil)
+	rec = httptest.NewRecorder()
+	r.ServeHTTP(rec, req)
+	assert.Equal(t, http.StatusForbidden, rec.Code)
+
+	// Без личности пользователя
+	req = httptest.NewRequest(http.MethodGet, "/anonymous/keys", nil)
+	rec = httptest.NewRecorder()
+	r.ServeHTTP(rec, req)
+	assert.Equal(t, http.StatusUnauthorized, rec.Code)
+
+	// Отзыв ключа
+	req = httptest.NewRequest(http.MethodDelete, "/api/user/keys/"+created.ID, nil)
+	rec = httptest.NewRecorder()
+	r.ServeHTTP(rec, req)
+	assert.Equal(t, http.StatusNoContent, rec.Code)
+
+	req = httptest.NewRequest(http.MethodDelete, "/api/user/keys/"+created.ID, nil)
+	rec = httptest.NewRecorder()
+	r.ServeHTTP(rec, req)
+	assert.Equal(t, http.StatusNotFound, rec.Code)
+}
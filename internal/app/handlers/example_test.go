@@ -10,6 +10,7 @@ import (
 	"net/http/httptest"
 
 	"github.com/alexuryumtsev/go-shortener/internal/app/handlers"
+	"github.com/alexuryumtsev/go-shortener/internal/app/middleware"
 	"github.com/alexuryumtsev/go-shortener/internal/app/models"
 	"github.com/alexuryumtsev/go-shortener/internal/app/service/user"
 )
@@ -22,6 +23,10 @@ func (m *MockURLService) ShortenerURL(ctx context.Context, originalURL, userID s
 	return "http://localhost:8080/6bdb5b0e", nil
 }
 
+func (m *MockURLService) ShortenerURLWithOptions(ctx context.Context, originalURL, userID string, opts models.ShortenOptions) (string, error) {
+	return "http://localhost:8080/" + opts.Alias, nil
+}
+
 func (m *MockURLService) SaveBatchShortenerURL(ctx context.Context, batchModels []models.URLBatchModel, userID string) ([]models.BatchResponseModel, error) {
 	// Фиксированный ответ для примера
 	var response []models.BatchResponseModel
@@ -42,6 +47,22 @@ func (m *MockURLService) DeleteUserURLsBatch(ctx context.Context, userID string,
 	return nil
 }
 
+func (m *MockURLService) EnqueueDeletion(ctx context.Context, userID string, shortURLs []string) (string, error) {
+	return "job-id", nil
+}
+
+func (m *MockURLService) GetDeletionStatus(ctx context.Context, userID, jobID string) (models.DeletionJobModel, error) {
+	return models.DeletionJobModel{ID: jobID, Status: "completed"}, nil
+}
+
+func (m *MockURLService) DeletionQueueDepth() int {
+	return 0
+}
+
+func (m *MockURLService) Shutdown(ctx context.Context) error {
+	return nil
+}
+
 func (m *MockURLService) GetURLByID(ctx context.Context, id string) (string, bool, error) {
 	return "https://practicum.yandex.ru", true, nil
 }
@@ -55,13 +76,23 @@ func (m *MockURLService) GetUserURLs(ctx context.Context, userID string) ([]mode
 	}, nil
 }
 
+func (m *MockURLService) RecordClick(ctx context.Context, event models.ClickEvent, clientIP string) {}
+
+func (m *MockURLService) GetLinkStats(ctx context.Context, userID, shortURL string) (models.LinkStatsModel, error) {
+	return models.LinkStatsModel{ShortURL: shortURL, Daily: []models.DailyStatsModel{}}, nil
+}
+
+func (m *MockURLService) GetStats(ctx context.Context) (models.StatsModel, error) {
+	return models.StatsModel{}, nil
+}
+
 func ExamplePostHandler() {
 	// Инициализация тестового окружения
 	userService := user.NewMockUserService("test-user")
 	urlService := &MockURLService{}
 
 	// Создание обработчика
-	handler := handlers.PostHandler(urlService, userService)
+	handler := middleware.AuthMiddleware(userService, handlers.PostHandler(urlService))
 
 	// Создание тестового запроса
 	longURL := "https://practicum.yandex.ru"
@@ -88,7 +119,7 @@ func ExamplePostJSONHandler() {
 	urlService := &MockURLService{}
 
 	// Создание обработчика
-	handler := handlers.PostJSONHandler(urlService, userService)
+	handler := middleware.AuthMiddleware(userService, handlers.PostJSONHandler(urlService))
 
 	// Подготовка JSON запроса
 	reqBody := models.RequestBody{URL: "https://practicum.yandex.ru"}
@@ -119,7 +150,7 @@ func ExamplePostBatchHandler() {
 	urlService := &MockURLService{}
 
 	// Создание обработчика
-	handler := handlers.PostBatchHandler(urlService, userService)
+	handler := middleware.AuthMiddleware(userService, handlers.PostBatchHandler(urlService))
 
 	// Подготовка пакетного запроса
 	batchRequest := []models.URLBatchModel{
@@ -159,7 +190,7 @@ func ExampleGetUserURLsHandler() {
 	urlService := &MockURLService{}
 
 	// Создание обработчика
-	handler := handlers.GetUserURLsHandler(urlService, userService)
+	handler := middleware.AuthMiddleware(userService, handlers.GetUserURLsHandler(urlService))
 
 	// Создание тестового запроса
 	req := httptest.NewRequest(http.MethodGet, "/api/user/urls", nil)
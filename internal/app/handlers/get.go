@@ -2,9 +2,13 @@ package handlers
 
 import (
 	"context"
+	"errors"
 	"net/http"
 	"time"
 
+	"github.com/alexuryumtsev/go-shortener/internal/app/metrics"
+	"github.com/alexuryumtsev/go-shortener/internal/app/middleware"
+	"github.com/alexuryumtsev/go-shortener/internal/app/models"
 	"github.com/alexuryumtsev/go-shortener/internal/app/service/url"
 	"github.com/go-chi/chi/v5"
 )
@@ -27,15 +31,35 @@ func GetHandler(urlService url.URLService) http.HandlerFunc {
 
 		// Обрабатываем результат
 		if !exists {
+			metrics.ObserveRedirect(metrics.RedirectMiss)
 			http.Error(w, "URL not found", http.StatusNotFound)
 			return
 		}
 
+		if err != nil {
+			metrics.ObserveRedirect(metrics.RedirectGone)
+		}
+
+		if errors.Is(err, url.ErrURLExpired) {
+			http.Error(w, "This URL is no longer available as it has expired", http.StatusGone)
+			return
+		}
+
 		if err != nil {
 			http.Error(w, "This URL is no longer available as it has been deleted by the owner", http.StatusGone)
 			return
 		}
 
+		metrics.ObserveRedirect(metrics.RedirectHit)
+
+		// Записываем переход асинхронно, чтобы не задерживать редирект
+		urlService.RecordClick(ctx, models.ClickEvent{
+			ShortID:   id,
+			Timestamp: time.Now(),
+			Referrer:  r.Referer(),
+			UserAgent: r.UserAgent(),
+		}, middleware.ClientIP(r))
+
 		// Перенаправляем на оригинальный URL
 		w.Header().Set("Location", originalURL)
 		w.WriteHeader(http.StatusTemporaryRedirect)
@@ -6,14 +6,18 @@ import (
 	"net/http/httptest"
 	"testing"
 
+	"github.com/alexuryumtsev/go-shortener/internal/app/middleware"
 	"github.com/alexuryumtsev/go-shortener/internal/app/models"
+	"github.com/alexuryumtsev/go-shortener/internal/app/validator"
 	"github.com/go-chi/chi/v5"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
 // MockURLServiceForGet - мок-реализация URLService для тестирования Get
 type MockURLServiceForGet struct {
-	urls map[string]string
+	urls   map[string]string
+	clicks []models.ClickEvent
 }
 
 func NewMockURLServiceForGet() *MockURLServiceForGet {
@@ -28,6 +32,10 @@ func (m *MockURLServiceForGet) ShortenerURL(ctx context.Context, originalURL, us
 	return "http://localhost/shortid", nil
 }
 
+func (m *MockURLServiceForGet) ShortenerURLWithOptions(ctx context.Context, originalURL, userID string, opts models.ShortenOptions) (string, error) {
+	return "http://localhost/" + opts.Alias, nil
+}
+
 func (m *MockURLServiceForGet) SaveBatchShortenerURL(ctx context.Context, batchModels []models.URLBatchModel, userID string) ([]models.BatchResponseModel, error) {
 	return nil, nil
 }
@@ -36,6 +44,22 @@ func (m *MockURLServiceForGet) DeleteUserURLsBatch(ctx context.Context, userID s
 	return nil
 }
 
+func (m *MockURLServiceForGet) EnqueueDeletion(ctx context.Context, userID string, shortURLs []string) (string, error) {
+	return "job-id", nil
+}
+
+func (m *MockURLServiceForGet) GetDeletionStatus(ctx context.Context, userID, jobID string) (models.DeletionJobModel, error) {
+	return models.DeletionJobModel{ID: jobID, Status: "completed"}, nil
+}
+
+func (m *MockURLServiceForGet) DeletionQueueDepth() int {
+	return 0
+}
+
+func (m *MockURLServiceForGet) Shutdown(ctx context.Context) error {
+	return nil
+}
+
 func (m *MockURLServiceForGet) GetURLByID(ctx context.Context, id string) (string, bool, error) {
 	url, exists := m.urls[id]
 	if !exists {
@@ -48,6 +72,19 @@ func (m *MockURLServiceForGet) GetUserURLs(ctx context.Context, userID string) (
 	return nil, nil
 }
 
+func (m *MockURLServiceForGet) RecordClick(ctx context.Context, event models.ClickEvent, clientIP string) {
+	event.IPHash = clientIP
+	m.clicks = append(m.clicks, event)
+}
+
+func (m *MockURLServiceForGet) GetLinkStats(ctx context.Context, userID, shortURL string) (models.LinkStatsModel, error) {
+	return models.LinkStatsModel{}, nil
+}
+
+func (m *MockURLServiceForGet) GetStats(ctx context.Context) (models.StatsModel, error) {
+	return models.StatsModel{URLs: len(m.urls)}, nil
+}
+
 func TestGetHandler(t *testing.T) {
 	// Инициализация мок-сервиса и маршрутизатора
 	mockURLService := NewMockURLServiceForGet()
@@ -107,6 +144,37 @@ func TestGetHandler(t *testing.T) {
 	}
 }
 
+func TestGetHandler_RecordsClick(t *testing.T) {
+	mockURLService := NewMockURLServiceForGet()
+	// Запрос приходит через доверенный прокси с адресом httptest по умолчанию
+	proxies, err := validator.ParseSubnets("192.0.2.1")
+	require.NoError(t, err)
+	r := chi.NewRouter()
+	r.Use(middleware.ClientIPMiddleware(proxies))
+	r.Get("/{id}", GetHandler(mockURLService))
+
+	req := httptest.NewRequest(http.MethodGet, "/0dd11111", nil)
+	req.Header.Set("Referer", "https://ref.example.com/")
+	req.Header.Set("User-Agent", "test-agent")
+	req.Header.Set("X-Real-IP", "10.0.0.1")
+	rec := httptest.NewRecorder()
+	r.ServeHTTP(rec, req)
+
+	assert.Equal(t, http.StatusTemporaryRedirect, rec.Code)
+	if assert.Len(t, mockURLService.clicks, 1) {
+		click := mockURLService.clicks[0]
+		assert.Equal(t, "0dd11111", click.ShortID)
+		assert.Equal(t, "https://ref.example.com/", click.Referrer)
+		assert.Equal(t, "test-agent", click.UserAgent)
+		assert.Equal(t, "10.0.0.1", click.IPHash)
+	}
+
+	// Переход по несуществующей ссылке не записывается
+	req = httptest.NewRequest(http.MethodGet, "/1111", nil)
+	r.ServeHTTP(httptest.NewRecorder(), req)
+	assert.Len(t, mockURLService.clicks, 1)
+}
+
 func BenchmarkGetHandler(b *testing.B) {
 	// Подготовка тестового окружения
 	mockURLService := NewMockURLServiceForGet()
@@ -0,0 +1,58 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/alexuryumtsev/go-shortener/internal/app/health"
+	"github.com/alexuryumtsev/go-shortener/internal/app/logger"
+	"github.com/alexuryumtsev/go-shortener/internal/app/storage"
+	"go.uber.org/zap"
+)
+
+// LivenessHandler сообщает, что процесс работает. Хранилище не проверяется,
+// чтобы его недоступность не приводила к перезапуску сервиса.
+//
+// Возвращает:
+//
+//	Код: 200 OK
+//	Тело: {"status": "up"}
+func LivenessHandler() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		writeHealth(w, r, http.StatusOK, map[string]storage.HealthStatus{"status": storage.HealthUp})
+	}
+}
+
+// ReadinessHandler проверяет готовность сервиса принимать запросы.
+// Компоненты в состоянии degraded не делают сервис неготовым.
+//
+// Возвращает:
+//   - Если сервис готов:
+//     Код: 200 OK
+//     Тело: {"status": "up"|"degraded", "components": {"<имя>": {"status": ..., "error": ..., "details": {...}}}}
+//   - Если компонент не работает или сервер завершает работу:
+//     Код: 503 Service Unavailable
+//     Тело: {"status": "down", "components": {...}}
+func ReadinessHandler(probe *health.Probe) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		report := probe.Check(r.Context())
+
+		status := http.StatusOK
+		if !report.Ready() {
+			status = http.StatusServiceUnavailable
+			logger.FromContext(r.Context()).Warn("Service is not ready", zap.Any("components", report.Components))
+		}
+		writeHealth(w, r, status, report)
+	}
+}
+
+// writeHealth записывает результат проверки в формате JSON.
+// Результаты не кэшируются: каждая проверка должна отражать текущее состояние.
+func writeHealth(w http.ResponseWriter, r *http.Request, status int, body any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "no-store")
+	w.WriteHeader(status)
+	if err := json.NewEncoder(w).Encode(body); err != nil {
+		logger.FromContext(r.Context()).Warn("Failed to encode health response", zap.Error(err))
+	}
+}
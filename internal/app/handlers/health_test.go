@@ -0,0 +1,45 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/alexuryumtsev/go-shortener/internal/app/health"
+	"github.com/alexuryumtsev/go-shortener/internal/app/storage"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLivenessHandler(t *testing.T) {
+	rec := httptest.NewRecorder()
+	LivenessHandler().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/healthz", nil))
+
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Equal(t, "application/json", rec.Header().Get("Content-Type"))
+	assert.JSONEq(t, `{"status":"up"}`, rec.Body.String())
+}
+
+func TestReadinessHandler(t *testing.T) {
+	probe := health.NewProbe(storage.NewMockStorage())
+	req := httptest.NewRequest(http.MethodGet, "/readyz", nil)
+
+	rec := httptest.NewRecorder()
+	ReadinessHandler(probe).ServeHTTP(rec, req)
+
+	require.Equal(t, http.StatusOK, rec.Code)
+	assert.Equal(t, "no-store", rec.Header().Get("Cache-Control"))
+	var report health.Report
+	require.NoError(t, json.NewDecoder(rec.Body).Decode(&report))
+	assert.Equal(t, storage.HealthUp, report.Status)
+	assert.Equal(t, storage.HealthUp, report.Components["mock"].Status)
+
+	// Во время завершения работы сервис не готов
+	probe.SetShuttingDown()
+	rec = httptest.NewRecorder()
+	ReadinessHandler(probe).ServeHTTP(rec, req)
+
+	assert.Equal(t, http.StatusServiceUnavailable, rec.Code)
+	assert.JSONEq(t, `{"status":"down","components":{"server":{"status":"down","error":"shutting down"}}}`, rec.Body.String())
+}
@@ -0,0 +1,38 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"time"
+
+	"github.com/alexuryumtsev/go-shortener/internal/app/service/url"
+)
+
+// InternalStatsHandler возвращает сводную статистику сервиса.
+// Доступ ограничивается доверенной подсетью (см. middleware.TrustedSubnetMiddleware).
+//
+// Возвращает:
+//   - В случае успеха:
+//     Код: 200 OK
+//     Тело: {"urls": <int>, "deleted_urls": <int>, "users": <int>}
+//   - В случае ошибки:
+//     Код: 500 Internal Server Error - если не удалось получить статистику
+func InternalStatsHandler(urlService url.URLService) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		// Создаем контекст с таймаутом
+		ctx, cancel := context.WithTimeout(r.Context(), 5*time.Second)
+		defer cancel()
+
+		stats, err := urlService.GetStats(ctx)
+		if err != nil {
+			http.Error(w, "Failed to get stats", http.StatusInternalServerError)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		if err := json.NewEncoder(w).Encode(stats); err != nil {
+			http.Error(w, "Failed to encode response", http.StatusInternalServerError)
+		}
+	}
+}
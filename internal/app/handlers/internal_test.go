@@ -0,0 +1,40 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/alexuryumtsev/go-shortener/internal/app/models"
+	"github.com/alexuryumtsev/go-shortener/internal/app/service/url"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestInternalStatsHandler(t *testing.T) {
+	mockURLService := url.NewMockURLService("http://localhost", nil)
+	mockURLService.AddURL("0dd11111", "https://practicum.yandex.ru/", "user1")
+	mockURLService.AddURL("0dd11112", "https://example.com/", "user1")
+	mockURLService.AddURL("0dd11113", "https://example.org/", "user2")
+	mockURLService.MarkURLAsDeleted("0dd11113")
+
+	req := httptest.NewRequest(http.MethodGet, "/api/internal/stats", nil)
+	rec := httptest.NewRecorder()
+	InternalStatsHandler(mockURLService).ServeHTTP(rec, req)
+
+	require.Equal(t, http.StatusOK, rec.Code)
+	assert.Equal(t, "application/json", rec.Header().Get("Content-Type"))
+
+	var stats models.StatsModel
+	require.NoError(t, json.NewDecoder(rec.Body).Decode(&stats))
+	assert.Equal(t, models.StatsModel{URLs: 2, DeletedURLs: 1, Users: 2}, stats)
+
+	// Ошибка хранилища
+	mockURLService.SetError(errors.New("storage error"))
+	rec = httptest.NewRecorder()
+	InternalStatsHandler(mockURLService).ServeHTTP(rec, req)
+
+	assert.Equal(t, http.StatusInternalServerError, rec.Code)
+}
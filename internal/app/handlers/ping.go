@@ -2,13 +2,18 @@
 package handlers
 
 import (
+	"context"
 	"net/http"
 
 	"github.com/alexuryumtsev/go-shortener/internal/app/storage"
-	"github.com/alexuryumtsev/go-shortener/internal/app/storage/pg"
 )
 
-// PingHandler проверяет соединение с базой данных.
+// pinger — хранилище, умеющее проверять своё соединение.
+type pinger interface {
+	Ping(ctx context.Context) error
+}
+
+// PingHandler проверяет соединение с хранилищем, если оно поддерживает проверку.
 //
 // Возвращает:
 //   - В случае успеха:
@@ -17,13 +22,13 @@ import (
 //     Код: 500 Internal Server Error - если соединение с БД не установлено
 func PingHandler(repo storage.URLStorage) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
-		// Проверяем, является ли хранилище экземпляром DatabaseStorage
-		if dbRepo, ok := repo.(*pg.DatabaseStorage); ok {
-			if err := dbRepo.Ping(r.Context()); err != nil {
+		// Хранилище может быть обёрнуто, поэтому проверяется интерфейс, а не тип
+		if p, ok := repo.(pinger); ok {
+			if err := p.Ping(r.Context()); err != nil {
 				http.Error(w, "Database connection error", http.StatusInternalServerError)
 				return
 			}
-			w.WriteHeader(http.StatusOK)
 		}
+		w.WriteHeader(http.StatusOK)
 	}
 }
@@ -3,20 +3,22 @@ package handlers
 import (
 	"context"
 	"encoding/json"
+	"fmt"
 	"io"
-	"log"
 	"net/http"
+	"strconv"
 	"strings"
 	"time"
 
+	"github.com/alexuryumtsev/go-shortener/internal/app/logger"
 	"github.com/alexuryumtsev/go-shortener/internal/app/middleware"
 	"github.com/alexuryumtsev/go-shortener/internal/app/models"
 	"github.com/alexuryumtsev/go-shortener/internal/app/service/url"
-	"github.com/alexuryumtsev/go-shortener/internal/app/service/user"
+	"go.uber.org/zap"
 )
 
 // PostHandler обрабатывает POST-запросы для создания короткого URL.
-func PostHandler(urlService url.URLService, userService user.UserService) http.HandlerFunc {
+func PostHandler(urlService url.URLService) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 		// Создаем контекст с таймаутом
 		ctx, cancel := context.WithTimeout(r.Context(), 5*time.Second)
@@ -32,16 +34,21 @@ func PostHandler(urlService url.URLService, userService user.UserService) http.H
 		defer func() {
 			if closeErr := r.Body.Close(); closeErr != nil {
 				// Логируем ошибку закрытия тела запроса
-				log.Printf("Error closing request body: %v", closeErr)
+				logger.FromContext(r.Context()).Warn("Error closing request body", zap.Error(closeErr))
 			}
 		}()
 
-		// Получаем данные
+		// Получаем данные, алиас и срок действия передаются в параметрах запроса
 		originalURL := strings.TrimSpace(string(body))
-		userID := userService.GetUserIDFromCookie(r)
+		opts, optsErr := queryShortenOptions(r)
+		if optsErr != nil {
+			http.Error(w, optsErr.Error(), http.StatusBadRequest)
+			return
+		}
+		userID := currentUserID(r)
 
 		// Вызываем бизнес-логику
-		shortenedURL, shortenerErr := urlService.ShortenerURL(ctx, originalURL, userID)
+		shortenedURL, shortenerErr := shorten(ctx, urlService, originalURL, userID, opts)
 
 		// Обрабатываем результат
 		if shortenerErr != nil {
@@ -55,7 +62,7 @@ func PostHandler(urlService url.URLService, userService user.UserService) http.H
 }
 
 // PostJSONHandler обрабатывает POST-запросы для создания короткого URL в формате JSON.
-func PostJSONHandler(urlService url.URLService, userService user.UserService) http.HandlerFunc {
+func PostJSONHandler(urlService url.URLService) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 		// Создаем контекст с таймаутом
 		ctx, cancel := context.WithTimeout(r.Context(), 5*time.Second)
@@ -70,10 +77,20 @@ func PostJSONHandler(urlService url.URLService, userService user.UserService) ht
 		defer r.Body.Close()
 
 		// Получаем данные
-		userID := userService.GetUserIDFromCookie(r)
+		userID := currentUserID(r)
+
+		ttl, err := url.TTLFromSeconds(req.TTL)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
 
 		// Вызываем бизнес-логику
-		shortenedURL, err := urlService.ShortenerURL(ctx, req.URL, userID)
+		opts := models.ShortenOptions{Alias: req.Alias, TTL: ttl}
+		if req.ExpiresAt != nil {
+			opts.ExpiresAt = *req.ExpiresAt
+		}
+		shortenedURL, err := shorten(ctx, urlService, req.URL, userID, opts)
 
 		// Обрабатываем результат
 		if err != nil {
@@ -95,7 +112,7 @@ func PostJSONHandler(urlService url.URLService, userService user.UserService) ht
 }
 
 // PostBatchHandler обрабатывает POST-запросы для создания множества коротких URL.
-func PostBatchHandler(urlService url.URLService, userService user.UserService) http.HandlerFunc {
+func PostBatchHandler(urlService url.URLService) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 		// Создаем контекст с таймаутом
 		ctx, cancel := context.WithTimeout(r.Context(), 10*time.Second)
@@ -110,7 +127,7 @@ func PostBatchHandler(urlService url.URLService, userService user.UserService) h
 		defer r.Body.Close()
 
 		// Получаем данные
-		userID := userService.GetUserIDFromCookie(r)
+		userID := currentUserID(r)
 
 		// Вызываем бизнес-логику
 		responseModels, err := urlService.SaveBatchShortenerURL(ctx, batchModels, userID)
@@ -128,3 +145,38 @@ func PostBatchHandler(urlService url.URLService, userService user.UserService) h
 		}
 	}
 }
+
+// shorten создаёт короткий URL с дополнительными параметрами, если они заданы.
+func shorten(ctx context.Context, urlService url.URLService, originalURL, userID string, opts models.ShortenOptions) (string, error) {
+	if opts == (models.ShortenOptions{}) {
+		return urlService.ShortenerURL(ctx, originalURL, userID)
+	}
+	return urlService.ShortenerURLWithOptions(ctx, originalURL, userID, opts)
+}
+
+// queryShortenOptions читает алиас (alias), TTL в секундах (ttl)
+// и срок действия в формате RFC 3339 (expires_at) из параметров запроса.
+func queryShortenOptions(r *http.Request) (models.ShortenOptions, error) {
+	query := r.URL.Query()
+	opts := models.ShortenOptions{Alias: query.Get("alias")}
+
+	if ttl := query.Get("ttl"); ttl != "" {
+		seconds, err := strconv.ParseInt(ttl, 10, 64)
+		if err != nil {
+			return opts, fmt.Errorf("invalid ttl: %w", err)
+		}
+		if opts.TTL, err = url.TTLFromSeconds(seconds); err != nil {
+			return opts, err
+		}
+	}
+
+	if expiresAt := query.Get("expires_at"); expiresAt != "" {
+		t, err := time.Parse(time.RFC3339, expiresAt)
+		if err != nil {
+			return opts, fmt.Errorf("invalid expires_at: %w", err)
+		}
+		opts.ExpiresAt = t
+	}
+
+	return opts, nil
+}
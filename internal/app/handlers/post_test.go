@@ -4,6 +4,7 @@ import (
 	"bytes"
 	"encoding/json"
 	"io"
+	"math"
 	"net/http"
 	"net/http/httptest"
 	"strings"
@@ -11,16 +12,14 @@ import (
 
 	"github.com/alexuryumtsev/go-shortener/internal/app/models"
 	"github.com/alexuryumtsev/go-shortener/internal/app/service/url"
-	"github.com/alexuryumtsev/go-shortener/internal/app/service/user"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 )
 
 func TestPostHandler(t *testing.T) {
 	userID := "test-user"
-	mockUserService := user.NewMockUserService(userID)
 	mockURLService := url.NewMockURLService("http://localhost:8080/", nil)
-	handler := PostHandler(mockURLService, mockUserService)
+	handler := withUser(userID, PostHandler(mockURLService))
 
 	type want struct {
 		code        int
@@ -30,6 +29,7 @@ func TestPostHandler(t *testing.T) {
 
 	testCases := []struct {
 		name     string
+		target   string
 		inputURL string
 		want     want
 	}{
@@ -51,12 +51,36 @@ func TestPostHandler(t *testing.T) {
 				contentType: "text/plain; charset=utf-8",
 			},
 		},
+		{
+			name:     "Negative TTL",
+			target:   "/?ttl=-1",
+			inputURL: "https://practicum.yandex.ru/",
+			want: want{
+				code:        http.StatusBadRequest,
+				body:        "invalid expiry",
+				contentType: "text/plain; charset=utf-8",
+			},
+		},
+		{
+			name:     "TTL overflowing time.Duration",
+			target:   "/?ttl=9223372036854775807",
+			inputURL: "https://practicum.yandex.ru/",
+			want: want{
+				code:        http.StatusBadRequest,
+				body:        "invalid expiry",
+				contentType: "text/plain; charset=utf-8",
+			},
+		},
 	}
 
 	for _, tc := range testCases {
 		t.Run(tc.name, func(t *testing.T) {
+			target := tc.target
+			if target == "" {
+				target = "/"
+			}
 			// тестовый HTTP-запрос.
-			req := httptest.NewRequest(http.MethodPost, "/", bytes.NewBufferString(tc.inputURL))
+			req := httptest.NewRequest(http.MethodPost, target, bytes.NewBufferString(tc.inputURL))
 			rec := httptest.NewRecorder()
 			handler(rec, req)
 
@@ -76,9 +100,8 @@ func TestPostJsonHandler(t *testing.T) {
 	userID := "test-user"
 
 	// тестовое хранилище.
-	mockUserService := user.NewMockUserService(userID)
 	mockURLService := url.NewMockURLService("http://localhost:8080/", nil)
-	handler := PostJSONHandler(mockURLService, mockUserService)
+	handler := withUser(userID, PostJSONHandler(mockURLService))
 
 	type want struct {
 		code         int
@@ -114,6 +137,15 @@ func TestPostJsonHandler(t *testing.T) {
 				contentType:  "Content-Type: application/json",
 			},
 		},
+		{
+			name: "TTL overflowing time.Duration",
+			want: want{
+				code:         http.StatusBadRequest,
+				body:         models.RequestBody{URL: "https://practicum.yandex.ru/", TTL: math.MaxInt64},
+				expectedBody: models.ResponseBody{},
+				contentType:  "Content-Type: application/json",
+			},
+		},
 	}
 
 	for _, tc := range testCases {
@@ -144,12 +176,11 @@ func TestPostJsonHandler(t *testing.T) {
 func BenchmarkPostHandlers(b *testing.B) {
 	// Подготовка тестовых данных
 	userID := "test-user"
-	mockUserService := user.NewMockUserService(userID)
 	mockURLService := url.NewMockURLService("http://localhost:8080/", nil)
 
 	// Подготовка обработчиков
-	plainHandler := PostHandler(mockURLService, mockUserService)
-	jsonHandler := PostJSONHandler(mockURLService, mockUserService)
+	plainHandler := withUser(userID, PostHandler(mockURLService))
+	jsonHandler := withUser(userID, PostJSONHandler(mockURLService))
 
 	// Тестовые данные
 	testURL := "https://practicum.yandex.ru/"
@@ -3,22 +3,25 @@ package handlers
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"net/http"
 	"time"
 
+	"github.com/alexuryumtsev/go-shortener/internal/app/models"
 	"github.com/alexuryumtsev/go-shortener/internal/app/service/url"
 	"github.com/alexuryumtsev/go-shortener/internal/app/service/user"
+	"github.com/go-chi/chi/v5"
 )
 
 // GetUserURLsHandler возвращает все URL текущего пользователя.
-func GetUserURLsHandler(urlService url.URLService, userService user.UserService) http.HandlerFunc {
+func GetUserURLsHandler(urlService url.URLService) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 		// Создаем контекст с таймаутом
 		ctx, cancel := context.WithTimeout(r.Context(), 5*time.Second)
 		defer cancel()
 
 		// Получаем ID пользователя
-		userID := userService.GetUserIDFromCookie(r)
+		userID := currentUserID(r)
 		if userID == "" {
 			http.Error(w, "Unauthorized", http.StatusUnauthorized)
 			return
@@ -46,11 +49,17 @@ func GetUserURLsHandler(urlService url.URLService, userService user.UserService)
 	}
 }
 
-// DeleteUserURLsHandler удаляет URL пользователя.
-func DeleteUserURLsHandler(urlService url.URLService, userService user.UserService) http.HandlerFunc {
+// DeleteUserURLsHandler ставит удаление URL пользователя в очередь.
+//
+// Возвращает:
+//   - В случае успеха:
+//     Код: 202 Accepted
+//     Тело: {"id": "<job_id>", "status": "pending"}
+//     Заголовок Location указывает на адрес проверки статуса задачи
+func DeleteUserURLsHandler(urlService url.URLService) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 		// Получаем ID пользователя
-		userID := userService.GetUserIDFromCookie(r)
+		userID := currentUserID(r)
 		if userID == "" {
 			http.Error(w, "Unauthorized", http.StatusUnauthorized)
 			return
@@ -63,21 +72,108 @@ func DeleteUserURLsHandler(urlService url.URLService, userService user.UserServi
 			return
 		}
 
-		// Запускаем асинхронный процесс удаления
-		go func() {
-			// Создаем отдельный контекст для асинхронной операции
-			deleteCtx, deleteCancel := context.WithTimeout(context.Background(), 30*time.Second)
-			defer deleteCancel()
-
-			if err := urlService.DeleteUserURLsBatch(deleteCtx, userID, shortURLs); err != nil {
-				if err := urlService.DeleteUserURLsBatch(r.Context(), userID, shortURLs); err != nil {
-					http.Error(w, "Internal Server Error", http.StatusInternalServerError)
-					return
-				}
+		// Ставим удаление в очередь
+		ctx, cancel := context.WithTimeout(r.Context(), 5*time.Second)
+		defer cancel()
+
+		jobID, err := urlService.EnqueueDeletion(ctx, userID, shortURLs)
+		if err != nil {
+			if errors.Is(err, url.ErrDeletionQueueClosed) {
+				http.Error(w, "Service is shutting down", http.StatusServiceUnavailable)
+				return
 			}
-		}()
+			http.Error(w, "Failed to enqueue deletion", http.StatusInternalServerError)
+			return
+		}
 
 		// Сразу возвращаем статус 202, т.к. удаление асинхронное
+		w.Header().Set("Content-Type", "application/json")
+		w.Header().Set("Location", "/api/user/deletions/"+jobID)
 		w.WriteHeader(http.StatusAccepted)
+		if err := json.NewEncoder(w).Encode(models.DeletionJobModel{ID: jobID, Status: url.DeletionStatusPending}); err != nil {
+			http.Error(w, "Failed to encode response", http.StatusInternalServerError)
+		}
 	}
 }
+
+// GetDeletionStatusHandler возвращает состояние задачи удаления текущего пользователя.
+//
+// Возвращает:
+//   - В случае успеха:
+//     Код: 200 OK
+//     Тело: {"id": "<job_id>", "status": "pending|completed|failed", "error": "..."}
+//   - В случае ошибки:
+//     Код: 401 Unauthorized - если пользователь не определён
+//     Код: 404 Not Found - если задача не найдена или принадлежит другому пользователю
+func GetDeletionStatusHandler(urlService url.URLService) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		// Получаем ID пользователя
+		userID := currentUserID(r)
+		if userID == "" {
+			http.Error(w, "Unauthorized", http.StatusUnauthorized)
+			return
+		}
+
+		jobID := chi.URLParam(r, "id")
+		job, err := urlService.GetDeletionStatus(r.Context(), userID, jobID)
+		if err != nil {
+			if errors.Is(err, url.ErrDeletionJobNotFound) {
+				http.Error(w, "Deletion job not found", http.StatusNotFound)
+				return
+			}
+			http.Error(w, "Failed to get deletion status", http.StatusInternalServerError)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		if err := json.NewEncoder(w).Encode(job); err != nil {
+			http.Error(w, "Failed to encode response", http.StatusInternalServerError)
+		}
+	}
+}
+
+// GetLinkStatsHandler возвращает статистику переходов по ссылке текущего пользователя.
+//
+// Возвращает:
+//   - В случае успеха:
+//     Код: 200 OK
+//     Тело: {"short_url": "...", "total_clicks": 10, "unique_visitors": 3, "daily": [...]}
+//   - В случае ошибки:
+//     Код: 401 Unauthorized - если пользователь не определён
+//     Код: 404 Not Found - если ссылка не найдена или принадлежит другому пользователю
+func GetLinkStatsHandler(urlService url.URLService) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		// Создаем контекст с таймаутом
+		ctx, cancel := context.WithTimeout(r.Context(), 5*time.Second)
+		defer cancel()
+
+		// Получаем ID пользователя
+		userID := currentUserID(r)
+		if userID == "" {
+			http.Error(w, "Unauthorized", http.StatusUnauthorized)
+			return
+		}
+
+		stats, err := urlService.GetLinkStats(ctx, userID, chi.URLParam(r, "id"))
+		if err != nil {
+			if errors.Is(err, url.ErrLinkNotFound) {
+				http.Error(w, "URL not found", http.StatusNotFound)
+				return
+			}
+			http.Error(w, "Failed to get link stats", http.StatusInternalServerError)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		if err := json.NewEncoder(w).Encode(stats); err != nil {
+			http.Error(w, "Failed to encode response", http.StatusInternalServerError)
+		}
+	}
+}
+
+// currentUserID возвращает ID пользователя, которого middleware аутентификации
+// поместила в контекст запроса, или пустую строку.
+func currentUserID(r *http.Request) string {
+	identity, _ := user.IdentityFromContext(r.Context())
+	return identity.UserID
+}
@@ -1,12 +1,15 @@
 package handlers
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
 	"io"
 	"net/http"
 	"net/http/httptest"
+	"strings"
 	"testing"
+	"time"
 
 	"github.com/alexuryumtsev/go-shortener/internal/app/models"
 	"github.com/alexuryumtsev/go-shortener/internal/app/service/url"
@@ -15,6 +18,15 @@ import (
 	"github.com/stretchr/testify/assert"
 )
 
+// withUser оборачивает обработчик так, чтобы запросы выполнялись от имени userID,
+// как после middleware аутентификации. Пустой userID означает отсутствие личности.
+func withUser(userID string, next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		identity := user.Identity{UserID: userID, Method: user.AuthMethodCookie, Anonymous: true}
+		next.ServeHTTP(w, r.WithContext(user.ContextWithIdentity(r.Context(), identity)))
+	}
+}
+
 func TestGetUserURLsHandler(t *testing.T) {
 	// Тестовые случаи
 	tests := []struct {
@@ -69,11 +81,10 @@ func TestGetUserURLsHandler(t *testing.T) {
 			// Подготовка тестового окружения
 			mockURLService := url.NewMockURLService("http://localhost", nil)
 			tt.setupMock(mockURLService)
-			mockUserService := user.NewMockUserService(tt.userID)
 
 			// Создание маршрутизатора и обработчика
 			r := chi.NewRouter()
-			handler := GetUserURLsHandler(mockURLService, mockUserService)
+			handler := withUser(tt.userID, GetUserURLsHandler(mockURLService))
 			r.Get("/api/user/urls", handler)
 
 			// Создание тестового запроса
@@ -116,8 +127,7 @@ func BenchmarkGetUserURLsHandler(b *testing.B) {
 				)
 			}
 
-			mockUserService := user.NewMockUserService(userID)
-			handler := GetUserURLsHandler(mockURLService, mockUserService)
+			handler := withUser(userID, GetUserURLsHandler(mockURLService))
 
 			r := chi.NewRouter()
 			r.Get("/api/user/urls", handler)
@@ -146,3 +156,70 @@ func BenchmarkGetUserURLsHandler(b *testing.B) {
 		})
 	}
 }
+
+func TestDeletionHandlers(t *testing.T) {
+	mockURLService := url.NewMockURLService("http://localhost", nil)
+	mockURLService.AddURL("0dd11111", "https://practicum.yandex.ru/", "test-user")
+
+	r := chi.NewRouter()
+	r.Delete("/api/user/urls", withUser("test-user", DeleteUserURLsHandler(mockURLService)))
+	r.Get("/api/user/deletions/{id}", withUser("test-user", GetDeletionStatusHandler(mockURLService)))
+	r.Get("/other/deletions/{id}", withUser("other-user", GetDeletionStatusHandler(mockURLService)))
+
+	// Постановка задачи удаления
+	req := httptest.NewRequest(http.MethodDelete, "/api/user/urls", strings.NewReader(`["0dd11111"]`))
+	rec := httptest.NewRecorder()
+	r.ServeHTTP(rec, req)
+
+	assert.Equal(t, http.StatusAccepted, rec.Code)
+	var job models.DeletionJobModel
+	assert.NoError(t, json.NewDecoder(rec.Body).Decode(&job))
+	assert.NotEmpty(t, job.ID)
+	assert.Equal(t, "/api/user/deletions/"+job.ID, rec.Header().Get("Location"))
+
+	// Проверка статуса владельцем задачи
+	req = httptest.NewRequest(http.MethodGet, "/api/user/deletions/"+job.ID, nil)
+	rec = httptest.NewRecorder()
+	r.ServeHTTP(rec, req)
+
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.NoError(t, json.NewDecoder(rec.Body).Decode(&job))
+	assert.Equal(t, url.DeletionStatusCompleted, job.Status)
+
+	// Чужая задача не видна
+	req = httptest.NewRequest(http.MethodGet, "/other/deletions/"+job.ID, nil)
+	rec = httptest.NewRecorder()
+	r.ServeHTTP(rec, req)
+
+	assert.Equal(t, http.StatusNotFound, rec.Code)
+}
+
+func TestGetLinkStatsHandler(t *testing.T) {
+	mockURLService := url.NewMockURLService("http://localhost", nil)
+	mockURLService.AddURL("0dd11111", "https://practicum.yandex.ru/", "test-user")
+	mockURLService.RecordClick(context.Background(), models.ClickEvent{ShortID: "0dd11111", Timestamp: time.Now()}, "10.0.0.1")
+	mockURLService.RecordClick(context.Background(), models.ClickEvent{ShortID: "0dd11111", Timestamp: time.Now()}, "10.0.0.1")
+
+	r := chi.NewRouter()
+	r.Get("/api/user/urls/{id}/stats", withUser("test-user", GetLinkStatsHandler(mockURLService)))
+	r.Get("/other/urls/{id}/stats", withUser("other-user", GetLinkStatsHandler(mockURLService)))
+
+	// Статистика владельца ссылки
+	req := httptest.NewRequest(http.MethodGet, "/api/user/urls/0dd11111/stats", nil)
+	rec := httptest.NewRecorder()
+	r.ServeHTTP(rec, req)
+
+	assert.Equal(t, http.StatusOK, rec.Code)
+	var stats models.LinkStatsModel
+	assert.NoError(t, json.NewDecoder(rec.Body).Decode(&stats))
+	assert.Equal(t, "http://localhost/0dd11111", stats.ShortURL)
+	assert.Equal(t, 2, stats.TotalClicks)
+	assert.Equal(t, 1, stats.UniqueVisitors)
+
+	// Чужая ссылка не видна
+	req = httptest.NewRequest(http.MethodGet, "/other/urls/0dd11111/stats", nil)
+	rec = httptest.NewRecorder()
+	r.ServeHTTP(rec, req)
+
+	assert.Equal(t, http.StatusNotFound, rec.Code)
+}
@@ -0,0 +1,88 @@
+// Package health собирает состояние компонентов сервиса для проверок
+// живости (liveness) и готовности (readiness).
+//
+// Живость означает, что процесс работает и отвечает на запросы; она не зависит
+// от хранилища, чтобы недоступная база не приводила к перезапуску сервиса.
+// Готовность означает, что сервис может обслуживать запросы: все компоненты
+// хранилища работают и сервер не завершается.
+package health
+
+import (
+	"context"
+	"sync/atomic"
+	"time"
+
+	"github.com/alexuryumtsev/go-shortener/internal/app/storage"
+)
+
+// DefaultTimeout ограничивает время проверки компонентов.
+const DefaultTimeout = 2 * time.Second
+
+// componentServer — имя компонента, отражающего состояние самого сервера.
+const componentServer = "server"
+
+// Report — результат проверки готовности.
+type Report struct {
+	// Status — HealthDown, если хотя бы один компонент не работает,
+	// HealthDegraded, если хотя бы один близок к отказу, иначе HealthUp
+	Status     storage.HealthStatus               `json:"status"`
+	Components map[string]storage.ComponentHealth `json:"components"`
+}
+
+// Ready сообщает, готов ли сервис принимать запросы.
+func (r Report) Ready() bool {
+	return r.Status != storage.HealthDown
+}
+
+// Probe проверяет готовность сервиса.
+type Probe struct {
+	checkers     []storage.HealthChecker
+	timeout      time.Duration
+	shuttingDown atomic.Bool
+}
+
+// NewProbe создаёт проверку готовности компонентов checkers.
+func NewProbe(checkers ...storage.HealthChecker) *Probe {
+	return &Probe{checkers: checkers, timeout: DefaultTimeout}
+}
+
+// SetShuttingDown отмечает, что сервер завершает работу: с этого момента
+// сервис не готов принимать запросы, и балансировщик перестаёт их направлять.
+func (p *Probe) SetShuttingDown() {
+	p.shuttingDown.Store(true)
+}
+
+// Check проверяет компоненты. Во время завершения работы компоненты не проверяются:
+// хранилище может быть уже закрыто.
+func (p *Probe) Check(ctx context.Context) Report {
+	if p.shuttingDown.Load() {
+		return Report{
+			Status: storage.HealthDown,
+			Components: map[string]storage.ComponentHealth{
+				componentServer: {Status: storage.HealthDown, Error: "shutting down"},
+			},
+		}
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, p.timeout)
+	defer cancel()
+
+	report := Report{
+		Status: storage.HealthUp,
+		Components: map[string]storage.ComponentHealth{
+			componentServer: {Status: storage.HealthUp},
+		},
+	}
+	for _, checker := range p.checkers {
+		for name, component := range checker.CheckHealth(ctx) {
+			report.Components[name] = component
+			switch {
+			case component.Status == storage.HealthDown:
+				report.Status = storage.HealthDown
+			case component.Status == storage.HealthDegraded && report.Status == storage.HealthUp:
+				report.Status = storage.HealthDegraded
+			}
+		}
+	}
+	return report
+}
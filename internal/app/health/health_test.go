@@ -0,0 +1,70 @@
+package health
+
+import (
+	"context"
+	"testing"
+
+	"github.com/alexuryumtsev/go-shortener/internal/app/storage"
+	"github.com/stretchr/testify/assert"
+)
+
+// checkerFunc позволяет использовать функцию как storage.HealthChecker.
+type checkerFunc func(ctx context.Context) map[string]storage.ComponentHealth
+
+func (f checkerFunc) CheckHealth(ctx context.Context) map[string]storage.ComponentHealth {
+	return f(ctx)
+}
+
+// component возвращает проверку одного компонента name с состоянием status.
+func component(name string, status storage.HealthStatus) storage.HealthChecker {
+	return checkerFunc(func(ctx context.Context) map[string]storage.ComponentHealth {
+		return map[string]storage.ComponentHealth{name: {Status: status}}
+	})
+}
+
+func TestProbe_Check(t *testing.T) {
+	tests := []struct {
+		name     string
+		checkers []storage.HealthChecker
+		want     storage.HealthStatus
+	}{
+		{name: "no components", want: storage.HealthUp},
+		{name: "all up", checkers: []storage.HealthChecker{component("db", storage.HealthUp), component("disk", storage.HealthUp)}, want: storage.HealthUp},
+		{name: "degraded", checkers: []storage.HealthChecker{component("db", storage.HealthDegraded), component("disk", storage.HealthUp)}, want: storage.HealthDegraded},
+		{name: "down wins", checkers: []storage.HealthChecker{component("db", storage.HealthDown), component("disk", storage.HealthDegraded)}, want: storage.HealthDown},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			report := NewProbe(tt.checkers...).Check(context.Background())
+			assert.Equal(t, tt.want, report.Status)
+			assert.Equal(t, tt.want != storage.HealthDown, report.Ready())
+			assert.Len(t, report.Components, len(tt.checkers)+1)
+			assert.Equal(t, storage.HealthUp, report.Components[componentServer].Status)
+		})
+	}
+}
+
+func TestProbe_ShuttingDown(t *testing.T) {
+	checked := false
+	probe := NewProbe(checkerFunc(func(ctx context.Context) map[string]storage.ComponentHealth {
+		checked = true
+		return map[string]storage.ComponentHealth{"db": {Status: storage.HealthUp}}
+	}))
+	assert.True(t, probe.Check(context.Background()).Ready())
+
+	checked = false
+	probe.SetShuttingDown()
+	report := probe.Check(context.Background())
+	assert.False(t, report.Ready())
+	assert.False(t, checked, "storage must not be checked during shutdown")
+	assert.Equal(t, storage.ComponentHealth{Status: storage.HealthDown, Error: "shutting down"}, report.Components[componentServer])
+}
+
+func TestProbe_Timeout(t *testing.T) {
+	probe := NewProbe(checkerFunc(func(ctx context.Context) map[string]storage.ComponentHealth {
+		_, ok := ctx.Deadline()
+		assert.True(t, ok, "check must be bounded by a timeout")
+		return nil
+	}))
+	probe.Check(context.Background())
+}
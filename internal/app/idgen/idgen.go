@@ -0,0 +1,197 @@
+// Package idgen предоставляет стратегии генерации коротких идентификаторов URL.
+package idgen
+
+import (
+	"crypto/md5"
+	"crypto/rand"
+	"fmt"
+	"math/big"
+	"strconv"
+	"sync/atomic"
+	"time"
+)
+
+// Названия стратегий генерации.
+const (
+	StrategyHash     = "hash"
+	StrategySequence = "sequence"
+	StrategyRandom   = "random"
+	StrategySqids    = "sqids"
+)
+
+// Длина идентификатора для стратегий с фиксированной длиной.
+const defaultIDLength = 8
+
+// base62Alphabet — алфавит для кодирования идентификаторов.
+const base62Alphabet = "0123456789abcdefghijklmnopqrstuvwxyzABCDEFGHIJKLMNOPQRSTUVWXYZ"
+
+// Generator генерирует кандидатов в короткие идентификаторы.
+// attempt начинается с нуля и увеличивается при каждой коллизии,
+// чтобы детерминированные стратегии могли выдать другой идентификатор.
+type Generator interface {
+	Generate(url string, attempt int) (string, error)
+}
+
+// New создаёт генератор по названию стратегии.
+// salt используется стратегиями hash и sqids; пустое значение допустимо.
+func New(strategy, salt string) (Generator, error) {
+	switch strategy {
+	case "", StrategyHash:
+		return NewHashGenerator(salt), nil
+	case StrategySequence:
+		return NewSequenceGenerator(uint64(time.Now().UnixMilli())), nil
+	case StrategyRandom:
+		return NewRandomGenerator(defaultIDLength), nil
+	case StrategySqids:
+		return NewSqidsGenerator(salt, uint64(time.Now().UnixMilli()), defaultIDLength), nil
+	default:
+		return nil, fmt.Errorf("unknown ID strategy %q", strategy)
+	}
+}
+
+// HashGenerator строит идентификатор из md5 оригинального URL.
+// При коллизии к URL добавляются соль и номер попытки.
+type HashGenerator struct {
+	salt string
+}
+
+// NewHashGenerator создаёт генератор на основе хеша.
+func NewHashGenerator(salt string) *HashGenerator {
+	return &HashGenerator{salt: salt}
+}
+
+// Generate возвращает первые 8 hex-символов md5 от URL.
+// Первая попытка совместима с идентификаторами, созданными ранее.
+func (g *HashGenerator) Generate(url string, attempt int) (string, error) {
+	data := url
+	if attempt > 0 {
+		data = url + g.salt + strconv.Itoa(attempt)
+	}
+	return fmt.Sprintf("%x", md5.Sum([]byte(data)))[:defaultIDLength], nil
+}
+
+// SequenceGenerator кодирует в base62 значения монотонно растущего счётчика.
+type SequenceGenerator struct {
+	counter atomic.Uint64
+}
+
+// NewSequenceGenerator создаёт генератор, счётчик которого начинается со start.
+func NewSequenceGenerator(start uint64) *SequenceGenerator {
+	g := &SequenceGenerator{}
+	g.counter.Store(start)
+	return g
+}
+
+// Generate возвращает следующее значение счётчика; URL и номер попытки не используются.
+func (g *SequenceGenerator) Generate(_ string, _ int) (string, error) {
+	return encodeBase62(g.counter.Add(1)), nil
+}
+
+// RandomGenerator генерирует криптографически случайные идентификаторы.
+type RandomGenerator struct {
+	length int
+}
+
+// NewRandomGenerator создаёт генератор случайных идентификаторов длины length.
+func NewRandomGenerator(length int) *RandomGenerator {
+	return &RandomGenerator{length: length}
+}
+
+// Generate возвращает случайную строку из алфавита base62.
+func (g *RandomGenerator) Generate(_ string, _ int) (string, error) {
+	limit := big.NewInt(int64(len(base62Alphabet)))
+	id := make([]byte, g.length)
+	for i := range id {
+		n, err := rand.Int(rand.Reader, limit)
+		if err != nil {
+			return "", fmt.Errorf("failed to generate random ID: %w", err)
+		}
+		id[i] = base62Alphabet[n.Int64()]
+	}
+	return string(id), nil
+}
+
+// SqidsGenerator кодирует значения счётчика в стиле Sqids: алфавит перемешивается
+// по соли, а для каждого значения дополнительно сдвигается, поэтому соседние
+// значения счётчика дают непохожие идентификаторы.
+type SqidsGenerator struct {
+	alphabet  string
+	minLength int
+	counter   atomic.Uint64
+}
+
+// NewSqidsGenerator создаёт генератор с алфавитом, перемешанным по salt.
+func NewSqidsGenerator(salt string, start uint64, minLength int) *SqidsGenerator {
+	g := &SqidsGenerator{
+		alphabet:  shuffle(base62Alphabet, salt),
+		minLength: minLength,
+	}
+	g.counter.Store(start)
+	return g
+}
+
+// Generate кодирует следующее значение счётчика.
+func (g *SqidsGenerator) Generate(_ string, _ int) (string, error) {
+	return g.Encode(g.counter.Add(1)), nil
+}
+
+// Encode кодирует число. Первый символ задаёт сдвиг алфавита для остальных символов,
+// недостающая до minLength длина дополняется символами сдвинутого алфавита.
+func (g *SqidsGenerator) Encode(n uint64) string {
+	size := uint64(len(g.alphabet))
+	offset := (n ^ (n >> 7)) % size
+	rotated := g.alphabet[offset:] + g.alphabet[:offset]
+
+	// Последний символ алфавита зарезервирован как разделитель дополнения
+	digits := rotated[:size-1]
+	separator := rotated[size-1]
+
+	var body []byte
+	base := uint64(len(digits))
+	for {
+		body = append([]byte{digits[n%base]}, body...)
+		n /= base
+		if n == 0 {
+			break
+		}
+	}
+
+	id := append([]byte{g.alphabet[offset]}, body...)
+	if len(id) < g.minLength {
+		id = append(id, separator)
+		for i := 0; len(id) < g.minLength; i++ {
+			id = append(id, rotated[i%len(digits)])
+		}
+	}
+	return string(id)
+}
+
+// encodeBase62 кодирует число в base62.
+func encodeBase62(n uint64) string {
+	if n == 0 {
+		return base62Alphabet[:1]
+	}
+
+	var buf [11]byte
+	i := len(buf)
+	for n > 0 {
+		i--
+		buf[i] = base62Alphabet[n%62]
+		n /= 62
+	}
+	return string(buf[i:])
+}
+
+// shuffle детерминированно перемешивает алфавит по соли.
+func shuffle(alphabet, salt string) string {
+	chars := []byte(alphabet)
+	if salt == "" {
+		return alphabet
+	}
+
+	for i, j := len(chars)-1, 0; i > 0; i-- {
+		j = (j + int(salt[i%len(salt)]) + i) % (i + 1)
+		chars[i], chars[j] = chars[j], chars[i]
+	}
+	return string(chars)
+}
@@ -0,0 +1,65 @@
+package idgen
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNew(t *testing.T) {
+	for _, strategy := range []string{"", StrategyHash, StrategySequence, StrategyRandom, StrategySqids} {
+		t.Run(strategy, func(t *testing.T) {
+			gen, err := New(strategy, "salt")
+			require.NoError(t, err)
+
+			id, err := gen.Generate("https://example.com", 0)
+			require.NoError(t, err)
+			assert.NotEmpty(t, id)
+		})
+	}
+
+	_, err := New("unknown", "")
+	assert.Error(t, err)
+}
+
+func TestHashGenerator(t *testing.T) {
+	gen := NewHashGenerator("salt")
+
+	// Первая попытка совпадает с прежним форматом md5[:8]
+	id, err := gen.Generate("https://practicum.yandex.ru", 0)
+	require.NoError(t, err)
+	assert.Equal(t, "6bdb5b0e", id)
+
+	// Повторная попытка даёт другой идентификатор
+	retry, err := gen.Generate("https://practicum.yandex.ru", 1)
+	require.NoError(t, err)
+	assert.NotEqual(t, id, retry)
+	assert.Len(t, retry, 8)
+}
+
+func TestCounterGenerators_Unique(t *testing.T) {
+	generators := map[string]Generator{
+		"sequence": NewSequenceGenerator(0),
+		"sqids":    NewSqidsGenerator("salt", 0, 8),
+		"random":   NewRandomGenerator(8),
+	}
+
+	for name, gen := range generators {
+		t.Run(name, func(t *testing.T) {
+			seen := make(map[string]bool)
+			for i := 0; i < 10000; i++ {
+				id, err := gen.Generate("", 0)
+				require.NoError(t, err)
+				assert.False(t, seen[id], "duplicate ID %s", id)
+				seen[id] = true
+			}
+		})
+	}
+}
+
+func TestSqidsGenerator_MinLength(t *testing.T) {
+	gen := NewSqidsGenerator("", 0, 8)
+	assert.Len(t, gen.Encode(1), 8)
+	assert.NotEqual(t, gen.Encode(1), gen.Encode(2))
+}
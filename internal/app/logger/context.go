@@ -0,0 +1,72 @@
+package logger
+
+import (
+	"context"
+
+	"github.com/go-chi/chi/v5"
+	"go.uber.org/zap"
+)
+
+// Имена полей, которыми логгер запроса дополняет записи.
+const (
+	fieldRequestID = "request_id"
+	fieldUserID    = "user_id"
+	fieldRoute     = "route"
+	fieldTraceID   = "trace_id"
+)
+
+// loggerKey — ключ контекста для логгера запроса.
+type loggerKey struct{}
+
+// requestInfoKey — ключ контекста для сведений о запросе, собираемых для записи Middleware.
+type requestInfoKey struct{}
+
+// requestInfo содержит сведения, которые становятся известны во внутренних
+// middleware, а попадают в запись о запросе во внешнем Middleware.
+type requestInfo struct {
+	userID string
+}
+
+// WithContext возвращает контекст с логгером l.
+func WithContext(ctx context.Context, l *zap.Logger) context.Context {
+	return context.WithValue(ctx, loggerKey{}, l)
+}
+
+// FromContext возвращает логгер запроса из контекста или логгер приложения,
+// если контекст его не содержит. После маршрутизации chi логгер дополняется
+// шаблоном маршрута: сам путь запроса может содержать идентификаторы ссылок.
+func FromContext(ctx context.Context) *zap.Logger {
+	l, ok := ctx.Value(loggerKey{}).(*zap.Logger)
+	if !ok {
+		l = baseLogger
+	}
+	if rctx := chi.RouteContext(ctx); rctx != nil {
+		if pattern := rctx.RoutePattern(); pattern != "" {
+			l = l.With(zap.String(fieldRoute, pattern))
+		}
+	}
+	return l
+}
+
+// With возвращает контекст, логгер которого дополнен полями fields.
+func With(ctx context.Context, fields ...zap.Field) context.Context {
+	l, ok := ctx.Value(loggerKey{}).(*zap.Logger)
+	if !ok {
+		l = baseLogger
+	}
+	return WithContext(ctx, l.With(fields...))
+}
+
+// WithUserID возвращает контекст, логгер которого дополнен идентификатором
+// пользователя. Идентификатор также попадает в запись Middleware о запросе.
+func WithUserID(ctx context.Context, userID string) context.Context {
+	if info, ok := ctx.Value(requestInfoKey{}).(*requestInfo); ok {
+		info.userID = userID
+	}
+	return With(ctx, zap.String(fieldUserID, userID))
+}
+
+// withRequestInfo возвращает контекст со сведениями о запросе info.
+func withRequestInfo(ctx context.Context, info *requestInfo) context.Context {
+	return context.WithValue(ctx, requestInfoKey{}, info)
+}
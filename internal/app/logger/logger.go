@@ -1,53 +1,108 @@
 // Package logger содержит функции для инициализации и использования логгирования в приложении.
 // Он использует библиотеку zap для создания логов и middleware для обработки HTTP-запросов.
+//
+// Логгер запроса передаётся через контекст (см. FromContext) и содержит
+// идентификатор запроса, идентификатор пользователя и шаблон маршрута.
 package logger
 
 import (
-	"log"
+	"fmt"
 	"net/http"
 	"time"
 
 	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
 )
 
-var sugarLogger *zap.SugaredLogger
+// Форматы вывода логов.
+const (
+	// FormatJSON выводит каждую запись одной строкой JSON.
+	FormatJSON = "json"
+	// FormatConsole выводит записи в удобном для чтения виде.
+	FormatConsole = "console"
+)
+
+// baseLogger — логгер приложения. До вызова Init пишет записи уровня info в JSON,
+// поэтому пакеты могут логировать и без явной инициализации, например в тестах.
+var baseLogger = zap.Must(newLogger(zapcore.InfoLevel, FormatJSON))
 
-// InitLogger инициализирует логгер с использованием zap.
-func InitLogger() {
-	logger, err := zap.NewProduction()
+// Init настраивает логгер приложения с минимальным уровнем level
+// (debug, info, warn, error) и форматом format (json, console).
+// Вывод стандартного пакета log перенаправляется в тот же логгер.
+func Init(level, format string) error {
+	lvl, err := zapcore.ParseLevel(level)
 	if err != nil {
-		// В случае ошибки инициализации используем стандартный логгер
-		log.Printf("Failed to initialize zap logger: %v", err)
-		return
+		return fmt.Errorf("invalid log level %q", level)
 	}
 
-	defer func() {
-		if err := logger.Sync(); err != nil {
-			// Логируем ошибку Sync(), но не прерываем работу,
-			// так как логгер уже инициализирован
-			log.Printf("Failed to sync logger: %v", err)
-		}
-	}()
+	l, err := newLogger(lvl, format)
+	if err != nil {
+		return err
+	}
+
+	baseLogger = l
+	zap.RedirectStdLog(l)
+	return nil
+}
+
+// newLogger создаёт логгер, пишущий в stderr.
+func newLogger(level zapcore.Level, format string) (*zap.Logger, error) {
+	cfg := zap.NewProductionConfig()
+	switch format {
+	case FormatJSON:
+	case FormatConsole:
+		cfg.Encoding = FormatConsole
+		cfg.EncoderConfig.EncodeLevel = zapcore.CapitalLevelEncoder
+		cfg.EncoderConfig.EncodeDuration = zapcore.StringDurationEncoder
+	default:
+		return nil, fmt.Errorf("unknown log format %q, expected %s or %s", format, FormatJSON, FormatConsole)
+	}
+	cfg.Level = zap.NewAtomicLevelAt(level)
+	cfg.EncoderConfig.EncodeTime = zapcore.ISO8601TimeEncoder
+	// Сэмплирование отбросило бы часть записей о запросах под нагрузкой
+	cfg.Sampling = nil
+
+	return cfg.Build()
+}
 
-	sugarLogger = logger.Sugar()
+// L возвращает логгер приложения без полей запроса.
+// Используется фоновыми задачами, не связанными с HTTP-запросом.
+func L() *zap.Logger {
+	return baseLogger
 }
 
 // Middleware создает middleware для логирования HTTP-запросов и ответов.
+// Запись содержит поля логгера запроса (см. RequestIDMiddleware), шаблон маршрута
+// и идентификатор пользователя, если запрос был аутентифицирован.
+// Путь запроса содержит короткие ID и попадает в запись только на уровне debug,
+// строка запроса не логируется никогда. Ответы 5xx логируются с уровнем error.
 func Middleware(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		start := time.Now()
 		ww := &responseWriter{w, http.StatusOK, 0}
+		info := &requestInfo{}
+
+		next.ServeHTTP(ww, r.WithContext(withRequestInfo(r.Context(), info)))
 
-		next.ServeHTTP(ww, r)
+		fields := []zap.Field{
+			zap.String("method", r.Method),
+			zap.Int("status", ww.status),
+			zap.Int("size", ww.size),
+			zap.Duration("duration", time.Since(start)),
+		}
+		if info.userID != "" {
+			fields = append(fields, zap.String(fieldUserID, info.userID))
+		}
 
-		duration := time.Since(start)
-		sugarLogger.Infow("HTTP request",
-			"method", r.Method,
-			"uri", r.RequestURI,
-			"status", ww.status,
-			"size", ww.size,
-			"duration", duration,
-		)
+		log := FromContext(r.Context())
+		if log.Core().Enabled(zapcore.DebugLevel) {
+			fields = append(fields, zap.String("path", r.URL.Path))
+		}
+		if ww.status >= http.StatusInternalServerError {
+			log.Error("HTTP request", fields...)
+			return
+		}
+		log.Info("HTTP request", fields...)
 	})
 }
 
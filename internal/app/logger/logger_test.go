@@ -0,0 +1,159 @@
+package logger
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel/trace"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+	"go.uber.org/zap/zaptest/observer"
+)
+
+// observe подменяет логгер приложения логгером, сохраняющим записи в памяти.
+func observe(t *testing.T) *observer.ObservedLogs {
+	t.Helper()
+	core, logs := observer.New(zapcore.DebugLevel)
+	previous := baseLogger
+	baseLogger = zap.New(core)
+	t.Cleanup(func() { baseLogger = previous })
+	return logs
+}
+
+func TestInit(t *testing.T) {
+	previous := baseLogger
+	t.Cleanup(func() { baseLogger = previous })
+
+	assert.ErrorContains(t, Init("verbose", FormatJSON), "invalid log level")
+	assert.ErrorContains(t, Init("info", "xml"), "unknown log format")
+
+	require.NoError(t, Init("warn", FormatConsole))
+	assert.False(t, L().Core().Enabled(zapcore.InfoLevel))
+	assert.True(t, L().Core().Enabled(zapcore.WarnLevel))
+}
+
+func TestRequestIDMiddleware(t *testing.T) {
+	var got string
+	handler := RequestIDMiddleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		got, _ = RequestIDFromContext(r.Context())
+	}))
+
+	tests := []struct {
+		name     string
+		header   string
+		accepted bool
+	}{
+		{name: "missing", header: "", accepted: false},
+		{name: "valid", header: "req-42_a.b:c", accepted: true},
+		{name: "uuid", header: "7d444840-9dc0-11d1-b245-5ffdce74fad2", accepted: true},
+		{name: "forged log line", header: "abc\nlevel=error", accepted: false},
+		{name: "spaces", header: "abc def", accepted: false},
+		{name: "too long", header: strings.Repeat("a", maxRequestIDLength+1), accepted: false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "/", nil)
+			if tt.header != "" {
+				req.Header.Set(RequestIDHeader, tt.header)
+			}
+			rec := httptest.NewRecorder()
+			handler.ServeHTTP(rec, req)
+
+			assert.Equal(t, got, rec.Header().Get(RequestIDHeader))
+			if tt.accepted {
+				assert.Equal(t, tt.header, got)
+				return
+			}
+			_, err := uuid.Parse(got)
+			assert.NoError(t, err, "generated ID must be a UUID")
+		})
+	}
+}
+
+func TestMiddleware_RequestFields(t *testing.T) {
+	logs := observe(t)
+
+	traceID := trace.TraceID{0x4b, 0xf9, 0x2f, 0x35}
+	withSpan := func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			sc := trace.NewSpanContext(trace.SpanContextConfig{TraceID: traceID, SpanID: trace.SpanID{1}})
+			next.ServeHTTP(w, r.WithContext(trace.ContextWithSpanContext(r.Context(), sc)))
+		})
+	}
+	authenticate := func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			next.ServeHTTP(w, r.WithContext(WithUserID(r.Context(), "user-1")))
+		})
+	}
+
+	r := chi.NewRouter()
+	r.Use(withSpan, RequestIDMiddleware, Middleware, authenticate)
+	r.Get("/{id}", func(w http.ResponseWriter, r *http.Request) {
+		FromContext(r.Context()).Info("handler")
+		w.WriteHeader(http.StatusTemporaryRedirect)
+	})
+	r.Post("/", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusInternalServerError)
+	})
+
+	req := httptest.NewRequest(http.MethodGet, "/abc", nil)
+	req.Header.Set(RequestIDHeader, "req-1")
+	r.ServeHTTP(httptest.NewRecorder(), req)
+
+	entries := logs.AllUntimed()
+	require.Len(t, entries, 2)
+	handler, access := entries[0], entries[1]
+
+	assert.Equal(t, "handler", handler.Message)
+	assert.Equal(t, map[string]any{
+		"request_id": "req-1",
+		"trace_id":   traceID.String(),
+		"user_id":    "user-1",
+		"route":      "/{id}",
+	}, handler.ContextMap())
+
+	assert.Equal(t, "HTTP request", access.Message)
+	assert.Equal(t, zapcore.InfoLevel, access.Level)
+	fields := access.ContextMap()
+	assert.Equal(t, "req-1", fields["request_id"])
+	assert.Equal(t, "user-1", fields["user_id"])
+	assert.Equal(t, "/{id}", fields["route"])
+	assert.Equal(t, int64(http.StatusTemporaryRedirect), fields["status"])
+	assert.NotContains(t, fields, "uri")
+	assert.Equal(t, "/abc", fields["path"], "path is logged at debug level")
+
+	// Ответы 5xx логируются как ошибки
+	r.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodPost, "/", nil))
+	failed := logs.AllUntimed()[2]
+	assert.Equal(t, zapcore.ErrorLevel, failed.Level)
+	assert.Equal(t, int64(http.StatusInternalServerError), failed.ContextMap()["status"])
+
+	// Выше уровня debug путь запроса не логируется
+	core, infoLogs := observer.New(zapcore.InfoLevel)
+	baseLogger = zap.New(core)
+	r.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/abc?token=secret", nil))
+	entries = infoLogs.AllUntimed()
+	require.Len(t, entries, 2)
+	assert.NotContains(t, entries[1].ContextMap(), "path")
+	assert.NotContains(t, entries[1].ContextMap(), "uri")
+}
+
+func TestFromContext_Default(t *testing.T) {
+	logs := observe(t)
+
+	FromContext(context.Background()).Info("background")
+	ctx := With(context.Background(), zap.String("job", "reap"))
+	FromContext(ctx).Info("job")
+
+	entries := logs.AllUntimed()
+	require.Len(t, entries, 2)
+	assert.Empty(t, entries[0].Context)
+	assert.Equal(t, map[string]any{"job": "reap"}, entries[1].ContextMap())
+}
@@ -0,0 +1,65 @@
+package logger
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/google/uuid"
+	"go.opentelemetry.io/otel/trace"
+	"go.uber.org/zap"
+)
+
+// RequestIDHeader — заголовок с идентификатором запроса.
+const RequestIDHeader = "X-Request-ID"
+
+// maxRequestIDLength ограничивает длину идентификатора, принятого от клиента.
+const maxRequestIDLength = 64
+
+// requestIDKey — ключ контекста для идентификатора запроса.
+type requestIDKey struct{}
+
+// RequestIDMiddleware назначает запросу идентификатор. Идентификатор из заголовка
+// X-Request-ID принимается, если он не длиннее 64 символов и состоит из букв,
+// цифр и символов "-_.:"; иначе генерируется UUID. Идентификатор возвращается
+// в заголовке ответа и добавляется в логгер запроса вместе с идентификатором
+// трассы, поэтому middleware подключается после tracing.Middleware.
+func RequestIDMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		id := r.Header.Get(RequestIDHeader)
+		if !validRequestID(id) {
+			id = uuid.NewString()
+		}
+		w.Header().Set(RequestIDHeader, id)
+
+		ctx := context.WithValue(r.Context(), requestIDKey{}, id)
+		fields := []zap.Field{zap.String(fieldRequestID, id)}
+		if sc := trace.SpanContextFromContext(ctx); sc.IsValid() {
+			fields = append(fields, zap.String(fieldTraceID, sc.TraceID().String()))
+		}
+
+		next.ServeHTTP(w, r.WithContext(With(ctx, fields...)))
+	})
+}
+
+// RequestIDFromContext возвращает идентификатор запроса из контекста.
+func RequestIDFromContext(ctx context.Context) (string, bool) {
+	id, ok := ctx.Value(requestIDKey{}).(string)
+	return id, ok
+}
+
+// validRequestID проверяет, можно ли принять идентификатор запроса от клиента.
+// Ограничение набора символов не даёт подделать записи в консольном формате логов.
+func validRequestID(id string) bool {
+	if id == "" || len(id) > maxRequestIDLength {
+		return false
+	}
+	for _, c := range id {
+		switch {
+		case c >= 'a' && c <= 'z', c >= 'A' && c <= 'Z', c >= '0' && c <= '9':
+		case c == '-', c == '_', c == '.', c == ':':
+		default:
+			return false
+		}
+	}
+	return true
+}
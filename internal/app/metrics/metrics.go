@@ -0,0 +1,145 @@
+// Package metrics собирает метрики приложения в формате Prometheus.
+//
+// Метрики регистрируются в собственном реестре пакета и отдаются обработчиком Handler:
+//   - shortener_http_requests_total и shortener_http_request_duration_seconds —
+//     запросы по шаблонам маршрутов chi (Middleware);
+//   - shortener_redirects_total — результаты переходов по коротким ссылкам;
+//   - shortener_storage_operation_duration_seconds и shortener_storage_operation_errors_total —
+//     операции хранилища по бэкендам (InstrumentStorage);
+//   - shortener_pgxpool_* — состояние пула соединений PostgreSQL (NewPoolCollector);
+//   - shortener_deletion_queue_depth — длина очереди удаления (RegisterDeletionQueue).
+package metrics
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+// namespace — префикс имён метрик приложения.
+const namespace = "shortener"
+
+// Результаты перехода по короткой ссылке.
+const (
+	// RedirectHit — ссылка найдена, выполнено перенаправление
+	RedirectHit = "hit"
+	// RedirectMiss — ссылка не найдена
+	RedirectMiss = "miss"
+	// RedirectGone — ссылка удалена владельцем или истекла
+	RedirectGone = "gone"
+)
+
+// unmatchedRoute — значение метки route для запросов без шаблона маршрута chi.
+// Подставлять путь запроса нельзя: число значений метки стало бы неограниченным.
+const unmatchedRoute = "unmatched"
+
+var registry = prometheus.NewRegistry()
+
+var (
+	httpRequests = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "http_requests_total",
+		Help:      "HTTP requests by method, route pattern and status code.",
+	}, []string{"method", "route", "code"})
+
+	httpDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "http_request_duration_seconds",
+		Help:      "HTTP request latency by method and route pattern.",
+		Buckets:   prometheus.DefBuckets,
+	}, []string{"method", "route"})
+
+	redirects = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "redirects_total",
+		Help:      "Short URL lookups by result: hit, miss or gone.",
+	}, []string{"result"})
+
+	storageDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "storage_operation_duration_seconds",
+		Help:      "Storage operation latency by backend and operation.",
+		Buckets:   []float64{.0001, .00025, .0005, .001, .0025, .005, .01, .025, .05, .1, .25, .5, 1, 2.5},
+	}, []string{"backend", "operation"})
+
+	storageErrors = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "storage_operation_errors_total",
+		Help:      "Failed storage operations by backend and operation.",
+	}, []string{"backend", "operation"})
+)
+
+func init() {
+	registry.MustRegister(
+		collectors.NewGoCollector(),
+		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+		httpRequests, httpDuration, redirects, storageDuration, storageErrors,
+	)
+	// Результаты переходов видны с нуля ещё до первого запроса
+	for _, result := range []string{RedirectHit, RedirectMiss, RedirectGone} {
+		redirects.WithLabelValues(result)
+	}
+}
+
+// Handler возвращает обработчик, отдающий метрики в формате Prometheus.
+// Обработчик не проверяет доступ: маршрутизатор открывает его только для доверенной подсети.
+func Handler() http.Handler {
+	return promhttp.HandlerFor(registry, promhttp.HandlerOpts{Registry: registry})
+}
+
+// Register регистрирует дополнительный сборщик метрик.
+func Register(c prometheus.Collector) error {
+	return registry.Register(c)
+}
+
+// RegisterDeletionQueue регистрирует метрику длины очереди удаления,
+// значение которой читается из depth при каждом сборе.
+func RegisterDeletionQueue(depth func() int) error {
+	return Register(prometheus.NewGaugeFunc(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "deletion_queue_depth",
+		Help:      "Deletion tasks waiting in the queue.",
+	}, func() float64 { return float64(depth()) }))
+}
+
+// ObserveRedirect учитывает результат перехода по короткой ссылке.
+func ObserveRedirect(result string) {
+	redirects.WithLabelValues(result).Inc()
+}
+
+// Middleware учитывает число и длительность HTTP-запросов.
+// Шаблон маршрута известен только после маршрутизации, поэтому читается после next.
+func Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		start := time.Now()
+		ww := &statusWriter{ResponseWriter: w, status: http.StatusOK}
+
+		next.ServeHTTP(ww, r)
+
+		route := unmatchedRoute
+		if rctx := chi.RouteContext(r.Context()); rctx != nil {
+			if pattern := rctx.RoutePattern(); pattern != "" {
+				route = pattern
+			}
+		}
+		httpRequests.WithLabelValues(r.Method, route, strconv.Itoa(ww.status)).Inc()
+		httpDuration.WithLabelValues(r.Method, route).Observe(time.Since(start).Seconds())
+	})
+}
+
+// statusWriter оборачивает http.ResponseWriter для захвата статуса ответа.
+type statusWriter struct {
+	http.ResponseWriter
+	status int
+}
+
+// WriteHeader записывает статус ответа и сохраняет его.
+func (w *statusWriter) WriteHeader(statusCode int) {
+	w.status = statusCode
+	w.ResponseWriter.WriteHeader(statusCode)
+}
@@ -0,0 +1,126 @@
+package metrics
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/alexuryumtsev/go-shortener/internal/app/models"
+	"github.com/alexuryumtsev/go-shortener/internal/app/storage"
+	"github.com/alexuryumtsev/go-shortener/internal/app/storage/memory"
+	"github.com/go-chi/chi/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// scrape возвращает текст метрик, отдаваемый Handler.
+func scrape(t *testing.T) string {
+	t.Helper()
+	rec := httptest.NewRecorder()
+	Handler().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+	require.Equal(t, http.StatusOK, rec.Code)
+	return rec.Body.String()
+}
+
+func TestMiddleware_RoutePattern(t *testing.T) {
+	r := chi.NewRouter()
+	r.Use(Middleware)
+	r.Route("/", func(r chi.Router) {
+		r.Get("/{id}", func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(http.StatusTemporaryRedirect)
+		})
+		r.Get("/api/user/urls/{id}/stats", func(w http.ResponseWriter, r *http.Request) {})
+	})
+
+	redirects := httpRequests.WithLabelValues(http.MethodGet, "/{id}", "307")
+	stats := httpRequests.WithLabelValues(http.MethodGet, "/api/user/urls/{id}/stats", "200")
+	// Без совпадения остаётся шаблон подмаршрутизатора
+	unmatched := httpRequests.WithLabelValues(http.MethodPost, "/*", "405")
+	before := []float64{testutil.ToFloat64(redirects), testutil.ToFloat64(stats), testutil.ToFloat64(unmatched)}
+
+	for _, req := range []*http.Request{
+		httptest.NewRequest(http.MethodGet, "/abc", nil),
+		httptest.NewRequest(http.MethodGet, "/xyz", nil),
+		httptest.NewRequest(http.MethodGet, "/api/user/urls/abc/stats", nil),
+		httptest.NewRequest(http.MethodPost, "/abc", nil),
+	} {
+		r.ServeHTTP(httptest.NewRecorder(), req)
+	}
+
+	// Разные ID учитываются под одним шаблоном маршрута
+	assert.Equal(t, before[0]+2, testutil.ToFloat64(redirects))
+	assert.Equal(t, before[1]+1, testutil.ToFloat64(stats))
+	assert.Equal(t, before[2]+1, testutil.ToFloat64(unmatched))
+	assert.Contains(t, scrape(t), `shortener_http_request_duration_seconds_count{method="GET",route="/{id}"}`)
+
+	// Вне маршрутизатора chi шаблона нет
+	before[0] = testutil.ToFloat64(httpRequests.WithLabelValues(http.MethodGet, unmatchedRoute, "404"))
+	Middleware(http.NotFoundHandler()).ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/abc", nil))
+	assert.Equal(t, before[0]+1, testutil.ToFloat64(httpRequests.WithLabelValues(http.MethodGet, unmatchedRoute, "404")))
+}
+
+func TestObserveRedirect(t *testing.T) {
+	before := testutil.ToFloat64(redirects.WithLabelValues(RedirectGone))
+	ObserveRedirect(RedirectGone)
+	assert.Equal(t, before+1, testutil.ToFloat64(redirects.WithLabelValues(RedirectGone)))
+
+	body := scrape(t)
+	for _, result := range []string{RedirectHit, RedirectMiss, RedirectGone} {
+		assert.Contains(t, body, `shortener_redirects_total{result="`+result+`"}`)
+	}
+}
+
+func TestInstrumentStorage(t *testing.T) {
+	ctx := context.Background()
+	s := InstrumentStorage("test", memory.NewInMemoryStorage())
+
+	require.NoError(t, s.Save(ctx, models.URLModel{ID: "a", URL: "https://a.com"}))
+	// Конфликт не считается ошибкой хранилища
+	require.ErrorIs(t, s.Save(ctx, models.URLModel{ID: "a", URL: "https://b.com"}), storage.ErrConflict)
+	_, exists := s.Get(ctx, "a")
+	assert.True(t, exists)
+
+	errStop := errors.New("stop")
+	assert.ErrorIs(t, s.ForEachURL(ctx, "", func(models.URLModel) error { return errStop }), errStop)
+
+	assert.Equal(t, 0.0, testutil.ToFloat64(storageErrors.WithLabelValues("test", "Save")))
+	assert.Equal(t, 1.0, testutil.ToFloat64(storageErrors.WithLabelValues("test", "ForEachURL")))
+
+	body := scrape(t)
+	assert.Contains(t, body, `shortener_storage_operation_duration_seconds_count{backend="test",operation="Save"} 2`)
+	assert.Contains(t, body, `shortener_storage_operation_duration_seconds_count{backend="test",operation="Get"} 1`)
+
+	// Ping и Close передаются исходному хранилищу
+	assert.NoError(t, s.(interface{ Ping(context.Context) error }).Ping(ctx))
+	assert.NoError(t, s.(io.Closer).Close())
+	assert.IsType(t, &memory.InMemoryStorage{}, s.(interface{ Unwrap() storage.URLStorage }).Unwrap())
+}
+
+func TestPoolCollector(t *testing.T) {
+	// Пул не подключается к базе до первого запроса
+	pool, err := pgxpool.New(context.Background(), "postgres://user@127.0.0.1:1/db?pool_max_conns=7")
+	require.NoError(t, err)
+	defer pool.Close()
+
+	collector := NewPoolCollector(pool)
+	assert.Equal(t, 12, testutil.CollectAndCount(collector))
+	assert.NoError(t, testutil.CollectAndCompare(collector, strings.NewReader(`
+# HELP shortener_pgxpool_max_conns Maximum size of the pool.
+# TYPE shortener_pgxpool_max_conns gauge
+shortener_pgxpool_max_conns 7
+`), "shortener_pgxpool_max_conns"))
+}
+
+func TestRegisterDeletionQueue(t *testing.T) {
+	depth := 3
+	require.NoError(t, RegisterDeletionQueue(func() int { return depth }))
+	assert.Contains(t, scrape(t), "shortener_deletion_queue_depth 3")
+	depth = 0
+	assert.Contains(t, scrape(t), "shortener_deletion_queue_depth 0")
+}
@@ -0,0 +1,76 @@
+package metrics
+
+import (
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// poolCollector отдаёт статистику пула соединений pgxpool.
+// Значения читаются из pool.Stat() при каждом сборе.
+type poolCollector struct {
+	pool *pgxpool.Pool
+
+	acquiredConns           *prometheus.Desc
+	idleConns               *prometheus.Desc
+	constructingConns       *prometheus.Desc
+	totalConns              *prometheus.Desc
+	maxConns                *prometheus.Desc
+	acquireCount            *prometheus.Desc
+	acquireDuration         *prometheus.Desc
+	canceledAcquireCount    *prometheus.Desc
+	emptyAcquireCount       *prometheus.Desc
+	newConnsCount           *prometheus.Desc
+	maxLifetimeDestroyCount *prometheus.Desc
+	maxIdleDestroyCount     *prometheus.Desc
+}
+
+// NewPoolCollector создаёт сборщик метрик shortener_pgxpool_* для пула pool.
+func NewPoolCollector(pool *pgxpool.Pool) prometheus.Collector {
+	desc := func(name, help string) *prometheus.Desc {
+		return prometheus.NewDesc(prometheus.BuildFQName(namespace, "pgxpool", name), help, nil, nil)
+	}
+	return &poolCollector{
+		pool:                    pool,
+		acquiredConns:           desc("acquired_conns", "Connections currently acquired from the pool."),
+		idleConns:               desc("idle_conns", "Idle connections in the pool."),
+		constructingConns:       desc("constructing_conns", "Connections being established."),
+		totalConns:              desc("total_conns", "Total connections in the pool."),
+		maxConns:                desc("max_conns", "Maximum size of the pool."),
+		acquireCount:            desc("acquires_total", "Successful acquires from the pool."),
+		acquireDuration:         desc("acquire_duration_seconds_total", "Total time spent acquiring connections."),
+		canceledAcquireCount:    desc("canceled_acquires_total", "Acquires canceled by their context."),
+		emptyAcquireCount:       desc("empty_acquires_total", "Acquires that had to wait for a connection."),
+		newConnsCount:           desc("new_conns_total", "Connections opened by the pool."),
+		maxLifetimeDestroyCount: desc("max_lifetime_destroys_total", "Connections closed for exceeding MaxConnLifetime."),
+		maxIdleDestroyCount:     desc("max_idle_destroys_total", "Connections closed for exceeding MaxConnIdleTime."),
+	}
+}
+
+// Describe отправляет описания метрик пула.
+func (c *poolCollector) Describe(ch chan<- *prometheus.Desc) {
+	prometheus.DescribeByCollect(c, ch)
+}
+
+// Collect отправляет текущие значения метрик пула.
+func (c *poolCollector) Collect(ch chan<- prometheus.Metric) {
+	stat := c.pool.Stat()
+	gauge := func(desc *prometheus.Desc, value float64) {
+		ch <- prometheus.MustNewConstMetric(desc, prometheus.GaugeValue, value)
+	}
+	counter := func(desc *prometheus.Desc, value float64) {
+		ch <- prometheus.MustNewConstMetric(desc, prometheus.CounterValue, value)
+	}
+
+	gauge(c.acquiredConns, float64(stat.AcquiredConns()))
+	gauge(c.idleConns, float64(stat.IdleConns()))
+	gauge(c.constructingConns, float64(stat.ConstructingConns()))
+	gauge(c.totalConns, float64(stat.TotalConns()))
+	gauge(c.maxConns, float64(stat.MaxConns()))
+	counter(c.acquireCount, float64(stat.AcquireCount()))
+	counter(c.acquireDuration, stat.AcquireDuration().Seconds())
+	counter(c.canceledAcquireCount, float64(stat.CanceledAcquireCount()))
+	counter(c.emptyAcquireCount, float64(stat.EmptyAcquireCount()))
+	counter(c.newConnsCount, float64(stat.NewConnsCount()))
+	counter(c.maxLifetimeDestroyCount, float64(stat.MaxLifetimeDestroyCount()))
+	counter(c.maxIdleDestroyCount, float64(stat.MaxIdleDestroyCount()))
+}
@@ -0,0 +1,188 @@
+package metrics
+
+import (
+	"context"
+	"errors"
+	"io"
+	"time"
+
+	"github.com/alexuryumtsev/go-shortener/internal/app/models"
+	"github.com/alexuryumtsev/go-shortener/internal/app/storage"
+)
+
+// InstrumentStorage оборачивает хранилище s, учитывая длительность и ошибки
+// каждой операции с меткой backend. storage.ErrConflict ошибкой не считается:
+// это ожидаемый ответ на занятый ID или логин.
+//
+// Обёртка передаёт Ping и Close хранилищу, если оно их реализует,
+// а исходное хранилище доступно через Unwrap.
+func InstrumentStorage(backend string, s storage.URLStorage) storage.URLStorage {
+	return &instrumentedStorage{next: s, backend: backend}
+}
+
+// instrumentedStorage — хранилище, учитывающее метрики операций.
+type instrumentedStorage struct {
+	next    storage.URLStorage
+	backend string
+}
+
+// observe учитывает операцию op, начатую в start и завершившуюся с ошибкой err.
+func (s *instrumentedStorage) observe(op string, start time.Time, err error) {
+	storageDuration.WithLabelValues(s.backend, op).Observe(time.Since(start).Seconds())
+	if err != nil && !errors.Is(err, storage.ErrConflict) {
+		storageErrors.WithLabelValues(s.backend, op).Inc()
+	}
+}
+
+// Unwrap возвращает исходное хранилище.
+func (s *instrumentedStorage) Unwrap() storage.URLStorage {
+	return s.next
+}
+
+// Ping проверяет доступность хранилища, если оно это поддерживает.
+func (s *instrumentedStorage) Ping(ctx context.Context) (err error) {
+	defer func(start time.Time) { s.observe("Ping", start, err) }(time.Now())
+	if p, ok := s.next.(interface{ Ping(context.Context) error }); ok {
+		return p.Ping(ctx)
+	}
+	return nil
+}
+
+// CheckHealth проверяет состояние хранилища; проверки в метриках не учитываются.
+func (s *instrumentedStorage) CheckHealth(ctx context.Context) map[string]storage.ComponentHealth {
+	return s.next.CheckHealth(ctx)
+}
+
+// Close закрывает хранилище, если оно это поддерживает.
+func (s *instrumentedStorage) Close() error {
+	if closer, ok := s.next.(io.Closer); ok {
+		return closer.Close()
+	}
+	return nil
+}
+
+// Get получает URL по короткому ID.
+func (s *instrumentedStorage) Get(ctx context.Context, id string) (models.URLModel, bool) {
+	defer s.observe("Get", time.Now(), nil)
+	return s.next.Get(ctx, id)
+}
+
+// GetByOriginalURL получает URL по оригинальному адресу.
+func (s *instrumentedStorage) GetByOriginalURL(ctx context.Context, originalURL string) (models.URLModel, bool) {
+	defer s.observe("GetByOriginalURL", time.Now(), nil)
+	return s.next.GetByOriginalURL(ctx, originalURL)
+}
+
+// GetUserURLs получает URL пользователя.
+func (s *instrumentedStorage) GetUserURLs(ctx context.Context, userID string) (urls []models.URLModel, err error) {
+	defer func(start time.Time) { s.observe("GetUserURLs", start, err) }(time.Now())
+	return s.next.GetUserURLs(ctx, userID)
+}
+
+// GetStats возвращает сводную статистику.
+func (s *instrumentedStorage) GetStats(ctx context.Context) (stats models.StatsModel, err error) {
+	defer func(start time.Time) { s.observe("GetStats", start, err) }(time.Now())
+	return s.next.GetStats(ctx)
+}
+
+// LoadFromFile загружает данные хранилища.
+func (s *instrumentedStorage) LoadFromFile() (err error) {
+	defer func(start time.Time) { s.observe("LoadFromFile", start, err) }(time.Now())
+	return s.next.LoadFromFile()
+}
+
+// ForEachURL обходит URL хранилища. Длительность включает время работы fn.
+func (s *instrumentedStorage) ForEachURL(ctx context.Context, afterID string, fn func(models.URLModel) error) (err error) {
+	defer func(start time.Time) { s.observe("ForEachURL", start, err) }(time.Now())
+	return s.next.ForEachURL(ctx, afterID, fn)
+}
+
+// Save сохраняет URL.
+func (s *instrumentedStorage) Save(ctx context.Context, urlModel models.URLModel) (err error) {
+	defer func(start time.Time) { s.observe("Save", start, err) }(time.Now())
+	return s.next.Save(ctx, urlModel)
+}
+
+// SaveBatch сохраняет пакет URL.
+func (s *instrumentedStorage) SaveBatch(ctx context.Context, urlModels []models.URLModel) (skipped []string, err error) {
+	defer func(start time.Time) { s.observe("SaveBatch", start, err) }(time.Now())
+	return s.next.SaveBatch(ctx, urlModels)
+}
+
+// DeleteUserURLs помечает URL пользователя удалёнными.
+func (s *instrumentedStorage) DeleteUserURLs(ctx context.Context, userID string, shortURLs []string) (err error) {
+	defer func(start time.Time) { s.observe("DeleteUserURLs", start, err) }(time.Now())
+	return s.next.DeleteUserURLs(ctx, userID, shortURLs)
+}
+
+// DeleteExpired удаляет истёкшие URL.
+func (s *instrumentedStorage) DeleteExpired(ctx context.Context, now time.Time, archive bool) (removed int, err error) {
+	defer func(start time.Time) { s.observe("DeleteExpired", start, err) }(time.Now())
+	return s.next.DeleteExpired(ctx, now, archive)
+}
+
+// SaveClicks сохраняет переходы.
+func (s *instrumentedStorage) SaveClicks(ctx context.Context, events []models.ClickEvent) (err error) {
+	defer func(start time.Time) { s.observe("SaveClicks", start, err) }(time.Now())
+	return s.next.SaveClicks(ctx, events)
+}
+
+// GetClickStats возвращает статистику переходов по ссылке.
+func (s *instrumentedStorage) GetClickStats(ctx context.Context, shortID string) (stats models.LinkStatsModel, err error) {
+	defer func(start time.Time) { s.observe("GetClickStats", start, err) }(time.Now())
+	return s.next.GetClickStats(ctx, shortID)
+}
+
+// SaveAPIKey сохраняет API-ключ.
+func (s *instrumentedStorage) SaveAPIKey(ctx context.Context, key models.APIKeyModel) (err error) {
+	defer func(start time.Time) { s.observe("SaveAPIKey", start, err) }(time.Now())
+	return s.next.SaveAPIKey(ctx, key)
+}
+
+// GetAPIKeyByHash получает API-ключ по хешу.
+func (s *instrumentedStorage) GetAPIKeyByHash(ctx context.Context, keyHash string) (models.APIKeyModel, bool) {
+	defer s.observe("GetAPIKeyByHash", time.Now(), nil)
+	return s.next.GetAPIKeyByHash(ctx, keyHash)
+}
+
+// GetUserAPIKeys возвращает API-ключи пользователя.
+func (s *instrumentedStorage) GetUserAPIKeys(ctx context.Context, userID string) (keys []models.APIKeyModel, err error) {
+	defer func(start time.Time) { s.observe("GetUserAPIKeys", start, err) }(time.Now())
+	return s.next.GetUserAPIKeys(ctx, userID)
+}
+
+// RevokeAPIKey удаляет API-ключ пользователя.
+func (s *instrumentedStorage) RevokeAPIKey(ctx context.Context, userID, id string) (revoked bool, err error) {
+	defer func(start time.Time) { s.observe("RevokeAPIKey", start, err) }(time.Now())
+	return s.next.RevokeAPIKey(ctx, userID, id)
+}
+
+// TouchAPIKey обновляет время последнего использования API-ключа.
+func (s *instrumentedStorage) TouchAPIKey(ctx context.Context, id string, usedAt time.Time) (err error) {
+	defer func(start time.Time) { s.observe("TouchAPIKey", start, err) }(time.Now())
+	return s.next.TouchAPIKey(ctx, id, usedAt)
+}
+
+// CreateUser сохраняет пользователя.
+func (s *instrumentedStorage) CreateUser(ctx context.Context, user models.UserModel) (err error) {
+	defer func(start time.Time) { s.observe("CreateUser", start, err) }(time.Now())
+	return s.next.CreateUser(ctx, user)
+}
+
+// GetUserByLogin получает пользователя по логину.
+func (s *instrumentedStorage) GetUserByLogin(ctx context.Context, login string) (models.UserModel, bool) {
+	defer s.observe("GetUserByLogin", time.Now(), nil)
+	return s.next.GetUserByLogin(ctx, login)
+}
+
+// GetUserByID получает пользователя по ID.
+func (s *instrumentedStorage) GetUserByID(ctx context.Context, id string) (models.UserModel, bool) {
+	defer s.observe("GetUserByID", time.Now(), nil)
+	return s.next.GetUserByID(ctx, id)
+}
+
+// ReassignUserURLs передаёт URL одного пользователя другому.
+func (s *instrumentedStorage) ReassignUserURLs(ctx context.Context, fromUserID, toUserID string) (moved int, err error) {
+	defer func(start time.Time) { s.observe("ReassignUserURLs", start, err) }(time.Now())
+	return s.next.ReassignUserURLs(ctx, fromUserID, toUserID)
+}
@@ -3,44 +3,165 @@
 package middleware
 
 import (
+	"context"
+	"errors"
 	"net/http"
+	"strings"
 	"time"
 
+	"github.com/alexuryumtsev/go-shortener/internal/app/logger"
+	"github.com/alexuryumtsev/go-shortener/internal/app/service/apikey"
 	"github.com/alexuryumtsev/go-shortener/internal/app/service/user"
+	"go.uber.org/zap"
 )
 
 const cookieName = "auth_token"
 
-// AuthMiddleware проверяет аутентификацию пользователя.
-// Если пользователь не аутентифицирован, создаёт новый идентификатор и cookie.
-// Добавляет информацию о пользователе в контекст запроса.
+// AuthMiddleware проверяет аутентификацию пользователя по куке.
+// Если кука отсутствует или её подпись не проверяется (например, после перезапуска
+// со случайным секретом), создаёт анонимного пользователя и выдаёт ему новую cookie.
+// Просроченный токен или токен отозванного ключа отклоняется с кодом 401
+// (см. user.IsTokenRejected): маршруты /api/user/* работают с данными владельца
+// куки, и подменять его новой личностью нельзя.
+// Личность пользователя (user.Identity) помещается в контекст запроса, откуда её
+// читают обработчики (см. user.IdentityFromContext).
+// Запросы, уже аутентифицированные BearerAuthMiddleware, пропускаются без изменений.
 func AuthMiddleware(userService user.UserService, next http.Handler) http.Handler {
+	return cookieAuth(userService, next, true)
+}
+
+// PublicAuthMiddleware работает как AuthMiddleware, но просроченный или отозванный
+// токен тоже заменяет новой анонимной личностью. Подключается к публичным маршрутам,
+// например GET /{id}: переход по ссылке не должен падать с 401 из-за старой куки.
+func PublicAuthMiddleware(userService user.UserService, next http.Handler) http.Handler {
+	return cookieAuth(userService, next, false)
+}
+
+// cookieAuth реализует AuthMiddleware и PublicAuthMiddleware; rejectRetired
+// определяет, отклонять ли просроченные и отозванные токены с кодом 401.
+func cookieAuth(userService user.UserService, next http.Handler, rejectRetired bool) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		cookie, err := r.Cookie(cookieName)
-		if err != nil {
-			// Кука отсутствует, создаем новый JWT
-			token, err := userService.GenerateUserToken()
-			if err != nil {
-				http.Error(w, "Failed to generate token", http.StatusInternalServerError)
+		if _, ok := user.IdentityFromContext(r.Context()); ok {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		var (
+			identity user.Identity
+			err      error
+			issue    = true
+		)
+		if cookie, cookieErr := r.Cookie(cookieName); cookieErr == nil {
+			identity, err = userService.Authenticate(cookie.Value)
+			if rejectRetired && user.IsTokenRejected(err) {
+				http.Error(w, "Unauthorized", http.StatusUnauthorized)
 				return
 			}
-			setAuthCookie(w, token)
-			r.AddCookie(&http.Cookie{Name: cookieName, Value: token})
-		} else {
-			// Проверяем JWT
-			userID, err := userService.VerifyUserToken(cookie.Value)
+			issue = err != nil
+		}
+
+		if issue {
+			// Куки нет, она подписана неизвестным ключом или на публичном маршруте
+			// просрочена: создаём анонимного пользователя
+			var token string
+			identity, token, err = userService.NewAnonymousUser()
 			if err != nil {
-				http.Error(w, "Unauthorized", http.StatusUnauthorized)
+				logger.FromContext(r.Context()).Error("Failed to generate token", zap.Error(err))
+				http.Error(w, "Failed to generate token", http.StatusInternalServerError)
 				return
 			}
-			r.AddCookie(&http.Cookie{Name: cookieName, Value: userID})
+			SetAuthCookie(w, token)
 		}
-		next.ServeHTTP(w, r)
+		identity.Method = user.AuthMethodCookie
+
+		next.ServeHTTP(w, r.WithContext(withIdentity(r.Context(), identity)))
 	})
 }
 
-// Устанавливаем JWT в куку
-func setAuthCookie(w http.ResponseWriter, token string) {
+// BearerAuthMiddleware аутентифицирует запросы с заголовком Authorization: Bearer.
+// Принимает JWT и API-ключи (с префиксом apikey.KeyPrefix). В отличие от куки,
+// некорректные учётные данные не заменяются новой анонимной личностью, а отклоняются
+// с кодом 401. API-ключ должен иметь область действия, соответствующую методу запроса,
+// иначе запрос отклоняется с кодом 403. Запросы без заголовка передаются дальше.
+func BearerAuthMiddleware(userService user.UserService, apiKeys apikey.Service) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			header := r.Header.Get("Authorization")
+			if header == "" {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			token, ok := bearerToken(header)
+			if !ok {
+				unauthorized(w)
+				return
+			}
+
+			var identity user.Identity
+			if strings.HasPrefix(token, apikey.KeyPrefix) {
+				apiKey, err := apiKeys.Authenticate(r.Context(), token)
+				if errors.Is(err, apikey.ErrInvalidKey) {
+					unauthorized(w)
+					return
+				} else if err != nil {
+					logger.FromContext(r.Context()).Error("Failed to authenticate API key", zap.Error(err))
+					http.Error(w, "Failed to authenticate", http.StatusInternalServerError)
+					return
+				}
+				identity = user.Identity{UserID: apiKey.UserID, Method: user.AuthMethodAPIKey, Scopes: apiKey.Scopes}
+			} else {
+				var err error
+				identity, err = userService.Authenticate(token)
+				if err != nil {
+					unauthorized(w)
+					return
+				}
+				identity.Method = user.AuthMethodBearer
+			}
+
+			if !identity.HasScope(requiredScope(r.Method)) {
+				http.Error(w, "Forbidden", http.StatusForbidden)
+				return
+			}
+
+			next.ServeHTTP(w, r.WithContext(withIdentity(r.Context(), identity)))
+		})
+	}
+}
+
+// withIdentity помещает личность пользователя в контекст и добавляет
+// идентификатор пользователя в логгер запроса.
+func withIdentity(ctx context.Context, identity user.Identity) context.Context {
+	return logger.WithUserID(user.ContextWithIdentity(ctx, identity), identity.UserID)
+}
+
+// bearerToken извлекает токен из значения заголовка Authorization.
+func bearerToken(header string) (string, bool) {
+	scheme, token, ok := strings.Cut(strings.TrimSpace(header), " ")
+	if !ok || !strings.EqualFold(scheme, "Bearer") {
+		return "", false
+	}
+	token = strings.TrimSpace(token)
+	return token, token != ""
+}
+
+// requiredScope возвращает область действия API-ключа, необходимую для метода запроса.
+func requiredScope(method string) string {
+	if method == http.MethodGet || method == http.MethodHead {
+		return apikey.ScopeURLsRead
+	}
+	return apikey.ScopeURLsWrite
+}
+
+// unauthorized отвечает кодом 401 с указанием схемы аутентификации.
+func unauthorized(w http.ResponseWriter) {
+	w.Header().Set("WWW-Authenticate", "Bearer")
+	http.Error(w, "Unauthorized", http.StatusUnauthorized)
+}
+
+// SetAuthCookie устанавливает JWT в куку auth_token.
+func SetAuthCookie(w http.ResponseWriter, token string) {
 	http.SetCookie(w, &http.Cookie{
 		Name:     cookieName,
 		Value:    token,
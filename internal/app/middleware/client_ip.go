@@ -0,0 +1,86 @@
+package middleware
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"strings"
+)
+
+// clientIPKey — ключ контекста для IP-адреса клиента.
+type clientIPKey struct{}
+
+// ClientIPMiddleware определяет IP-адрес клиента и сохраняет его в контексте запроса
+// (см. ClientIP). Заголовки X-Real-IP и X-Forwarded-For учитываются, только если
+// соединение установлено доверенным прокси из trustedProxies: иначе клиент мог бы
+// подставить любой адрес, например обойти ограничение частоты запросов
+// или доступ по доверенной подсети.
+//
+// В X-Forwarded-For адреса просматриваются справа налево, и клиентом считается
+// первый адрес не из доверенных прокси: левые адреса списка может дописать сам клиент.
+func ClientIPMiddleware(trustedProxies []*net.IPNet) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			ip := resolveClientIP(r, trustedProxies)
+			next.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), clientIPKey{}, ip)))
+		})
+	}
+}
+
+// ClientIP возвращает IP-адрес клиента, определённый ClientIPMiddleware.
+// Без middleware возвращается адрес соединения: заголовки не учитываются.
+func ClientIP(r *http.Request) string {
+	if ip, ok := r.Context().Value(clientIPKey{}).(string); ok {
+		return ip
+	}
+	return remoteIP(r)
+}
+
+// resolveClientIP определяет адрес клиента с учётом доверенных прокси.
+func resolveClientIP(r *http.Request, trustedProxies []*net.IPNet) string {
+	remote := remoteIP(r)
+	if !trusted(remote, trustedProxies) {
+		return remote
+	}
+
+	if ip := strings.TrimSpace(r.Header.Get("X-Real-IP")); net.ParseIP(ip) != nil {
+		return ip
+	}
+
+	client := remote
+	hops := strings.Split(strings.Join(r.Header.Values("X-Forwarded-For"), ","), ",")
+	for i := len(hops) - 1; i >= 0; i-- {
+		ip := strings.TrimSpace(hops[i])
+		if net.ParseIP(ip) == nil {
+			break
+		}
+		client = ip
+		if !trusted(ip, trustedProxies) {
+			break
+		}
+	}
+	return client
+}
+
+// remoteIP возвращает адрес соединения без порта.
+func remoteIP(r *http.Request) string {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+	return host
+}
+
+// trusted сообщает, входит ли адрес ip в одну из подсетей subnets.
+func trusted(ip string, subnets []*net.IPNet) bool {
+	parsed := net.ParseIP(ip)
+	if parsed == nil {
+		return false
+	}
+	for _, subnet := range subnets {
+		if subnet.Contains(parsed) {
+			return true
+		}
+	}
+	return false
+}
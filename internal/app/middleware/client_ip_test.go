@@ -0,0 +1,59 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/alexuryumtsev/go-shortener/internal/app/validator"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestClientIPMiddleware(t *testing.T) {
+	proxies, err := validator.ParseSubnets("10.0.0.0/8, 192.168.0.1")
+	require.NoError(t, err)
+
+	tests := []struct {
+		name       string
+		remoteAddr string
+		headers    map[string]string
+		want       string
+	}{
+		{"no headers", "203.0.113.5:1234", nil, "203.0.113.5"},
+		{"untrusted X-Real-IP", "203.0.113.5:1234", map[string]string{"X-Real-IP": "198.51.100.1"}, "203.0.113.5"},
+		{"untrusted X-Forwarded-For", "203.0.113.5:1234", map[string]string{"X-Forwarded-For": "198.51.100.1"}, "203.0.113.5"},
+		{"trusted X-Real-IP", "10.1.2.3:1234", map[string]string{"X-Real-IP": "198.51.100.1"}, "198.51.100.1"},
+		{"trusted invalid X-Real-IP", "10.1.2.3:1234", map[string]string{"X-Real-IP": "unknown"}, "10.1.2.3"},
+		{"X-Forwarded-For through proxy chain", "10.1.2.3:1234", map[string]string{"X-Forwarded-For": "198.51.100.1, 192.168.0.1"}, "198.51.100.1"},
+		{"X-Forwarded-For with spoofed left part", "10.1.2.3:1234", map[string]string{"X-Forwarded-For": "1.1.1.1, 198.51.100.1"}, "198.51.100.1"},
+		{"X-Forwarded-For of proxies only", "10.1.2.3:1234", map[string]string{"X-Forwarded-For": "10.9.9.9"}, "10.9.9.9"},
+		{"X-Forwarded-For with garbage", "10.1.2.3:1234", map[string]string{"X-Forwarded-For": "garbage, 10.9.9.9"}, "10.9.9.9"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got string
+			handler := ClientIPMiddleware(proxies)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				got = ClientIP(r)
+			}))
+
+			req := httptest.NewRequest(http.MethodGet, "/", nil)
+			req.RemoteAddr = tt.remoteAddr
+			for name, value := range tt.headers {
+				req.Header.Set(name, value)
+			}
+			handler.ServeHTTP(httptest.NewRecorder(), req)
+
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestClientIP_WithoutMiddleware(t *testing.T) {
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	req.RemoteAddr = "203.0.113.5:1234"
+	req.Header.Set("X-Real-IP", "198.51.100.1")
+
+	assert.Equal(t, "203.0.113.5", ClientIP(req))
+}
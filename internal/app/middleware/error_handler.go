@@ -6,9 +6,13 @@ import (
 	"errors"
 	"net/http"
 
+	"github.com/alexuryumtsev/go-shortener/internal/app/logger"
 	"github.com/alexuryumtsev/go-shortener/internal/app/models"
+	"github.com/alexuryumtsev/go-shortener/internal/app/service/url"
+	"github.com/alexuryumtsev/go-shortener/internal/app/storage"
 	"github.com/jackc/pgerrcode"
 	"github.com/jackc/pgx/v5/pgconn"
+	"go.uber.org/zap"
 )
 
 // ErrorMiddleware — middleware для обработки ошибок.
@@ -17,6 +21,8 @@ func ErrorMiddleware(next http.Handler) http.Handler {
 		defer func() {
 			// Поймаем панику, если она возникла
 			if rec := recover(); rec != nil {
+				logger.FromContext(r.Context()).Error("Panic while handling request",
+					zap.Any("panic", rec), zap.Stack("stack"))
 				http.Error(w, "Internal server error", http.StatusInternalServerError)
 			}
 		}()
@@ -28,8 +34,28 @@ func ErrorMiddleware(next http.Handler) http.Handler {
 
 // ProcessError — функция для обработки ошибок в контексте работы с БД.
 func ProcessError(w http.ResponseWriter, inputErr error, shortenedURL string, responseString bool) {
+	// Алиас занят другим URL: конфликт без возврата чужой ссылки
+	if errors.Is(inputErr, url.ErrAliasTaken) {
+		if responseString {
+			http.Error(w, url.ErrAliasTaken.Error(), http.StatusConflict)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusConflict)
+		if encodeErr := json.NewEncoder(w).Encode(models.ResponseBody{
+			Error: url.ErrAliasTaken.Error(),
+		}); encodeErr != nil {
+			http.Error(w, "Failed to encode response", http.StatusInternalServerError)
+		}
+		return
+	}
+
+	// URL уже сокращён: конфликт с возвратом существующей ссылки
 	var pgErr *pgconn.PgError
-	if errors.As(inputErr, &pgErr) && pgErr.Code == pgerrcode.UniqueViolation {
+	isConflict := errors.Is(inputErr, storage.ErrConflict) ||
+		(errors.As(inputErr, &pgErr) && pgErr.Code == pgerrcode.UniqueViolation)
+	if isConflict {
 		w.Header().Set("Content-Type", "application/json")
 		w.WriteHeader(http.StatusConflict)
 
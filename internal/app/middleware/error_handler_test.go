@@ -3,12 +3,15 @@ package middleware
 import (
 	"encoding/json"
 	"errors"
+	"fmt"
 	"io"
 	"net/http"
 	"net/http/httptest"
 	"testing"
 
 	"github.com/alexuryumtsev/go-shortener/internal/app/models"
+	"github.com/alexuryumtsev/go-shortener/internal/app/service/url"
+	"github.com/alexuryumtsev/go-shortener/internal/app/storage"
 	"github.com/jackc/pgerrcode"
 	"github.com/jackc/pgx/v5/pgconn"
 	"github.com/stretchr/testify/assert"
@@ -84,6 +87,24 @@ func TestProcessError(t *testing.T) {
 			wantStatus:     http.StatusConflict,
 			wantJSON:       true,
 		},
+		{
+			name:           "Storage conflict error",
+			err:            fmt.Errorf("%w: URL already shortened", storage.ErrConflict),
+			shortenedURL:   "http://short.url/abc",
+			responseString: true,
+			wantStatus:     http.StatusConflict,
+			wantBody:       "http://short.url/abc",
+			wantJSON:       false,
+		},
+		{
+			name:           "Alias taken error with string response",
+			err:            fmt.Errorf("correlation_id 1: %w", url.ErrAliasTaken),
+			shortenedURL:   "",
+			responseString: true,
+			wantStatus:     http.StatusConflict,
+			wantBody:       url.ErrAliasTaken.Error() + "\n",
+			wantJSON:       false,
+		},
 		{
 			name:         "Generic error",
 			err:          errors.New("test error"),
@@ -1,12 +1,18 @@
 package middleware
 
 import (
+	"context"
 	"net/http"
 	"net/http/httptest"
 	"testing"
+	"time"
 
+	"github.com/alexuryumtsev/go-shortener/internal/app/service/apikey"
 	"github.com/alexuryumtsev/go-shortener/internal/app/service/user"
+	"github.com/alexuryumtsev/go-shortener/internal/app/storage/memory"
+	"github.com/golang-jwt/jwt/v5"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
 func TestAuthMiddleware(t *testing.T) {
@@ -80,3 +86,145 @@ func TestAuthMiddleware(t *testing.T) {
 		assert.Equal(t, http.StatusOK, result.StatusCode)
 	})
 }
+
+func TestAuthMiddleware_Identity(t *testing.T) {
+	userService := user.NewUserService("test-secret")
+
+	var got user.Identity
+	handler := AuthMiddleware(userService, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		identity, ok := user.IdentityFromContext(r.Context())
+		require.True(t, ok)
+		got = identity
+		w.WriteHeader(http.StatusOK)
+	}))
+
+	// Первый запрос: личность нового анонимного пользователя доступна сразу
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/", nil))
+	require.Equal(t, http.StatusOK, rec.Code)
+	cookies := rec.Result().Cookies()
+	require.Len(t, cookies, 1)
+	first := got
+	assert.NotEmpty(t, first.UserID)
+	assert.True(t, first.Anonymous)
+	assert.Equal(t, user.AuthMethodCookie, first.Method)
+
+	// Повторный запрос с выданной кукой получает ту же личность без новой куки
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	req.AddCookie(cookies[0])
+	rec = httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+	require.Equal(t, http.StatusOK, rec.Code)
+	assert.Empty(t, rec.Result().Cookies())
+	assert.Equal(t, first, got)
+
+	// Токен зарегистрированного пользователя
+	token, err := userService.GenerateTokenForUser("registered")
+	require.NoError(t, err)
+	req = httptest.NewRequest(http.MethodGet, "/", nil)
+	req.AddCookie(&http.Cookie{Name: cookieName, Value: token})
+	rec = httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+	require.Equal(t, http.StatusOK, rec.Code)
+	assert.Equal(t, "registered", got.UserID)
+	assert.True(t, got.Authenticated())
+
+	// Токен, подпись которого не проверяется, заменяется новой анонимной личностью:
+	// так куки переживают перезапуск сервиса со случайным секретом
+	_, foreignToken, err := user.NewUserService("previous-random-secret").NewAnonymousUser()
+	require.NoError(t, err)
+	for _, value := range []string{"invalid", foreignToken} {
+		req = httptest.NewRequest(http.MethodGet, "/", nil)
+		req.AddCookie(&http.Cookie{Name: cookieName, Value: value})
+		rec = httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+		require.Equal(t, http.StatusOK, rec.Code)
+		assert.Len(t, rec.Result().Cookies(), 1, "a new cookie must be issued")
+		assert.True(t, got.Anonymous)
+		assert.NotEqual(t, first.UserID, got.UserID)
+	}
+
+	// Просроченный токен, подписанный действующим ключом, отклоняется
+	expired, err := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.MapClaims{
+		"user_id": "expired-user",
+		"exp":     time.Now().Add(-time.Hour).Unix(),
+	}).SignedString([]byte("test-secret"))
+	require.NoError(t, err)
+	req = httptest.NewRequest(http.MethodGet, "/", nil)
+	req.AddCookie(&http.Cookie{Name: cookieName, Value: expired})
+	rec = httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+	assert.Equal(t, http.StatusUnauthorized, rec.Code)
+	assert.Empty(t, rec.Result().Cookies())
+
+	// На публичных маршрутах просроченный токен заменяется новой анонимной личностью
+	public := PublicAuthMiddleware(userService, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		got, _ = user.IdentityFromContext(r.Context())
+		w.WriteHeader(http.StatusOK)
+	}))
+	req = httptest.NewRequest(http.MethodGet, "/abc", nil)
+	req.AddCookie(&http.Cookie{Name: cookieName, Value: expired})
+	rec = httptest.NewRecorder()
+	public.ServeHTTP(rec, req)
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Len(t, rec.Result().Cookies(), 1, "a new cookie must be issued")
+	assert.True(t, got.Anonymous)
+	assert.NotEqual(t, "expired-user", got.UserID)
+}
+
+func TestBearerAuthMiddleware(t *testing.T) {
+	userService := user.NewUserService("test-secret")
+	apiKeys := apikey.NewService(memory.NewInMemoryStorage())
+
+	var gotUserID string
+	handler := BearerAuthMiddleware(userService, apiKeys)(AuthMiddleware(userService,
+		http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			identity, _ := user.IdentityFromContext(r.Context())
+			gotUserID = identity.UserID
+			w.WriteHeader(http.StatusOK)
+		})))
+
+	tokenIdentity, token, err := userService.NewAnonymousUser()
+	require.NoError(t, err)
+	tokenUserID := tokenIdentity.UserID
+
+	_, readKey, err := apiKeys.Create(context.Background(), "key-owner", "reports", []string{apikey.ScopeURLsRead})
+	require.NoError(t, err)
+
+	tests := []struct {
+		name       string
+		method     string
+		header     string
+		wantCode   int
+		wantUserID string
+		wantCookie bool
+	}{
+		{"JWT", http.MethodGet, "Bearer " + token, http.StatusOK, tokenUserID, false},
+		{"API key", http.MethodGet, "Bearer " + readKey, http.StatusOK, "key-owner", false},
+		{"API key without scope", http.MethodPost, "Bearer " + readKey, http.StatusForbidden, "", false},
+		{"Unknown API key", http.MethodGet, "Bearer " + apikey.KeyPrefix + "unknown", http.StatusUnauthorized, "", false},
+		{"Invalid JWT", http.MethodGet, "Bearer invalid", http.StatusUnauthorized, "", false},
+		{"Unsupported scheme", http.MethodGet, "Basic dXNlcjpwYXNz", http.StatusUnauthorized, "", false},
+		{"No header", http.MethodGet, "", http.StatusOK, "", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			gotUserID = ""
+			req := httptest.NewRequest(tt.method, "/api/user/urls", nil)
+			if tt.header != "" {
+				req.Header.Set("Authorization", tt.header)
+			}
+			rec := httptest.NewRecorder()
+
+			handler.ServeHTTP(rec, req)
+
+			assert.Equal(t, tt.wantCode, rec.Code)
+			if tt.wantUserID != "" {
+				assert.Equal(t, tt.wantUserID, gotUserID)
+			}
+			// Анонимная личность выдаётся только запросам без заголовка Authorization
+			assert.Equal(t, tt.wantCookie, len(rec.Result().Cookies()) > 0)
+		})
+	}
+}
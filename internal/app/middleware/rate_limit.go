@@ -0,0 +1,78 @@
+package middleware
+
+import (
+	"math"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/alexuryumtsev/go-shortener/internal/app/logger"
+	"github.com/alexuryumtsev/go-shortener/internal/app/ratelimit"
+	"github.com/alexuryumtsev/go-shortener/internal/app/service/user"
+	"github.com/go-chi/chi/v5"
+	"go.uber.org/zap"
+)
+
+// RateLimitMiddleware ограничивает частоту запросов к маршрутам routes по правилам rules.
+// Маршрут определяется по шаблону chi ("GET /{id}"), поэтому все короткие ссылки
+// расходуют одно ведро клиента. Клиент определяется по ID зарегистрированного
+// пользователя или API-ключа, а для анонимных пользователей — по IP-адресу:
+// анонимный ID выдаётся каждому запросу без куки, и ограничение по нему легко обойти.
+//
+// В ответ добавляются заголовки RateLimit-Limit, RateLimit-Remaining, RateLimit-Reset
+// и RateLimit-Policy; при превышении ограничения запрос отклоняется с кодом
+// 429 Too Many Requests и заголовком Retry-After.
+// Если limiter недоступен, запрос пропускается, чтобы сбой хранилища не останавливал сервис.
+func RateLimitMiddleware(limiter ratelimit.Limiter, rules ratelimit.Rules, routes chi.Routes) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			pattern := routes.Find(chi.NewRouteContext(), r.Method, r.URL.Path)
+			if pattern == "" {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			limit := rules.For(r.Method, pattern)
+			if !limit.Enabled() {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			result, err := limiter.Allow(r.Context(), ratelimit.RouteKey(r.Method, pattern)+" "+rateLimitClient(r), limit)
+			if err != nil {
+				logger.FromContext(r.Context()).Warn("Rate limiter error, request is allowed", zap.Error(err))
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			h := w.Header()
+			h.Set("RateLimit-Limit", strconv.Itoa(limit.Requests))
+			h.Set("RateLimit-Remaining", strconv.Itoa(result.Remaining))
+			h.Set("RateLimit-Reset", ceilSeconds(result.Reset))
+			h.Set("RateLimit-Policy", strconv.Itoa(limit.Requests)+";w="+ceilSeconds(limit.Period))
+
+			if !result.Allowed {
+				h.Set("Retry-After", ceilSeconds(max(result.RetryAfter, time.Second)))
+				http.Error(w, "Too Many Requests", http.StatusTooManyRequests)
+				return
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// rateLimitClient возвращает ключ клиента для ограничения частоты запросов.
+// Адрес определяет ClientIPMiddleware: заголовки X-Real-IP и X-Forwarded-For
+// от клиентов не из доверенных прокси игнорируются, поэтому подделанный заголовок
+// не даёт клиенту новое ведро на каждый запрос.
+func rateLimitClient(r *http.Request) string {
+	if identity, ok := user.IdentityFromContext(r.Context()); ok && identity.Authenticated() {
+		return "user:" + identity.UserID
+	}
+	return "ip:" + ClientIP(r)
+}
+
+// ceilSeconds возвращает длительность в целых секундах с округлением вверх.
+func ceilSeconds(d time.Duration) string {
+	return strconv.Itoa(int(math.Ceil(d.Seconds())))
+}
@@ -0,0 +1,100 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/alexuryumtsev/go-shortener/internal/app/ratelimit"
+	"github.com/alexuryumtsev/go-shortener/internal/app/service/user"
+	"github.com/alexuryumtsev/go-shortener/internal/app/validator"
+	"github.com/go-chi/chi/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRateLimitMiddleware(t *testing.T) {
+	rules := ratelimit.Rules{
+		Routes: map[string]ratelimit.Limit{
+			ratelimit.RouteKey(http.MethodGet, "/{id}"): {Requests: 2, Period: time.Minute},
+		},
+	}
+
+	r := chi.NewRouter()
+	r.Use(RateLimitMiddleware(ratelimit.NewMemoryLimiter(), rules, r))
+	ok := func(w http.ResponseWriter, r *http.Request) { w.WriteHeader(http.StatusOK) }
+	r.Get("/{id}", ok)
+	r.Get("/ping", ok)
+
+	do := func(path, ip string, identity *user.Identity) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, path, nil)
+		req.RemoteAddr = ip + ":1234"
+		if identity != nil {
+			req = req.WithContext(user.ContextWithIdentity(req.Context(), *identity))
+		}
+		rec := httptest.NewRecorder()
+		r.ServeHTTP(rec, req)
+		return rec
+	}
+
+	// Все короткие ссылки расходуют одно ведро клиента
+	rec := do("/aaa", "10.0.0.1", nil)
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Equal(t, "2", rec.Header().Get("RateLimit-Limit"))
+	assert.Equal(t, "1", rec.Header().Get("RateLimit-Remaining"))
+	assert.Equal(t, "2;w=60", rec.Header().Get("RateLimit-Policy"))
+
+	assert.Equal(t, http.StatusOK, do("/bbb", "10.0.0.1", nil).Code)
+
+	rec = do("/ccc", "10.0.0.1", nil)
+	assert.Equal(t, http.StatusTooManyRequests, rec.Code)
+	assert.Equal(t, "30", rec.Header().Get("Retry-After"))
+	assert.Equal(t, "0", rec.Header().Get("RateLimit-Remaining"))
+
+	// Маршруты без ограничения не затрагиваются
+	rec = do("/ping", "10.0.0.1", nil)
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Empty(t, rec.Header().Get("RateLimit-Limit"))
+
+	// Анонимные пользователи ограничиваются по IP, даже с разными ID
+	anonymous := &user.Identity{UserID: "fresh", Method: user.AuthMethodCookie, Anonymous: true}
+	assert.Equal(t, http.StatusTooManyRequests, do("/ddd", "10.0.0.1", anonymous).Code)
+	assert.Equal(t, http.StatusOK, do("/ddd", "10.0.0.2", anonymous).Code)
+
+	// Зарегистрированные пользователи — по ID, независимо от адреса
+	registered := &user.Identity{UserID: "alice", Method: user.AuthMethodBearer}
+	assert.Equal(t, http.StatusOK, do("/eee", "10.0.0.1", registered).Code)
+	assert.Equal(t, http.StatusOK, do("/eee", "10.0.0.3", registered).Code)
+	assert.Equal(t, http.StatusTooManyRequests, do("/eee", "10.0.0.4", registered).Code)
+}
+
+func TestRateLimitMiddleware_ForwardedHeaders(t *testing.T) {
+	rules := ratelimit.Rules{Default: ratelimit.Limit{Requests: 1, Period: time.Minute}}
+	proxies, err := validator.ParseSubnets("10.0.0.1")
+	require.NoError(t, err)
+
+	r := chi.NewRouter()
+	r.Use(ClientIPMiddleware(proxies))
+	r.Use(RateLimitMiddleware(ratelimit.NewMemoryLimiter(), rules, r))
+	r.Get("/{id}", func(w http.ResponseWriter, r *http.Request) { w.WriteHeader(http.StatusOK) })
+
+	do := func(remoteAddr, header, value string) int {
+		req := httptest.NewRequest(http.MethodGet, "/abc", nil)
+		req.RemoteAddr = remoteAddr
+		req.Header.Set(header, value)
+		rec := httptest.NewRecorder()
+		r.ServeHTTP(rec, req)
+		return rec.Code
+	}
+
+	// Клиент не получает новое ведро, меняя заголовки в каждом запросе
+	assert.Equal(t, http.StatusOK, do("203.0.113.5:1234", "X-Real-IP", "198.51.100.1"))
+	assert.Equal(t, http.StatusTooManyRequests, do("203.0.113.5:1234", "X-Real-IP", "198.51.100.2"))
+	assert.Equal(t, http.StatusTooManyRequests, do("203.0.113.5:1234", "X-Forwarded-For", "198.51.100.3"))
+
+	// Клиенты за доверенным прокси различаются по заголовку
+	assert.Equal(t, http.StatusOK, do("10.0.0.1:1234", "X-Real-IP", "198.51.100.1"))
+	assert.Equal(t, http.StatusOK, do("10.0.0.1:1234", "X-Forwarded-For", "198.51.100.2"))
+	assert.Equal(t, http.StatusTooManyRequests, do("10.0.0.1:1234", "X-Forwarded-For", "198.51.100.2"))
+}
@@ -0,0 +1,33 @@
+package middleware
+
+import (
+	"net"
+	"net/http"
+)
+
+// TrustedSubnetMiddleware пропускает только запросы из доверенной подсети trustedSubnet (CIDR).
+// IP-адрес клиента определяется ClientIPMiddleware: заголовок X-Real-IP учитывается,
+// только если запрос пришёл через доверенный прокси.
+// Если подсеть не задана или некорректна, доступ запрещён всем.
+func TrustedSubnetMiddleware(trustedSubnet string) func(http.Handler) http.Handler {
+	var subnet *net.IPNet
+	if trustedSubnet != "" {
+		_, subnet, _ = net.ParseCIDR(trustedSubnet)
+	}
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if subnet == nil {
+				http.Error(w, "Forbidden", http.StatusForbidden)
+				return
+			}
+
+			ip := net.ParseIP(ClientIP(r))
+			if ip == nil || !subnet.Contains(ip) {
+				http.Error(w, "Forbidden", http.StatusForbidden)
+				return
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
@@ -0,0 +1,50 @@
+package middleware
+
+import (
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTrustedSubnetMiddleware(t *testing.T) {
+	next := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	})
+
+	// Заголовкам доверяется только от прокси 10.0.0.1
+	proxies := []*net.IPNet{{IP: net.IPv4(10, 0, 0, 1).To4(), Mask: net.CIDRMask(32, 32)}}
+
+	tests := []struct {
+		name       string
+		subnet     string
+		realIP     string
+		remoteAddr string
+		want       int
+	}{
+		{"X-Real-IP from proxy inside subnet", "192.168.1.0/24", "192.168.1.10", "10.0.0.1:1234", http.StatusOK},
+		{"X-Real-IP from proxy outside subnet", "192.168.1.0/24", "192.168.2.10", "10.0.0.1:1234", http.StatusForbidden},
+		{"Spoofed X-Real-IP from untrusted client", "192.168.1.0/24", "192.168.1.10", "203.0.113.5:1234", http.StatusForbidden},
+		{"Remote address inside subnet", "10.0.0.0/8", "", "10.1.2.3:1234", http.StatusOK},
+		{"Remote address outside subnet", "10.0.0.0/8", "", "127.0.0.1:1234", http.StatusForbidden},
+		{"Invalid X-Real-IP from proxy", "192.168.1.0/24", "not-an-ip", "10.0.0.1:1234", http.StatusForbidden},
+		{"Empty subnet", "", "10.1.2.3", "10.1.2.3:1234", http.StatusForbidden},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "/api/internal/stats", nil)
+			req.RemoteAddr = tt.remoteAddr
+			if tt.realIP != "" {
+				req.Header.Set("X-Real-IP", tt.realIP)
+			}
+			rec := httptest.NewRecorder()
+
+			ClientIPMiddleware(proxies)(TrustedSubnetMiddleware(tt.subnet)(next)).ServeHTTP(rec, req)
+
+			assert.Equal(t, tt.want, rec.Code)
+		})
+	}
+}
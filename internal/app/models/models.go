@@ -1,21 +1,43 @@
 // Package models содержит структуры и модели данных, используемые в приложении.
 package models
 
+import "time"
+
 // URLModel представляет собой модель для хранения информации о URL.
 // Содержит идентификатор, оригинальный URL, идентификатор пользователя и флаг удаления.
 // Используется для работы с базой данных и хранения информации о сокращённых URL.
+// Нулевой ExpiresAt означает бессрочную ссылку.
 type URLModel struct {
-	ID      string
-	URL     string
-	UserID  string
-	Deleted bool
+	ID        string
+	URL       string
+	UserID    string
+	Deleted   bool
+	ExpiresAt time.Time
+}
+
+// Expired сообщает, истёк ли срок действия ссылки к моменту now.
+func (m URLModel) Expired(now time.Time) bool {
+	return !m.ExpiresAt.IsZero() && !now.Before(m.ExpiresAt)
+}
+
+// ShortenOptions содержит необязательные параметры создания короткого URL.
+// TTL и ExpiresAt взаимоисключающие: TTL отсчитывается от момента создания.
+type ShortenOptions struct {
+	Alias     string
+	TTL       time.Duration
+	ExpiresAt time.Time
 }
 
 // URLBatchModel представляет собой модель для пакетной обработки URL.
 // Используется при создании множества коротких URL за один запрос.
+// Необязательный Alias задаёт пользовательский короткий идентификатор,
+// TTL (в секундах) или ExpiresAt — срок действия ссылки.
 type URLBatchModel struct {
-	CorrelationID string `json:"correlation_id"`
-	OriginalURL   string `json:"original_url"`
+	CorrelationID string     `json:"correlation_id"`
+	OriginalURL   string     `json:"original_url"`
+	Alias         string     `json:"alias,omitempty"`
+	TTL           int64      `json:"ttl,omitempty"`
+	ExpiresAt     *time.Time `json:"expires_at,omitempty"`
 }
 
 // BatchResponseModel представляет собой модель ответа для пакетной обработки URL.
@@ -26,18 +48,115 @@ type BatchResponseModel struct {
 }
 
 // RequestBody определяет структуру входных данных.
+// Необязательный Alias задаёт пользовательский короткий идентификатор,
+// TTL (в секундах) или ExpiresAt — срок действия ссылки.
 type RequestBody struct {
-	URL string `json:"url"`
+	URL       string     `json:"url"`
+	Alias     string     `json:"alias,omitempty"`
+	TTL       int64      `json:"ttl,omitempty"`
+	ExpiresAt *time.Time `json:"expires_at,omitempty"`
 }
 
 // ResponseBody определяет структуру ответа.
+// Error заполняется, если короткий URL не может быть возвращён.
 type ResponseBody struct {
-	ShortURL string `json:"result"`
+	ShortURL string `json:"result,omitempty"`
+	Error    string `json:"error,omitempty"`
 }
 
 // UserURLModel представляет собой модель для URL пользователя.
-// Содержит короткий URL и оригинальный URL.
+// Содержит короткий URL, оригинальный URL и срок действия, если он задан.
 type UserURLModel struct {
-	ShortURL    string `json:"short_url"`
-	OriginalURL string `json:"original_url"`
+	ShortURL    string     `json:"short_url"`
+	OriginalURL string     `json:"original_url"`
+	ExpiresAt   *time.Time `json:"expires_at,omitempty"`
+}
+
+// DeletionJobModel представляет собой модель состояния асинхронной задачи удаления URL.
+// Содержит идентификатор задачи, её статус и описание ошибки, если удаление не удалось.
+type DeletionJobModel struct {
+	ID     string `json:"id"`
+	Status string `json:"status"`
+	Error  string `json:"error,omitempty"`
+}
+
+// ClickEvent представляет собой событие перехода по короткой ссылке.
+// IP-адрес клиента хранится только в виде хеша.
+type ClickEvent struct {
+	ShortID   string
+	Timestamp time.Time
+	Referrer  string
+	UserAgent string
+	IPHash    string
+}
+
+// LinkStatsModel представляет собой статистику переходов по короткой ссылке.
+// Содержит общее число переходов, число уникальных посетителей и разбивку по дням.
+type LinkStatsModel struct {
+	ShortURL       string            `json:"short_url"`
+	TotalClicks    int               `json:"total_clicks"`
+	UniqueVisitors int               `json:"unique_visitors"`
+	Daily          []DailyStatsModel `json:"daily"`
+}
+
+// DailyStatsModel представляет собой статистику переходов за один день (UTC).
+type DailyStatsModel struct {
+	Date           string `json:"date"`
+	Clicks         int    `json:"clicks"`
+	UniqueVisitors int    `json:"unique_visitors"`
+}
+
+// StatsModel представляет собой сводную статистику сервиса.
+// URLs — действующие ссылки, DeletedURLs — удалённые пользователями, Users — число пользователей.
+type StatsModel struct {
+	URLs        int `json:"urls"`
+	DeletedURLs int `json:"deleted_urls"`
+	Users       int `json:"users"`
+}
+
+// APIKeyModel представляет собой API-ключ пользователя.
+// Сам ключ не хранится: сохраняется только его хеш KeyHash.
+type APIKeyModel struct {
+	ID         string     `json:"id"`
+	UserID     string     `json:"-"`
+	Name       string     `json:"name"`
+	KeyHash    string     `json:"-"`
+	Scopes     []string   `json:"scopes"`
+	CreatedAt  time.Time  `json:"created_at"`
+	LastUsedAt *time.Time `json:"last_used_at,omitempty"`
+}
+
+// APIKeyRequest представляет собой запрос на создание API-ключа.
+type APIKeyRequest struct {
+	Name   string   `json:"name"`
+	Scopes []string `json:"scopes,omitempty"`
+}
+
+// APIKeyResponse представляет собой ответ на создание API-ключа.
+// Key возвращается только один раз, при создании.
+type APIKeyResponse struct {
+	APIKeyModel
+	Key string `json:"key"`
+}
+
+// UserModel представляет собой зарегистрированного пользователя.
+type UserModel struct {
+	ID           string    `json:"user_id"`
+	Login        string    `json:"login"`
+	PasswordHash string    `json:"-"`
+	CreatedAt    time.Time `json:"created_at"`
+}
+
+// CredentialsRequest представляет собой запрос на регистрацию или вход.
+type CredentialsRequest struct {
+	Login    string `json:"login"`
+	Password string `json:"password"`
+}
+
+// AuthResponse представляет собой ответ на регистрацию или вход.
+// MergedURLs — число ссылок анонимного пользователя, перенесённых в аккаунт.
+type AuthResponse struct {
+	UserID     string `json:"user_id"`
+	Login      string `json:"login"`
+	MergedURLs int    `json:"merged_urls"`
 }
@@ -0,0 +1,137 @@
+// Package ratelimit реализует ограничение частоты запросов по алгоритму token bucket.
+//
+// Limiter — интерфейс хранилища «вёдер»: в одном экземпляре сервиса достаточно
+// MemoryLimiter, для нескольких экземпляров за балансировщиком нужна реализация
+// поверх общего хранилища (например, Redis) с той же семантикой.
+package ratelimit
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ErrInvalidLimit возвращается при разборе некорректного описания ограничения.
+var ErrInvalidLimit = errors.New("invalid rate limit")
+
+// Limit описывает ограничение: не более Requests запросов за Period.
+// Ёмкость ведра равна Requests, поэтому допускается всплеск из Requests запросов,
+// после чего ведро пополняется равномерно. Нулевое значение означает отсутствие ограничения.
+type Limit struct {
+	Requests int
+	Period   time.Duration
+}
+
+// Enabled сообщает, задано ли ограничение.
+func (l Limit) Enabled() bool {
+	return l.Requests > 0 && l.Period > 0
+}
+
+// String возвращает ограничение в формате ParseLimit.
+func (l Limit) String() string {
+	if !l.Enabled() {
+		return ""
+	}
+	return strconv.Itoa(l.Requests) + "/" + l.Period.String()
+}
+
+// ParseLimit разбирает ограничение вида "100/1m": число запросов и период.
+// Пустая строка означает отсутствие ограничения.
+func ParseLimit(s string) (Limit, error) {
+	s = strings.TrimSpace(s)
+	if s == "" {
+		return Limit{}, nil
+	}
+
+	requests, period, ok := strings.Cut(s, "/")
+	if !ok {
+		return Limit{}, fmt.Errorf("%w %q: expected <requests>/<period>", ErrInvalidLimit, s)
+	}
+	n, err := strconv.Atoi(strings.TrimSpace(requests))
+	if err != nil || n <= 0 {
+		return Limit{}, fmt.Errorf("%w %q: requests must be a positive integer", ErrInvalidLimit, s)
+	}
+	d, err := time.ParseDuration(strings.TrimSpace(period))
+	if err != nil || d <= 0 {
+		return Limit{}, fmt.Errorf("%w %q: period must be a positive duration", ErrInvalidLimit, s)
+	}
+	return Limit{Requests: n, Period: d}, nil
+}
+
+// Rules задаёт ограничения по маршрутам.
+type Rules struct {
+	// Default применяется к маршрутам без собственного ограничения
+	Default Limit
+	// Routes содержит ограничения маршрутов по ключу "<METHOD> <шаблон chi>",
+	// например "POST /api/shorten/batch" или "GET /{id}"
+	Routes map[string]Limit
+}
+
+// ParseRules разбирает ограничение по умолчанию и список ограничений маршрутов
+// вида "POST /api/shorten/batch=10/1m,GET /{id}=300/1m".
+func ParseRules(defaultLimit, routes string) (Rules, error) {
+	def, err := ParseLimit(defaultLimit)
+	if err != nil {
+		return Rules{}, err
+	}
+
+	rules := Rules{Default: def, Routes: make(map[string]Limit)}
+	for _, entry := range strings.Split(routes, ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+
+		route, spec, ok := strings.Cut(entry, "=")
+		if !ok {
+			return Rules{}, fmt.Errorf("%w %q: expected <METHOD> <route>=<requests>/<period>", ErrInvalidLimit, entry)
+		}
+		method, pattern, ok := strings.Cut(strings.TrimSpace(route), " ")
+		pattern = strings.TrimSpace(pattern)
+		if !ok || method == "" || !strings.HasPrefix(pattern, "/") {
+			return Rules{}, fmt.Errorf("%w %q: route must be <METHOD> </path>", ErrInvalidLimit, entry)
+		}
+
+		limit, err := ParseLimit(spec)
+		if err != nil {
+			return Rules{}, err
+		}
+		rules.Routes[RouteKey(method, pattern)] = limit
+	}
+	return rules, nil
+}
+
+// RouteKey возвращает ключ маршрута для Rules.Routes.
+func RouteKey(method, pattern string) string {
+	return strings.ToUpper(method) + " " + pattern
+}
+
+// For возвращает ограничение маршрута.
+func (r Rules) For(method, pattern string) Limit {
+	if limit, ok := r.Routes[RouteKey(method, pattern)]; ok {
+		return limit
+	}
+	return r.Default
+}
+
+// Result — результат проверки запроса.
+type Result struct {
+	// Allowed сообщает, разрешён ли запрос
+	Allowed bool
+	// Remaining — число запросов, которые можно выполнить прямо сейчас
+	Remaining int
+	// Reset — время до полного пополнения ведра
+	Reset time.Duration
+	// RetryAfter — время до появления следующего разрешённого запроса;
+	// заполняется, только если запрос отклонён
+	RetryAfter time.Duration
+}
+
+// Limiter расходует запросы из ведра key с ограничением limit.
+// Реализации должны быть безопасны для конкурентного использования.
+type Limiter interface {
+	Allow(ctx context.Context, key string, limit Limit) (Result, error)
+}
@@ -0,0 +1,81 @@
+package ratelimit
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseRules(t *testing.T) {
+	rules, err := ParseRules("100/1m", "POST /api/shorten/batch=10/1m, get /{id}=5/1s")
+	require.NoError(t, err)
+
+	assert.Equal(t, Limit{Requests: 10, Period: time.Minute}, rules.For("POST", "/api/shorten/batch"))
+	assert.Equal(t, Limit{Requests: 5, Period: time.Second}, rules.For("GET", "/{id}"))
+	assert.Equal(t, Limit{Requests: 100, Period: time.Minute}, rules.For("GET", "/api/user/urls"))
+	assert.Equal(t, "10/1m0s", rules.For("POST", "/api/shorten/batch").String())
+
+	rules, err = ParseRules("", "")
+	require.NoError(t, err)
+	assert.False(t, rules.For("GET", "/{id}").Enabled())
+
+	for _, tt := range []struct{ def, routes string }{
+		{"100", ""},
+		{"0/1m", ""},
+		{"10/forever", ""},
+		{"", "/api/shorten=10/1m"},
+		{"", "POST /api/shorten"},
+		{"", "POST api/shorten=10/1m"},
+	} {
+		_, err := ParseRules(tt.def, tt.routes)
+		assert.ErrorIs(t, err, ErrInvalidLimit, "%q %q", tt.def, tt.routes)
+	}
+}
+
+func TestMemoryLimiter(t *testing.T) {
+	ctx := context.Background()
+	now := time.Now()
+	limiter := NewMemoryLimiter()
+	limiter.now = func() time.Time { return now }
+	limit := Limit{Requests: 3, Period: 3 * time.Second}
+
+	// Всплеск размером с ёмкость ведра
+	for i := 2; i >= 0; i-- {
+		result, err := limiter.Allow(ctx, "client", limit)
+		require.NoError(t, err)
+		assert.True(t, result.Allowed)
+		assert.Equal(t, i, result.Remaining)
+	}
+
+	result, err := limiter.Allow(ctx, "client", limit)
+	require.NoError(t, err)
+	assert.False(t, result.Allowed)
+	assert.Equal(t, time.Second, result.RetryAfter)
+	assert.Equal(t, 3*time.Second, result.Reset)
+
+	// Другие клиенты расходуют свои вёдра
+	result, err = limiter.Allow(ctx, "other", limit)
+	require.NoError(t, err)
+	assert.True(t, result.Allowed)
+
+	// Ведро пополняется равномерно
+	now = now.Add(time.Second)
+	result, err = limiter.Allow(ctx, "client", limit)
+	require.NoError(t, err)
+	assert.True(t, result.Allowed)
+	assert.Equal(t, 0, result.Remaining)
+
+	// Полностью пополнившиеся вёдра удаляются
+	now = now.Add(sweepInterval)
+	_, err = limiter.Allow(ctx, "client", limit)
+	require.NoError(t, err)
+	assert.Len(t, limiter.buckets, 1)
+
+	// Без ограничения запросы не учитываются
+	result, err = limiter.Allow(ctx, "client", Limit{})
+	require.NoError(t, err)
+	assert.True(t, result.Allowed)
+}
@@ -3,20 +3,26 @@
 package router
 
 import (
-	"log"
 	"net/http"
 	"net/http/pprof"
 
 	"github.com/alexuryumtsev/go-shortener/config"
 	"github.com/alexuryumtsev/go-shortener/internal/app/compress"
 	"github.com/alexuryumtsev/go-shortener/internal/app/handlers"
+	"github.com/alexuryumtsev/go-shortener/internal/app/health"
 	"github.com/alexuryumtsev/go-shortener/internal/app/logger"
+	"github.com/alexuryumtsev/go-shortener/internal/app/metrics"
 	"github.com/alexuryumtsev/go-shortener/internal/app/middleware"
+	"github.com/alexuryumtsev/go-shortener/internal/app/ratelimit"
+	"github.com/alexuryumtsev/go-shortener/internal/app/service/account"
+	"github.com/alexuryumtsev/go-shortener/internal/app/service/apikey"
 	"github.com/alexuryumtsev/go-shortener/internal/app/service/url"
 	"github.com/alexuryumtsev/go-shortener/internal/app/service/user"
 	"github.com/alexuryumtsev/go-shortener/internal/app/storage"
-	"github.com/alexuryumtsev/go-shortener/internal/app/storage/file"
+	"github.com/alexuryumtsev/go-shortener/internal/app/tracing"
+	"github.com/alexuryumtsev/go-shortener/internal/app/validator"
 	"github.com/go-chi/chi/v5"
+	"go.uber.org/zap"
 )
 
 // setupProfiling добавляет маршруты для профилирования
@@ -26,40 +32,93 @@ func setupProfiling(r chi.Router) {
 }
 
 // ShortenerRouter создает маршруты для приложения.
-func ShortenerRouter(cfg *config.Config, repo storage.URLStorage, userService user.UserService, urlService url.URLService) chi.Router {
-	// Загрузка данных из файла, если используется файловое хранилище.
-	if fileRepo, ok := repo.(*file.FileStorage); ok {
-		if err := fileRepo.LoadFromFile(); err != nil {
-			log.Printf("Error loading storage from file: %v", err)
-		}
+// probe проверяет готовность сервиса для /readyz.
+func ShortenerRouter(cfg *config.Config, repo storage.URLStorage, userService user.UserService, urlService url.URLService, apiKeys apikey.Service, accounts account.Service, limiter ratelimit.Limiter, probe *health.Probe) chi.Router {
+	// Загрузка данных из файла; хранилища без файла ничего не делают.
+	// Хранилище может быть обёрнуто метриками, поэтому тип не проверяется.
+	if err := repo.LoadFromFile(); err != nil {
+		logger.L().Error("Error loading storage from file", zap.Error(err))
 	}
 
 	// Регистрация маршрутов.
 	r := chi.NewRouter()
 
+	r.Use(tracing.Middleware)
+	r.Use(logger.RequestIDMiddleware)
+
+	// Адрес клиента определяется один раз для всех middleware и обработчиков
+	proxies, err := validator.ParseSubnets(cfg.TrustedProxies)
+	if err != nil {
+		logger.L().Error("Invalid trusted proxies, forwarded headers are ignored", zap.Error(err))
+	}
+	r.Use(middleware.ClientIPMiddleware(proxies))
+	r.Use(metrics.Middleware)
 	r.Use(logger.Middleware)
 	r.Use(compress.GzipMiddleware)
 	r.Use(middleware.ErrorMiddleware)
 
-	// Добавляем middleware с userService
-	r.Use(func(next http.Handler) http.Handler {
-		return middleware.AuthMiddleware(userService, next)
-	})
+	// Пробы оркестратора и сбор метрик не требуют аутентификации и не ограничиваются
+	// по частоте, поэтому регистрируются до соответствующих middleware
+	r.Get("/healthz", handlers.LivenessHandler())
+	r.Get("/readyz", handlers.ReadinessHandler(probe))
+
+	// Метрики раскрывают внутреннее состояние сервиса, поэтому доступны только
+	// из доверенной подсети, но без аутентификации
+	r.With(middleware.TrustedSubnetMiddleware(cfg.TrustedSubnet)).
+		Method(http.MethodGet, "/metrics", metrics.Handler())
+
+	// Ограничение частоты запросов определяет маршрут по всему маршрутизатору
+	rules, err := ratelimit.ParseRules(cfg.RateLimit, cfg.RateLimitRoutes)
+	if err != nil {
+		logger.L().Error("Invalid rate limits, rate limiting is disabled", zap.Error(err))
+	}
+	rateLimit := middleware.RateLimitMiddleware(limiter, rules, r)
+
+	r.Group(func(r chi.Router) {
+		// Заголовок Authorization проверяется раньше куки
+		r.Use(middleware.BearerAuthMiddleware(userService, apiKeys))
+
+		// Публичные маршруты: просроченная кука заменяется новой анонимной личностью
+		r.Group(func(r chi.Router) {
+			r.Use(func(next http.Handler) http.Handler {
+				return middleware.PublicAuthMiddleware(userService, next)
+			})
+
+			// Ограничение частоты запросов учитывает личность пользователя,
+			// поэтому подключается после аутентификации
+			r.Use(rateLimit)
+
+			// Добавляем профилирование только в режиме отладки
+			if cfg.Debug {
+				setupProfiling(r)
+			}
+
+			r.Post("/", handlers.PostHandler(urlService))
+			r.Get("/{id}", handlers.GetHandler(urlService))
+			r.Get("/ping", handlers.PingHandler(repo))
+			r.Post("/api/shorten", handlers.PostJSONHandler(urlService))
+			r.Post("/api/shorten/batch", handlers.PostBatchHandler(urlService))
+			r.With(middleware.TrustedSubnetMiddleware(cfg.TrustedSubnet)).
+				Get("/api/internal/stats", handlers.InternalStatsHandler(urlService))
+		})
+
+		// Маршруты пользователя: просроченная кука отклоняется с кодом 401
+		r.Group(func(r chi.Router) {
+			r.Use(func(next http.Handler) http.Handler {
+				return middleware.AuthMiddleware(userService, next)
+			})
+			r.Use(rateLimit)
 
-	r.Route("/", func(r chi.Router) {
-		// Добавляем профилирование только в режиме отладки
-		log.Println(cfg.Debug)
-		if cfg.Debug {
-			setupProfiling(r)
-		}
-
-		r.Post("/", handlers.PostHandler(urlService, userService))
-		r.Get("/{id}", handlers.GetHandler(urlService))
-		r.Get("/ping", handlers.PingHandler(repo))
-		r.Get("/api/user/urls", handlers.GetUserURLsHandler(urlService, userService))
-		r.Delete("/api/user/urls", handlers.DeleteUserURLsHandler(urlService, userService))
-		r.Post("/api/shorten", handlers.PostJSONHandler(urlService, userService))
-		r.Post("/api/shorten/batch", handlers.PostBatchHandler(urlService, userService))
+			r.Get("/api/user/urls", handlers.GetUserURLsHandler(urlService))
+			r.Delete("/api/user/urls", handlers.DeleteUserURLsHandler(urlService))
+			r.Get("/api/user/urls/{id}/stats", handlers.GetLinkStatsHandler(urlService))
+			r.Post("/api/user/register", handlers.RegisterHandler(accounts, userService))
+			r.Post("/api/user/login", handlers.LoginHandler(accounts, userService))
+			r.Post("/api/user/keys", handlers.CreateAPIKeyHandler(apiKeys))
+			r.Get("/api/user/keys", handlers.ListAPIKeysHandler(apiKeys))
+			r.Delete("/api/user/keys/{id}", handlers.RevokeAPIKeyHandler(apiKeys))
+			r.Get("/api/user/deletions/{id}", handlers.GetDeletionStatusHandler(urlService))
+		})
 	})
 
 	return r
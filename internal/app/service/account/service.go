@@ -0,0 +1,134 @@
+// Package account предоставляет регистрацию и вход зарегистрированных пользователей.
+//
+// Анонимный пользователь получает случайный ID в куке, и его ссылки теряются вместе
+// с истёкшей кукой. После регистрации или входа ссылки, созданные анонимно,
+// переносятся в аккаунт.
+package account
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"regexp"
+	"time"
+
+	"github.com/alexuryumtsev/go-shortener/internal/app/models"
+	"github.com/alexuryumtsev/go-shortener/internal/app/service/user"
+	"github.com/alexuryumtsev/go-shortener/internal/app/storage"
+	"golang.org/x/crypto/bcrypt"
+)
+
+const (
+	// minPasswordLength — минимальная длина пароля в байтах
+	minPasswordLength = 8
+	// maxPasswordLength — ограничение bcrypt на длину пароля в байтах
+	maxPasswordLength = 72
+)
+
+// loginRe задаёт допустимый формат логина.
+var loginRe = regexp.MustCompile(`^[a-zA-Z0-9._@-]{3,64}$`)
+
+var (
+	// ErrInvalidLogin возвращается при недопустимом формате логина.
+	ErrInvalidLogin = errors.New("login must be 3-64 characters: letters, digits, '.', '_', '@' or '-'")
+	// ErrInvalidPassword возвращается, если пароль слишком короткий или длинный.
+	ErrInvalidPassword = fmt.Errorf("password must be %d-%d bytes long", minPasswordLength, maxPasswordLength)
+	// ErrLoginTaken возвращается при регистрации с занятым логином.
+	ErrLoginTaken = fmt.Errorf("login is already taken: %w", storage.ErrConflict)
+	// ErrInvalidCredentials возвращается при неверном логине или пароле.
+	ErrInvalidCredentials = errors.New("invalid login or password")
+)
+
+// Service определяет интерфейс регистрации и входа.
+type Service interface {
+	// Register создаёт аккаунт и переносит в него ссылки анонимного пользователя anonymousID.
+	// Возвращает аккаунт и число перенесённых ссылок.
+	Register(ctx context.Context, login, password, anonymousID string) (models.UserModel, int, error)
+
+	// Login проверяет логин и пароль и переносит в аккаунт ссылки анонимного пользователя anonymousID.
+	// Возвращает аккаунт и число перенесённых ссылок.
+	Login(ctx context.Context, login, password, anonymousID string) (models.UserModel, int, error)
+}
+
+// service реализация Service
+type service struct {
+	storage storage.UserStorage
+	cost    int
+	// dummyHash сравнивается с паролем, если пользователь не найден,
+	// чтобы время ответа не выдавало существование логина
+	dummyHash []byte
+}
+
+// NewService создаёт сервис аккаунтов поверх хранилища.
+func NewService(storage storage.UserStorage) Service {
+	return newService(storage, bcrypt.DefaultCost)
+}
+
+// newService создаёт сервис с заданной стоимостью bcrypt.
+func newService(storage storage.UserStorage, cost int) *service {
+	dummyHash, _ := bcrypt.GenerateFromPassword([]byte("dummy-password"), cost)
+	return &service{storage: storage, cost: cost, dummyHash: dummyHash}
+}
+
+// Register создаёт аккаунт с паролем, хешированным bcrypt.
+func (s *service) Register(ctx context.Context, login, password, anonymousID string) (models.UserModel, int, error) {
+	if !loginRe.MatchString(login) {
+		return models.UserModel{}, 0, ErrInvalidLogin
+	}
+	if len(password) < minPasswordLength || len(password) > maxPasswordLength {
+		return models.UserModel{}, 0, ErrInvalidPassword
+	}
+
+	hash, err := bcrypt.GenerateFromPassword([]byte(password), s.cost)
+	if err != nil {
+		return models.UserModel{}, 0, fmt.Errorf("failed to hash password: %w", err)
+	}
+
+	account := models.UserModel{
+		ID:           user.GenerateUserID(),
+		Login:        login,
+		PasswordHash: string(hash),
+		CreatedAt:    time.Now().UTC(),
+	}
+	if err := s.storage.CreateUser(ctx, account); err != nil {
+		if errors.Is(err, storage.ErrConflict) {
+			return models.UserModel{}, 0, ErrLoginTaken
+		}
+		return models.UserModel{}, 0, err
+	}
+
+	merged, err := s.merge(ctx, anonymousID, account.ID)
+	return account, merged, err
+}
+
+// Login проверяет пароль аккаунта.
+func (s *service) Login(ctx context.Context, login, password, anonymousID string) (models.UserModel, int, error) {
+	account, exists := s.storage.GetUserByLogin(ctx, login)
+	if !exists {
+		bcrypt.CompareHashAndPassword(s.dummyHash, []byte(password))
+		return models.UserModel{}, 0, ErrInvalidCredentials
+	}
+	if err := bcrypt.CompareHashAndPassword([]byte(account.PasswordHash), []byte(password)); err != nil {
+		return models.UserModel{}, 0, ErrInvalidCredentials
+	}
+
+	merged, err := s.merge(ctx, anonymousID, account.ID)
+	return account, merged, err
+}
+
+// merge переносит ссылки и API-ключи анонимного пользователя anonymousID в аккаунт accountID.
+// Ссылки другого зарегистрированного пользователя не переносятся.
+func (s *service) merge(ctx context.Context, anonymousID, accountID string) (int, error) {
+	if anonymousID == "" || anonymousID == accountID {
+		return 0, nil
+	}
+	if _, registered := s.storage.GetUserByID(ctx, anonymousID); registered {
+		return 0, nil
+	}
+
+	merged, err := s.storage.ReassignUserURLs(ctx, anonymousID, accountID)
+	if err != nil {
+		return 0, fmt.Errorf("failed to merge anonymous URLs: %w", err)
+	}
+	return merged, nil
+}
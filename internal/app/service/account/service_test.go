@@ -0,0 +1,79 @@
+package account
+
+import (
+	"context"
+	"testing"
+
+	"github.com/alexuryumtsev/go-shortener/internal/app/models"
+	"github.com/alexuryumtsev/go-shortener/internal/app/storage"
+	"github.com/alexuryumtsev/go-shortener/internal/app/storage/memory"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/crypto/bcrypt"
+)
+
+func TestService(t *testing.T) {
+	ctx := context.Background()
+	repo := memory.NewInMemoryStorage()
+	svc := newService(repo, bcrypt.MinCost)
+
+	_, err := repo.SaveBatch(ctx, []models.URLModel{
+		{ID: "anon1", URL: "https://example.com/1", UserID: "anonymous"},
+		{ID: "anon2", URL: "https://example.com/2", UserID: "anonymous"},
+		{ID: "later", URL: "https://example.com/3", UserID: "anonymous-later"},
+	})
+	require.NoError(t, err)
+
+	t.Run("Register", func(t *testing.T) {
+		acc, merged, err := svc.Register(ctx, "alice", "correct horse", "anonymous")
+		require.NoError(t, err)
+		assert.Equal(t, "alice", acc.Login)
+		assert.NotEqual(t, "correct horse", acc.PasswordHash)
+		assert.Equal(t, 2, merged)
+
+		urls, err := repo.GetUserURLs(ctx, acc.ID)
+		require.NoError(t, err)
+		assert.Len(t, urls, 2)
+
+		_, _, err = svc.Register(ctx, "alice", "another password", "")
+		assert.ErrorIs(t, err, ErrLoginTaken)
+		assert.ErrorIs(t, err, storage.ErrConflict)
+
+		_, _, err = svc.Register(ctx, "a", "correct horse", "")
+		assert.ErrorIs(t, err, ErrInvalidLogin)
+
+		_, _, err = svc.Register(ctx, "bob", "short", "")
+		assert.ErrorIs(t, err, ErrInvalidPassword)
+	})
+
+	t.Run("Login", func(t *testing.T) {
+		_, _, err := svc.Login(ctx, "alice", "wrong password", "")
+		assert.ErrorIs(t, err, ErrInvalidCredentials)
+
+		_, _, err = svc.Login(ctx, "nobody", "correct horse", "")
+		assert.ErrorIs(t, err, ErrInvalidCredentials)
+
+		acc, merged, err := svc.Login(ctx, "alice", "correct horse", "anonymous-later")
+		require.NoError(t, err)
+		assert.Equal(t, 1, merged)
+
+		urls, err := repo.GetUserURLs(ctx, acc.ID)
+		require.NoError(t, err)
+		assert.Len(t, urls, 3)
+	})
+
+	t.Run("Registered users are not merged", func(t *testing.T) {
+		bob, _, err := svc.Register(ctx, "bob", "bob password", "")
+		require.NoError(t, err)
+		require.NoError(t, repo.Save(ctx, models.URLModel{ID: "bob1", URL: "https://example.com/bob", UserID: bob.ID}))
+
+		// Вход в чужой аккаунт с куки Боба не забирает его ссылки
+		_, merged, err := svc.Login(ctx, "alice", "correct horse", bob.ID)
+		require.NoError(t, err)
+		assert.Zero(t, merged)
+
+		urls, err := repo.GetUserURLs(ctx, bob.ID)
+		require.NoError(t, err)
+		assert.Len(t, urls, 1)
+	})
+}
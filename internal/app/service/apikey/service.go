@@ -0,0 +1,163 @@
+// Package apikey предоставляет сервис долгоживущих API-ключей
+// для программных клиентов (CI-скриптов, интеграций).
+package apikey
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/alexuryumtsev/go-shortener/internal/app/models"
+	"github.com/alexuryumtsev/go-shortener/internal/app/storage"
+	"github.com/google/uuid"
+)
+
+// Области действия API-ключей.
+const (
+	// ScopeURLsRead разрешает чтение: запросы GET и HEAD
+	ScopeURLsRead = "urls:read"
+	// ScopeURLsWrite разрешает изменение: создание и удаление ссылок
+	ScopeURLsWrite = "urls:write"
+)
+
+// KeyPrefix — префикс, по которому API-ключ отличается от JWT.
+const KeyPrefix = "shk_"
+
+const (
+	// keyBytes — число случайных байт в ключе
+	keyBytes = 32
+	// maxNameLength — максимальная длина имени ключа в символах
+	maxNameLength = 100
+	// touchInterval — минимальный интервал обновления времени последнего использования,
+	// чтобы не писать в хранилище на каждый запрос
+	touchInterval = time.Minute
+)
+
+var (
+	// ErrInvalidName возвращается, если имя ключа пустое или слишком длинное.
+	ErrInvalidName = errors.New("invalid API key name")
+	// ErrInvalidScope возвращается при неизвестной области действия.
+	ErrInvalidScope = errors.New("invalid API key scope")
+	// ErrKeyNotFound возвращается, если ключ не найден или принадлежит другому пользователю.
+	ErrKeyNotFound = errors.New("API key not found")
+	// ErrInvalidKey возвращается при аутентификации неизвестным или отозванным ключом.
+	ErrInvalidKey = errors.New("invalid API key")
+)
+
+// Service определяет интерфейс для работы с API-ключами.
+type Service interface {
+	// Create создаёт ключ пользователя и возвращает его вместе с открытым значением,
+	// которое больше нигде не сохраняется
+	Create(ctx context.Context, userID, name string, scopes []string) (models.APIKeyModel, string, error)
+
+	// List возвращает ключи пользователя
+	List(ctx context.Context, userID string) ([]models.APIKeyModel, error)
+
+	// Revoke отзывает ключ пользователя
+	Revoke(ctx context.Context, userID, id string) error
+
+	// Authenticate находит ключ по открытому значению и отмечает его использование
+	Authenticate(ctx context.Context, key string) (models.APIKeyModel, error)
+}
+
+// service реализация Service
+type service struct {
+	storage storage.APIKeyStorage
+	now     func() time.Time
+}
+
+// NewService создаёт сервис API-ключей поверх хранилища.
+func NewService(storage storage.APIKeyStorage) Service {
+	return &service{storage: storage, now: time.Now}
+}
+
+// Create создаёт API-ключ. Если области действия не заданы, ключ получает все.
+func (s *service) Create(ctx context.Context, userID, name string, scopes []string) (models.APIKeyModel, string, error) {
+	name = strings.TrimSpace(name)
+	if name == "" || utf8.RuneCountInString(name) > maxNameLength {
+		return models.APIKeyModel{}, "", ErrInvalidName
+	}
+
+	if len(scopes) == 0 {
+		scopes = []string{ScopeURLsRead, ScopeURLsWrite}
+	}
+	for _, scope := range scopes {
+		if scope != ScopeURLsRead && scope != ScopeURLsWrite {
+			return models.APIKeyModel{}, "", fmt.Errorf("%w: %q", ErrInvalidScope, scope)
+		}
+	}
+	scopes = slices.Compact(slices.Sorted(slices.Values(scopes)))
+
+	raw := make([]byte, keyBytes)
+	if _, err := rand.Read(raw); err != nil {
+		return models.APIKeyModel{}, "", fmt.Errorf("failed to generate API key: %w", err)
+	}
+	key := KeyPrefix + base64.RawURLEncoding.EncodeToString(raw)
+
+	apiKey := models.APIKeyModel{
+		ID:        uuid.NewString(),
+		UserID:    userID,
+		Name:      name,
+		KeyHash:   hashKey(key),
+		Scopes:    scopes,
+		CreatedAt: s.now().UTC(),
+	}
+	if err := s.storage.SaveAPIKey(ctx, apiKey); err != nil {
+		return models.APIKeyModel{}, "", err
+	}
+	return apiKey, key, nil
+}
+
+// List возвращает ключи пользователя в порядке создания.
+func (s *service) List(ctx context.Context, userID string) ([]models.APIKeyModel, error) {
+	return s.storage.GetUserAPIKeys(ctx, userID)
+}
+
+// Revoke отзывает ключ. Чужой или несуществующий ключ даёт ErrKeyNotFound.
+func (s *service) Revoke(ctx context.Context, userID, id string) error {
+	revoked, err := s.storage.RevokeAPIKey(ctx, userID, id)
+	if err != nil {
+		return err
+	}
+	if !revoked {
+		return ErrKeyNotFound
+	}
+	return nil
+}
+
+// Authenticate проверяет ключ и обновляет время его последнего использования
+// не чаще, чем раз в touchInterval.
+func (s *service) Authenticate(ctx context.Context, key string) (models.APIKeyModel, error) {
+	if !strings.HasPrefix(key, KeyPrefix) {
+		return models.APIKeyModel{}, ErrInvalidKey
+	}
+
+	apiKey, exists := s.storage.GetAPIKeyByHash(ctx, hashKey(key))
+	if !exists {
+		return models.APIKeyModel{}, ErrInvalidKey
+	}
+
+	now := s.now().UTC()
+	if apiKey.LastUsedAt == nil || now.Sub(*apiKey.LastUsedAt) >= touchInterval {
+		if err := s.storage.TouchAPIKey(ctx, apiKey.ID, now); err != nil {
+			return models.APIKeyModel{}, err
+		}
+		apiKey.LastUsedAt = &now
+	}
+	return apiKey, nil
+}
+
+// hashKey возвращает хеш ключа для хранения.
+// Ключ содержит 256 бит случайных данных, поэтому медленная функция хеширования не нужна.
+func hashKey(key string) string {
+	sum := sha256.Sum256([]byte(key))
+	return hex.EncodeToString(sum[:])
+}
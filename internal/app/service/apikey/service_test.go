@@ -0,0 +1,75 @@
+package apikey
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/alexuryumtsev/go-shortener/internal/app/storage/memory"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestService(t *testing.T) {
+	ctx := context.Background()
+	repo := memory.NewInMemoryStorage()
+	now := time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC)
+	svc := &service{storage: repo, now: func() time.Time { return now }}
+
+	apiKey, key, err := svc.Create(ctx, "user1", " ci ", nil)
+	require.NoError(t, err)
+	assert.True(t, strings.HasPrefix(key, KeyPrefix))
+	assert.Equal(t, "ci", apiKey.Name)
+	assert.Equal(t, []string{ScopeURLsRead, ScopeURLsWrite}, apiKey.Scopes)
+	assert.NotContains(t, apiKey.KeyHash, key, "key must not be stored in plain text")
+
+	t.Run("Validation", func(t *testing.T) {
+		_, _, err := svc.Create(ctx, "user1", "", nil)
+		assert.ErrorIs(t, err, ErrInvalidName)
+
+		_, _, err = svc.Create(ctx, "user1", "admin", []string{"admin"})
+		assert.ErrorIs(t, err, ErrInvalidScope)
+	})
+
+	t.Run("Authenticate", func(t *testing.T) {
+		authenticated, err := svc.Authenticate(ctx, key)
+		require.NoError(t, err)
+		assert.Equal(t, apiKey.ID, authenticated.ID)
+		assert.Equal(t, "user1", authenticated.UserID)
+		require.NotNil(t, authenticated.LastUsedAt)
+		assert.Equal(t, now, *authenticated.LastUsedAt)
+
+		// Время использования обновляется не чаще, чем раз в touchInterval
+		now = now.Add(time.Second)
+		authenticated, err = svc.Authenticate(ctx, key)
+		require.NoError(t, err)
+		assert.Equal(t, now.Add(-time.Second), *authenticated.LastUsedAt)
+
+		_, err = svc.Authenticate(ctx, KeyPrefix+"unknown")
+		assert.ErrorIs(t, err, ErrInvalidKey)
+		_, err = svc.Authenticate(ctx, "not-a-key")
+		assert.ErrorIs(t, err, ErrInvalidKey)
+	})
+
+	t.Run("List and revoke", func(t *testing.T) {
+		readOnly, _, err := svc.Create(ctx, "user1", "reports", []string{ScopeURLsRead, ScopeURLsRead})
+		require.NoError(t, err)
+		assert.Equal(t, []string{ScopeURLsRead}, readOnly.Scopes)
+
+		keys, err := svc.List(ctx, "user1")
+		require.NoError(t, err)
+		assert.Len(t, keys, 2)
+
+		// Чужой ключ отозвать нельзя
+		assert.ErrorIs(t, svc.Revoke(ctx, "user2", apiKey.ID), ErrKeyNotFound)
+
+		require.NoError(t, svc.Revoke(ctx, "user1", apiKey.ID))
+		_, err = svc.Authenticate(ctx, key)
+		assert.ErrorIs(t, err, ErrInvalidKey)
+
+		keys, err = svc.List(ctx, "user1")
+		require.NoError(t, err)
+		assert.Len(t, keys, 1)
+	})
+}
@@ -0,0 +1,138 @@
+package url
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/alexuryumtsev/go-shortener/internal/app/logger"
+	"github.com/alexuryumtsev/go-shortener/internal/app/models"
+	"github.com/alexuryumtsev/go-shortener/internal/app/storage"
+	"go.uber.org/zap"
+)
+
+// Параметры записи переходов по умолчанию.
+const (
+	defaultClickBufferSize    = 4096
+	defaultClickFlushInterval = time.Second
+	clickBatchSize            = 500
+	clickFlushTimeout         = 10 * time.Second
+)
+
+// ErrLinkNotFound возвращается, если ссылка не найдена или принадлежит другому пользователю.
+var ErrLinkNotFound = errors.New("link not found")
+
+// clickRecorder асинхронно записывает события переходов в хранилище.
+// События складываются в ограниченный буфер, не блокируя редирект;
+// при переполнении буфера событие отбрасывается и учитывается в счётчике dropped.
+type clickRecorder struct {
+	storage  storage.URLStorage
+	interval time.Duration
+	events   chan models.ClickEvent
+	dropped  atomic.Uint64
+
+	closeMu sync.RWMutex
+	closed  bool
+
+	done chan struct{}
+}
+
+// newClickRecorder создаёт буфер событий ёмкостью bufferSize и запускает запись.
+func newClickRecorder(storage storage.URLStorage, bufferSize int, interval time.Duration) *clickRecorder {
+	r := &clickRecorder{
+		storage:  storage,
+		interval: interval,
+		events:   make(chan models.ClickEvent, bufferSize),
+		done:     make(chan struct{}),
+	}
+	go r.run()
+	return r
+}
+
+// record добавляет событие в буфер без блокировки.
+func (r *clickRecorder) record(event models.ClickEvent) {
+	// Удерживаем closeMu на время отправки, чтобы close не закрыл канал под нами.
+	r.closeMu.RLock()
+	defer r.closeMu.RUnlock()
+	if r.closed {
+		return
+	}
+
+	select {
+	case r.events <- event:
+	default:
+		r.dropped.Add(1)
+	}
+}
+
+// run накапливает события и записывает их пакетами по размеру или по таймеру.
+func (r *clickRecorder) run() {
+	defer close(r.done)
+
+	ticker := time.NewTicker(r.interval)
+	defer ticker.Stop()
+
+	batch := make([]models.ClickEvent, 0, clickBatchSize)
+	for {
+		select {
+		case event, ok := <-r.events:
+			if !ok {
+				r.flush(batch)
+				return
+			}
+			batch = append(batch, event)
+			if len(batch) >= clickBatchSize {
+				r.flush(batch)
+				batch = batch[:0]
+			}
+		case <-ticker.C:
+			r.flush(batch)
+			batch = batch[:0]
+		}
+	}
+}
+
+// flush записывает пакет событий в хранилище.
+func (r *clickRecorder) flush(batch []models.ClickEvent) {
+	if dropped := r.dropped.Swap(0); dropped > 0 {
+		logger.L().Warn("Dropped click events: buffer is full", zap.Uint64("count", dropped))
+	}
+	if len(batch) == 0 {
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), clickFlushTimeout)
+	defer cancel()
+
+	if err := r.storage.SaveClicks(ctx, batch); err != nil {
+		logger.L().Error("Failed to save click events", zap.Int("count", len(batch)), zap.Error(err))
+	}
+}
+
+// close прекращает приём событий и ожидает записи накопленных.
+func (r *clickRecorder) close(ctx context.Context) error {
+	r.closeMu.Lock()
+	if !r.closed {
+		r.closed = true
+		close(r.events)
+	}
+	r.closeMu.Unlock()
+
+	select {
+	case <-r.done:
+		return nil
+	case <-ctx.Done():
+		return fmt.Errorf("pending click events were not saved: %w", ctx.Err())
+	}
+}
+
+// hashIP возвращает хеш IP-адреса с солью, чтобы не хранить адреса посетителей.
+func hashIP(ip, salt string) string {
+	sum := sha256.Sum256([]byte(salt + ip))
+	return hex.EncodeToString(sum[:])
+}
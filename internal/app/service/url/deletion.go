@@ -0,0 +1,329 @@
+package url
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/alexuryumtsev/go-shortener/internal/app/logger"
+	"github.com/alexuryumtsev/go-shortener/internal/app/models"
+	"github.com/alexuryumtsev/go-shortener/internal/app/storage"
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+)
+
+// Статусы задачи удаления.
+const (
+	DeletionStatusPending   = "pending"
+	DeletionStatusCompleted = "completed"
+	DeletionStatusFailed    = "failed"
+)
+
+// Параметры очереди удаления по умолчанию.
+const (
+	defaultDeleteWorkers   = 4
+	defaultDeleteQueueSize = 1024
+	deleteFlushInterval    = 100 * time.Millisecond
+	deleteMaxRetries       = 3
+	deleteRetryBaseDelay   = 100 * time.Millisecond
+	deleteBatchTimeout     = 30 * time.Second
+	deletionJobTTL         = time.Hour
+)
+
+var (
+	// ErrDeletionQueueClosed возвращается при постановке задачи после остановки очереди.
+	ErrDeletionQueueClosed = errors.New("deletion queue is closed")
+
+	// ErrDeletionJobNotFound возвращается, если задача удаления не найдена
+	// или принадлежит другому пользователю.
+	ErrDeletionJobNotFound = errors.New("deletion job not found")
+)
+
+// deletionJob хранит состояние задачи удаления, созданной одним запросом.
+type deletionJob struct {
+	id         string
+	userID     string
+	pending    int
+	err        error
+	finishedAt time.Time
+}
+
+// status возвращает текущий статус задачи.
+func (j *deletionJob) status() string {
+	switch {
+	case j.pending > 0:
+		return DeletionStatusPending
+	case j.err != nil:
+		return DeletionStatusFailed
+	default:
+		return DeletionStatusCompleted
+	}
+}
+
+// deletionTask — часть задачи удаления, не превышающая размер батча.
+type deletionTask struct {
+	jobID  string
+	userID string
+	ids    []string
+}
+
+// deletionQueue — ограниченная очередь удаления с пулом воркеров.
+// Задачи всех пользователей сливаются в один канал, воркеры собирают их в батчи
+// размером batchSize и удаляют с повторными попытками.
+//
+// Канал задач закрывается только после выхода всех отправителей (senders),
+// а отправители, ожидающие места в очереди, прерываются закрытием quit.
+// Если остановка не дождалась воркеров, отмена stopCtx прерывает удаление
+// и ожидание между повторными попытками.
+//
+// Очередь и состояние задач хранятся только в памяти: при аварийном завершении
+// процесса задачи, ещё не выполненные воркерами, теряются, а их статус
+// перестаёт быть доступен. Клиент, не дождавшийся статуса completed,
+// может повторить запрос на удаление — он идемпотентен.
+type deletionQueue struct {
+	storage   storage.URLStorage
+	batchSize int
+	tasks     chan deletionTask
+
+	mu   sync.Mutex
+	jobs map[string]*deletionJob
+
+	closeMu sync.Mutex
+	closed  bool
+	quit    chan struct{}
+	senders sync.WaitGroup
+
+	stopCtx context.Context
+	stop    context.CancelFunc
+
+	wg sync.WaitGroup
+}
+
+// newDeletionQueue создаёт очередь и запускает workers воркеров.
+func newDeletionQueue(storage storage.URLStorage, batchSize, workers, queueSize int) *deletionQueue {
+	q := &deletionQueue{
+		storage:   storage,
+		batchSize: batchSize,
+		tasks:     make(chan deletionTask, queueSize),
+		jobs:      make(map[string]*deletionJob),
+		quit:      make(chan struct{}),
+	}
+	q.stopCtx, q.stop = context.WithCancel(context.Background())
+
+	for i := 0; i < workers; i++ {
+		q.wg.Add(1)
+		go q.worker()
+	}
+
+	return q
+}
+
+// enqueue разбивает ids на части по batchSize и ставит их в очередь.
+// Блокируется, пока в очереди нет места, не отменён контекст и очередь не закрыта.
+func (q *deletionQueue) enqueue(ctx context.Context, userID string, ids []string) (string, error) {
+	q.closeMu.Lock()
+	if q.closed {
+		q.closeMu.Unlock()
+		return "", ErrDeletionQueueClosed
+	}
+	// Пока отправитель зарегистрирован, close не закроет канал задач
+	q.senders.Add(1)
+	q.closeMu.Unlock()
+	defer q.senders.Done()
+
+	parts := (len(ids) + q.batchSize - 1) / q.batchSize
+	job := &deletionJob{id: uuid.New().String(), userID: userID, pending: parts}
+	if parts == 0 {
+		job.finishedAt = time.Now()
+	}
+
+	q.mu.Lock()
+	q.pruneLocked()
+	q.jobs[job.id] = job
+	q.mu.Unlock()
+
+	for i := 0; i < len(ids); i += q.batchSize {
+		end := min(i+q.batchSize, len(ids))
+		task := deletionTask{jobID: job.id, userID: userID, ids: ids[i:end]}
+		var err error
+		select {
+		case q.tasks <- task:
+			continue
+		case <-ctx.Done():
+			err = ctx.Err()
+		case <-q.quit:
+			err = ErrDeletionQueueClosed
+		}
+
+		// Неотправленные части считаем проваленными
+		q.mu.Lock()
+		job.pending -= parts - i/q.batchSize
+		job.err = err
+		if job.pending == 0 {
+			job.finishedAt = time.Now()
+		}
+		q.mu.Unlock()
+		return job.id, fmt.Errorf("failed to enqueue deletion: %w", err)
+	}
+
+	return job.id, nil
+}
+
+// depth возвращает число задач в канале, ещё не взятых воркерами.
+func (q *deletionQueue) depth() int {
+	return len(q.tasks)
+}
+
+// status возвращает состояние задачи удаления пользователя.
+func (q *deletionQueue) status(userID, jobID string) (models.DeletionJobModel, error) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	job, ok := q.jobs[jobID]
+	if !ok || job.userID != userID {
+		return models.DeletionJobModel{}, ErrDeletionJobNotFound
+	}
+
+	result := models.DeletionJobModel{ID: job.id, Status: job.status()}
+	if job.pending == 0 && job.err != nil {
+		result.Error = job.err.Error()
+	}
+	return result, nil
+}
+
+// pruneLocked удаляет сведения о давно завершённых задачах.
+func (q *deletionQueue) pruneLocked() {
+	now := time.Now()
+	for id, job := range q.jobs {
+		if job.pending == 0 && now.Sub(job.finishedAt) > deletionJobTTL {
+			delete(q.jobs, id)
+		}
+	}
+}
+
+// worker читает задачи из очереди, собирает их в батч и выполняет удаление.
+func (q *deletionQueue) worker() {
+	defer q.wg.Done()
+
+	for {
+		task, ok := <-q.tasks
+		if !ok {
+			return
+		}
+
+		batch := []deletionTask{task}
+		size := len(task.ids)
+
+		// Добираем задачи до размера батча или до истечения интервала
+		timer := time.NewTimer(deleteFlushInterval)
+	collect:
+		for size < q.batchSize {
+			select {
+			case next, ok := <-q.tasks:
+				if !ok {
+					break collect
+				}
+				batch = append(batch, next)
+				size += len(next.ids)
+			case <-timer.C:
+				break collect
+			}
+		}
+		timer.Stop()
+
+		q.process(batch)
+	}
+}
+
+// process удаляет URL из батча, группируя их по пользователям.
+func (q *deletionQueue) process(batch []deletionTask) {
+	byUser := make(map[string][]string)
+	for _, task := range batch {
+		byUser[task.userID] = append(byUser[task.userID], task.ids...)
+	}
+
+	userErrs := make(map[string]error, len(byUser))
+	for userID, ids := range byUser {
+		if err := q.deleteWithRetry(userID, ids); err != nil {
+			logger.L().Error("Failed to delete URLs", zap.String("user_id", userID), zap.Int("count", len(ids)), zap.Error(err))
+			userErrs[userID] = err
+		}
+	}
+
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	for _, task := range batch {
+		job, ok := q.jobs[task.jobID]
+		if !ok {
+			continue
+		}
+		if err := userErrs[task.userID]; err != nil {
+			job.err = err
+		}
+		job.pending--
+		if job.pending == 0 {
+			job.finishedAt = time.Now()
+		}
+	}
+}
+
+// deleteWithRetry выполняет удаление с экспоненциальной задержкой между попытками.
+// Остановка очереди прерывает и удаление, и ожидание следующей попытки.
+func (q *deletionQueue) deleteWithRetry(userID string, ids []string) error {
+	var err error
+	delay := deleteRetryBaseDelay
+	for attempt := 0; attempt <= deleteMaxRetries; attempt++ {
+		if attempt > 0 {
+			timer := time.NewTimer(delay)
+			select {
+			case <-timer.C:
+			case <-q.stopCtx.Done():
+				timer.Stop()
+				return fmt.Errorf("deletion queue stopped after %d attempts: %w", attempt, err)
+			}
+			delay *= 2
+		}
+
+		ctx, cancel := context.WithTimeout(q.stopCtx, deleteBatchTimeout)
+		err = q.storage.DeleteUserURLs(ctx, userID, ids)
+		cancel()
+		if err == nil {
+			return nil
+		}
+	}
+	return fmt.Errorf("failed after %d attempts: %w", deleteMaxRetries+1, err)
+}
+
+// close прекращает приём задач и ожидает, пока воркеры обработают очередь.
+// Отправители, ожидающие места в очереди, получают ErrDeletionQueueClosed.
+// При отмене ctx прерывает текущие удаления и повторные попытки и возвращает
+// ошибку, не дожидаясь воркеров: оставшиеся задачи завершаются со статусом failed.
+// Повторный вызов продолжает ожидание.
+func (q *deletionQueue) close(ctx context.Context) error {
+	q.closeMu.Lock()
+	if !q.closed {
+		q.closed = true
+		close(q.quit)
+		go func() {
+			q.senders.Wait()
+			close(q.tasks)
+		}()
+	}
+	q.closeMu.Unlock()
+
+	done := make(chan struct{})
+	go func() {
+		q.wg.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		q.stop()
+		return fmt.Errorf("pending deletions were not finished: %w", ctx.Err())
+	}
+}
@@ -6,6 +6,7 @@ import (
 	"strings"
 
 	"github.com/alexuryumtsev/go-shortener/internal/app/models"
+	"github.com/alexuryumtsev/go-shortener/internal/app/storage"
 )
 
 // MockURLService - это структура, которая реализует интерфейс URLService для тестирования
@@ -14,6 +15,8 @@ type MockURLService struct {
 	urls        map[string]string   // мапа для хранения id -> original_url
 	userURLs    map[string][]string // мапа для хранения user_id -> []short_url
 	deletedURLs map[string]bool     // мапа для хранения удаленных URLs
+	jobs        map[string]string   // мапа для хранения job_id -> user_id
+	clicks      map[string][]models.ClickEvent
 	err         error
 }
 
@@ -24,6 +27,8 @@ func NewMockURLService(baseURL string, err error) *MockURLService {
 		urls:        make(map[string]string),
 		userURLs:    make(map[string][]string),
 		deletedURLs: make(map[string]bool),
+		jobs:        make(map[string]string),
+		clicks:      make(map[string][]models.ClickEvent),
 		err:         err,
 	}
 }
@@ -41,6 +46,26 @@ func (m *MockURLService) ShortenerURL(ctx context.Context, originalURL, userID s
 	return fmt.Sprintf("%s/%s", m.baseURL, id), m.err
 }
 
+// ShortenerURLWithOptions сохраняет URL под заданным алиасом или сгенерированным ID
+func (m *MockURLService) ShortenerURLWithOptions(ctx context.Context, originalURL, userID string, opts models.ShortenOptions) (string, error) {
+	if opts.Alias == "" {
+		return m.ShortenerURL(ctx, originalURL, userID)
+	}
+	if originalURL == "" {
+		return "", fmt.Errorf("empty URL")
+	}
+
+	alias := opts.Alias
+	if existing, exists := m.urls[alias]; exists && existing != originalURL {
+		return "", ErrAliasTaken
+	}
+
+	m.urls[alias] = originalURL
+	m.userURLs[userID] = append(m.userURLs[userID], alias)
+
+	return fmt.Sprintf("%s/%s", m.baseURL, alias), m.err
+}
+
 // SaveBatchShortenerURL сохраняет пакет URL и возвращает их сокращенные версии
 func (m *MockURLService) SaveBatchShortenerURL(ctx context.Context, batchModels []models.URLBatchModel, userID string) ([]models.BatchResponseModel, error) {
 	if m.err != nil {
@@ -74,6 +99,35 @@ func (m *MockURLService) DeleteUserURLsBatch(ctx context.Context, userID string,
 	return nil
 }
 
+// EnqueueDeletion синхронно помечает URL пользователя как удаленные и возвращает ID задачи
+func (m *MockURLService) EnqueueDeletion(ctx context.Context, userID string, shortURLs []string) (string, error) {
+	if err := m.DeleteUserURLsBatch(ctx, userID, shortURLs); err != nil {
+		return "", err
+	}
+
+	jobID := fmt.Sprintf("job-%d", len(m.jobs)+1)
+	m.jobs[jobID] = userID
+	return jobID, nil
+}
+
+// GetDeletionStatus возвращает завершенный статус для ранее созданной задачи
+func (m *MockURLService) GetDeletionStatus(ctx context.Context, userID, jobID string) (models.DeletionJobModel, error) {
+	if owner, exists := m.jobs[jobID]; !exists || owner != userID {
+		return models.DeletionJobModel{}, ErrDeletionJobNotFound
+	}
+	return models.DeletionJobModel{ID: jobID, Status: DeletionStatusCompleted}, nil
+}
+
+// DeletionQueueDepth всегда возвращает 0, так как мок удаляет URL сразу
+func (m *MockURLService) DeletionQueueDepth() int {
+	return 0
+}
+
+// Shutdown ничего не делает, так как мок не запускает фоновых задач
+func (m *MockURLService) Shutdown(ctx context.Context) error {
+	return nil
+}
+
 // GetURLByID возвращает предустановленный оригинальный URL
 func (m *MockURLService) GetURLByID(ctx context.Context, id string) (string, bool, error) {
 	if m.err != nil {
@@ -108,6 +162,51 @@ func (m *MockURLService) GetUserURLs(ctx context.Context, userID string) ([]mode
 	return result, nil
 }
 
+// RecordClick синхронно сохраняет переход
+func (m *MockURLService) RecordClick(ctx context.Context, event models.ClickEvent, clientIP string) {
+	event.IPHash = clientIP
+	m.clicks[event.ShortID] = append(m.clicks[event.ShortID], event)
+}
+
+// GetLinkStats возвращает статистику переходов по ссылке пользователя
+func (m *MockURLService) GetLinkStats(ctx context.Context, userID, shortURL string) (models.LinkStatsModel, error) {
+	if m.err != nil {
+		return models.LinkStatsModel{}, m.err
+	}
+
+	id := strings.TrimPrefix(shortURL, m.baseURL+"/")
+	for _, userURL := range m.userURLs[userID] {
+		if userURL == id {
+			stats := storage.AggregateClicks(m.clicks[id])
+			stats.ShortURL = fmt.Sprintf("%s/%s", m.baseURL, id)
+			return stats, nil
+		}
+	}
+	return models.LinkStatsModel{}, ErrLinkNotFound
+}
+
+// GetStats возвращает сводную статистику по добавленным URL
+func (m *MockURLService) GetStats(ctx context.Context) (models.StatsModel, error) {
+	if m.err != nil {
+		return models.StatsModel{}, m.err
+	}
+
+	var stats models.StatsModel
+	for id := range m.urls {
+		if m.deletedURLs[id] {
+			stats.DeletedURLs++
+		} else {
+			stats.URLs++
+		}
+	}
+	for _, ids := range m.userURLs {
+		if len(ids) > 0 {
+			stats.Users++
+		}
+	}
+	return stats, nil
+}
+
 // SetError устанавливает ошибку для тестирования
 func (m *MockURLService) SetError(err error) {
 	m.err = err
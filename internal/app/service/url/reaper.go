@@ -0,0 +1,79 @@
+package url
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/alexuryumtsev/go-shortener/internal/app/logger"
+	"github.com/alexuryumtsev/go-shortener/internal/app/storage"
+	"go.uber.org/zap"
+)
+
+// expiryReaper периодически удаляет из хранилища URL с истёкшим сроком действия.
+type expiryReaper struct {
+	storage  storage.URLStorage
+	interval time.Duration
+	archive  bool
+
+	stop chan struct{}
+	once sync.Once
+	done chan struct{}
+}
+
+// newExpiryReaper создаёт и запускает сборщик истёкших ссылок.
+func newExpiryReaper(storage storage.URLStorage, interval time.Duration, archive bool) *expiryReaper {
+	r := &expiryReaper{
+		storage:  storage,
+		interval: interval,
+		archive:  archive,
+		stop:     make(chan struct{}),
+		done:     make(chan struct{}),
+	}
+	go r.run()
+	return r
+}
+
+// run выполняет очистку с заданным интервалом до вызова close.
+func (r *expiryReaper) run() {
+	defer close(r.done)
+
+	ticker := time.NewTicker(r.interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-r.stop:
+			return
+		case <-ticker.C:
+			r.reap()
+		}
+	}
+}
+
+// reap удаляет истёкшие ссылки один раз.
+func (r *expiryReaper) reap() {
+	ctx, cancel := context.WithTimeout(context.Background(), r.interval)
+	defer cancel()
+
+	removed, err := r.storage.DeleteExpired(ctx, time.Now(), r.archive)
+	if err != nil {
+		logger.L().Error("Failed to delete expired URLs", zap.Error(err))
+		return
+	}
+	if removed > 0 {
+		logger.L().Info("Removed expired URLs", zap.Int("count", removed), zap.Bool("archived", r.archive))
+	}
+}
+
+// close останавливает сборщик и ожидает завершения текущей очистки.
+func (r *expiryReaper) close(ctx context.Context) error {
+	r.once.Do(func() { close(r.stop) })
+
+	select {
+	case <-r.done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
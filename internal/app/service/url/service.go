@@ -3,14 +3,15 @@ package url
 
 import (
 	"context"
-	"crypto/md5"
 	"errors"
 	"fmt"
 	"strings"
 	"time"
 
+	"github.com/alexuryumtsev/go-shortener/internal/app/idgen"
 	"github.com/alexuryumtsev/go-shortener/internal/app/models"
 	"github.com/alexuryumtsev/go-shortener/internal/app/storage"
+	"github.com/alexuryumtsev/go-shortener/internal/app/validator"
 	"github.com/jackc/pgerrcode"
 	"github.com/jackc/pgx/v5/pgconn"
 )
@@ -20,17 +21,41 @@ type URLService interface {
 	// ShortenerURL создает короткий URL для переданного оригинального URL
 	ShortenerURL(ctx context.Context, originalURL, userID string) (string, error)
 
+	// ShortenerURLWithOptions создает короткий URL с алиасом и сроком действия
+	ShortenerURLWithOptions(ctx context.Context, originalURL, userID string, opts models.ShortenOptions) (string, error)
+
 	// SaveBatchShortenerURL сохраняет пакет URL и возвращает их сокращенные версии
 	SaveBatchShortenerURL(ctx context.Context, batchModels []models.URLBatchModel, userID string) ([]models.BatchResponseModel, error)
 
 	// DeleteUserURLsBatch помечает URL пользователя как удаленные
 	DeleteUserURLsBatch(ctx context.Context, userID string, shortURLs []string) error
 
+	// EnqueueDeletion ставит удаление URL пользователя в очередь и возвращает ID задачи
+	EnqueueDeletion(ctx context.Context, userID string, shortURLs []string) (string, error)
+
+	// GetDeletionStatus возвращает состояние задачи удаления пользователя
+	GetDeletionStatus(ctx context.Context, userID, jobID string) (models.DeletionJobModel, error)
+
+	// DeletionQueueDepth возвращает число частей задач удаления, ожидающих в очереди
+	DeletionQueueDepth() int
+
+	// Shutdown ожидает завершения фоновых удалений или истечения контекста
+	Shutdown(ctx context.Context) error
+
 	// GetURLByID получает оригинальный URL по ID
 	GetURLByID(ctx context.Context, id string) (string, bool, error)
 
 	// GetUserURLs получает все URL пользователя
 	GetUserURLs(ctx context.Context, userID string) ([]models.UserURLModel, error)
+
+	// RecordClick асинхронно записывает переход по короткой ссылке
+	RecordClick(ctx context.Context, event models.ClickEvent, clientIP string)
+
+	// GetLinkStats возвращает статистику переходов по ссылке пользователя
+	GetLinkStats(ctx context.Context, userID, shortURL string) (models.LinkStatsModel, error)
+
+	// GetStats возвращает сводную статистику сервиса
+	GetStats(ctx context.Context) (models.StatsModel, error)
 }
 
 // urlService реализация URLService
@@ -38,77 +63,498 @@ type urlService struct {
 	storage   storage.URLStorage
 	baseURL   string
 	batchSize int
+	idGen     idgen.Generator
+	deletions *deletionQueue
+	reaper    *expiryReaper
+	clicks    *clickRecorder
+	clickSalt string
+}
+
+// maxIDAttempts ограничивает число попыток подобрать свободный ID.
+const maxIDAttempts = 10
+
+var (
+	// ErrIDExhausted возвращается, если за maxIDAttempts попыток не удалось подобрать свободный ID.
+	ErrIDExhausted = errors.New("failed to generate unique short ID")
+
+	// ErrAliasTaken возвращается, если алиас уже указывает на другой URL.
+	// Оборачивает storage.ErrConflict, так как тоже означает конфликт.
+	ErrAliasTaken = fmt.Errorf("alias is taken by another URL: %w", storage.ErrConflict)
+
+	// ErrInvalidExpiry возвращается при некорректных ttl или expires_at.
+	ErrInvalidExpiry = errors.New("invalid expiry")
+
+	// ErrURLDeleted возвращается при обращении к удалённому URL.
+	ErrURLDeleted = errors.New("URL was deleted")
+
+	// ErrURLExpired возвращается при обращении к URL с истёкшим сроком действия.
+	ErrURLExpired = errors.New("URL has expired")
+)
+
+// options содержит необязательные параметры сервиса.
+type options struct {
+	deleteWorkers   int
+	deleteQueueSize int
+	idGen           idgen.Generator
+	reapInterval    time.Duration
+	reapArchive     bool
+	clickBuffer     int
+	clickInterval   time.Duration
+	clickSalt       string
+}
+
+// Option задаёт необязательный параметр сервиса.
+type Option func(*options)
+
+// WithIDGenerator задаёт стратегию генерации коротких ID.
+func WithIDGenerator(gen idgen.Generator) Option {
+	return func(o *options) {
+		if gen != nil {
+			o.idGen = gen
+		}
+	}
+}
+
+// WithExpiryReaper включает периодическое удаление истёкших ссылок.
+// При archive = true хранилище переносит их в архив вместо окончательного удаления.
+func WithExpiryReaper(interval time.Duration, archive bool) Option {
+	return func(o *options) {
+		o.reapInterval = interval
+		o.reapArchive = archive
+	}
 }
 
-// NewURLService создаёт новый экземпляр сервиса для работы с URL.
-func NewURLService(storage storage.URLStorage, baseURL string, batchSize int) URLService {
-	return &urlService{
+// WithClickRecorder задаёт ёмкость буфера событий переходов и период их записи в хранилище.
+func WithClickRecorder(bufferSize int, flushInterval time.Duration) Option {
+	return func(o *options) {
+		if bufferSize > 0 {
+			o.clickBuffer = bufferSize
+		}
+		if flushInterval > 0 {
+			o.clickInterval = flushInterval
+		}
+	}
+}
+
+// WithClickSalt задаёт соль для хеширования IP-адресов посетителей.
+func WithClickSalt(salt string) Option {
+	return func(o *options) {
+		o.clickSalt = salt
+	}
+}
+
+// WithDeleteWorkers задаёт количество воркеров очереди удаления.
+func WithDeleteWorkers(n int) Option {
+	return func(o *options) {
+		if n > 0 {
+			o.deleteWorkers = n
+		}
+	}
+}
+
+// WithDeleteQueueSize задаёт ёмкость очереди удаления.
+func WithDeleteQueueSize(n int) Option {
+	return func(o *options) {
+		if n > 0 {
+			o.deleteQueueSize = n
+		}
+	}
+}
+
+// NewURLService создаёт новый экземпляр сервиса для работы с URL
+// и запускает воркеры очереди удаления и запись переходов.
+func NewURLService(storage storage.URLStorage, baseURL string, batchSize int, opts ...Option) URLService {
+	o := options{
+		deleteWorkers:   defaultDeleteWorkers,
+		deleteQueueSize: defaultDeleteQueueSize,
+		idGen:           idgen.NewHashGenerator(""),
+		clickBuffer:     defaultClickBufferSize,
+		clickInterval:   defaultClickFlushInterval,
+	}
+	for _, opt := range opts {
+		opt(&o)
+	}
+
+	if batchSize <= 0 {
+		batchSize = 1
+	}
+
+	s := &urlService{
 		storage:   storage,
 		baseURL:   strings.TrimSuffix(baseURL, "/"),
 		batchSize: batchSize,
+		idGen:     o.idGen,
+		deletions: newDeletionQueue(storage, batchSize, o.deleteWorkers, o.deleteQueueSize),
+		clicks:    newClickRecorder(storage, o.clickBuffer, o.clickInterval),
+		clickSalt: o.clickSalt,
 	}
+	if o.reapInterval > 0 {
+		s.reaper = newExpiryReaper(storage, o.reapInterval, o.reapArchive)
+	}
+	return s
 }
 
-// ShortenerURL сокращает URL и сохраняет в базе
+// ShortenerURL сокращает URL и сохраняет в базе.
+// Если URL уже сокращён, возвращает существующую ссылку и ошибку storage.ErrConflict.
 func (s *urlService) ShortenerURL(ctx context.Context, originalURL, userID string) (string, error) {
+	return s.ShortenerURLWithOptions(ctx, originalURL, userID, models.ShortenOptions{})
+}
+
+// ShortenerURLWithOptions сокращает URL с учётом алиаса и срока действия.
+// Для алиаса, уже указывающего на этот же URL, возвращает ссылку и storage.ErrConflict,
+// для алиаса, занятого другим URL или недействующей ссылкой, — ErrAliasTaken.
+func (s *urlService) ShortenerURLWithOptions(ctx context.Context, originalURL, userID string, opts models.ShortenOptions) (string, error) {
 	if originalURL == "" {
 		return "", fmt.Errorf("empty URL")
 	}
 
-	id := generateID(originalURL)
-	urlModel := models.URLModel{ID: id, URL: originalURL, UserID: userID}
-	shortenedURL := s.baseURL + "/" + id
-
-	err := s.storage.Save(ctx, urlModel)
+	expiresAt, err := resolveExpiry(opts.TTL, opts.ExpiresAt, time.Now())
 	if err != nil {
-		var pgErr *pgconn.PgError
-		if errors.As(err, &pgErr) && pgErr.Code == pgerrcode.UniqueViolation {
-			return shortenedURL, err
+		return "", err
+	}
+
+	urlModel := models.URLModel{URL: originalURL, UserID: userID, ExpiresAt: expiresAt}
+	if opts.Alias != "" {
+		urlModel.ID = opts.Alias
+		return s.shortenAlias(ctx, urlModel)
+	}
+
+	// Стратегии со случайными ID не дают одинаковый ID для одного URL, поэтому ищем по оригиналу
+	if existing, ok := s.storage.GetByOriginalURL(ctx, originalURL); ok && active(existing, time.Now()) {
+		return s.baseURL + "/" + existing.ID, fmt.Errorf("%w: URL already shortened", storage.ErrConflict)
+	}
+
+	for attempt := 0; attempt < maxIDAttempts; attempt++ {
+		id, exists, err := s.nextFreeID(ctx, originalURL, nil)
+		if err != nil {
+			return "", err
+		}
+
+		shortenedURL := s.baseURL + "/" + id
+		if exists {
+			return shortenedURL, fmt.Errorf("%w: URL already shortened", storage.ErrConflict)
+		}
+
+		urlModel.ID = id
+		err = s.storage.Save(ctx, urlModel)
+		if err == nil {
+			return shortenedURL, nil
 		}
+		if !isConflict(err) {
+			return "", err
+		}
+		// ID заняли между проверкой и записью — повторяем поиск свободного ID
+	}
+
+	return "", ErrIDExhausted
+}
+
+// shortenAlias сохраняет URL под пользовательским алиасом из urlModel.ID.
+// Другие короткие ID того же URL не мешают созданию алиаса.
+func (s *urlService) shortenAlias(ctx context.Context, urlModel models.URLModel) (string, error) {
+	alias := urlModel.ID
+	if err := validator.ValidateAlias(alias); err != nil {
 		return "", err
 	}
 
-	return shortenedURL, nil
+	shortenedURL := s.baseURL + "/" + alias
+	if err := s.checkAlias(ctx, urlModel.URL, alias); err != nil {
+		if errors.Is(err, ErrAliasTaken) {
+			return "", err
+		}
+		return shortenedURL, err
+	}
+
+	err := s.storage.Save(ctx, urlModel)
+	if err == nil {
+		return shortenedURL, nil
+	}
+	if !isConflict(err) {
+		return "", err
+	}
+
+	// Алиас заняли между проверкой и записью — выясняем, каким URL
+	if err := s.checkAlias(ctx, urlModel.URL, alias); err != nil && errors.Is(err, ErrAliasTaken) {
+		return "", err
+	}
+	return shortenedURL, fmt.Errorf("%w: URL already shortened", storage.ErrConflict)
+}
+
+// MaxTTL — наибольший срок действия ссылки, задаваемый через TTL.
+const MaxTTL = 100 * 365 * 24 * time.Hour
+
+// TTLFromSeconds переводит TTL в секундах из запроса в time.Duration.
+// Отрицательный TTL и TTL больше MaxTTL дают ErrInvalidExpiry: без проверки
+// умножение на time.Second переполняется и даёт произвольный срок.
+// Используется и HTTP-, и gRPC-обработчиками.
+func TTLFromSeconds(seconds int64) (time.Duration, error) {
+	switch {
+	case seconds < 0:
+		return 0, fmt.Errorf("%w: ttl must be positive", ErrInvalidExpiry)
+	case seconds > int64(MaxTTL/time.Second):
+		return 0, fmt.Errorf("%w: ttl must not exceed %d seconds", ErrInvalidExpiry, int64(MaxTTL/time.Second))
+	default:
+		return time.Duration(seconds) * time.Second, nil
+	}
+}
+
+// resolveExpiry вычисляет момент истечения ссылки из TTL или абсолютного срока.
+// Нулевой результат означает бессрочную ссылку.
+func resolveExpiry(ttl time.Duration, expiresAt time.Time, now time.Time) (time.Time, error) {
+	switch {
+	case ttl != 0 && !expiresAt.IsZero():
+		return time.Time{}, fmt.Errorf("%w: ttl and expires_at are mutually exclusive", ErrInvalidExpiry)
+	case ttl < 0:
+		return time.Time{}, fmt.Errorf("%w: ttl must be positive", ErrInvalidExpiry)
+	case ttl > MaxTTL:
+		return time.Time{}, fmt.Errorf("%w: ttl must not exceed %s", ErrInvalidExpiry, MaxTTL)
+	case ttl > 0:
+		return now.Add(ttl).UTC(), nil
+	case !expiresAt.IsZero() && !expiresAt.After(now):
+		return time.Time{}, fmt.Errorf("%w: expires_at must be in the future", ErrInvalidExpiry)
+	default:
+		return expiresAt.UTC(), nil
+	}
+}
+
+// checkAlias проверяет, свободен ли алиас для originalURL.
+// Алиас, занятый другим URL либо удалённой или истёкшей ссылкой, даёт ErrAliasTaken:
+// такая запись остаётся в хранилище и не даст сохранить новую.
+func (s *urlService) checkAlias(ctx context.Context, originalURL, alias string) error {
+	stored, exists := s.storage.Get(ctx, alias)
+	if !exists {
+		return nil
+	}
+	if stored.URL != originalURL || !active(stored, time.Now()) {
+		return ErrAliasTaken
+	}
+	return fmt.Errorf("%w: URL already shortened", storage.ErrConflict)
 }
 
-// SaveBatchShortenerURL сохраняет пакет URL и возвращает их сокращенные версии
+// active сообщает, можно ли вернуть существующую ссылку вместо создания новой:
+// удалённые и истёкшие ссылки не возвращаются.
+func active(urlModel models.URLModel, now time.Time) bool {
+	return !urlModel.Deleted && !urlModel.Expired(now)
+}
+
+// SaveBatchShortenerURL сохраняет пакет URL и возвращает их сокращенные версии.
+// Уже сокращённые URL не сохраняются повторно, для них возвращаются существующие ссылки.
+// ID, занятые другими запросами между подбором и записью, подбираются заново,
+// а занятый так алиас даёт ErrAliasTaken.
 func (s *urlService) SaveBatchShortenerURL(ctx context.Context, batchModels []models.URLBatchModel, userID string) ([]models.BatchResponseModel, error) {
 	if len(batchModels) == 0 {
 		return nil, fmt.Errorf("empty batch")
 	}
 
 	var urlModels []models.URLModel
+	ids := make([]string, 0, len(batchModels))
+	reserved := make(map[string]string, len(batchModels))
+	aliases := make(map[string]string)
 	for _, req := range batchModels {
 		if req.OriginalURL == "" {
 			return nil, fmt.Errorf("empty URL in batch")
 		}
 
-		urlModels = append(urlModels, models.URLModel{
-			ID:     generateID(req.OriginalURL),
-			URL:    req.OriginalURL,
-			UserID: userID,
-		})
+		var expiresAt time.Time
+		if req.ExpiresAt != nil {
+			expiresAt = *req.ExpiresAt
+		}
+		ttl, err := TTLFromSeconds(req.TTL)
+		if err != nil {
+			return nil, fmt.Errorf("correlation_id %s: %w", req.CorrelationID, err)
+		}
+		expiresAt, err = resolveExpiry(ttl, expiresAt, time.Now())
+		if err != nil {
+			return nil, fmt.Errorf("correlation_id %s: %w", req.CorrelationID, err)
+		}
+
+		id, err := s.batchID(ctx, req, reserved)
+		if err != nil {
+			return nil, err
+		}
+
+		if _, ok := reserved[id]; !ok {
+			reserved[id] = req.OriginalURL
+			if req.Alias != "" {
+				aliases[id] = req.CorrelationID
+			}
+			urlModels = append(urlModels, models.URLModel{
+				ID:        id,
+				URL:       req.OriginalURL,
+				UserID:    userID,
+				ExpiresAt: expiresAt,
+			})
+		}
+		ids = append(ids, id)
+	}
+
+	renamed := make(map[string]string)
+	for attempt := 0; len(urlModels) > 0; attempt++ {
+		if attempt == maxIDAttempts {
+			return nil, ErrIDExhausted
+		}
+
+		skipped, err := s.storage.SaveBatch(ctx, urlModels)
+		if err != nil {
+			return nil, err
+		}
+		urlModels, err = s.retrySkipped(ctx, urlModels, skipped, reserved, aliases, renamed)
+		if err != nil {
+			return nil, err
+		}
 	}
 
 	// Подготовка ответа
-	var responseModels []models.BatchResponseModel
-	for i, urlModel := range urlModels {
+	responseModels := make([]models.BatchResponseModel, 0, len(batchModels))
+	for i, req := range batchModels {
+		id := ids[i]
+		for next, ok := renamed[id]; ok; next, ok = renamed[id] {
+			id = next
+		}
 		responseModels = append(responseModels, models.BatchResponseModel{
-			CorrelationID: batchModels[i].CorrelationID,
-			ShortURL:      s.baseURL + "/" + urlModel.ID,
+			CorrelationID: req.CorrelationID,
+			ShortURL:      s.baseURL + "/" + id,
 		})
 	}
+	return responseModels, nil
+}
+
+// retrySkipped разбирает ID, пропущенные SaveBatch, и возвращает URL для повторной записи.
+// Если под ID уже хранится тот же URL, ссылка используется как есть; иначе для URL
+// подбирается новый ID (замена записывается в renamed), а для алиаса возвращается ErrAliasTaken.
+func (s *urlService) retrySkipped(ctx context.Context, urlModels []models.URLModel, skipped []string, reserved, aliases, renamed map[string]string) ([]models.URLModel, error) {
+	if len(skipped) == 0 {
+		return nil, nil
+	}
 
-	err := s.storage.SaveBatch(ctx, urlModels)
+	byID := make(map[string]models.URLModel, len(urlModels))
+	for _, urlModel := range urlModels {
+		byID[urlModel.ID] = urlModel
+	}
+
+	retry := make([]models.URLModel, 0, len(skipped))
+	for _, id := range skipped {
+		urlModel := byID[id]
+		stored, found := s.storage.Get(ctx, id)
+		switch {
+		case !found:
+			// Запись успели удалить — пробуем сохранить под тем же ID
+			retry = append(retry, urlModel)
+			continue
+		case stored.URL == urlModel.URL && active(stored, time.Now()):
+			continue
+		}
+
+		if correlationID, ok := aliases[id]; ok {
+			return nil, fmt.Errorf("correlation_id %s: %w", correlationID, ErrAliasTaken)
+		}
+
+		newID, exists, err := s.nextFreeID(ctx, urlModel.URL, reserved)
+		if err != nil {
+			return nil, err
+		}
+		renamed[id] = newID
+		if exists {
+			continue
+		}
+		reserved[newID] = urlModel.URL
+		urlModel.ID = newID
+		retry = append(retry, urlModel)
+	}
+	return retry, nil
+}
+
+// batchID подбирает ID для URL из пакета с учётом ID, уже выданных в этом пакете.
+func (s *urlService) batchID(ctx context.Context, req models.URLBatchModel, reserved map[string]string) (string, error) {
+	originalURL := req.OriginalURL
+	if req.Alias != "" {
+		return s.batchAlias(ctx, req, reserved)
+	}
+
+	// Повтор URL внутри пакета получает тот же ID
+	for id, url := range reserved {
+		if url == originalURL {
+			return id, nil
+		}
+	}
+
+	if existing, ok := s.storage.GetByOriginalURL(ctx, originalURL); ok && active(existing, time.Now()) {
+		// Помечаем ID как выданный, чтобы не сохранять его повторно
+		reserved[existing.ID] = originalURL
+		return existing.ID, nil
+	}
+
+	id, exists, err := s.nextFreeID(ctx, originalURL, reserved)
 	if err != nil {
-		var pgErr *pgconn.PgError
-		if errors.As(err, &pgErr) && pgErr.Code == pgerrcode.UniqueViolation {
-			return responseModels, err
+		return "", err
+	}
+	if exists {
+		reserved[id] = originalURL
+	}
+	return id, nil
+}
+
+// batchAlias проверяет алиас из пакета на занятость в хранилище и внутри пакета.
+func (s *urlService) batchAlias(ctx context.Context, req models.URLBatchModel, reserved map[string]string) (string, error) {
+	if err := validator.ValidateAlias(req.Alias); err != nil {
+		return "", fmt.Errorf("correlation_id %s: %w", req.CorrelationID, err)
+	}
+
+	if url, ok := reserved[req.Alias]; ok {
+		if url != req.OriginalURL {
+			return "", fmt.Errorf("correlation_id %s: %w", req.CorrelationID, ErrAliasTaken)
 		}
-		return nil, err
+		return req.Alias, nil
 	}
 
-	return responseModels, nil
+	err := s.checkAlias(ctx, req.OriginalURL, req.Alias)
+	switch {
+	case err == nil:
+		return req.Alias, nil
+	case errors.Is(err, ErrAliasTaken):
+		return "", fmt.Errorf("correlation_id %s: %w", req.CorrelationID, err)
+	case errors.Is(err, storage.ErrConflict):
+		// Алиас уже указывает на этот URL — повторно не сохраняем
+		reserved[req.Alias] = req.OriginalURL
+		return req.Alias, nil
+	default:
+		return "", err
+	}
+}
+
+// nextFreeID запрашивает у генератора ID, пока не найдёт свободный.
+// Занятый ID считается коллизией, только если под ним хранится другой URL;
+// если под ним хранится тот же URL, возвращается exists = true.
+func (s *urlService) nextFreeID(ctx context.Context, originalURL string, reserved map[string]string) (id string, exists bool, err error) {
+	for attempt := 0; attempt < maxIDAttempts; attempt++ {
+		id, err = s.idGen.Generate(originalURL, attempt)
+		if err != nil {
+			return "", false, fmt.Errorf("failed to generate ID: %w", err)
+		}
+
+		if _, taken := reserved[id]; taken {
+			continue
+		}
+
+		stored, found := s.storage.Get(ctx, id)
+		if !found {
+			return id, false, nil
+		}
+		// Удалённая ссылка занимает ID навсегда, а истёкшая — до удаления сборщиком,
+		// поэтому обе считаются коллизией
+		if stored.URL == originalURL && active(stored, time.Now()) {
+			return id, true, nil
+		}
+	}
+
+	return "", false, ErrIDExhausted
+}
+
+// isConflict проверяет, что ошибка хранилища вызвана занятым идентификатором.
+func isConflict(err error) bool {
+	var pgErr *pgconn.PgError
+	return errors.Is(err, storage.ErrConflict) ||
+		(errors.As(err, &pgErr) && pgErr.Code == pgerrcode.UniqueViolation)
 }
 
 // DeleteUserURLsBatch помечает URL пользователя как удаленные
@@ -121,18 +567,7 @@ func (s *urlService) DeleteUserURLsBatch(ctx context.Context, userID string, sho
 	ctx, cancel := context.WithTimeout(ctx, 30*time.Second)
 	defer cancel()
 
-	// Извлекаем ID из полных URLs, если переданы полные URL
-	var ids []string
-	for _, urlStr := range shortURLs {
-		if strings.HasPrefix(urlStr, s.baseURL) {
-			// Если это полный URL, извлекаем ID
-			id := strings.TrimPrefix(urlStr, s.baseURL+"/")
-			ids = append(ids, id)
-		} else {
-			// Иначе считаем, что это уже ID
-			ids = append(ids, urlStr)
-		}
-	}
+	ids := s.extractIDs(shortURLs)
 
 	// Разбиваем на партии для обработки
 	for i := 0; i < len(ids); i += s.batchSize {
@@ -150,7 +585,39 @@ func (s *urlService) DeleteUserURLsBatch(ctx context.Context, userID string, sho
 	return nil
 }
 
-// GetURLByID получает оригинальный URL по ID
+// EnqueueDeletion ставит удаление URL пользователя в очередь.
+// Удаление выполняется воркерами пакетами по batchSize, состояние доступно через GetDeletionStatus.
+// Очередь хранится в памяти, поэтому при аварийном завершении процесса
+// невыполненные задачи теряются (см. deletionQueue).
+func (s *urlService) EnqueueDeletion(ctx context.Context, userID string, shortURLs []string) (string, error) {
+	return s.deletions.enqueue(ctx, userID, s.extractIDs(shortURLs))
+}
+
+// GetDeletionStatus возвращает состояние задачи удаления пользователя
+func (s *urlService) GetDeletionStatus(ctx context.Context, userID, jobID string) (models.DeletionJobModel, error) {
+	return s.deletions.status(userID, jobID)
+}
+
+// DeletionQueueDepth возвращает число частей задач удаления, ожидающих в очереди.
+func (s *urlService) DeletionQueueDepth() int {
+	return s.deletions.depth()
+}
+
+// Shutdown прекращает приём новых задач удаления и ожидает обработки уже поставленных,
+// записывает накопленные переходы, затем останавливает сборщик истёкших ссылок.
+// Возвращает ошибку контекста, если задачи не успели завершиться: в этом случае
+// фоновые воркеры ещё могут обращаться к хранилищу.
+func (s *urlService) Shutdown(ctx context.Context) error {
+	// Остановку получают все фоновые задачи, даже если предыдущие не успели завершиться
+	errs := []error{s.deletions.close(ctx), s.clicks.close(ctx)}
+	if s.reaper != nil {
+		errs = append(errs, s.reaper.close(ctx))
+	}
+	return errors.Join(errs...)
+}
+
+// GetURLByID получает оригинальный URL по ID.
+// Для удалённого URL возвращает ErrURLDeleted, для истёкшего — ErrURLExpired.
 func (s *urlService) GetURLByID(ctx context.Context, id string) (string, bool, error) {
 	urlModel, exists := s.storage.Get(ctx, id)
 	if !exists {
@@ -158,7 +625,11 @@ func (s *urlService) GetURLByID(ctx context.Context, id string) (string, bool, e
 	}
 
 	if urlModel.Deleted {
-		return "", true, fmt.Errorf("URL was deleted")
+		return "", true, ErrURLDeleted
+	}
+
+	if urlModel.Expired(time.Now()) {
+		return "", true, ErrURLExpired
 	}
 
 	return urlModel.URL, true, nil
@@ -171,20 +642,72 @@ func (s *urlService) GetUserURLs(ctx context.Context, userID string) ([]models.U
 		return nil, err
 	}
 
+	now := time.Now()
 	var userURLs []models.UserURLModel
 	for _, urlModel := range urls {
-		if !urlModel.Deleted {
-			userURLs = append(userURLs, models.UserURLModel{
-				ShortURL:    s.baseURL + "/" + urlModel.ID,
-				OriginalURL: urlModel.URL,
-			})
+		if urlModel.Deleted || urlModel.Expired(now) {
+			continue
 		}
+
+		userURL := models.UserURLModel{
+			ShortURL:    s.baseURL + "/" + urlModel.ID,
+			OriginalURL: urlModel.URL,
+		}
+		if !urlModel.ExpiresAt.IsZero() {
+			expiresAt := urlModel.ExpiresAt
+			userURL.ExpiresAt = &expiresAt
+		}
+		userURLs = append(userURLs, userURL)
 	}
 
 	return userURLs, nil
 }
 
-// generateID создает короткий идентификатор для URL
-func generateID(url string) string {
-	return fmt.Sprintf("%x", md5.Sum([]byte(url)))[:8]
+// RecordClick ставит переход в очередь на запись, не блокируя вызывающего.
+// IP-адрес клиента сохраняется только в виде хеша с солью.
+func (s *urlService) RecordClick(ctx context.Context, event models.ClickEvent, clientIP string) {
+	if event.Timestamp.IsZero() {
+		event.Timestamp = time.Now()
+	}
+	event.IPHash = hashIP(clientIP, s.clickSalt)
+	s.clicks.record(event)
+}
+
+// GetLinkStats возвращает статистику переходов по ссылке.
+// Если ссылка не найдена или принадлежит другому пользователю, возвращает ErrLinkNotFound.
+func (s *urlService) GetLinkStats(ctx context.Context, userID, shortURL string) (models.LinkStatsModel, error) {
+	id := s.extractIDs([]string{shortURL})[0]
+
+	urlModel, exists := s.storage.Get(ctx, id)
+	if !exists || urlModel.UserID != userID {
+		return models.LinkStatsModel{}, ErrLinkNotFound
+	}
+
+	stats, err := s.storage.GetClickStats(ctx, id)
+	if err != nil {
+		return models.LinkStatsModel{}, err
+	}
+	stats.ShortURL = s.baseURL + "/" + id
+	return stats, nil
+}
+
+// GetStats возвращает сводную статистику сервиса: число действующих
+// и удалённых ссылок и число пользователей.
+func (s *urlService) GetStats(ctx context.Context) (models.StatsModel, error) {
+	return s.storage.GetStats(ctx)
+}
+
+// extractIDs извлекает ID из полных URLs, если переданы полные URL
+func (s *urlService) extractIDs(shortURLs []string) []string {
+	ids := make([]string, 0, len(shortURLs))
+	for _, urlStr := range shortURLs {
+		if strings.HasPrefix(urlStr, s.baseURL) {
+			// Если это полный URL, извлекаем ID
+			ids = append(ids, strings.TrimPrefix(urlStr, s.baseURL+"/"))
+		} else {
+			// Иначе считаем, что это уже ID
+			ids = append(ids, urlStr)
+		}
+	}
+	return ids
 }
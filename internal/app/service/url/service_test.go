@@ -2,11 +2,16 @@ package url
 
 import (
 	"context"
+	"errors"
+	"math"
 	"testing"
+	"time"
 
 	"github.com/alexuryumtsev/go-shortener/internal/app/models"
 	"github.com/alexuryumtsev/go-shortener/internal/app/storage"
+	"github.com/alexuryumtsev/go-shortener/internal/app/validator"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
 func TestURLService(t *testing.T) {
@@ -40,9 +45,18 @@ func TestURLService(t *testing.T) {
 		}
 	})
 
+	t.Run("ShortenerURL conflict", func(t *testing.T) {
+		originalURL := "https://example.com"
+
+		// Повторное сокращение возвращает существующую ссылку и конфликт
+		shortURL, err := service.ShortenerURL(ctx, originalURL, "another-user")
+		assert.ErrorIs(t, err, storage.ErrConflict)
+		assert.Contains(t, shortURL, baseURL)
+	})
+
 	t.Run("GetURLByID", func(t *testing.T) {
 		// Сначала сохраним URL
-		originalURL := "https://example.com"
+		originalURL := "https://example.com/get"
 		userID := "test-user"
 		shortURL, err := service.ShortenerURL(ctx, originalURL, userID)
 		assert.NoError(t, err)
@@ -66,3 +80,428 @@ func TestURLService(t *testing.T) {
 		assert.NotEmpty(t, userURLs)
 	})
 }
+
+func TestURLService_Shutdown(t *testing.T) {
+	mockStorage := storage.NewMockStorage()
+	service := NewURLService(mockStorage, "http://localhost:8080", 10)
+	ctx := context.Background()
+
+	shortURL, err := service.ShortenerURL(ctx, "https://example.com", "test-user")
+	assert.NoError(t, err)
+	id := shortURL[len("http://localhost:8080")+1:]
+
+	// Фоновое удаление должно завершиться до возврата из Shutdown
+	jobID, err := service.EnqueueDeletion(ctx, "test-user", []string{id})
+	assert.NoError(t, err)
+	assert.NoError(t, service.Shutdown(ctx))
+
+	_, exists, err := service.GetURLByID(ctx, id)
+	assert.True(t, exists)
+	assert.Error(t, err)
+
+	job, err := service.GetDeletionStatus(ctx, "test-user", jobID)
+	assert.NoError(t, err)
+	assert.Equal(t, DeletionStatusCompleted, job.Status)
+
+	// Чужая задача не видна
+	_, err = service.GetDeletionStatus(ctx, "other-user", jobID)
+	assert.ErrorIs(t, err, ErrDeletionJobNotFound)
+
+	// После остановки новые задачи не принимаются
+	_, err = service.EnqueueDeletion(ctx, "test-user", []string{id})
+	assert.ErrorIs(t, err, ErrDeletionQueueClosed)
+}
+
+// fixedGenerator возвращает ID из списка по номеру попытки.
+type fixedGenerator []string
+
+func (g fixedGenerator) Generate(_ string, attempt int) (string, error) {
+	return g[attempt%len(g)], nil
+}
+
+func TestURLService_IDCollision(t *testing.T) {
+	mockStorage := storage.NewMockStorage()
+	baseURL := "http://localhost:8080"
+	service := NewURLService(mockStorage, baseURL, 10, WithIDGenerator(fixedGenerator{"taken", "free"}))
+	ctx := context.Background()
+
+	// ID "taken" уже занят другим URL
+	assert.NoError(t, mockStorage.Save(ctx, models.URLModel{ID: "taken", URL: "https://other.com", UserID: "other"}))
+
+	shortURL, err := service.ShortenerURL(ctx, "https://example.com", "test-user")
+	assert.NoError(t, err)
+	assert.Equal(t, baseURL+"/free", shortURL)
+
+	// Чужая ссылка не перезаписана
+	stored, exists := mockStorage.Get(ctx, "taken")
+	assert.True(t, exists)
+	assert.Equal(t, "https://other.com", stored.URL)
+
+	// Оба ID заняты другими URL — свободный ID не найден
+	_, err = service.ShortenerURL(ctx, "https://third.com", "test-user")
+	assert.ErrorIs(t, err, ErrIDExhausted)
+}
+
+// racingStorage перед первой пакетной записью сохраняет URL из race,
+// как будто их записал параллельный запрос.
+type racingStorage struct {
+	*storage.MockStorage
+	race []models.URLModel
+}
+
+func (s *racingStorage) SaveBatch(ctx context.Context, urlModels []models.URLModel) ([]string, error) {
+	for _, urlModel := range s.race {
+		if err := s.MockStorage.Save(ctx, urlModel); err != nil {
+			return nil, err
+		}
+	}
+	s.race = nil
+	return s.MockStorage.SaveBatch(ctx, urlModels)
+}
+
+func TestURLService_BatchConcurrentSave(t *testing.T) {
+	baseURL := "http://localhost:8080"
+	ctx := context.Background()
+
+	t.Run("ID taken by another URL", func(t *testing.T) {
+		repo := &racingStorage{MockStorage: storage.NewMockStorage(), race: []models.URLModel{
+			{ID: "taken", URL: "https://other.com", UserID: "other"},
+		}}
+		service := NewURLService(repo, baseURL, 10, WithIDGenerator(fixedGenerator{"taken", "free"}))
+
+		responseModels, err := service.SaveBatchShortenerURL(ctx, []models.URLBatchModel{
+			{CorrelationID: "1", OriginalURL: "https://example.com"},
+			{CorrelationID: "2", OriginalURL: "https://example.com"},
+		}, "test-user")
+		require.NoError(t, err)
+		for _, resp := range responseModels {
+			assert.Equal(t, baseURL+"/free", resp.ShortURL, resp.CorrelationID)
+		}
+
+		// Чужая ссылка не перезаписана, а URL сохранён под новым ID
+		stored, _ := repo.Get(ctx, "taken")
+		assert.Equal(t, "https://other.com", stored.URL)
+		stored, _ = repo.Get(ctx, "free")
+		assert.Equal(t, "https://example.com", stored.URL)
+	})
+
+	t.Run("ID taken by the same URL", func(t *testing.T) {
+		repo := &racingStorage{MockStorage: storage.NewMockStorage(), race: []models.URLModel{
+			{ID: "taken", URL: "https://example.com", UserID: "other"},
+		}}
+		service := NewURLService(repo, baseURL, 10, WithIDGenerator(fixedGenerator{"taken", "free"}))
+
+		responseModels, err := service.SaveBatchShortenerURL(ctx, []models.URLBatchModel{
+			{CorrelationID: "1", OriginalURL: "https://example.com"},
+		}, "test-user")
+		require.NoError(t, err)
+		assert.Equal(t, baseURL+"/taken", responseModels[0].ShortURL)
+		_, exists := repo.Get(ctx, "free")
+		assert.False(t, exists)
+	})
+
+	t.Run("Alias taken by another URL", func(t *testing.T) {
+		repo := &racingStorage{MockStorage: storage.NewMockStorage(), race: []models.URLModel{
+			{ID: "spring-sale", URL: "https://other.com", UserID: "other"},
+		}}
+		service := NewURLService(repo, baseURL, 10)
+
+		_, err := service.SaveBatchShortenerURL(ctx, []models.URLBatchModel{
+			{CorrelationID: "1", OriginalURL: "https://example.com/sale", Alias: "spring-sale"},
+		}, "test-user")
+		assert.ErrorIs(t, err, ErrAliasTaken)
+	})
+}
+
+// blockingStorage задерживает удаление URL, пока не закрыт канал release.
+type blockingStorage struct {
+	*storage.MockStorage
+	started chan struct{}
+	release chan struct{}
+}
+
+func (s *blockingStorage) DeleteUserURLs(ctx context.Context, userID string, shortURLs []string) error {
+	s.started <- struct{}{}
+	<-s.release
+	return nil
+}
+
+func TestDeletionQueue_Close(t *testing.T) {
+	repo := &blockingStorage{MockStorage: storage.NewMockStorage(), started: make(chan struct{}, 1), release: make(chan struct{})}
+	q := newDeletionQueue(repo, 1, 1, 1)
+	ctx := context.Background()
+
+	// Воркер занят первой задачей, вторая заполняет очередь, третья ждёт места
+	_, err := q.enqueue(ctx, "user", []string{"a"})
+	assert.NoError(t, err)
+	<-repo.started
+	_, err = q.enqueue(ctx, "user", []string{"b"})
+	assert.NoError(t, err)
+
+	type result struct {
+		jobID string
+		err   error
+	}
+	blocked := make(chan result)
+	go func() {
+		jobID, err := q.enqueue(ctx, "user", []string{"c"})
+		blocked <- result{jobID, err}
+	}()
+
+	// Даём отправителю дойти до ожидания места в очереди
+	time.Sleep(20 * time.Millisecond)
+
+	// close не ждёт воркеров дольше контекста и освобождает ждущего отправителя
+	timeoutCtx, cancel := context.WithTimeout(ctx, 50*time.Millisecond)
+	defer cancel()
+	assert.ErrorIs(t, q.close(timeoutCtx), context.DeadlineExceeded)
+
+	res := <-blocked
+	assert.ErrorIs(t, res.err, ErrDeletionQueueClosed)
+	if res.jobID != "" {
+		// Неотправленная задача завершается с ошибкой
+		job, err := q.status("user", res.jobID)
+		assert.NoError(t, err)
+		assert.Equal(t, DeletionStatusFailed, job.Status)
+	}
+
+	_, err = q.enqueue(ctx, "user", []string{"d"})
+	assert.ErrorIs(t, err, ErrDeletionQueueClosed)
+
+	// Поставленные задачи обрабатываются до конца
+	close(repo.release)
+	assert.NoError(t, q.close(ctx))
+}
+
+// failingStorage всегда отказывает в удалении URL.
+type failingStorage struct {
+	*storage.MockStorage
+	attempts chan struct{}
+}
+
+func (s *failingStorage) DeleteUserURLs(ctx context.Context, userID string, shortURLs []string) error {
+	s.attempts <- struct{}{}
+	return errors.New("storage unavailable")
+}
+
+func TestDeletionQueue_CloseInterruptsRetries(t *testing.T) {
+	repo := &failingStorage{MockStorage: storage.NewMockStorage(), attempts: make(chan struct{}, deleteMaxRetries+1)}
+	q := newDeletionQueue(repo, 1, 1, 1)
+	ctx := context.Background()
+
+	jobID, err := q.enqueue(ctx, "user", []string{"a"})
+	require.NoError(t, err)
+	<-repo.attempts
+
+	// Воркер ждёт повторной попытки; истёкший контекст остановки прерывает ожидание
+	cancelled, cancel := context.WithCancel(ctx)
+	cancel()
+	assert.ErrorIs(t, q.close(cancelled), context.Canceled)
+
+	start := time.Now()
+	require.NoError(t, q.close(ctx))
+	assert.Less(t, time.Since(start), deleteRetryBaseDelay)
+
+	job, err := q.status("user", jobID)
+	require.NoError(t, err)
+	assert.Equal(t, DeletionStatusFailed, job.Status)
+}
+
+func TestURLService_Alias(t *testing.T) {
+	mockStorage := storage.NewMockStorage()
+	baseURL := "http://localhost:8080"
+	service := NewURLService(mockStorage, baseURL, 10)
+	ctx := context.Background()
+
+	t.Run("Create alias", func(t *testing.T) {
+		shortURL, err := service.ShortenerURLWithOptions(ctx, "https://example.com/sale", "test-user", models.ShortenOptions{Alias: "spring-sale"})
+		assert.NoError(t, err)
+		assert.Equal(t, baseURL+"/spring-sale", shortURL)
+	})
+
+	t.Run("Same URL already shortened", func(t *testing.T) {
+		shortURL, err := service.ShortenerURLWithOptions(ctx, "https://example.com/sale", "test-user", models.ShortenOptions{Alias: "spring-sale"})
+		assert.ErrorIs(t, err, storage.ErrConflict)
+		assert.NotErrorIs(t, err, ErrAliasTaken)
+		assert.Equal(t, baseURL+"/spring-sale", shortURL)
+	})
+
+	t.Run("Alias taken by another URL", func(t *testing.T) {
+		shortURL, err := service.ShortenerURLWithOptions(ctx, "https://example.com/other", "test-user", models.ShortenOptions{Alias: "spring-sale"})
+		assert.ErrorIs(t, err, ErrAliasTaken)
+		assert.Empty(t, shortURL)
+	})
+
+	t.Run("Alias taken by deleted or expired URL", func(t *testing.T) {
+		_, err := mockStorage.SaveBatch(ctx, []models.URLModel{
+			{ID: "deleted-sale", URL: "https://example.com/deleted", UserID: "test-user", Deleted: true},
+			{ID: "expired-sale", URL: "https://example.com/expired", UserID: "test-user", ExpiresAt: time.Now().Add(-time.Minute)},
+		})
+		assert.NoError(t, err)
+
+		for alias, originalURL := range map[string]string{
+			"deleted-sale": "https://example.com/deleted",
+			"expired-sale": "https://example.com/expired",
+		} {
+			// Другой URL не может занять алиас, пока запись остаётся в хранилище
+			shortURL, err := service.ShortenerURLWithOptions(ctx, "https://example.com/new", "test-user", models.ShortenOptions{Alias: alias})
+			assert.ErrorIs(t, err, ErrAliasTaken, alias)
+			assert.Empty(t, shortURL)
+
+			// Тот же URL тоже не получает недействующую ссылку
+			_, err = service.ShortenerURLWithOptions(ctx, originalURL, "test-user", models.ShortenOptions{Alias: alias})
+			assert.ErrorIs(t, err, ErrAliasTaken, alias)
+		}
+	})
+
+	t.Run("Reserved alias", func(t *testing.T) {
+		_, err := service.ShortenerURLWithOptions(ctx, "https://example.com/ping", "test-user", models.ShortenOptions{Alias: "ping"})
+		assert.ErrorIs(t, err, validator.ErrInvalidAlias)
+	})
+
+	t.Run("Batch with aliases", func(t *testing.T) {
+		batch := []models.URLBatchModel{
+			{CorrelationID: "1", OriginalURL: "https://example.com/a", Alias: "summer-sale"},
+			{CorrelationID: "2", OriginalURL: "https://example.com/b"},
+		}
+		responseModels, err := service.SaveBatchShortenerURL(ctx, batch, "test-user")
+		assert.NoError(t, err)
+		assert.Equal(t, baseURL+"/summer-sale", responseModels[0].ShortURL)
+
+		// Алиас внутри пакета занят другим URL
+		batch = []models.URLBatchModel{
+			{CorrelationID: "1", OriginalURL: "https://example.com/c", Alias: "winter-sale"},
+			{CorrelationID: "2", OriginalURL: "https://example.com/d", Alias: "winter-sale"},
+		}
+		_, err = service.SaveBatchShortenerURL(ctx, batch, "test-user")
+		assert.ErrorIs(t, err, ErrAliasTaken)
+	})
+}
+
+func TestURLService_Expiry(t *testing.T) {
+	mockStorage := storage.NewMockStorage()
+	baseURL := "http://localhost:8080"
+	service := NewURLService(mockStorage, baseURL, 10)
+	ctx := context.Background()
+
+	t.Run("Invalid expiry", func(t *testing.T) {
+		_, err := service.ShortenerURLWithOptions(ctx, "https://example.com/a", "test-user",
+			models.ShortenOptions{ExpiresAt: time.Now().Add(-time.Hour)})
+		assert.ErrorIs(t, err, ErrInvalidExpiry)
+
+		_, err = service.ShortenerURLWithOptions(ctx, "https://example.com/a", "test-user",
+			models.ShortenOptions{TTL: time.Hour, ExpiresAt: time.Now().Add(time.Hour)})
+		assert.ErrorIs(t, err, ErrInvalidExpiry)
+
+		// TTL, при котором умножение на time.Second переполняется, отклоняется
+		_, err = service.SaveBatchShortenerURL(ctx, []models.URLBatchModel{
+			{CorrelationID: "1", OriginalURL: "https://example.com/a", TTL: math.MaxInt64 / 1000},
+		}, "test-user")
+		assert.ErrorIs(t, err, ErrInvalidExpiry)
+	})
+
+	t.Run("TTL bounds", func(t *testing.T) {
+		ttl, err := TTLFromSeconds(3600)
+		assert.NoError(t, err)
+		assert.Equal(t, time.Hour, ttl)
+
+		ttl, err = TTLFromSeconds(int64(MaxTTL / time.Second))
+		assert.NoError(t, err)
+		assert.Equal(t, MaxTTL, ttl)
+
+		for _, seconds := range []int64{-1, int64(MaxTTL/time.Second) + 1, math.MaxInt64} {
+			_, err := TTLFromSeconds(seconds)
+			assert.ErrorIs(t, err, ErrInvalidExpiry, seconds)
+		}
+	})
+
+	t.Run("Expiry shown in user URLs", func(t *testing.T) {
+		_, err := service.ShortenerURLWithOptions(ctx, "https://example.com/ttl", "ttl-user",
+			models.ShortenOptions{TTL: time.Hour})
+		assert.NoError(t, err)
+
+		userURLs, err := service.GetUserURLs(ctx, "ttl-user")
+		assert.NoError(t, err)
+		assert.Len(t, userURLs, 1)
+		assert.NotNil(t, userURLs[0].ExpiresAt)
+	})
+
+	t.Run("Expired URL", func(t *testing.T) {
+		// Срок действия уже истёк
+		assert.NoError(t, mockStorage.Save(ctx, models.URLModel{
+			ID: "expired1", URL: "https://example.com/old", UserID: "test-user", ExpiresAt: time.Now().Add(-time.Minute),
+		}))
+
+		_, exists, err := service.GetURLByID(ctx, "expired1")
+		assert.True(t, exists)
+		assert.ErrorIs(t, err, ErrURLExpired)
+
+		// Повторное сокращение истёкшего URL создаёт новую ссылку
+		_, err = service.ShortenerURL(ctx, "https://example.com/old", "test-user")
+		assert.NoError(t, err)
+	})
+
+	t.Run("Deleted URL", func(t *testing.T) {
+		assert.NoError(t, mockStorage.Save(ctx, models.URLModel{
+			ID: "deleted1", URL: "https://example.com/gone", UserID: "test-user", Deleted: true,
+		}))
+
+		// Повторное сокращение удалённого URL создаёт новую ссылку
+		shortURL, err := service.ShortenerURL(ctx, "https://example.com/gone", "test-user")
+		assert.NoError(t, err)
+		assert.NotEqual(t, baseURL+"/deleted1", shortURL)
+
+		// а следующие возвращают её же, не создавая новых
+		for i := 0; i < 3; i++ {
+			again, err := service.ShortenerURL(ctx, "https://example.com/gone", "test-user")
+			assert.ErrorIs(t, err, storage.ErrConflict)
+			assert.Equal(t, shortURL, again)
+		}
+
+		responseModels, err := service.SaveBatchShortenerURL(ctx, []models.URLBatchModel{
+			{CorrelationID: "1", OriginalURL: "https://example.com/gone-batch"},
+		}, "test-user")
+		assert.NoError(t, err)
+		assert.NoError(t, mockStorage.DeleteUserURLs(ctx, "test-user", []string{responseModels[0].ShortURL[len(baseURL)+1:]}))
+
+		again, err := service.SaveBatchShortenerURL(ctx, []models.URLBatchModel{
+			{CorrelationID: "1", OriginalURL: "https://example.com/gone-batch"},
+		}, "test-user")
+		assert.NoError(t, err)
+		assert.NotEqual(t, responseModels[0].ShortURL, again[0].ShortURL)
+	})
+}
+
+func TestURLService_Clicks(t *testing.T) {
+	mockStorage := storage.NewMockStorage()
+	baseURL := "http://localhost:8080"
+	service := NewURLService(mockStorage, baseURL, 10, WithClickSalt("salt"))
+	ctx := context.Background()
+
+	assert.NoError(t, mockStorage.Save(ctx, models.URLModel{ID: "stats1", URL: "https://example.com/stats", UserID: "owner"}))
+
+	day := time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC)
+	service.RecordClick(ctx, models.ClickEvent{ShortID: "stats1", Timestamp: day}, "10.0.0.1")
+	service.RecordClick(ctx, models.ClickEvent{ShortID: "stats1", Timestamp: day.Add(time.Hour)}, "10.0.0.1")
+	service.RecordClick(ctx, models.ClickEvent{ShortID: "stats1", Timestamp: day.Add(24 * time.Hour)}, "10.0.0.2")
+
+	// Shutdown записывает накопленные события в хранилище
+	assert.NoError(t, service.Shutdown(ctx))
+
+	stats, err := service.GetLinkStats(ctx, "owner", baseURL+"/stats1")
+	assert.NoError(t, err)
+	assert.Equal(t, baseURL+"/stats1", stats.ShortURL)
+	assert.Equal(t, 3, stats.TotalClicks)
+	assert.Equal(t, 2, stats.UniqueVisitors)
+	assert.Equal(t, []models.DailyStatsModel{
+		{Date: "2024-03-01", Clicks: 2, UniqueVisitors: 1},
+		{Date: "2024-03-02", Clicks: 1, UniqueVisitors: 1},
+	}, stats.Daily)
+
+	// Статистика доступна только владельцу ссылки
+	_, err = service.GetLinkStats(ctx, "other", "stats1")
+	assert.ErrorIs(t, err, ErrLinkNotFound)
+
+	_, err = service.GetLinkStats(ctx, "owner", "missing")
+	assert.ErrorIs(t, err, ErrLinkNotFound)
+}
@@ -0,0 +1,155 @@
+package url
+
+import (
+	"context"
+	"errors"
+
+	"github.com/alexuryumtsev/go-shortener/internal/app/models"
+	"github.com/alexuryumtsev/go-shortener/internal/app/storage"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// instrumentation — имя библиотеки инструментирования сервиса.
+const instrumentation = "github.com/alexuryumtsev/go-shortener/internal/app/service/url"
+
+// Атрибуты спанов сервиса. Оригинальные URL в спаны не пишутся.
+const (
+	attrShortID   = attribute.Key("shortener.short_id")
+	attrBatchSize = attribute.Key("shortener.batch_size")
+	attrFound     = attribute.Key("shortener.found")
+)
+
+// NewTracedURLService оборачивает сервис next, создавая спан для каждого вызова.
+// Спаны вложены в спан запроса из контекста, а запросы хранилища — в спаны сервиса.
+func NewTracedURLService(next URLService) URLService {
+	return &tracedURLService{next: next, tracer: otel.Tracer(instrumentation)}
+}
+
+// tracedURLService — URLService, создающий спаны вызовов.
+type tracedURLService struct {
+	next   URLService
+	tracer trace.Tracer
+}
+
+// start начинает спан метода name.
+func (s *tracedURLService) start(ctx context.Context, name string, attrs ...attribute.KeyValue) (context.Context, trace.Span) {
+	return s.tracer.Start(ctx, "url."+name, trace.WithAttributes(attrs...))
+}
+
+// end завершает спан, отмечая ошибку err. Ожидаемые ответы сервиса —
+// удалённая, истёкшая или не найденная ссылка, занятый URL — записываются
+// как события, но ошибкой спана не считаются.
+func end(span trace.Span, err error) {
+	if err != nil {
+		span.RecordError(err)
+		if !expectedError(err) {
+			span.SetStatus(codes.Error, err.Error())
+		}
+	}
+	span.End()
+}
+
+// expectedError сообщает, является ли err штатным ответом сервиса.
+func expectedError(err error) bool {
+	for _, target := range []error{storage.ErrConflict, ErrURLDeleted, ErrURLExpired, ErrLinkNotFound, ErrDeletionJobNotFound, ErrInvalidExpiry} {
+		if errors.Is(err, target) {
+			return true
+		}
+	}
+	return false
+}
+
+// ShortenerURL создает короткий URL.
+func (s *tracedURLService) ShortenerURL(ctx context.Context, originalURL, userID string) (shortURL string, err error) {
+	ctx, span := s.start(ctx, "ShortenerURL")
+	defer func() { end(span, err) }()
+	return s.next.ShortenerURL(ctx, originalURL, userID)
+}
+
+// ShortenerURLWithOptions создает короткий URL с алиасом и сроком действия.
+func (s *tracedURLService) ShortenerURLWithOptions(ctx context.Context, originalURL, userID string, opts models.ShortenOptions) (shortURL string, err error) {
+	ctx, span := s.start(ctx, "ShortenerURLWithOptions", attribute.Bool("shortener.alias", opts.Alias != ""))
+	defer func() { end(span, err) }()
+	return s.next.ShortenerURLWithOptions(ctx, originalURL, userID, opts)
+}
+
+// SaveBatchShortenerURL сохраняет пакет URL.
+func (s *tracedURLService) SaveBatchShortenerURL(ctx context.Context, batchModels []models.URLBatchModel, userID string) (result []models.BatchResponseModel, err error) {
+	ctx, span := s.start(ctx, "SaveBatchShortenerURL", attrBatchSize.Int(len(batchModels)))
+	defer func() { end(span, err) }()
+	return s.next.SaveBatchShortenerURL(ctx, batchModels, userID)
+}
+
+// DeleteUserURLsBatch помечает URL пользователя как удаленные.
+func (s *tracedURLService) DeleteUserURLsBatch(ctx context.Context, userID string, shortURLs []string) (err error) {
+	ctx, span := s.start(ctx, "DeleteUserURLsBatch", attrBatchSize.Int(len(shortURLs)))
+	defer func() { end(span, err) }()
+	return s.next.DeleteUserURLsBatch(ctx, userID, shortURLs)
+}
+
+// EnqueueDeletion ставит удаление URL в очередь.
+func (s *tracedURLService) EnqueueDeletion(ctx context.Context, userID string, shortURLs []string) (jobID string, err error) {
+	ctx, span := s.start(ctx, "EnqueueDeletion", attrBatchSize.Int(len(shortURLs)))
+	defer func() { end(span, err) }()
+	return s.next.EnqueueDeletion(ctx, userID, shortURLs)
+}
+
+// GetDeletionStatus возвращает состояние задачи удаления.
+func (s *tracedURLService) GetDeletionStatus(ctx context.Context, userID, jobID string) (job models.DeletionJobModel, err error) {
+	ctx, span := s.start(ctx, "GetDeletionStatus")
+	defer func() { end(span, err) }()
+	return s.next.GetDeletionStatus(ctx, userID, jobID)
+}
+
+// DeletionQueueDepth возвращает длину очереди удаления; спан не создаётся.
+func (s *tracedURLService) DeletionQueueDepth() int {
+	return s.next.DeletionQueueDepth()
+}
+
+// Shutdown останавливает фоновые задачи сервиса.
+func (s *tracedURLService) Shutdown(ctx context.Context) (err error) {
+	ctx, span := s.start(ctx, "Shutdown")
+	defer func() { end(span, err) }()
+	return s.next.Shutdown(ctx)
+}
+
+// GetURLByID получает оригинальный URL по ID.
+func (s *tracedURLService) GetURLByID(ctx context.Context, id string) (originalURL string, exists bool, err error) {
+	ctx, span := s.start(ctx, "GetURLByID", attrShortID.String(id))
+	defer func() {
+		span.SetAttributes(attrFound.Bool(exists))
+		end(span, err)
+	}()
+	return s.next.GetURLByID(ctx, id)
+}
+
+// GetUserURLs получает все URL пользователя.
+func (s *tracedURLService) GetUserURLs(ctx context.Context, userID string) (urls []models.UserURLModel, err error) {
+	ctx, span := s.start(ctx, "GetUserURLs")
+	defer func() { end(span, err) }()
+	return s.next.GetUserURLs(ctx, userID)
+}
+
+// RecordClick ставит переход в очередь записи.
+func (s *tracedURLService) RecordClick(ctx context.Context, event models.ClickEvent, clientIP string) {
+	ctx, span := s.start(ctx, "RecordClick", attrShortID.String(event.ShortID))
+	defer span.End()
+	s.next.RecordClick(ctx, event, clientIP)
+}
+
+// GetLinkStats возвращает статистику переходов по ссылке.
+func (s *tracedURLService) GetLinkStats(ctx context.Context, userID, shortURL string) (stats models.LinkStatsModel, err error) {
+	ctx, span := s.start(ctx, "GetLinkStats", attrShortID.String(shortURL))
+	defer func() { end(span, err) }()
+	return s.next.GetLinkStats(ctx, userID, shortURL)
+}
+
+// GetStats возвращает сводную статистику сервиса.
+func (s *tracedURLService) GetStats(ctx context.Context) (stats models.StatsModel, err error) {
+	ctx, span := s.start(ctx, "GetStats")
+	defer func() { end(span, err) }()
+	return s.next.GetStats(ctx)
+}
@@ -0,0 +1,57 @@
+package url
+
+import (
+	"context"
+	"testing"
+
+	"github.com/alexuryumtsev/go-shortener/internal/app/models"
+	"github.com/alexuryumtsev/go-shortener/internal/app/storage"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/codes"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+)
+
+func TestTracedURLService(t *testing.T) {
+	recorder := tracetest.NewSpanRecorder()
+	previous := otel.GetTracerProvider()
+	otel.SetTracerProvider(sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder)))
+	t.Cleanup(func() { otel.SetTracerProvider(previous) })
+
+	mockStorage := storage.NewMockStorage()
+	service := NewTracedURLService(NewURLService(mockStorage, "http://localhost:8080", 10))
+	ctx, parent := otel.Tracer("test").Start(context.Background(), "request")
+
+	_, err := service.SaveBatchShortenerURL(ctx, []models.URLBatchModel{
+		{CorrelationID: "1", OriginalURL: "https://a.com"},
+		{CorrelationID: "2", OriginalURL: "https://b.com"},
+	}, "user")
+	require.NoError(t, err)
+
+	require.NoError(t, mockStorage.Save(ctx, models.URLModel{ID: "gone", URL: "https://gone.com", UserID: "user", Deleted: true}))
+	_, _, err = service.GetURLByID(ctx, "gone")
+	require.ErrorIs(t, err, ErrURLDeleted)
+
+	_, err = service.GetStats(ctx)
+	require.NoError(t, err)
+	parent.End()
+
+	spans := recorder.Ended()
+	require.Len(t, spans, 4)
+	batch, get := spans[0], spans[1]
+
+	assert.Equal(t, "url.SaveBatchShortenerURL", batch.Name())
+	assert.Equal(t, parent.SpanContext().SpanID(), batch.Parent().SpanID())
+	assert.Contains(t, batch.Attributes(), attrBatchSize.Int(2))
+
+	// Удалённая ссылка — штатный ответ: ошибка записана, но спан не ошибочный
+	assert.Equal(t, "url.GetURLByID", get.Name())
+	assert.Contains(t, get.Attributes(), attrShortID.String("gone"))
+	assert.Equal(t, codes.Unset, get.Status().Code)
+	if assert.Len(t, get.Events(), 1) {
+		assert.Equal(t, "exception", get.Events()[0].Name)
+	}
+	assert.Equal(t, "url.GetStats", spans[2].Name())
+}
@@ -0,0 +1,57 @@
+package user
+
+import (
+	"context"
+	"slices"
+)
+
+// AuthMethod — способ, которым аутентифицирован запрос.
+type AuthMethod string
+
+// Способы аутентификации.
+const (
+	// AuthMethodCookie — JWT в куке auth_token
+	AuthMethodCookie AuthMethod = "cookie"
+	// AuthMethodBearer — JWT в заголовке Authorization
+	AuthMethodBearer AuthMethod = "bearer"
+	// AuthMethodAPIKey — API-ключ в заголовке Authorization
+	AuthMethodAPIKey AuthMethod = "api_key"
+)
+
+// Identity описывает пользователя, от имени которого выполняется запрос.
+type Identity struct {
+	// UserID — идентификатор пользователя
+	UserID string
+	// Method — способ аутентификации
+	Method AuthMethod
+	// Anonymous сообщает, что пользователь не зарегистрирован:
+	// его идентификатор выдан автоматически и живёт, пока жив токен
+	Anonymous bool
+	// Scopes — области действия API-ключа; для остальных способов не ограничены
+	Scopes []string
+}
+
+// Authenticated сообщает, что запрос выполняет зарегистрированный пользователь.
+func (id Identity) Authenticated() bool {
+	return id.UserID != "" && !id.Anonymous
+}
+
+// HasScope сообщает, разрешена ли запросу область действия scope.
+// Ограничения действуют только для API-ключей.
+func (id Identity) HasScope(scope string) bool {
+	return id.Method != AuthMethodAPIKey || slices.Contains(id.Scopes, scope)
+}
+
+// identityKey — ключ контекста для Identity.
+type identityKey struct{}
+
+// ContextWithIdentity возвращает контекст с личностью пользователя.
+func ContextWithIdentity(ctx context.Context, identity Identity) context.Context {
+	return context.WithValue(ctx, identityKey{}, identity)
+}
+
+// IdentityFromContext возвращает личность пользователя, установленную middleware аутентификации.
+func IdentityFromContext(ctx context.Context) (Identity, bool) {
+	identity, ok := ctx.Value(identityKey{}).(Identity)
+	return identity, ok && identity.UserID != ""
+}
@@ -0,0 +1,301 @@
+package user
+
+import (
+	"crypto"
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/hex"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// Ошибки проверки ключа подписи токена.
+var (
+	ErrUnknownKey     = errors.New("unknown signing key")
+	ErrKeyRetired     = errors.New("signing key grace period is over")
+	ErrNoSigningKey   = errors.New("signing key can only verify tokens")
+	ErrUnsupportedKey = errors.New("unsupported key type")
+)
+
+// Key — ключ подписи JWT.
+// Идентификатор ключа (kid) вычисляется по ключевому материалу, поэтому один
+// и тот же ключ получает одинаковый kid во всех экземплярах сервиса.
+type Key struct {
+	id     string
+	method jwt.SigningMethod
+	// sign равен nil у ключей, пригодных только для проверки подписи
+	sign   crypto.PrivateKey
+	verify crypto.PublicKey
+}
+
+// ID возвращает идентификатор ключа, который записывается в заголовок kid.
+func (k Key) ID() string {
+	return k.id
+}
+
+// NewHMACKey создаёт симметричный ключ HS256.
+func NewHMACKey(secret []byte) Key {
+	return Key{id: keyID(secret), method: jwt.SigningMethodHS256, sign: secret, verify: secret}
+}
+
+// GenerateHMACKey создаёт симметричный ключ HS256 со случайным секретом.
+func GenerateHMACKey() (Key, error) {
+	secret := make([]byte, 32)
+	if _, err := rand.Read(secret); err != nil {
+		return Key{}, fmt.Errorf("failed to generate secret: %w", err)
+	}
+	return NewHMACKey(secret), nil
+}
+
+// ParsePEMKey разбирает ключ в формате PEM.
+// Алгоритм определяется типом ключа: RSA — RS256, Ed25519 — EdDSA.
+// Закрытый ключ пригоден для подписи и проверки, открытый — только для проверки.
+func ParsePEMKey(data []byte) (Key, error) {
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return Key{}, errors.New("no PEM data found")
+	}
+
+	var (
+		parsed any
+		err    error
+	)
+	switch block.Type {
+	case "RSA PRIVATE KEY":
+		parsed, err = x509.ParsePKCS1PrivateKey(block.Bytes)
+	case "PRIVATE KEY":
+		parsed, err = x509.ParsePKCS8PrivateKey(block.Bytes)
+	case "RSA PUBLIC KEY":
+		parsed, err = x509.ParsePKCS1PublicKey(block.Bytes)
+	case "PUBLIC KEY":
+		parsed, err = x509.ParsePKIXPublicKey(block.Bytes)
+	default:
+		return Key{}, fmt.Errorf("%w: PEM block %q", ErrUnsupportedKey, block.Type)
+	}
+	if err != nil {
+		return Key{}, fmt.Errorf("failed to parse key: %w", err)
+	}
+
+	var key Key
+	switch k := parsed.(type) {
+	case *rsa.PrivateKey:
+		key = Key{method: jwt.SigningMethodRS256, sign: k, verify: &k.PublicKey}
+	case *rsa.PublicKey:
+		key = Key{method: jwt.SigningMethodRS256, verify: k}
+	case ed25519.PrivateKey:
+		key = Key{method: jwt.SigningMethodEdDSA, sign: k, verify: k.Public()}
+	case ed25519.PublicKey:
+		key = Key{method: jwt.SigningMethodEdDSA, verify: k}
+	default:
+		return Key{}, fmt.Errorf("%w: %T", ErrUnsupportedKey, parsed)
+	}
+
+	der, err := x509.MarshalPKIXPublicKey(key.verify)
+	if err != nil {
+		return Key{}, fmt.Errorf("failed to marshal public key: %w", err)
+	}
+	key.id = keyID(der)
+	return key, nil
+}
+
+// LoadPEMKey читает ключ в формате PEM из файла.
+func LoadPEMKey(path string) (Key, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return Key{}, fmt.Errorf("failed to read key file: %w", err)
+	}
+	key, err := ParsePEMKey(data)
+	if err != nil {
+		return Key{}, fmt.Errorf("%s: %w", path, err)
+	}
+	return key, nil
+}
+
+// keyID возвращает идентификатор ключа: начало SHA-256 от ключевого материала.
+func keyID(material []byte) string {
+	sum := sha256.Sum256(material)
+	return hex.EncodeToString(sum[:8])
+}
+
+// PreviousKey — предыдущий ключ подписи и момент, когда он перестал быть активным.
+type PreviousKey struct {
+	Key       Key
+	RetiredAt time.Time
+}
+
+// retiredKey — предыдущий ключ, принимаемый до окончания льготного периода.
+type retiredKey struct {
+	Key
+	notAfter time.Time
+}
+
+// KeyRing хранит активный ключ подписи и предыдущие ключи.
+// Токены подписываются активным ключом; токены, подписанные предыдущими
+// ключами, принимаются до окончания льготного периода, поэтому ротация
+// ключа не разлогинивает пользователей.
+type KeyRing struct {
+	active  Key
+	retired map[string]retiredKey
+	now     func() time.Time
+}
+
+// NewKeyRing создаёт набор ключей с активным ключом active.
+// Ключи previous принимаются при проверке в течение gracePeriod с момента их
+// вывода из использования (RetiredAt). Момент задаётся явно, а не отсчитывается
+// от запуска, поэтому перезапуск не продлевает срок действия старых ключей.
+func NewKeyRing(active Key, previous []PreviousKey, gracePeriod time.Duration) (*KeyRing, error) {
+	if active.sign == nil {
+		return nil, ErrNoSigningKey
+	}
+
+	ring := &KeyRing{
+		active:  active,
+		retired: make(map[string]retiredKey, len(previous)),
+		now:     time.Now,
+	}
+	for _, prev := range previous {
+		if prev.Key.id == active.id {
+			continue
+		}
+		ring.retired[prev.Key.id] = retiredKey{Key: prev.Key, notAfter: prev.RetiredAt.Add(gracePeriod)}
+	}
+	return ring, nil
+}
+
+// Sign подписывает claims активным ключом и записывает его идентификатор в kid.
+func (kr *KeyRing) Sign(claims jwt.Claims) (string, error) {
+	token := jwt.NewWithClaims(kr.active.method, claims)
+	token.Header["kid"] = kr.active.id
+	return token.SignedString(kr.active.sign)
+}
+
+// Keyfunc возвращает функцию выбора ключа проверки для jwt.Parse.
+// Ключ выбирается по kid; токены без kid, выпущенные до появления набора ключей,
+// проверяются всеми действующими ключами с подходящим алгоритмом.
+func (kr *KeyRing) Keyfunc() jwt.Keyfunc {
+	return func(token *jwt.Token) (any, error) {
+		kid, _ := token.Header["kid"].(string)
+		if kid == "" {
+			return kr.legacyKeys(token.Method.Alg())
+		}
+
+		key, err := kr.lookup(kid)
+		if err != nil {
+			return nil, err
+		}
+		if key.method.Alg() != token.Method.Alg() {
+			return nil, fmt.Errorf("unexpected signing method %s", token.Method.Alg())
+		}
+		return key.verify, nil
+	}
+}
+
+// lookup находит действующий ключ по идентификатору.
+func (kr *KeyRing) lookup(kid string) (Key, error) {
+	if kid == kr.active.id {
+		return kr.active, nil
+	}
+	key, ok := kr.retired[kid]
+	if !ok {
+		return Key{}, ErrUnknownKey
+	}
+	if kr.now().After(key.notAfter) {
+		return Key{}, ErrKeyRetired
+	}
+	return key.Key, nil
+}
+
+// legacyKeys возвращает все действующие ключи алгоритма alg.
+func (kr *KeyRing) legacyKeys(alg string) (jwt.VerificationKeySet, error) {
+	var set jwt.VerificationKeySet
+	if kr.active.method.Alg() == alg {
+		set.Keys = append(set.Keys, kr.active.verify)
+	}
+	now := kr.now()
+	for _, key := range kr.retired {
+		if key.method.Alg() == alg && !now.After(key.notAfter) {
+			set.Keys = append(set.Keys, key.verify)
+		}
+	}
+	if len(set.Keys) == 0 {
+		return set, ErrUnknownKey
+	}
+	return set, nil
+}
+
+// previousKeyFilePrefix отмечает предыдущий ключ, заданный путём к файлу PEM.
+const previousKeyFilePrefix = "file:"
+
+// retiredAtSeparator отделяет предыдущий ключ от момента его вывода из использования.
+const retiredAtSeparator = "@"
+
+// ErrNoRetirementTime возвращается, если для предыдущего ключа не указан момент
+// вывода из использования.
+var ErrNoRetirementTime = errors.New("previous key has no retirement time, expected <key>@<RFC3339 time>")
+
+// ParsePreviousKey разбирает предыдущий ключ в формате "<ключ>@<момент RFC 3339>",
+// где ключ — секрет HS256 или путь к файлу PEM с префиксом "file:".
+// Разделителем считается последний символ "@", поэтому он допустим в секрете.
+func ParsePreviousKey(entry string) (PreviousKey, error) {
+	idx := strings.LastIndex(entry, retiredAtSeparator)
+	if idx < 0 {
+		return PreviousKey{}, ErrNoRetirementTime
+	}
+	retiredAt, err := time.Parse(time.RFC3339, entry[idx+len(retiredAtSeparator):])
+	if err != nil {
+		return PreviousKey{}, fmt.Errorf("%w: %w", ErrNoRetirementTime, err)
+	}
+
+	material := entry[:idx]
+	if path, ok := strings.CutPrefix(material, previousKeyFilePrefix); ok {
+		key, err := LoadPEMKey(path)
+		if err != nil {
+			return PreviousKey{}, err
+		}
+		return PreviousKey{Key: key, RetiredAt: retiredAt}, nil
+	}
+	return PreviousKey{Key: NewHMACKey([]byte(material)), RetiredAt: retiredAt}, nil
+}
+
+// LoadKeyRing создаёт набор ключей по параметрам конфигурации.
+// Активный ключ читается из keyFile (RS256 или EdDSA), иначе используется секрет HS256;
+// если не задано ни то ни другое, генерируется случайный секрет.
+// Каждый элемент previous разбирается ParsePreviousKey; ошибка не раскрывает секрет,
+// а указывает номер элемента.
+func LoadKeyRing(secret, keyFile string, previous []string, gracePeriod time.Duration) (*KeyRing, error) {
+	var (
+		active Key
+		err    error
+	)
+	switch {
+	case keyFile != "":
+		active, err = LoadPEMKey(keyFile)
+	case secret != "":
+		active = NewHMACKey([]byte(secret))
+	default:
+		active, err = GenerateHMACKey()
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	keys := make([]PreviousKey, 0, len(previous))
+	for i, entry := range previous {
+		key, err := ParsePreviousKey(entry)
+		if err != nil {
+			return nil, fmt.Errorf("previous key #%d: %w", i+1, err)
+		}
+		keys = append(keys, key)
+	}
+
+	return NewKeyRing(active, keys, gracePeriod)
+}
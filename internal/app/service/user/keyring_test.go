@@ -0,0 +1,158 @@
+package user
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/pem"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// writePEM сохраняет ключ в файл PEM и возвращает путь к нему.
+func writePEM(t *testing.T, blockType string, der []byte) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "key.pem")
+	require.NoError(t, os.WriteFile(path, pem.EncodeToMemory(&pem.Block{Type: blockType, Bytes: der}), 0600))
+	return path
+}
+
+func TestKeyRing_Asymmetric(t *testing.T) {
+	_, edKey, err := ed25519.GenerateKey(rand.Reader)
+	require.NoError(t, err)
+	edDER, err := x509.MarshalPKCS8PrivateKey(edKey)
+	require.NoError(t, err)
+
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+
+	tests := []struct {
+		name string
+		path string
+		alg  string
+	}{
+		{"Ed25519", writePEM(t, "PRIVATE KEY", edDER), "EdDSA"},
+		{"RSA", writePEM(t, "RSA PRIVATE KEY", x509.MarshalPKCS1PrivateKey(rsaKey)), "RS256"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			keys, err := LoadKeyRing("", tt.path, nil, time.Hour)
+			require.NoError(t, err)
+			service := NewUserServiceWithKeys(keys)
+
+			_, token, err := service.NewAnonymousUser()
+			require.NoError(t, err)
+
+			parsed, _, err := jwt.NewParser().ParseUnverified(token, jwt.MapClaims{})
+			require.NoError(t, err)
+			assert.Equal(t, tt.alg, parsed.Method.Alg())
+			assert.Equal(t, keys.active.ID(), parsed.Header["kid"])
+
+			identity, err := service.Authenticate(token)
+			assert.NoError(t, err)
+			assert.NotEmpty(t, identity.UserID)
+		})
+	}
+
+	t.Run("Public key cannot sign", func(t *testing.T) {
+		pubDER, err := x509.MarshalPKIXPublicKey(edKey.Public())
+		require.NoError(t, err)
+
+		_, err = LoadKeyRing("", writePEM(t, "PUBLIC KEY", pubDER), nil, time.Hour)
+		assert.ErrorIs(t, err, ErrNoSigningKey)
+	})
+
+	t.Run("Algorithm confusion", func(t *testing.T) {
+		keys, err := LoadKeyRing("", tests[1].path, nil, time.Hour)
+		require.NoError(t, err)
+
+		// Токен HS256, подписанный открытым ключом RSA как секретом, отклоняется
+		pubDER, err := x509.MarshalPKIXPublicKey(&rsaKey.PublicKey)
+		require.NoError(t, err)
+		token := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.MapClaims{"user_id": "attacker"})
+		token.Header["kid"] = keys.active.ID()
+		forged, err := token.SignedString(pubDER)
+		require.NoError(t, err)
+
+		_, err = NewUserServiceWithKeys(keys).Authenticate(forged)
+		assert.Error(t, err)
+	})
+}
+
+func TestKeyRing_Rotation(t *testing.T) {
+	oldService := NewUserService("old-secret")
+	_, oldToken, err := oldService.NewAnonymousUser()
+	require.NoError(t, err)
+	legacyToken := createTestToken(t, "old-secret", "legacy-user", time.Hour)
+
+	// Ключ выведен из использования полчаса назад, льготный период — час
+	retiredAt := time.Now().Add(-30 * time.Minute).UTC().Format(time.RFC3339)
+	keys, err := LoadKeyRing("new-secret", "", []string{"old-secret@" + retiredAt}, time.Hour)
+	require.NoError(t, err)
+	service := NewUserServiceWithKeys(keys)
+
+	_, newToken, err := service.NewAnonymousUser()
+	require.NoError(t, err)
+
+	// В течение льготного периода принимаются токены, подписанные предыдущим ключом
+	_, err = service.Authenticate(newToken)
+	assert.NoError(t, err)
+	_, err = service.Authenticate(oldToken)
+	assert.NoError(t, err)
+	identity, err := service.Authenticate(legacyToken)
+	assert.NoError(t, err)
+	assert.Equal(t, "legacy-user", identity.UserID)
+
+	// После окончания льготного периода — только токены активного ключа
+	keys.now = func() time.Time { return time.Now().Add(time.Hour) }
+	_, err = service.Authenticate(newToken)
+	assert.NoError(t, err)
+	_, err = service.Authenticate(oldToken)
+	assert.ErrorIs(t, err, ErrKeyRetired)
+	_, err = service.Authenticate(legacyToken)
+	assert.Error(t, err)
+
+	// Токены неизвестных ключей отклоняются
+	_, err = NewUserService("other-secret").Authenticate(newToken)
+	assert.ErrorIs(t, err, ErrUnknownKey)
+}
+
+func TestKeyRing_RetirementTime(t *testing.T) {
+	oldService := NewUserService("old-secret")
+	_, oldToken, err := oldService.NewAnonymousUser()
+	require.NoError(t, err)
+
+	// Перезапуск не продлевает льготный период: он отсчитывается от момента вывода ключа
+	retiredAt := time.Now().Add(-2 * time.Hour).UTC().Format(time.RFC3339)
+	keys, err := LoadKeyRing("new-secret", "", []string{"old-secret@" + retiredAt}, time.Hour)
+	require.NoError(t, err)
+	_, err = NewUserServiceWithKeys(keys).Authenticate(oldToken)
+	assert.ErrorIs(t, err, ErrKeyRetired)
+	assert.True(t, IsTokenRejected(err), "tokens of retired keys are rejected, not replaced")
+
+	// Токен неизвестного ключа можно заменить новой личностью
+	_, err = NewUserService("other-secret").Authenticate(oldToken)
+	assert.False(t, IsTokenRejected(err))
+
+	// Символ "@" допустим в секрете: разделителем служит последний
+	key, err := ParsePreviousKey("p@ss@2025-01-31T12:00:00Z")
+	require.NoError(t, err)
+	assert.Equal(t, NewHMACKey([]byte("p@ss")).ID(), key.Key.ID())
+	assert.Equal(t, time.Date(2025, 1, 31, 12, 0, 0, 0, time.UTC), key.RetiredAt)
+
+	// Без момента вывода ключ не принимается, а секрет не попадает в ошибку
+	for _, entry := range []string{"old-secret", "old-secret@yesterday"} {
+		_, err = LoadKeyRing("new-secret", "", []string{entry}, time.Hour)
+		assert.ErrorIs(t, err, ErrNoRetirementTime)
+		assert.ErrorContains(t, err, "previous key #1")
+		assert.NotContains(t, err.Error(), "old-secret")
+	}
+}
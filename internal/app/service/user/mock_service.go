@@ -1,7 +1,5 @@
 package user
 
-import "net/http"
-
 // MockUserService - это структура, которая реализует интерфейс UserService
 type MockUserService struct {
 	userID string
@@ -12,17 +10,17 @@ func NewMockUserService(userID string) *MockUserService {
 	return &MockUserService{userID: userID}
 }
 
-// GetUserIDFromCookie - это метод, который возвращает userID из cookie.
-func (m *MockUserService) GetUserIDFromCookie(r *http.Request) string {
-	return m.userID
+// NewAnonymousUser - это метод, который создаёт анонимного пользователя с заданным userID.
+func (m *MockUserService) NewAnonymousUser() (Identity, string, error) {
+	return Identity{UserID: m.userID, Anonymous: true}, "mock-token", nil
 }
 
-// GenerateUserToken - это метод, который генерирует токен для пользователя.
-func (m *MockUserService) GenerateUserToken() (string, error) {
+// GenerateTokenForUser - это метод, который генерирует токен для заданного пользователя.
+func (m *MockUserService) GenerateTokenForUser(userID string) (string, error) {
 	return "mock-token", nil
 }
 
-// VerifyUserToken - это метод, который проверяет токен пользователя и возвращает userID.
-func (m *MockUserService) VerifyUserToken(token string) (string, error) {
-	return m.userID, nil
+// Authenticate - это метод, который проверяет токен и возвращает пользователя с заданным userID.
+func (m *MockUserService) Authenticate(token string) (Identity, error) {
+	return Identity{UserID: m.userID}, nil
 }
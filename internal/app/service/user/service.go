@@ -3,28 +3,38 @@ package user
 
 import (
 	"errors"
-	"net/http"
 	"time"
 
 	"github.com/golang-jwt/jwt/v5"
 	"github.com/google/uuid"
 )
 
-// UserService интерфейс для работы с пользователями
+// UserService интерфейс для работы с токенами пользователей.
+// Различает анонимных пользователей, которым ID выдаётся автоматически,
+// и зарегистрированных, вошедших в аккаунт.
 type UserService interface {
-	GetUserIDFromCookie(r *http.Request) string
-	GenerateUserToken() (string, error)
-	VerifyUserToken(token string) (string, error)
+	// NewAnonymousUser создаёт анонимного пользователя и возвращает его личность и токен
+	NewAnonymousUser() (Identity, string, error)
+	// GenerateTokenForUser создаёт токен зарегистрированного пользователя
+	GenerateTokenForUser(userID string) (string, error)
+	// Authenticate проверяет токен и возвращает личность пользователя
+	Authenticate(token string) (Identity, error)
 }
 
 // userService реализация UserService
 type userService struct {
-	secretKey []byte
+	keys *KeyRing
 }
 
-// NewUserService конструктор UserService
+// NewUserService конструктор UserService, подписывающий токены секретом secretKey (HS256)
 func NewUserService(secretKey string) UserService {
-	return &userService{secretKey: []byte(secretKey)}
+	keys, _ := NewKeyRing(NewHMACKey([]byte(secretKey)), nil, 0)
+	return NewUserServiceWithKeys(keys)
+}
+
+// NewUserServiceWithKeys конструктор UserService с набором ключей подписи keys
+func NewUserServiceWithKeys(keys *KeyRing) UserService {
+	return &userService{keys: keys}
 }
 
 // GenerateUserID генерирует случайный ID пользователя
@@ -33,46 +43,55 @@ func GenerateUserID() string {
 	return id.String()
 }
 
-// GenerateUserToken создает JWT-токен для пользователя
-func (u *userService) GenerateUserToken() (string, error) {
-	token := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.MapClaims{
-		"user_id": GenerateUserID(),
-		"exp":     time.Now().Add(24 * time.Hour).Unix(),
-	})
-
-	return token.SignedString(u.secretKey)
+// NewAnonymousUser создаёт анонимного пользователя со случайным ID и токен для него
+func (u *userService) NewAnonymousUser() (Identity, string, error) {
+	identity := Identity{UserID: GenerateUserID(), Anonymous: true}
+	token, err := u.sign(identity)
+	if err != nil {
+		return Identity{}, "", err
+	}
+	return identity, token, nil
 }
 
-// VerifyUserToken проверяет и извлекает user_id из токена
-func (u *userService) VerifyUserToken(tokenString string) (string, error) {
-	token, err := jwt.Parse(tokenString, func(token *jwt.Token) (interface{}, error) {
-		if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
-			return nil, errors.New("unexpected signing method")
-		}
-		return u.secretKey, nil
-	})
+// GenerateTokenForUser создает JWT-токен для зарегистрированного пользователя userID
+func (u *userService) GenerateTokenForUser(userID string) (string, error) {
+	return u.sign(Identity{UserID: userID})
+}
 
-	if err != nil {
-		return "", err
+// sign подписывает токен пользователя. Токены зарегистрированных пользователей
+// помечаются claim registered; токены без него считаются анонимными.
+func (u *userService) sign(identity Identity) (string, error) {
+	claims := jwt.MapClaims{
+		"user_id": identity.UserID,
+		"exp":     time.Now().Add(24 * time.Hour).Unix(),
 	}
-
-	if claims, ok := token.Claims.(jwt.MapClaims); ok && token.Valid {
-		if userID, ok := claims["user_id"].(string); ok {
-			return userID, nil
-		}
+	if !identity.Anonymous {
+		claims["registered"] = true
 	}
-	return "", errors.New("invalid token claims")
+	return u.keys.Sign(claims)
+}
+
+// IsTokenRejected сообщает, что ошибка Authenticate относится к токену, подписанному
+// известным ключом, но просроченному или подписанному отозванным ключом. Остальные
+// ошибки означают, что токен не выпускался этим сервисом (или подписан случайным
+// секретом до перезапуска), и вместо него можно выдать новую анонимную личность.
+func IsTokenRejected(err error) bool {
+	return errors.Is(err, jwt.ErrTokenExpired) || errors.Is(err, ErrKeyRetired)
 }
 
-// GetUserIDFromCookie получает user_id из куки
-func (u *userService) GetUserIDFromCookie(r *http.Request) string {
-	cookie, err := r.Cookie("auth_token")
+// Authenticate проверяет токен и возвращает личность пользователя.
+// Способ аутентификации устанавливает вызывающий.
+func (u *userService) Authenticate(tokenString string) (Identity, error) {
+	token, err := jwt.Parse(tokenString, u.keys.Keyfunc())
 	if err != nil {
-		return ""
+		return Identity{}, err
 	}
-	userID, err := u.VerifyUserToken(cookie.Value)
-	if err != nil {
-		return ""
+
+	if claims, ok := token.Claims.(jwt.MapClaims); ok && token.Valid {
+		if userID, ok := claims["user_id"].(string); ok && userID != "" {
+			registered, _ := claims["registered"].(bool)
+			return Identity{UserID: userID, Anonymous: !registered}, nil
+		}
 	}
-	return userID
+	return Identity{}, errors.New("invalid token claims")
 }
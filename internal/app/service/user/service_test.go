@@ -1,7 +1,7 @@
 package user
 
 import (
-	"net/http"
+	"context"
 	"testing"
 	"time"
 
@@ -23,10 +23,13 @@ func TestUserService(t *testing.T) {
 		assert.NotEqual(t, id1, id2)
 	})
 
-	t.Run("GenerateUserToken", func(t *testing.T) {
-		token, err := service.GenerateUserToken()
+	t.Run("NewAnonymousUser", func(t *testing.T) {
+		identity, token, err := service.NewAnonymousUser()
 		require.NoError(t, err)
 		assert.NotEmpty(t, token)
+		assert.NotEmpty(t, identity.UserID)
+		assert.True(t, identity.Anonymous)
+		assert.False(t, identity.Authenticated())
 
 		// Verify token structure
 		parsedToken, err := jwt.Parse(token, func(token *jwt.Token) (interface{}, error) {
@@ -37,11 +40,27 @@ func TestUserService(t *testing.T) {
 
 		claims, ok := parsedToken.Claims.(jwt.MapClaims)
 		require.True(t, ok)
-		assert.NotEmpty(t, claims["user_id"])
+		assert.Equal(t, identity.UserID, claims["user_id"])
 		assert.NotEmpty(t, claims["exp"])
+		assert.NotContains(t, claims, "registered")
+
+		authenticated, err := service.Authenticate(token)
+		require.NoError(t, err)
+		assert.Equal(t, identity, authenticated)
+	})
+
+	t.Run("GenerateTokenForUser", func(t *testing.T) {
+		token, err := service.GenerateTokenForUser("registered-user")
+		require.NoError(t, err)
+
+		identity, err := service.Authenticate(token)
+		require.NoError(t, err)
+		assert.Equal(t, "registered-user", identity.UserID)
+		assert.False(t, identity.Anonymous)
+		assert.True(t, identity.Authenticated())
 	})
 
-	t.Run("VerifyUserToken", func(t *testing.T) {
+	t.Run("Authenticate", func(t *testing.T) {
 		tests := []struct {
 			name      string
 			token     string
@@ -71,69 +90,42 @@ func TestUserService(t *testing.T) {
 
 		for _, tt := range tests {
 			t.Run(tt.name, func(t *testing.T) {
-				userID, err := service.VerifyUserToken(tt.token)
+				identity, err := service.Authenticate(tt.token)
 				if tt.wantError {
 					assert.Error(t, err)
-					assert.Empty(t, userID)
+					assert.Empty(t, identity.UserID)
 				} else {
 					assert.NoError(t, err)
-					assert.NotEmpty(t, userID)
+					assert.NotEmpty(t, identity.UserID)
+					// Токены без claim registered принадлежат анонимным пользователям
+					assert.True(t, identity.Anonymous)
 				}
 			})
 		}
 	})
+}
 
-	t.Run("GetUserIDFromCookie", func(t *testing.T) {
-		tests := []struct {
-			name        string
-			setupCookie func() *http.Request
-			wantUserID  bool
-		}{
-			{
-				name: "Valid cookie",
-				setupCookie: func() *http.Request {
-					req := &http.Request{Header: make(http.Header)}
-					token := createTestToken(t, secretKey, "test-user", time.Hour)
-					req.AddCookie(&http.Cookie{
-						Name:  "auth_token",
-						Value: token,
-					})
-					return req
-				},
-				wantUserID: true,
-			},
-			{
-				name: "No cookie",
-				setupCookie: func() *http.Request {
-					return &http.Request{Header: make(http.Header)}
-				},
-				wantUserID: false,
-			},
-			{
-				name: "Invalid cookie value",
-				setupCookie: func() *http.Request {
-					req := &http.Request{Header: make(http.Header)}
-					req.AddCookie(&http.Cookie{
-						Name:  "auth_token",
-						Value: "invalid-token",
-					})
-					return req
-				},
-				wantUserID: false,
-			},
-		}
+func TestIdentity(t *testing.T) {
+	t.Run("Context", func(t *testing.T) {
+		_, ok := IdentityFromContext(context.Background())
+		assert.False(t, ok)
 
-		for _, tt := range tests {
-			t.Run(tt.name, func(t *testing.T) {
-				req := tt.setupCookie()
-				userID := service.GetUserIDFromCookie(req)
-				if tt.wantUserID {
-					assert.NotEmpty(t, userID)
-				} else {
-					assert.Empty(t, userID)
-				}
-			})
-		}
+		_, ok = IdentityFromContext(ContextWithIdentity(context.Background(), Identity{}))
+		assert.False(t, ok, "identity without user ID")
+
+		identity := Identity{UserID: "user", Method: AuthMethodCookie, Anonymous: true}
+		got, ok := IdentityFromContext(ContextWithIdentity(context.Background(), identity))
+		assert.True(t, ok)
+		assert.Equal(t, identity, got)
+	})
+
+	t.Run("HasScope", func(t *testing.T) {
+		apiKey := Identity{UserID: "user", Method: AuthMethodAPIKey, Scopes: []string{"urls:read"}}
+		assert.True(t, apiKey.HasScope("urls:read"))
+		assert.False(t, apiKey.HasScope("urls:write"))
+
+		bearer := Identity{UserID: "user", Method: AuthMethodBearer}
+		assert.True(t, bearer.HasScope("urls:write"))
 	})
 }
 
@@ -0,0 +1,14 @@
+package storage
+
+import (
+	"sort"
+
+	"github.com/alexuryumtsev/go-shortener/internal/app/models"
+)
+
+// SortAPIKeys упорядочивает ключи по времени создания.
+func SortAPIKeys(keys []models.APIKeyModel) {
+	sort.SliceStable(keys, func(i, j int) bool {
+		return keys[i].CreatedAt.Before(keys[j].CreatedAt)
+	})
+}
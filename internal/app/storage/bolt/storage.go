@@ -0,0 +1,842 @@
+// Package bolt предоставляет реализацию хранилища во встраиваемой базе bbolt.
+//
+// Данные хранятся в B+-деревьях одного файла, поэтому хранилище не требует
+// отдельного сервера, как PostgreSQL, и не держит все данные в памяти, как файловое.
+// Каждая операция записи выполняется в отдельной транзакции, которая сбрасывается
+// на диск при фиксации.
+//
+// Бакеты:
+//   - urls: короткий ID → запись URL;
+//   - user_urls: ID пользователя, 0x00, порядковый номер → короткий ID
+//     (URL пользователя в порядке создания);
+//   - original_urls: оригинальный URL, 0x00, короткий ID → пусто
+//     (поиск сокращённых ссылок по оригинальному URL);
+//   - expiry: момент истечения, короткий ID → пусто (поиск истёкших ссылок);
+//   - archive: короткий ID → запись URL с истёкшим сроком;
+//   - clicks: короткий ID → вложенный бакет событий переходов;
+//   - api_keys, api_key_hashes: API-ключи и индекс по хешу;
+//   - users, user_ids: пользователи по логину и индекс по ID.
+package bolt
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"path/filepath"
+	"time"
+
+	"github.com/alexuryumtsev/go-shortener/internal/app/fileutils"
+	"github.com/alexuryumtsev/go-shortener/internal/app/models"
+	"github.com/alexuryumtsev/go-shortener/internal/app/storage"
+	bbolt "go.etcd.io/bbolt"
+)
+
+// Имена бакетов.
+var (
+	bucketURLs         = []byte("urls")
+	bucketUserURLs     = []byte("user_urls")
+	bucketOriginalURLs = []byte("original_urls")
+	bucketExpiry       = []byte("expiry")
+	bucketArchive      = []byte("archive")
+	bucketClicks       = []byte("clicks")
+	bucketAPIKeys      = []byte("api_keys")
+	bucketAPIKeyHashes = []byte("api_key_hashes")
+	bucketUsers        = []byte("users")
+	bucketUserIDs      = []byte("user_ids")
+
+	allBuckets = [][]byte{
+		bucketURLs, bucketUserURLs, bucketOriginalURLs, bucketExpiry, bucketArchive,
+		bucketClicks, bucketAPIKeys, bucketAPIKeyHashes, bucketUsers, bucketUserIDs,
+	}
+)
+
+// openTimeout — время ожидания блокировки файла базы: bbolt не позволяет
+// открыть один файл из двух процессов, и без таймаута второй процесс зависнет.
+const openTimeout = time.Second
+
+// keySeparator разделяет части составных ключей индексов.
+const keySeparator = 0x00
+
+// pageSize — число URL, читаемых ForEachURL за одну транзакцию.
+const pageSize = 1000
+
+// BoltStorage управляет сохранением и получением данных во встраиваемой базе bbolt.
+type BoltStorage struct {
+	db *bbolt.DB
+}
+
+// urlRecord — запись URL в бакете urls.
+type urlRecord struct {
+	UserID      string     `json:"uuid"`
+	ShortURL    string     `json:"short_url"`
+	OriginalURL string     `json:"original_url"`
+	Deleted     bool       `json:"is_deleted"`
+	ExpiresAt   *time.Time `json:"expires_at,omitempty"`
+	// Seq — порядковый номер записи в индексе user_urls
+	Seq uint64 `json:"seq"`
+}
+
+// apiKeyRecord — запись API-ключа в бакете api_keys.
+type apiKeyRecord struct {
+	ID         string     `json:"id"`
+	UserID     string     `json:"user_id"`
+	Name       string     `json:"name"`
+	KeyHash    string     `json:"key_hash"`
+	Scopes     []string   `json:"scopes"`
+	CreatedAt  time.Time  `json:"created_at"`
+	LastUsedAt *time.Time `json:"last_used_at,omitempty"`
+}
+
+// userRecord — запись пользователя в бакете users.
+type userRecord struct {
+	ID           string    `json:"user_id"`
+	Login        string    `json:"login"`
+	PasswordHash string    `json:"password_hash"`
+	CreatedAt    time.Time `json:"created_at"`
+}
+
+// NewBoltStorage открывает базу path, создавая файл и бакеты при необходимости.
+func NewBoltStorage(path string) (*BoltStorage, error) {
+	if err := fileutils.EnsureDirExists(path); err != nil {
+		return nil, err
+	}
+
+	db, err := bbolt.Open(path, 0600, &bbolt.Options{Timeout: openTimeout})
+	if err != nil {
+		return nil, fmt.Errorf("failed to open %s: %w", path, err)
+	}
+
+	err = db.Update(func(tx *bbolt.Tx) error {
+		for _, name := range allBuckets {
+			if _, err := tx.CreateBucketIfNotExists(name); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		db.Close()
+		return nil, fmt.Errorf("failed to create buckets: %w", err)
+	}
+
+	return &BoltStorage{db: db}, nil
+}
+
+// Save сохраняет URL.
+// Если короткий ID уже занят, возвращает storage.ErrConflict.
+func (s *BoltStorage) Save(ctx context.Context, urlModel models.URLModel) error {
+	err := s.db.Update(func(tx *bbolt.Tx) error {
+		if tx.Bucket(bucketURLs).Get([]byte(urlModel.ID)) != nil {
+			return fmt.Errorf("%w: short URL %q already exists", storage.ErrConflict, urlModel.ID)
+		}
+		return putURL(tx, urlModel)
+	})
+	if err != nil && !errors.Is(err, storage.ErrConflict) {
+		return fmt.Errorf("failed to save URL: %w", err)
+	}
+	return err
+}
+
+// SaveBatch сохраняет множество URL в одной транзакции.
+// URL с уже занятыми короткими ID пропускаются, их ID возвращаются в skipped.
+func (s *BoltStorage) SaveBatch(ctx context.Context, urlModels []models.URLModel) (skipped []string, err error) {
+	err = s.db.Update(func(tx *bbolt.Tx) error {
+		skipped = skipped[:0]
+		urls := tx.Bucket(bucketURLs)
+		for _, urlModel := range urlModels {
+			if urls.Get([]byte(urlModel.ID)) != nil {
+				skipped = append(skipped, urlModel.ID)
+				continue
+			}
+			if err := putURL(tx, urlModel); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to save URLs: %w", err)
+	}
+	return skipped, nil
+}
+
+// Get возвращает оригинальный URL по идентификатору.
+func (s *BoltStorage) Get(ctx context.Context, id string) (models.URLModel, bool) {
+	var (
+		urlModel models.URLModel
+		exists   bool
+	)
+	err := s.db.View(func(tx *bbolt.Tx) error {
+		rec, err := getURL(tx, id)
+		if err != nil || rec == nil {
+			return err
+		}
+		urlModel, exists = rec.model(), true
+		return nil
+	})
+	if err != nil {
+		return models.URLModel{}, false
+	}
+	return urlModel, exists
+}
+
+// GetByOriginalURL возвращает сокращённый URL по оригинальному URL.
+// Если у URL несколько коротких ссылок, бессрочные предпочтительнее,
+// затем — с самым поздним сроком действия.
+func (s *BoltStorage) GetByOriginalURL(ctx context.Context, originalURL string) (models.URLModel, bool) {
+	var (
+		best   models.URLModel
+		exists bool
+	)
+	err := s.db.View(func(tx *bbolt.Tx) error {
+		prefix := indexPrefix(originalURL)
+		c := tx.Bucket(bucketOriginalURLs).Cursor()
+		for k, _ := c.Seek(prefix); k != nil && bytes.HasPrefix(k, prefix); k, _ = c.Next() {
+			rec, err := getURL(tx, string(k[len(prefix):]))
+			if err != nil {
+				return err
+			}
+			if rec == nil {
+				continue
+			}
+			if urlModel := rec.model(); !exists || storage.PreferURL(urlModel, best) {
+				best, exists = urlModel, true
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return models.URLModel{}, false
+	}
+	return best, exists
+}
+
+// GetUserURLs возвращает все URL, сокращённые пользователем, в порядке создания.
+func (s *BoltStorage) GetUserURLs(ctx context.Context, userID string) ([]models.URLModel, error) {
+	var urls []models.URLModel
+	err := s.db.View(func(tx *bbolt.Tx) error {
+		prefix := indexPrefix(userID)
+		c := tx.Bucket(bucketUserURLs).Cursor()
+		for k, id := c.Seek(prefix); k != nil && bytes.HasPrefix(k, prefix); k, id = c.Next() {
+			rec, err := getURL(tx, string(id))
+			if err != nil {
+				return err
+			}
+			if rec != nil {
+				urls = append(urls, rec.model())
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to get user URLs: %w", err)
+	}
+	return urls, nil
+}
+
+// GetStats возвращает сводную статистику по записям хранилища.
+func (s *BoltStorage) GetStats(ctx context.Context) (models.StatsModel, error) {
+	var urls []models.URLModel
+	err := s.db.View(func(tx *bbolt.Tx) error {
+		return tx.Bucket(bucketURLs).ForEach(func(_, v []byte) error {
+			var rec urlRecord
+			if err := json.Unmarshal(v, &rec); err != nil {
+				return err
+			}
+			urls = append(urls, rec.model())
+			return nil
+		})
+	})
+	if err != nil {
+		return models.StatsModel{}, fmt.Errorf("failed to get stats: %w", err)
+	}
+	return storage.CountStats(urls, time.Now()), nil
+}
+
+// ForEachURL вызывает fn для URL с коротким ID больше afterID в порядке возрастания ID.
+// URL читаются страницами в отдельных транзакциях, чтобы fn мог писать в базу:
+// запись внутри читающей транзакции той же горутины может привести к взаимоблокировке.
+func (s *BoltStorage) ForEachURL(ctx context.Context, afterID string, fn func(models.URLModel) error) error {
+	for {
+		page := make([]models.URLModel, 0, pageSize)
+		err := s.db.View(func(tx *bbolt.Tx) error {
+			c := tx.Bucket(bucketURLs).Cursor()
+			k, v := c.Seek([]byte(afterID))
+			if k != nil && string(k) == afterID {
+				k, v = c.Next()
+			}
+			for ; k != nil && len(page) < pageSize; k, v = c.Next() {
+				var rec urlRecord
+				if err := json.Unmarshal(v, &rec); err != nil {
+					return fmt.Errorf("failed to decode record %q: %w", k, err)
+				}
+				page = append(page, rec.model())
+			}
+			return nil
+		})
+		if err != nil {
+			return fmt.Errorf("failed to read URLs: %w", err)
+		}
+
+		for _, urlModel := range page {
+			if err := ctx.Err(); err != nil {
+				return err
+			}
+			if err := fn(urlModel); err != nil {
+				return err
+			}
+		}
+		if len(page) < pageSize {
+			return nil
+		}
+		afterID = page[len(page)-1].ID
+	}
+}
+
+// LoadFromFile ничего не делает: данные читаются из базы по запросу.
+func (s *BoltStorage) LoadFromFile() error {
+	return nil
+}
+
+// Ping проверяет, что база открыта.
+func (s *BoltStorage) Ping(ctx context.Context) error {
+	return s.db.View(func(tx *bbolt.Tx) error { return nil })
+}
+
+// CheckHealth проверяет, что база открыта, а в её каталог можно записать файл
+// и на диске есть свободное место.
+func (s *BoltStorage) CheckHealth(ctx context.Context) map[string]storage.ComponentHealth {
+	path := s.db.Path()
+	details := map[string]any{"path": path}
+	db := storage.ComponentHealth{Status: storage.HealthUp, Details: details}
+	err := s.db.View(func(tx *bbolt.Tx) error {
+		details["size_bytes"] = tx.Size()
+		return nil
+	})
+	if err != nil {
+		db = storage.ComponentHealth{Status: storage.HealthDown, Error: err.Error(), Details: details}
+	}
+
+	return map[string]storage.ComponentHealth{
+		"bolt": db,
+		"disk": storage.CheckDirectory(filepath.Dir(path)),
+	}
+}
+
+// Close закрывает базу, дожидаясь завершения текущих транзакций.
+func (s *BoltStorage) Close() error {
+	return s.db.Close()
+}
+
+// DeleteUserURLs помечает URL пользователя как удалённые.
+// Чужие и несуществующие URL пропускаются.
+func (s *BoltStorage) DeleteUserURLs(ctx context.Context, userID string, shortURLs []string) error {
+	err := s.db.Update(func(tx *bbolt.Tx) error {
+		for _, id := range shortURLs {
+			rec, err := getURL(tx, id)
+			if err != nil {
+				return err
+			}
+			if rec == nil || rec.UserID != userID || rec.Deleted {
+				continue
+			}
+			rec.Deleted = true
+			if err := putRecord(tx.Bucket(bucketURLs), id, rec); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("failed to delete URLs: %w", err)
+	}
+	return nil
+}
+
+// DeleteExpired удаляет URL с истёкшим сроком действия, находя их по индексу expiry.
+// При archive = true удалённые записи переносятся в бакет archive.
+// Переходы по удалённым ссылкам удаляются в той же транзакции.
+func (s *BoltStorage) DeleteExpired(ctx context.Context, now time.Time, archive bool) (int, error) {
+	removed := 0
+	err := s.db.Update(func(tx *bbolt.Tx) error {
+		var expired []string
+		bound := expiryKey(now, "")
+		c := tx.Bucket(bucketExpiry).Cursor()
+		for k, _ := c.First(); k != nil && bytes.Compare(k[:8], bound[:8]) <= 0; k, _ = c.Next() {
+			expired = append(expired, string(k[8:]))
+		}
+
+		for _, id := range expired {
+			rec, err := getURL(tx, id)
+			if err != nil {
+				return err
+			}
+			if rec == nil {
+				continue
+			}
+			if err := deleteURL(tx, id, rec); err != nil {
+				return err
+			}
+			if err := deleteClicks(tx, id); err != nil {
+				return err
+			}
+			if archive {
+				if err := putRecord(tx.Bucket(bucketArchive), id, rec); err != nil {
+					return err
+				}
+			}
+			removed++
+		}
+		return nil
+	})
+	if err != nil {
+		return 0, fmt.Errorf("failed to delete expired URLs: %w", err)
+	}
+	return removed, nil
+}
+
+// SaveClicks сохраняет события переходов во вложенные бакеты ссылок.
+func (s *BoltStorage) SaveClicks(ctx context.Context, events []models.ClickEvent) error {
+	err := s.db.Update(func(tx *bbolt.Tx) error {
+		clicks := tx.Bucket(bucketClicks)
+		for _, event := range events {
+			bucket, err := clicks.CreateBucketIfNotExists([]byte(event.ShortID))
+			if err != nil {
+				return err
+			}
+			seq, err := bucket.NextSequence()
+			if err != nil {
+				return err
+			}
+			data, err := json.Marshal(event)
+			if err != nil {
+				return err
+			}
+			if err := bucket.Put(sequenceKey(seq), data); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("failed to save clicks: %w", err)
+	}
+	return nil
+}
+
+// GetClickStats возвращает статистику переходов по короткой ссылке.
+func (s *BoltStorage) GetClickStats(ctx context.Context, shortID string) (models.LinkStatsModel, error) {
+	var events []models.ClickEvent
+	err := s.db.View(func(tx *bbolt.Tx) error {
+		bucket := tx.Bucket(bucketClicks).Bucket([]byte(shortID))
+		if bucket == nil {
+			return nil
+		}
+		return bucket.ForEach(func(_, v []byte) error {
+			var event models.ClickEvent
+			if err := json.Unmarshal(v, &event); err != nil {
+				return err
+			}
+			events = append(events, event)
+			return nil
+		})
+	})
+	if err != nil {
+		return models.LinkStatsModel{}, fmt.Errorf("failed to get clicks: %w", err)
+	}
+	return storage.AggregateClicks(events), nil
+}
+
+// SaveAPIKey сохраняет API-ключ и индекс по его хешу.
+func (s *BoltStorage) SaveAPIKey(ctx context.Context, key models.APIKeyModel) error {
+	err := s.db.Update(func(tx *bbolt.Tx) error {
+		if err := putRecord(tx.Bucket(bucketAPIKeys), key.ID, newAPIKeyRecord(key)); err != nil {
+			return err
+		}
+		return tx.Bucket(bucketAPIKeyHashes).Put([]byte(key.KeyHash), []byte(key.ID))
+	})
+	if err != nil {
+		return fmt.Errorf("failed to save API key: %w", err)
+	}
+	return nil
+}
+
+// GetAPIKeyByHash возвращает API-ключ по хешу.
+func (s *BoltStorage) GetAPIKeyByHash(ctx context.Context, keyHash string) (models.APIKeyModel, bool) {
+	var (
+		key    models.APIKeyModel
+		exists bool
+	)
+	err := s.db.View(func(tx *bbolt.Tx) error {
+		id := tx.Bucket(bucketAPIKeyHashes).Get([]byte(keyHash))
+		if id == nil {
+			return nil
+		}
+		var rec apiKeyRecord
+		found, err := getRecord(tx.Bucket(bucketAPIKeys), string(id), &rec)
+		if err != nil || !found {
+			return err
+		}
+		key, exists = rec.model(), true
+		return nil
+	})
+	if err != nil {
+		return models.APIKeyModel{}, false
+	}
+	return key, exists
+}
+
+// GetUserAPIKeys возвращает API-ключи пользователя в порядке создания.
+func (s *BoltStorage) GetUserAPIKeys(ctx context.Context, userID string) ([]models.APIKeyModel, error) {
+	var keys []models.APIKeyModel
+	err := s.db.View(func(tx *bbolt.Tx) error {
+		return tx.Bucket(bucketAPIKeys).ForEach(func(_, v []byte) error {
+			var rec apiKeyRecord
+			if err := json.Unmarshal(v, &rec); err != nil {
+				return err
+			}
+			if rec.UserID == userID {
+				keys = append(keys, rec.model())
+			}
+			return nil
+		})
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to get API keys: %w", err)
+	}
+	storage.SortAPIKeys(keys)
+	return keys, nil
+}
+
+// RevokeAPIKey удаляет API-ключ пользователя.
+func (s *BoltStorage) RevokeAPIKey(ctx context.Context, userID, id string) (bool, error) {
+	revoked := false
+	err := s.db.Update(func(tx *bbolt.Tx) error {
+		keys := tx.Bucket(bucketAPIKeys)
+		var rec apiKeyRecord
+		found, err := getRecord(keys, id, &rec)
+		if err != nil || !found || rec.UserID != userID {
+			return err
+		}
+		if err := keys.Delete([]byte(id)); err != nil {
+			return err
+		}
+		revoked = true
+		return tx.Bucket(bucketAPIKeyHashes).Delete([]byte(rec.KeyHash))
+	})
+	if err != nil {
+		return false, fmt.Errorf("failed to revoke API key: %w", err)
+	}
+	return revoked, nil
+}
+
+// TouchAPIKey обновляет время последнего использования API-ключа.
+func (s *BoltStorage) TouchAPIKey(ctx context.Context, id string, usedAt time.Time) error {
+	err := s.db.Update(func(tx *bbolt.Tx) error {
+		keys := tx.Bucket(bucketAPIKeys)
+		var rec apiKeyRecord
+		found, err := getRecord(keys, id, &rec)
+		if err != nil || !found {
+			return err
+		}
+		rec.LastUsedAt = &usedAt
+		return putRecord(keys, id, rec)
+	})
+	if err != nil {
+		return fmt.Errorf("failed to update API key: %w", err)
+	}
+	return nil
+}
+
+// CreateUser сохраняет пользователя.
+// Если логин занят, возвращает storage.ErrConflict.
+func (s *BoltStorage) CreateUser(ctx context.Context, user models.UserModel) error {
+	return s.db.Update(func(tx *bbolt.Tx) error {
+		users := tx.Bucket(bucketUsers)
+		if users.Get([]byte(user.Login)) != nil {
+			return storage.ErrConflict
+		}
+		rec := userRecord{ID: user.ID, Login: user.Login, PasswordHash: user.PasswordHash, CreatedAt: user.CreatedAt}
+		if err := putRecord(users, user.Login, rec); err != nil {
+			return fmt.Errorf("failed to create user: %w", err)
+		}
+		return tx.Bucket(bucketUserIDs).Put([]byte(user.ID), []byte(user.Login))
+	})
+}
+
+// GetUserByLogin возвращает пользователя по логину.
+func (s *BoltStorage) GetUserByLogin(ctx context.Context, login string) (models.UserModel, bool) {
+	var (
+		user   models.UserModel
+		exists bool
+	)
+	err := s.db.View(func(tx *bbolt.Tx) error {
+		var err error
+		user, exists, err = getUser(tx, login)
+		return err
+	})
+	if err != nil {
+		return models.UserModel{}, false
+	}
+	return user, exists
+}
+
+// GetUserByID возвращает пользователя по идентификатору.
+func (s *BoltStorage) GetUserByID(ctx context.Context, id string) (models.UserModel, bool) {
+	var (
+		user   models.UserModel
+		exists bool
+	)
+	err := s.db.View(func(tx *bbolt.Tx) error {
+		login := tx.Bucket(bucketUserIDs).Get([]byte(id))
+		if login == nil {
+			return nil
+		}
+		var err error
+		user, exists, err = getUser(tx, string(login))
+		return err
+	})
+	if err != nil {
+		return models.UserModel{}, false
+	}
+	return user, exists
+}
+
+// ReassignUserURLs передаёт URL и API-ключи пользователя fromUserID пользователю toUserID.
+// URL добавляются в конец списка toUserID в прежнем порядке.
+func (s *BoltStorage) ReassignUserURLs(ctx context.Context, fromUserID, toUserID string) (int, error) {
+	moved := 0
+	err := s.db.Update(func(tx *bbolt.Tx) error {
+		var ids []string
+		prefix := indexPrefix(fromUserID)
+		c := tx.Bucket(bucketUserURLs).Cursor()
+		for k, id := c.Seek(prefix); k != nil && bytes.HasPrefix(k, prefix); k, id = c.Next() {
+			ids = append(ids, string(id))
+		}
+
+		for _, id := range ids {
+			rec, err := getURL(tx, id)
+			if err != nil {
+				return err
+			}
+			if rec == nil {
+				continue
+			}
+			if err := deleteURL(tx, id, rec); err != nil {
+				return err
+			}
+			urlModel := rec.model()
+			urlModel.UserID = toUserID
+			if err := putURL(tx, urlModel); err != nil {
+				return err
+			}
+			moved++
+		}
+		return reassignAPIKeys(tx, fromUserID, toUserID)
+	})
+	if err != nil {
+		return 0, fmt.Errorf("failed to reassign URLs: %w", err)
+	}
+	return moved, nil
+}
+
+// reassignAPIKeys передаёт API-ключи пользователя fromUserID пользователю toUserID.
+func reassignAPIKeys(tx *bbolt.Tx, fromUserID, toUserID string) error {
+	keys := tx.Bucket(bucketAPIKeys)
+	var moved []apiKeyRecord
+	err := keys.ForEach(func(_, v []byte) error {
+		var rec apiKeyRecord
+		if err := json.Unmarshal(v, &rec); err != nil {
+			return err
+		}
+		if rec.UserID == fromUserID {
+			moved = append(moved, rec)
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	// Изменять бакет во время ForEach нельзя, поэтому ключи обновляются после обхода
+	for _, rec := range moved {
+		rec.UserID = toUserID
+		if err := putRecord(keys, rec.ID, rec); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// putURL сохраняет новую запись URL и добавляет её во все индексы.
+func putURL(tx *bbolt.Tx, urlModel models.URLModel) error {
+	urls := tx.Bucket(bucketURLs)
+	seq, err := urls.NextSequence()
+	if err != nil {
+		return err
+	}
+
+	rec := newURLRecord(urlModel, seq)
+	if err := putRecord(urls, urlModel.ID, rec); err != nil {
+		return err
+	}
+	if err := tx.Bucket(bucketUserURLs).Put(userURLKey(rec.UserID, seq), []byte(urlModel.ID)); err != nil {
+		return err
+	}
+	if err := tx.Bucket(bucketOriginalURLs).Put(indexKey(rec.OriginalURL, urlModel.ID), nil); err != nil {
+		return err
+	}
+	if rec.ExpiresAt != nil {
+		return tx.Bucket(bucketExpiry).Put(expiryKey(*rec.ExpiresAt, urlModel.ID), nil)
+	}
+	return nil
+}
+
+// deleteURL удаляет запись URL id и её ключи во всех индексах.
+func deleteURL(tx *bbolt.Tx, id string, rec *urlRecord) error {
+	if err := tx.Bucket(bucketURLs).Delete([]byte(id)); err != nil {
+		return err
+	}
+	if err := tx.Bucket(bucketUserURLs).Delete(userURLKey(rec.UserID, rec.Seq)); err != nil {
+		return err
+	}
+	if err := tx.Bucket(bucketOriginalURLs).Delete(indexKey(rec.OriginalURL, id)); err != nil {
+		return err
+	}
+	if rec.ExpiresAt != nil {
+		return tx.Bucket(bucketExpiry).Delete(expiryKey(*rec.ExpiresAt, id))
+	}
+	return nil
+}
+
+// deleteClicks удаляет вложенный бакет переходов по ссылке id, если он есть.
+func deleteClicks(tx *bbolt.Tx, id string) error {
+	clicks := tx.Bucket(bucketClicks)
+	if clicks.Bucket([]byte(id)) == nil {
+		return nil
+	}
+	return clicks.DeleteBucket([]byte(id))
+}
+
+// getURL возвращает запись URL id или nil, если её нет.
+func getURL(tx *bbolt.Tx, id string) (*urlRecord, error) {
+	var rec urlRecord
+	found, err := getRecord(tx.Bucket(bucketURLs), id, &rec)
+	if err != nil || !found {
+		return nil, err
+	}
+	return &rec, nil
+}
+
+// getUser возвращает пользователя по логину.
+func getUser(tx *bbolt.Tx, login string) (models.UserModel, bool, error) {
+	var rec userRecord
+	found, err := getRecord(tx.Bucket(bucketUsers), login, &rec)
+	if err != nil || !found {
+		return models.UserModel{}, false, err
+	}
+	return models.UserModel{ID: rec.ID, Login: rec.Login, PasswordHash: rec.PasswordHash, CreatedAt: rec.CreatedAt}, true, nil
+}
+
+// putRecord сохраняет v в формате JSON под ключом key.
+func putRecord(bucket *bbolt.Bucket, key string, v any) error {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+	return bucket.Put([]byte(key), data)
+}
+
+// getRecord читает запись key в v и сообщает, найдена ли она.
+func getRecord(bucket *bbolt.Bucket, key string, v any) (bool, error) {
+	data := bucket.Get([]byte(key))
+	if data == nil {
+		return false, nil
+	}
+	if err := json.Unmarshal(data, v); err != nil {
+		return false, fmt.Errorf("failed to decode record %q: %w", key, err)
+	}
+	return true, nil
+}
+
+// indexPrefix возвращает префикс ключей индекса для значения value.
+// Разделитель не даёт префиксу "ab" совпасть с ключами значения "abc".
+func indexPrefix(value string) []byte {
+	return append([]byte(value), keySeparator)
+}
+
+// indexKey возвращает ключ индекса: значение, разделитель и короткий ID.
+func indexKey(value, id string) []byte {
+	return append(indexPrefix(value), id...)
+}
+
+// userURLKey возвращает ключ индекса user_urls. Порядковый номер в big-endian
+// упорядочивает URL пользователя по времени создания.
+func userURLKey(userID string, seq uint64) []byte {
+	return append(indexPrefix(userID), sequenceKey(seq)...)
+}
+
+// expiryKey возвращает ключ индекса expiry: момент истечения в big-endian и короткий ID.
+func expiryKey(expiresAt time.Time, id string) []byte {
+	key := binary.BigEndian.AppendUint64(nil, uint64(expiresAt.UnixNano()))
+	return append(key, id...)
+}
+
+// sequenceKey кодирует порядковый номер так, что ключи упорядочены по возрастанию номера.
+func sequenceKey(seq uint64) []byte {
+	return binary.BigEndian.AppendUint64(nil, seq)
+}
+
+// newURLRecord преобразует модель URL в запись бакета urls.
+func newURLRecord(urlModel models.URLModel, seq uint64) urlRecord {
+	rec := urlRecord{
+		UserID:      urlModel.UserID,
+		ShortURL:    urlModel.ID,
+		OriginalURL: urlModel.URL,
+		Deleted:     urlModel.Deleted,
+		Seq:         seq,
+	}
+	if !urlModel.ExpiresAt.IsZero() {
+		expiresAt := urlModel.ExpiresAt.UTC()
+		rec.ExpiresAt = &expiresAt
+	}
+	return rec
+}
+
+// model преобразует запись бакета urls в модель URL.
+func (rec urlRecord) model() models.URLModel {
+	urlModel := models.URLModel{ID: rec.ShortURL, URL: rec.OriginalURL, UserID: rec.UserID, Deleted: rec.Deleted}
+	if rec.ExpiresAt != nil {
+		urlModel.ExpiresAt = *rec.ExpiresAt
+	}
+	return urlModel
+}
+
+// newAPIKeyRecord преобразует модель API-ключа в запись бакета api_keys.
+func newAPIKeyRecord(key models.APIKeyModel) apiKeyRecord {
+	return apiKeyRecord{
+		ID:         key.ID,
+		UserID:     key.UserID,
+		Name:       key.Name,
+		KeyHash:    key.KeyHash,
+		Scopes:     key.Scopes,
+		CreatedAt:  key.CreatedAt,
+		LastUsedAt: key.LastUsedAt,
+	}
+}
+
+// model преобразует запись бакета api_keys в модель API-ключа.
+func (rec apiKeyRecord) model() models.APIKeyModel {
+	return models.APIKeyModel{
+		ID:         rec.ID,
+		UserID:     rec.UserID,
+		Name:       rec.Name,
+		KeyHash:    rec.KeyHash,
+		Scopes:     rec.Scopes,
+		CreatedAt:  rec.CreatedAt,
+		LastUsedAt: rec.LastUsedAt,
+	}
+}
@@ -0,0 +1,205 @@
+package bolt
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/alexuryumtsev/go-shortener/internal/app/models"
+	"github.com/alexuryumtsev/go-shortener/internal/app/storage"
+	"github.com/alexuryumtsev/go-shortener/internal/app/storage/storagetest"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newTestStorage(t *testing.T) (*BoltStorage, string) {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "data", "storage.db")
+	s, err := NewBoltStorage(path)
+	require.NoError(t, err)
+	t.Cleanup(func() { s.Close() })
+	return s, path
+}
+
+func TestStorage_SaveAndLoad(t *testing.T) {
+	s, path := newTestStorage(t)
+	ctx := context.Background()
+
+	expiresAt := time.Now().Add(time.Hour).UTC().Truncate(time.Second)
+	urlModel := models.URLModel{ID: "a", URL: "https://a.com", UserID: "user", ExpiresAt: expiresAt}
+	require.NoError(t, s.Save(ctx, urlModel))
+	_, err := s.SaveBatch(ctx, []models.URLModel{
+		{ID: "b", URL: "https://b.com", UserID: "user"},
+		{ID: "a", URL: "https://other.com", UserID: "user"},
+		{ID: "c", URL: "https://c.com", UserID: "other"},
+	})
+	require.NoError(t, err)
+
+	// Занятый короткий ID — конфликт
+	assert.ErrorIs(t, s.Save(ctx, models.URLModel{ID: "b", URL: "https://x.com"}), storage.ErrConflict)
+
+	// Данные переживают повторное открытие базы
+	require.NoError(t, s.Close())
+	s, err = NewBoltStorage(path)
+	require.NoError(t, err)
+	defer s.Close()
+
+	result, exists := s.Get(ctx, "a")
+	assert.True(t, exists)
+	assert.Equal(t, urlModel, result)
+
+	_, exists = s.Get(ctx, "missing")
+	assert.False(t, exists)
+
+	urls, err := s.GetUserURLs(ctx, "user")
+	require.NoError(t, err)
+	if assert.Len(t, urls, 2) {
+		assert.Equal(t, []string{"a", "b"}, []string{urls[0].ID, urls[1].ID})
+	}
+
+	stats, err := s.GetStats(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, models.StatsModel{URLs: 3, Users: 2}, stats)
+
+	assert.NoError(t, s.Ping(ctx))
+}
+
+func TestStorage_GetByOriginalURL(t *testing.T) {
+	s, _ := newTestStorage(t)
+	ctx := context.Background()
+	now := time.Now().UTC().Truncate(time.Second)
+
+	_, err := s.SaveBatch(ctx, []models.URLModel{
+		{ID: "soon", URL: "https://a.com", ExpiresAt: now.Add(time.Minute)},
+		{ID: "later", URL: "https://a.com", ExpiresAt: now.Add(time.Hour)},
+		{ID: "prefix", URL: "https://a.com/path"},
+	})
+	require.NoError(t, err)
+
+	result, exists := s.GetByOriginalURL(ctx, "https://a.com")
+	assert.True(t, exists)
+	assert.Equal(t, "later", result.ID)
+
+	// Бессрочная ссылка предпочтительнее
+	require.NoError(t, s.Save(ctx, models.URLModel{ID: "alias", URL: "https://a.com"}))
+	result, _ = s.GetByOriginalURL(ctx, "https://a.com")
+	assert.Equal(t, "alias", result.ID)
+
+	_, exists = s.GetByOriginalURL(ctx, "https://a.co")
+	assert.False(t, exists)
+}
+
+func TestStorage_DeleteAndExpire(t *testing.T) {
+	s, _ := newTestStorage(t)
+	ctx := context.Background()
+	now := time.Now().UTC().Truncate(time.Second)
+
+	_, err := s.SaveBatch(ctx, []models.URLModel{
+		{ID: "a", URL: "https://a.com", UserID: "user"},
+		{ID: "b", URL: "https://b.com", UserID: "user", ExpiresAt: now.Add(-time.Minute)},
+		{ID: "c", URL: "https://c.com", UserID: "other", ExpiresAt: now.Add(time.Hour)},
+	})
+	require.NoError(t, err)
+
+	// Чужие URL не удаляются
+	require.NoError(t, s.DeleteUserURLs(ctx, "user", []string{"a", "c", "missing"}))
+	result, _ := s.Get(ctx, "a")
+	assert.True(t, result.Deleted)
+	result, _ = s.Get(ctx, "c")
+	assert.False(t, result.Deleted)
+
+	removed, err := s.DeleteExpired(ctx, now, true)
+	require.NoError(t, err)
+	assert.Equal(t, 1, removed)
+
+	_, exists := s.Get(ctx, "b")
+	assert.False(t, exists)
+	_, exists = s.GetByOriginalURL(ctx, "https://b.com")
+	assert.False(t, exists)
+	urls, err := s.GetUserURLs(ctx, "user")
+	require.NoError(t, err)
+	assert.Len(t, urls, 1)
+
+	removed, err = s.DeleteExpired(ctx, now.Add(2*time.Hour), false)
+	require.NoError(t, err)
+	assert.Equal(t, 1, removed)
+}
+
+func TestStorage_ReassignUserURLs(t *testing.T) {
+	s, _ := newTestStorage(t)
+	ctx := context.Background()
+
+	_, err := s.SaveBatch(ctx, []models.URLModel{
+		{ID: "a", URL: "https://a.com", UserID: "account"},
+		{ID: "b", URL: "https://b.com", UserID: "anon"},
+		{ID: "c", URL: "https://c.com", UserID: "anon"},
+	})
+	require.NoError(t, err)
+
+	moved, err := s.ReassignUserURLs(ctx, "anon", "account")
+	require.NoError(t, err)
+	assert.Equal(t, 2, moved)
+
+	urls, err := s.GetUserURLs(ctx, "account")
+	require.NoError(t, err)
+	if assert.Len(t, urls, 3) {
+		assert.Equal(t, []string{"a", "b", "c"}, []string{urls[0].ID, urls[1].ID, urls[2].ID})
+	}
+	urls, err = s.GetUserURLs(ctx, "anon")
+	require.NoError(t, err)
+	assert.Empty(t, urls)
+}
+
+func TestStorage_ClicksKeysUsers(t *testing.T) {
+	s, _ := newTestStorage(t)
+	ctx := context.Background()
+	now := time.Date(2025, 1, 2, 10, 0, 0, 0, time.UTC)
+
+	require.NoError(t, s.SaveClicks(ctx, []models.ClickEvent{
+		{ShortID: "a", Timestamp: now, IPHash: "1"},
+		{ShortID: "a", Timestamp: now.Add(time.Hour), IPHash: "1"},
+		{ShortID: "b", Timestamp: now, IPHash: "2"},
+	}))
+	stats, err := s.GetClickStats(ctx, "a")
+	require.NoError(t, err)
+	assert.Equal(t, 2, stats.TotalClicks)
+	assert.Equal(t, 1, stats.UniqueVisitors)
+
+	key := models.APIKeyModel{ID: "k1", UserID: "user", Name: "ci", KeyHash: "hash", Scopes: []string{"read"}, CreatedAt: now}
+	require.NoError(t, s.SaveAPIKey(ctx, key))
+	require.NoError(t, s.TouchAPIKey(ctx, "k1", now.Add(time.Minute)))
+	stored, exists := s.GetAPIKeyByHash(ctx, "hash")
+	assert.True(t, exists)
+	assert.Equal(t, now.Add(time.Minute), *stored.LastUsedAt)
+	keys, err := s.GetUserAPIKeys(ctx, "user")
+	require.NoError(t, err)
+	assert.Len(t, keys, 1)
+
+	revoked, err := s.RevokeAPIKey(ctx, "other", "k1")
+	require.NoError(t, err)
+	assert.False(t, revoked)
+	revoked, err = s.RevokeAPIKey(ctx, "user", "k1")
+	require.NoError(t, err)
+	assert.True(t, revoked)
+	_, exists = s.GetAPIKeyByHash(ctx, "hash")
+	assert.False(t, exists)
+
+	user := models.UserModel{ID: "u1", Login: "alice", PasswordHash: "hash", CreatedAt: now}
+	require.NoError(t, s.CreateUser(ctx, user))
+	assert.ErrorIs(t, s.CreateUser(ctx, models.UserModel{ID: "u2", Login: "alice"}), storage.ErrConflict)
+	byLogin, exists := s.GetUserByLogin(ctx, "alice")
+	assert.True(t, exists)
+	assert.Equal(t, user, byLogin)
+	byID, exists := s.GetUserByID(ctx, "u1")
+	assert.True(t, exists)
+	assert.Equal(t, user, byID)
+}
+
+func TestConformance(t *testing.T) {
+	storagetest.Run(t, func(t *testing.T) storage.URLStorage {
+		s, err := NewBoltStorage(filepath.Join(t.TempDir(), "storage.db"))
+		require.NoError(t, err)
+		return s
+	})
+}
@@ -0,0 +1,77 @@
+package storage
+
+import (
+	"sort"
+
+	"github.com/alexuryumtsev/go-shortener/internal/app/models"
+)
+
+// clickDateLayout — формат даты в дневной статистике переходов.
+const clickDateLayout = "2006-01-02"
+
+// AggregateClicks считает статистику переходов по событиям одной ссылки.
+// Используется хранилищами, которые не умеют агрегировать данные сами.
+func AggregateClicks(events []models.ClickEvent) models.LinkStatsModel {
+	counter := NewClickCounter()
+	for _, event := range events {
+		counter.Add(event)
+	}
+	return counter.Stats()
+}
+
+// ClickCounter накапливает статистику переходов по одной ссылке по мере
+// поступления событий, не храня сами события.
+type ClickCounter struct {
+	total    int
+	visitors map[string]struct{}
+	days     map[string]*dayCounter
+}
+
+// dayCounter — статистика переходов за один день.
+type dayCounter struct {
+	clicks   int
+	visitors map[string]struct{}
+}
+
+// NewClickCounter создаёт пустой счётчик переходов.
+func NewClickCounter() *ClickCounter {
+	return &ClickCounter{
+		visitors: make(map[string]struct{}),
+		days:     make(map[string]*dayCounter),
+	}
+}
+
+// Add учитывает событие перехода.
+func (c *ClickCounter) Add(event models.ClickEvent) {
+	c.total++
+	c.visitors[event.IPHash] = struct{}{}
+
+	date := event.Timestamp.UTC().Format(clickDateLayout)
+	day, ok := c.days[date]
+	if !ok {
+		day = &dayCounter{visitors: make(map[string]struct{})}
+		c.days[date] = day
+	}
+	day.clicks++
+	day.visitors[event.IPHash] = struct{}{}
+}
+
+// Stats возвращает накопленную статистику с разбивкой по дням в порядке возрастания даты.
+func (c *ClickCounter) Stats() models.LinkStatsModel {
+	stats := models.LinkStatsModel{
+		TotalClicks:    c.total,
+		UniqueVisitors: len(c.visitors),
+		Daily:          make([]models.DailyStatsModel, 0, len(c.days)),
+	}
+	for date, day := range c.days {
+		stats.Daily = append(stats.Daily, models.DailyStatsModel{
+			Date:           date,
+			Clicks:         day.clicks,
+			UniqueVisitors: len(day.visitors),
+		})
+	}
+	sort.Slice(stats.Daily, func(i, j int) bool {
+		return stats.Daily[i].Date < stats.Daily[j].Date
+	})
+	return stats
+}
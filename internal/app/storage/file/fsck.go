@@ -0,0 +1,109 @@
+package file
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+
+	"github.com/alexuryumtsev/go-shortener/internal/app/fileutils"
+)
+
+// CheckResult — результат проверки журнала файлового хранилища.
+type CheckResult struct {
+	fileutils.ReplayReport
+	// Size — размер журнала в байтах до восстановления
+	Size int64
+	// URLs — число URL, восстановленных из журнала
+	URLs int
+	// Repaired сообщает, что журнал был перезаписан
+	Repaired bool
+}
+
+// Damaged сообщает, найдены ли в журнале повреждения.
+func (r CheckResult) Damaged() bool {
+	return len(r.Skipped) > 0 || r.TailSize > 0 || r.Unterminated
+}
+
+// Check проверяет контрольные суммы и содержимое журнала path.
+// Если repair выставлен и журнал повреждён, повреждённые записи отбрасываются,
+// а журнал перезаписывается целыми записями: новый журнал пишется во временный
+// файл, который затем атомарно заменяет старый.
+//
+// Check не согласуется с работающим сервером и должен вызываться, пока хранилище
+// не используется.
+func Check(path string, repair bool) (CheckResult, error) {
+	var result CheckResult
+
+	file, err := os.Open(path)
+	if err != nil {
+		return result, err
+	}
+	defer file.Close()
+
+	info, err := file.Stat()
+	if err != nil {
+		return result, err
+	}
+	result.Size = info.Size()
+
+	// Применяем события так же, как при загрузке хранилища,
+	// чтобы найти записи с корректной суммой, но неверным содержимым
+	scratch := &FileStorage{}
+	scratch.resetLocked()
+	var events []fileutils.Event
+	apply := func(event fileutils.Event) error {
+		if err := scratch.applyLocked(event); err != nil {
+			return err
+		}
+		events = append(events, event)
+		return nil
+	}
+
+	fs := fileutils.NewFileStorage(path)
+	result.ReplayReport, err = fs.RecoverEvents(bufio.NewReader(file), apply)
+	if err != nil {
+		return result, fmt.Errorf("failed to check %s: %w", path, err)
+	}
+	result.URLs = len(scratch.data)
+
+	if !repair || !result.Damaged() {
+		return result, nil
+	}
+	if err := rewriteLog(fs, path, events); err != nil {
+		return result, fmt.Errorf("failed to repair %s: %w", path, err)
+	}
+	result.Repaired = true
+	return result, nil
+}
+
+// rewriteLog атомарно заменяет журнал path журналом из событий events.
+func rewriteLog(fs *fileutils.FileStorage, path string, events []fileutils.Event) error {
+	tmpPath := path + ".fsck"
+	tmp, err := os.OpenFile(tmpPath, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0644)
+	if err != nil {
+		return err
+	}
+	committed := false
+	defer func() {
+		if !committed {
+			tmp.Close()
+			os.Remove(tmpPath)
+		}
+	}()
+
+	if _, err := fs.AppendEvents(tmp, events); err != nil {
+		return err
+	}
+	if err := tmp.Sync(); err != nil {
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	if err := os.Rename(tmpPath, path); err != nil {
+		return err
+	}
+	committed = true
+	syncDir(path)
+	return nil
+}
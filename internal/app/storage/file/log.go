@@ -0,0 +1,427 @@
+package file
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/alexuryumtsev/go-shortener/internal/app/fileutils"
+	"github.com/alexuryumtsev/go-shortener/internal/app/logger"
+	"github.com/alexuryumtsev/go-shortener/internal/app/models"
+	"go.uber.org/zap"
+)
+
+// SyncPolicy определяет, когда записи журнала сбрасываются на диск (fsync).
+type SyncPolicy string
+
+// Политики сброса журнала на диск.
+const (
+	// SyncAlways сбрасывает журнал после каждой операции записи
+	SyncAlways SyncPolicy = "always"
+	// SyncInterval сбрасывает журнал в фоне с заданным периодом;
+	// при сбое питания теряются записи за последний период
+	SyncInterval SyncPolicy = "interval"
+	// SyncNever оставляет сброс на усмотрение операционной системы
+	SyncNever SyncPolicy = "never"
+)
+
+// ParseSyncPolicy разбирает название политики сброса журнала.
+func ParseSyncPolicy(name string) (SyncPolicy, error) {
+	switch policy := SyncPolicy(name); policy {
+	case SyncAlways, SyncInterval, SyncNever:
+		return policy, nil
+	default:
+		return "", fmt.Errorf("unknown file sync policy %q: expected always, interval or never", name)
+	}
+}
+
+const (
+	// defaultSyncInterval — период сброса журнала для SyncInterval по умолчанию
+	defaultSyncInterval = time.Second
+	// compactMinStale — минимальное число устаревших событий для фонового сжатия:
+	// сжимать маленький журнал нет смысла
+	compactMinStale = 1000
+)
+
+// options — параметры файлового хранилища.
+type options struct {
+	sync            SyncPolicy
+	syncInterval    time.Duration
+	compactInterval time.Duration
+}
+
+// Option задаёт параметр файлового хранилища.
+type Option func(*options)
+
+// WithSync задаёт политику сброса журнала на диск и период сброса для SyncInterval.
+func WithSync(policy SyncPolicy, interval time.Duration) Option {
+	return func(o *options) {
+		o.sync = policy
+		if interval > 0 {
+			o.syncInterval = interval
+		}
+	}
+}
+
+// WithCompaction включает фоновое сжатие журнала с периодом проверки interval.
+// Журнал сжимается, когда устаревших событий в нём не меньше, чем актуальных записей.
+func WithCompaction(interval time.Duration) Option {
+	return func(o *options) {
+		o.compactInterval = interval
+	}
+}
+
+// resetLocked очищает индексы.
+// Вызывающий должен удерживать s.mu.
+func (s *FileStorage) resetLocked() {
+	s.data = make(map[string]models.URLModel)
+	s.byURL = make(map[string][]string)
+	s.userData = make(map[string][]string)
+}
+
+// ensureLoaded загружает журнал при первом обращении к хранилищу.
+// Ошибка загрузки запоминается и возвращается последующим вызовам:
+// повторное чтение всего журнала при каждом запросе только нагружало бы диск.
+func (s *FileStorage) ensureLoaded() error {
+	s.mu.RLock()
+	loaded, loadErr := s.log != nil || s.closed, s.loadErr
+	s.mu.RUnlock()
+	if loaded || loadErr != nil {
+		return loadErr
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.log != nil || s.closed || s.loadErr != nil {
+		return s.loadErr
+	}
+	s.loadErr = s.loadLocked()
+	return s.loadErr
+}
+
+// loadLocked открывает журнал на дозапись и строит по нему индексы.
+// Вызывающий должен удерживать s.mu.
+func (s *FileStorage) loadLocked() error {
+	if s.closed {
+		return ErrStorageClosed
+	}
+
+	file, err := os.OpenFile(s.filePath, os.O_RDWR|os.O_CREATE|os.O_APPEND, 0644)
+	if err != nil {
+		return err
+	}
+
+	s.resetLocked()
+	report, err := s.fileStorage.ReplayEvents(bufio.NewReader(file), s.applyLocked)
+	if err != nil {
+		file.Close()
+		s.resetLocked()
+		if errors.Is(err, fileutils.ErrCorruptedRecord) {
+			return fmt.Errorf("failed to load %s: %w (run \"shortener fsck -repair %s\" to skip damaged records)",
+				s.filePath, err, s.filePath)
+		}
+		return fmt.Errorf("failed to load %s: %w", s.filePath, err)
+	}
+
+	// Оборванный хвост — последняя запись, недописанная при сбое.
+	// Обрезаем его, иначе новые записи окажутся после повреждённой
+	if report.TailSize > 0 {
+		if err := file.Truncate(report.ValidSize); err != nil {
+			file.Close()
+			s.resetLocked()
+			return fmt.Errorf("failed to truncate torn tail of %s: %w", s.filePath, err)
+		}
+		logger.L().Warn("Truncated torn tail of file storage",
+			zap.String("path", s.filePath), zap.Int64("size", report.TailSize), zap.Int64("offset", report.ValidSize))
+	}
+
+	if report.Unterminated {
+		if _, err := file.WriteString("\n"); err != nil {
+			file.Close()
+			s.resetLocked()
+			return err
+		}
+		report.ValidSize++
+	}
+
+	if s.log != nil {
+		s.log.Close()
+	}
+	s.log = file
+	s.size = report.ValidSize
+	s.events = report.Events
+	s.writeErr = nil
+	return nil
+}
+
+// validateEvent проверяет событие до записи в журнал: событие,
+// которое нельзя применить, сделало бы журнал незагружаемым.
+func validateEvent(event fileutils.Event) error {
+	switch event.Op {
+	case fileutils.OpCreate:
+		if event.URL.ID == "" || event.URL.URL == "" {
+			return fmt.Errorf("invalid data format: short_url or original_url is empty")
+		}
+	case fileutils.OpDelete, fileutils.OpExpire, fileutils.OpOwner:
+	default:
+		return fmt.Errorf("unknown event %q", event.Op)
+	}
+	return nil
+}
+
+// applyLocked применяет событие журнала к индексам.
+// Вызывающий должен удерживать s.mu.
+func (s *FileStorage) applyLocked(event fileutils.Event) error {
+	if err := validateEvent(event); err != nil {
+		return err
+	}
+
+	id := event.URL.ID
+	switch event.Op {
+	case fileutils.OpCreate:
+		s.removeLocked(id)
+		s.addLocked(event.URL)
+	case fileutils.OpDelete:
+		if urlModel, exists := s.data[id]; exists {
+			urlModel.Deleted = true
+			s.data[id] = urlModel
+		}
+	case fileutils.OpExpire:
+		s.removeLocked(id)
+	case fileutils.OpOwner:
+		if urlModel, exists := s.data[id]; exists {
+			s.removeLocked(id)
+			urlModel.UserID = event.URL.UserID
+			s.addLocked(urlModel)
+		}
+	}
+	return nil
+}
+
+// addLocked добавляет URL в индексы.
+// Вызывающий должен удерживать s.mu.
+func (s *FileStorage) addLocked(urlModel models.URLModel) {
+	s.data[urlModel.ID] = urlModel
+	s.byURL[urlModel.URL] = append(s.byURL[urlModel.URL], urlModel.ID)
+	s.userData[urlModel.UserID] = append(s.userData[urlModel.UserID], urlModel.ID)
+}
+
+// removeLocked удаляет URL из индексов.
+// Вызывающий должен удерживать s.mu.
+func (s *FileStorage) removeLocked(id string) {
+	urlModel, exists := s.data[id]
+	if !exists {
+		return
+	}
+	delete(s.data, id)
+	removeID(s.byURL, urlModel.URL, id)
+	removeID(s.userData, urlModel.UserID, id)
+}
+
+// removeID удаляет id из списка index[key]; пустой список удаляется целиком.
+func removeID(index map[string][]string, key, id string) {
+	ids := index[key]
+	for i := range ids {
+		if ids[i] == id {
+			ids = append(ids[:i], ids[i+1:]...)
+			break
+		}
+	}
+	if len(ids) == 0 {
+		delete(index, key)
+	} else {
+		index[key] = ids
+	}
+}
+
+// appendLocked дописывает события в журнал и применяет их к индексам.
+// Индексы меняются только после успешной записи и сброса на диск. При ошибке
+// журнал обрезается до прежнего размера, поэтому частично записанная операция
+// не остаётся ни в журнале, ни в памяти. Если обрезать журнал не удалось,
+// дальнейшие записи отклоняются до перезагрузки журнала.
+// Вызывающий должен удерживать s.mu.
+func (s *FileStorage) appendLocked(events []fileutils.Event) error {
+	if len(events) == 0 {
+		return nil
+	}
+	if s.writeErr != nil {
+		return s.writeErr
+	}
+	for _, event := range events {
+		if err := validateEvent(event); err != nil {
+			return err
+		}
+	}
+
+	n, err := s.fileStorage.AppendEvents(s.log, events)
+	if err == nil && s.opts.sync == SyncAlways {
+		err = s.log.Sync()
+	}
+	if err != nil {
+		if truncErr := s.log.Truncate(s.size); truncErr != nil {
+			s.writeErr = fmt.Errorf("file storage %s is inconsistent after failed write: %w", s.filePath, errors.Join(err, truncErr))
+			return s.writeErr
+		}
+		return err
+	}
+
+	s.size += int64(n)
+	if s.opts.sync != SyncAlways {
+		s.dirty = true
+	}
+	for _, event := range events {
+		// События проверены до записи, поэтому применение не завершается ошибкой
+		_ = s.applyLocked(event)
+	}
+	s.events += len(events)
+	return nil
+}
+
+// run выполняет фоновый сброс журнала на диск и его сжатие до вызова Close.
+func (s *FileStorage) run() {
+	defer close(s.done)
+
+	var syncTick, compactTick <-chan time.Time
+	if s.opts.sync == SyncInterval && s.opts.syncInterval > 0 {
+		ticker := time.NewTicker(s.opts.syncInterval)
+		defer ticker.Stop()
+		syncTick = ticker.C
+	}
+	if s.opts.compactInterval > 0 {
+		ticker := time.NewTicker(s.opts.compactInterval)
+		defer ticker.Stop()
+		compactTick = ticker.C
+	}
+
+	for {
+		select {
+		case <-s.stop:
+			return
+		case <-syncTick:
+			if err := s.sync(); err != nil {
+				logger.L().Error("Failed to sync file storage", zap.String("path", s.filePath), zap.Error(err))
+			}
+		case <-compactTick:
+			if !s.needsCompaction() {
+				continue
+			}
+			if err := s.Compact(); err != nil {
+				logger.L().Error("Failed to compact file storage", zap.String("path", s.filePath), zap.Error(err))
+			}
+		}
+	}
+}
+
+// sync сбрасывает на диск записи журнала, сделанные после предыдущего сброса.
+func (s *FileStorage) sync() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if !s.dirty || s.log == nil {
+		return nil
+	}
+	s.dirty = false
+	return s.log.Sync()
+}
+
+// needsCompaction сообщает, достаточно ли в журнале устаревших событий для сжатия.
+func (s *FileStorage) needsCompaction() bool {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	stale := s.events - len(s.data)
+	return stale >= compactMinStale && stale >= len(s.data)
+}
+
+// Compact перезаписывает журнал так, что в нём остаётся по одному событию
+// создания на каждый URL. Новый журнал пишется во временный файл, который
+// затем атомарно заменяет старый, поэтому при сбое на любом шаге на диске
+// остаётся целый журнал. Чтение и запись не блокируются на время записи снимка:
+// события, дописанные в старый журнал за это время, переносятся в конец нового.
+func (s *FileStorage) Compact() error {
+	s.compactMu.Lock()
+	defer s.compactMu.Unlock()
+
+	if err := s.ensureLoaded(); err != nil {
+		return err
+	}
+
+	// Снимок состояния; порядок URL каждого пользователя сохраняется
+	s.mu.RLock()
+	if s.closed {
+		s.mu.RUnlock()
+		return ErrStorageClosed
+	}
+	snapshot := make([]fileutils.Event, 0, len(s.data))
+	for _, ids := range s.userData {
+		for _, id := range ids {
+			snapshot = append(snapshot, fileutils.Event{Op: fileutils.OpCreate, URL: s.data[id]})
+		}
+	}
+	offset, eventsAtSnapshot := s.size, s.events
+	s.mu.RUnlock()
+
+	// Временный файл открыт на дозапись и после переименования становится журналом:
+	// повторное открытие после замены могло бы не удаться и оставить s.log
+	// указывающим на удалённый файл
+	tmpPath := s.filePath + ".compact"
+	tmp, err := os.OpenFile(tmpPath, os.O_RDWR|os.O_CREATE|os.O_TRUNC|os.O_APPEND, 0644)
+	if err != nil {
+		return err
+	}
+	committed := false
+	defer func() {
+		if !committed {
+			tmp.Close()
+			os.Remove(tmpPath)
+		}
+	}()
+
+	size, err := s.fileStorage.AppendEvents(tmp, snapshot)
+	if err != nil {
+		return fmt.Errorf("failed to write compacted log: %w", err)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.closed {
+		return ErrStorageClosed
+	}
+
+	// Переносим события, дописанные во время записи снимка
+	tail, err := io.Copy(tmp, io.NewSectionReader(s.log, offset, s.size-offset))
+	if err != nil {
+		return fmt.Errorf("failed to copy log tail: %w", err)
+	}
+	if err := tmp.Sync(); err != nil {
+		return err
+	}
+	if err := os.Rename(tmpPath, s.filePath); err != nil {
+		return err
+	}
+	committed = true
+	syncDir(s.filePath)
+
+	// Старый дескриптор указывает на заменённый файл
+	s.log.Close()
+	s.log = tmp
+	s.size = int64(size) + tail
+	s.events = len(snapshot) + s.events - eventsAtSnapshot
+	s.dirty = false
+	return nil
+}
+
+// syncDir сбрасывает на диск каталог файла path, чтобы переименование пережило сбой питания.
+// Ошибки игнорируются: не все файловые системы поддерживают fsync каталога.
+func syncDir(path string) {
+	dir, err := os.Open(filepath.Dir(path))
+	if err != nil {
+		return
+	}
+	dir.Sync()
+	dir.Close()
+}
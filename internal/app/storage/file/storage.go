@@ -1,186 +1,936 @@
 // Package file предоставляет реализацию хранилища для работы с файлами.
+//
+// URL хранятся в журнале событий (создание, удаление, истечение срока, смена владельца),
+// в который записи только дописываются. Журнал читается один раз при запуске и
+// восстанавливает индексы в памяти, поэтому чтение не обращается к диску.
+// Устаревшие события периодически убираются сжатием журнала (см. Compact).
+//
+// Каждая запись журнала снабжена длиной и контрольной суммой. Недописанная при сбое
+// последняя запись обрезается при загрузке; повреждение в середине журнала
+// останавливает загрузку и исправляется командой "shortener fsck -repair" (см. Check).
 package file
 
 import (
+	"bufio"
 	"context"
+	"errors"
 	"fmt"
 	"os"
+	"path/filepath"
 	"sync"
+	"time"
 
 	"github.com/alexuryumtsev/go-shortener/internal/app/fileutils"
+	"github.com/alexuryumtsev/go-shortener/internal/app/logger"
 	"github.com/alexuryumtsev/go-shortener/internal/app/models"
+	"github.com/alexuryumtsev/go-shortener/internal/app/storage"
+	"go.uber.org/zap"
 )
 
 // FileStorage управляет сохранением и получением данных в файле.
 type FileStorage struct {
-	mu          sync.RWMutex
-	data        map[string]models.URLModel
-	userData    map[string][]models.URLModel
-	filePath    string
-	counter     int
+	mu       sync.RWMutex
+	data     map[string]models.URLModel
+	byURL    map[string][]string
+	userData map[string][]string
+	filePath string
+	opts     options
+
 	fileStorage *fileutils.FileStorage
+	// log — журнал, открытый на дозапись; nil, пока журнал не загружен
+	log *os.File
+	// size — размер журнала в байтах, events — число событий в нём
+	size   int64
+	events int
+	// dirty отмечает записи, ещё не сброшенные на диск
+	dirty  bool
+	closed bool
+	// loadErr — ошибка последней загрузки журнала; повторно журнал загружается
+	// только явным вызовом LoadFromFile, а не при каждом обращении
+	loadErr error
+	// writeErr — ошибка, после которой журнал не удалось вернуть к последней
+	// целой записи; запись отклоняется до перезагрузки журнала
+	writeErr error
+
+	// compactMu не даёт выполнять сжатие и перезагрузку журнала одновременно
+	compactMu sync.Mutex
+	stop      chan struct{}
+	done      chan struct{}
+	closeOnce sync.Once
+
+	// users загружаются из своего файла при первом обращении
+	users map[string]models.UserModel
+
+	// apiKeysMu защищает файл API-ключей и поля ниже, поэтому проверка ключа
+	// при каждом запросе не ждёт записи URL. apiKeys загружаются из файла
+	// при первом обращении, apiKeyHashes — индекс ID ключей по хешу
+	apiKeysMu    sync.RWMutex
+	apiKeys      map[string]models.APIKeyModel
+	apiKeyHashes map[string]string
+	// apiKeysDirty отмечает время использования ключей, ещё не записанное в файл;
+	// apiKeysFlushed — время последней перезаписи файла ключей
+	apiKeysDirty   bool
+	apiKeysFlushed time.Time
+
+	// clicksMu защищает файл статистики переходов и clicks — статистику
+	// по коротким ID, которая загружается из файла при первом обращении
+	clicksMu sync.Mutex
+	clicks   map[string]*storage.ClickCounter
 }
 
+// ErrStorageClosed возвращается при попытке записи в закрытое хранилище.
+var ErrStorageClosed = errors.New("file storage is closed")
+
+// apiKeysFlushInterval — минимальный интервал между перезаписями файла API-ключей
+// ради времени их последнего использования.
+const apiKeysFlushInterval = time.Minute
+
 // NewFileStorage создаёт новое файловое хранилище.
-func NewFileStorage(filePath string) *FileStorage {
-	return &FileStorage{
-		data:        make(map[string]models.URLModel),
-		userData:    make(map[string][]models.URLModel),
+// Без опций каждая запись сбрасывается на диск, а фоновое сжатие журнала отключено.
+func NewFileStorage(filePath string, opts ...Option) *FileStorage {
+	o := options{sync: SyncAlways, syncInterval: defaultSyncInterval}
+	for _, opt := range opts {
+		opt(&o)
+	}
+
+	s := &FileStorage{
 		filePath:    filePath,
-		counter:     0,
+		opts:        o,
 		fileStorage: fileutils.NewFileStorage(filePath),
 	}
+	s.resetLocked()
+
+	if (o.sync == SyncInterval && o.syncInterval > 0) || o.compactInterval > 0 {
+		s.stop = make(chan struct{})
+		s.done = make(chan struct{})
+		go s.run()
+	}
+	return s
 }
 
-// Save сохраняет URL и записывает данные в файл.
+// Save сохраняет URL и дописывает событие в журнал.
+// Если короткий ID уже занят, возвращает storage.ErrConflict.
 func (s *FileStorage) Save(ctx context.Context, urlModel models.URLModel) error {
+	if err := s.ensureLoaded(); err != nil {
+		return err
+	}
+
 	s.mu.Lock()
 	defer s.mu.Unlock()
 
-	// Проверяем, существует ли уже оригинальный URL
-	for _, existingURL := range s.data {
-		if existingURL.URL == urlModel.URL {
-			// Если URL уже существует, ничего не делаем
-			return nil
-		}
+	if s.closed {
+		return ErrStorageClosed
 	}
-
-	s.data[urlModel.ID] = urlModel
-	userID := urlModel.UserID
-	s.userData[userID] = append(s.userData[userID], urlModel)
-
-	file, err := os.OpenFile(s.filePath, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
-	if err != nil {
-		return err
+	if _, exists := s.data[urlModel.ID]; exists {
+		return fmt.Errorf("%w: short URL %q already exists", storage.ErrConflict, urlModel.ID)
 	}
-	defer file.Close()
-
-	return s.fileStorage.SaveRecord(file, urlModel)
+	return s.appendLocked([]fileutils.Event{{Op: fileutils.OpCreate, URL: urlModel}})
 }
 
-// SaveBatch сохраняет множество URL в файл.
-func (s *FileStorage) SaveBatch(ctx context.Context, urlModels []models.URLModel) error {
+// SaveBatch сохраняет множество URL одной записью в журнал.
+// URL с уже занятыми короткими ID, в том числе повторяющимися в пакете, пропускаются,
+// их ID возвращаются в skipped.
+func (s *FileStorage) SaveBatch(ctx context.Context, urlModels []models.URLModel) (skipped []string, err error) {
+	if err := s.ensureLoaded(); err != nil {
+		return nil, err
+	}
+
 	s.mu.Lock()
 	defer s.mu.Unlock()
 
-	file, err := os.OpenFile(s.filePath, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
-	if err != nil {
-		return err
+	if s.closed {
+		return nil, ErrStorageClosed
 	}
-	defer file.Close()
 
+	events := make([]fileutils.Event, 0, len(urlModels))
+	seen := make(map[string]struct{}, len(urlModels))
 	for _, urlModel := range urlModels {
-		// Проверяем, существует ли уже оригинальный URL
-		for _, existingURL := range s.data {
-			if existingURL.URL == urlModel.URL {
-				// Если URL уже существует, ничего не делаем
-				continue
-			}
-		}
-
-		userID := urlModel.UserID
-		s.data[urlModel.ID] = urlModel
-		s.userData[userID] = append(s.userData[userID], urlModel)
-
-		if err := s.fileStorage.SaveRecord(file, urlModel); err != nil {
-			return err
+		_, exists := s.data[urlModel.ID]
+		if _, dup := seen[urlModel.ID]; exists || dup {
+			skipped = append(skipped, urlModel.ID)
+			continue
 		}
+		seen[urlModel.ID] = struct{}{}
+		events = append(events, fileutils.Event{Op: fileutils.OpCreate, URL: urlModel})
 	}
 
-	return nil
+	if err := s.appendLocked(events); err != nil {
+		return nil, err
+	}
+	return skipped, nil
 }
 
 // Get возвращает оригинальный URL по идентификатору.
 func (s *FileStorage) Get(ctx context.Context, id string) (models.URLModel, bool) {
-	s.LoadFromFile()
+	if err := s.ensureLoaded(); err != nil {
+		return models.URLModel{}, false
+	}
+
 	s.mu.RLock()
 	defer s.mu.RUnlock()
 	urlModel, exists := s.data[id]
-	return models.URLModel{ID: id, URL: urlModel.URL, Deleted: urlModel.Deleted}, exists
+	return urlModel, exists
+}
+
+// GetByOriginalURL возвращает сокращённый URL по оригинальному URL.
+// Если у URL несколько коротких ссылок, выбирается предпочтительная (см. storage.PreferURL).
+func (s *FileStorage) GetByOriginalURL(ctx context.Context, originalURL string) (models.URLModel, bool) {
+	if err := s.ensureLoaded(); err != nil {
+		return models.URLModel{}, false
+	}
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	var (
+		best   models.URLModel
+		exists bool
+	)
+	for _, id := range s.byURL[originalURL] {
+		if urlModel := s.data[id]; !exists || storage.PreferURL(urlModel, best) {
+			best, exists = urlModel, true
+		}
+	}
+	return best, exists
 }
 
-// GetUserURLs возвращает все URL, сокращённые пользователем.
+// GetUserURLs возвращает все URL, сокращённые пользователем, в порядке создания.
 func (s *FileStorage) GetUserURLs(ctx context.Context, userID string) ([]models.URLModel, error) {
-	s.LoadFromFile()
+	if err := s.ensureLoaded(); err != nil {
+		return nil, err
+	}
+
 	s.mu.RLock()
 	defer s.mu.RUnlock()
-	urls, exists := s.userData[userID]
-	if !exists {
+	ids := s.userData[userID]
+	if len(ids) == 0 {
 		return nil, nil
 	}
+	urls := make([]models.URLModel, 0, len(ids))
+	for _, id := range ids {
+		urls = append(urls, s.data[id])
+	}
 	return urls, nil
 }
 
-// LoadFromFile загружает данные из файла.
+// GetStats возвращает сводную статистику по записям хранилища.
+func (s *FileStorage) GetStats(ctx context.Context) (models.StatsModel, error) {
+	if err := s.ensureLoaded(); err != nil {
+		return models.StatsModel{}, err
+	}
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	urls := make([]models.URLModel, 0, len(s.data))
+	for _, urlModel := range s.data {
+		urls = append(urls, urlModel)
+	}
+	return storage.CountStats(urls, time.Now()), nil
+}
+
+// ForEachURL вызывает fn для URL с коротким ID больше afterID в порядке возрастания ID.
+func (s *FileStorage) ForEachURL(ctx context.Context, afterID string, fn func(models.URLModel) error) error {
+	if err := s.ensureLoaded(); err != nil {
+		return err
+	}
+
+	s.mu.RLock()
+	urls := make([]models.URLModel, 0, len(s.data))
+	for id, urlModel := range s.data {
+		if id > afterID {
+			urls = append(urls, urlModel)
+		}
+	}
+	s.mu.RUnlock()
+	return storage.ForEachSorted(ctx, urls, fn)
+}
+
+// LoadFromFile читает журнал и заново строит индексы в памяти.
+// Если файла нет, он создаётся.
 func (s *FileStorage) LoadFromFile() error {
+	s.compactMu.Lock()
+	defer s.compactMu.Unlock()
+
 	s.mu.Lock()
 	defer s.mu.Unlock()
+	s.loadErr = s.loadLocked()
+	return s.loadErr
+}
 
-	file, err := os.Open(s.filePath)
-	if os.IsNotExist(err) {
-		// Если файл не существует, создаем его.
-		file, err = os.Create(s.filePath)
+// Ping проверяет соединение с базой данных (для файлового хранилища всегда возвращает nil).
+func (s *FileStorage) Ping(ctx context.Context) error {
+	return nil
+}
+
+// CheckHealth проверяет, что журнал загружен, открыт на запись и не остался
+// несогласованным после неудачной записи, а в его каталог
+// можно записать файл и на диске есть свободное место.
+func (s *FileStorage) CheckHealth(ctx context.Context) map[string]storage.ComponentHealth {
+	result := map[string]storage.ComponentHealth{
+		"disk": storage.CheckDirectory(filepath.Dir(s.filePath)),
+	}
+
+	details := map[string]any{"path": s.filePath, "sync": string(s.opts.sync)}
+	if err := s.ensureLoaded(); err != nil {
+		result["file"] = storage.ComponentHealth{Status: storage.HealthDown, Error: err.Error(), Details: details}
+		return result
+	}
+
+	s.mu.RLock()
+	closed, writeErr := s.closed, s.writeErr
+	details["size_bytes"] = s.size
+	details["events"] = s.events
+	s.mu.RUnlock()
+
+	if closed {
+		result["file"] = storage.ComponentHealth{Status: storage.HealthDown, Error: ErrStorageClosed.Error(), Details: details}
+		return result
+	}
+	if writeErr != nil {
+		result["file"] = storage.ComponentHealth{Status: storage.HealthDown, Error: writeErr.Error(), Details: details}
+		return result
+	}
+	result["file"] = storage.ComponentHealth{Status: storage.HealthUp, Details: details}
+	return result
+}
+
+// Close закрывает файловое хранилище.
+// Останавливает фоновые задачи и дожидается завершения текущих операций записи,
+// после чего сбрасывает журнал на диск; новые записи отклоняются.
+func (s *FileStorage) Close() error {
+	var err error
+	s.closeOnce.Do(func() {
+		if s.stop != nil {
+			close(s.stop)
+			<-s.done
+		}
+
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		s.closed = true
+
+		s.apiKeysMu.Lock()
+		if s.apiKeysDirty {
+			err = s.rewriteAPIKeysLocked()
+		}
+		s.apiKeysMu.Unlock()
+		if s.log != nil {
+			err = errors.Join(err, s.log.Sync(), s.log.Close())
+			s.log = nil
+		}
+	})
+	return err
+}
+
+// DeleteUserURLs помечает URL пользователя как удалённые.
+func (s *FileStorage) DeleteUserURLs(ctx context.Context, userID string, shortURLs []string) error {
+	if err := s.ensureLoaded(); err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.closed {
+		return ErrStorageClosed
+	}
+
+	var events []fileutils.Event
+	for _, shortURL := range shortURLs {
+		if urlModel, exists := s.data[shortURL]; exists && urlModel.UserID == userID && !urlModel.Deleted {
+			events = append(events, fileutils.Event{Op: fileutils.OpDelete, URL: urlModel})
+		}
+	}
+
+	return s.appendLocked(events)
+}
+
+// DeleteExpired удаляет URL с истёкшим сроком действия.
+// При archive = true удалённые записи дописываются в файл <path>.archive.
+// Переходы по удалённым ссылкам удаляются из файла <path>.clicks.
+func (s *FileStorage) DeleteExpired(ctx context.Context, now time.Time, archive bool) (int, error) {
+	if err := s.ensureLoaded(); err != nil {
+		return 0, err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.closed {
+		return 0, ErrStorageClosed
+	}
+
+	var events []fileutils.Event
+	expired := make(map[string]struct{})
+	for _, urlModel := range s.data {
+		if urlModel.Expired(now) {
+			events = append(events, fileutils.Event{Op: fileutils.OpExpire, URL: urlModel})
+			expired[urlModel.ID] = struct{}{}
+		}
+	}
+	if len(events) == 0 {
+		return 0, nil
+	}
+
+	// Переходы удаляются до записи в журнал: после сбоя между шагами ссылки
+	// останутся истёкшими без статистики, а не освободят ID с чужими переходами
+	if err := s.purgeClicks(expired); err != nil {
+		return 0, fmt.Errorf("failed to purge clicks: %w", err)
+	}
+
+	if archive {
+		file, err := os.OpenFile(s.filePath+".archive", os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
 		if err != nil {
-			return err
+			return 0, err
 		}
 		defer file.Close()
+
+		for _, event := range events {
+			if err := s.fileStorage.SaveRecord(file, event.URL); err != nil {
+				return 0, err
+			}
+		}
+	}
+
+	if err := s.appendLocked(events); err != nil {
+		return 0, err
+	}
+	return len(events), nil
+}
+
+// SaveClicks дописывает события переходов в файл <path>.clicks и учитывает их
+// в статистике. Файл статистики защищён отдельной блокировкой, поэтому запись
+// переходов не задерживает чтение и запись URL.
+func (s *FileStorage) SaveClicks(ctx context.Context, events []models.ClickEvent) error {
+	if s.isClosed() {
+		return ErrStorageClosed
+	}
+
+	s.clicksMu.Lock()
+	defer s.clicksMu.Unlock()
+
+	if err := s.loadClicksLocked(); err != nil {
+		return err
+	}
+
+	file, err := os.OpenFile(s.clicksPath(), os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	if err := s.fileStorage.SaveClickRecords(file, events); err != nil {
+		return err
+	}
+	for _, event := range events {
+		s.addClickLocked(event)
+	}
+	return nil
+}
+
+// GetClickStats возвращает статистику переходов по ссылке. Файл <path>.clicks
+// читается только при первом обращении, далее статистика ведётся в памяти.
+func (s *FileStorage) GetClickStats(ctx context.Context, shortID string) (models.LinkStatsModel, error) {
+	s.clicksMu.Lock()
+	defer s.clicksMu.Unlock()
+
+	if err := s.loadClicksLocked(); err != nil {
+		return models.LinkStatsModel{}, err
+	}
+	counter, ok := s.clicks[shortID]
+	if !ok {
+		return storage.AggregateClicks(nil), nil
+	}
+	return counter.Stats(), nil
+}
+
+// purgeClicks удаляет переходы по ссылкам ids из файла статистики, переписывая
+// его через временный файл. Вызывается под s.mu, берёт clicksMu.
+func (s *FileStorage) purgeClicks(ids map[string]struct{}) error {
+	s.clicksMu.Lock()
+	defer s.clicksMu.Unlock()
+
+	if err := s.loadClicksLocked(); err != nil {
+		return err
+	}
+	found := false
+	for id := range ids {
+		if _, ok := s.clicks[id]; ok {
+			found = true
+			break
+		}
+	}
+	if !found {
+		return nil
+	}
+
+	src, err := os.Open(s.clicksPath())
+	if err != nil {
+		return err
+	}
+	defer src.Close()
+
+	tmpPath := s.clicksPath() + ".tmp"
+	file, err := os.OpenFile(tmpPath, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0644)
+	if err != nil {
+		return err
+	}
+	committed := false
+	defer func() {
+		if !committed {
+			file.Close()
+			os.Remove(tmpPath)
+		}
+	}()
+
+	buffered := bufio.NewWriter(file)
+	var saveErr error
+	_, err = s.fileStorage.LoadClickRecords(src, func(event models.ClickEvent) {
+		if _, purged := ids[event.ShortID]; purged || saveErr != nil {
+			return
+		}
+		saveErr = s.fileStorage.SaveClickRecords(buffered, []models.ClickEvent{event})
+	})
+	if err != nil {
+		return err
+	}
+	if saveErr != nil {
+		return saveErr
+	}
+	if err := buffered.Flush(); err != nil {
+		return err
+	}
+	if err := file.Sync(); err != nil {
+		return err
+	}
+	if err := file.Close(); err != nil {
+		return err
+	}
+	if err := os.Rename(tmpPath, s.clicksPath()); err != nil {
+		return err
+	}
+	committed = true
+	syncDir(s.clicksPath())
+
+	for id := range ids {
+		delete(s.clicks, id)
+	}
+	return nil
+}
+
+// loadClicksLocked читает файл статистики переходов, если он ещё не прочитан.
+// Оборванный хвост файла обрезается, как и у журнала URL. Вызывается под clicksMu.
+func (s *FileStorage) loadClicksLocked() error {
+	if s.clicks != nil {
+		return nil
+	}
+
+	clicks := make(map[string]*storage.ClickCounter)
+	file, err := os.OpenFile(s.clicksPath(), os.O_RDWR, 0)
+	if os.IsNotExist(err) {
+		s.clicks = clicks
 		return nil
 	} else if err != nil {
 		return err
 	}
 	defer file.Close()
 
-	data, err := s.fileStorage.LoadRecords(file)
+	s.clicks = clicks
+	validSize, err := s.fileStorage.LoadClickRecords(file, s.addClickLocked)
 	if err != nil {
+		s.clicks = nil
+		return fmt.Errorf("failed to load clicks: %w", err)
+	}
+
+	info, err := file.Stat()
+	if err != nil {
+		s.clicks = nil
 		return err
 	}
+	if tail := info.Size() - validSize; tail > 0 {
+		if err := file.Truncate(validSize); err != nil {
+			s.clicks = nil
+			return fmt.Errorf("failed to truncate torn tail of %s: %w", s.clicksPath(), err)
+		}
+		logger.L().Warn("Truncated torn tail of clicks file",
+			zap.String("path", s.clicksPath()), zap.Int64("size", tail), zap.Int64("offset", validSize))
+	}
+	return nil
+}
+
+// addClickLocked учитывает событие перехода в статистике. Вызывается под clicksMu.
+func (s *FileStorage) addClickLocked(event models.ClickEvent) {
+	counter, ok := s.clicks[event.ShortID]
+	if !ok {
+		counter = storage.NewClickCounter()
+		s.clicks[event.ShortID] = counter
+	}
+	counter.Add(event)
+}
+
+// clicksPath возвращает путь к файлу статистики переходов.
+func (s *FileStorage) clicksPath() string {
+	return s.filePath + ".clicks"
+}
+
+// SaveAPIKey сохраняет API-ключ в файл <path>.apikeys.
+func (s *FileStorage) SaveAPIKey(ctx context.Context, key models.APIKeyModel) error {
+	if s.isClosed() {
+		return ErrStorageClosed
+	}
+
+	s.apiKeysMu.Lock()
+	defer s.apiKeysMu.Unlock()
+
+	if err := s.loadAPIKeysLocked(); err != nil {
+		return err
+	}
+	if old, exists := s.apiKeys[key.ID]; exists {
+		delete(s.apiKeyHashes, old.KeyHash)
+	}
+	s.apiKeys[key.ID] = key
+	s.apiKeyHashes[key.KeyHash] = key.ID
+	return s.rewriteAPIKeysLocked()
+}
+
+// GetAPIKeyByHash возвращает API-ключ по хешу.
+func (s *FileStorage) GetAPIKeyByHash(ctx context.Context, keyHash string) (models.APIKeyModel, bool) {
+	if err := s.ensureAPIKeys(); err != nil {
+		return models.APIKeyModel{}, false
+	}
+
+	s.apiKeysMu.RLock()
+	defer s.apiKeysMu.RUnlock()
+	id, ok := s.apiKeyHashes[keyHash]
+	if !ok {
+		return models.APIKeyModel{}, false
+	}
+	return s.apiKeys[id], true
+}
 
-	// Валидация формата данных
-	for shortURL, urlModel := range data {
-		if shortURL == "" || urlModel.URL == "" {
-			return fmt.Errorf("invalid data format: short_url or original_url is empty")
+// GetUserAPIKeys возвращает API-ключи пользователя в порядке создания.
+func (s *FileStorage) GetUserAPIKeys(ctx context.Context, userID string) ([]models.APIKeyModel, error) {
+	if err := s.ensureAPIKeys(); err != nil {
+		return nil, err
+	}
+
+	s.apiKeysMu.RLock()
+	defer s.apiKeysMu.RUnlock()
+	var keys []models.APIKeyModel
+	for _, key := range s.apiKeys {
+		if key.UserID == userID {
+			keys = append(keys, key)
+		}
+	}
+	storage.SortAPIKeys(keys)
+	return keys, nil
+}
+
+// RevokeAPIKey удаляет API-ключ пользователя и перезаписывает файл ключей.
+func (s *FileStorage) RevokeAPIKey(ctx context.Context, userID, id string) (bool, error) {
+	if s.isClosed() {
+		return false, ErrStorageClosed
+	}
+
+	s.apiKeysMu.Lock()
+	defer s.apiKeysMu.Unlock()
+
+	if err := s.loadAPIKeysLocked(); err != nil {
+		return false, err
+	}
+	key, exists := s.apiKeys[id]
+	if !exists || key.UserID != userID {
+		return false, nil
+	}
+	delete(s.apiKeys, id)
+	delete(s.apiKeyHashes, key.KeyHash)
+	return true, s.rewriteAPIKeysLocked()
+}
+
+// TouchAPIKey обновляет время последнего использования API-ключа в памяти.
+// Файл ключей перезаписывается не чаще, чем раз в apiKeysFlushInterval, и при
+// закрытии хранилища; при сбое теряется только время последнего использования.
+func (s *FileStorage) TouchAPIKey(ctx context.Context, id string, usedAt time.Time) error {
+	if s.isClosed() {
+		return ErrStorageClosed
+	}
+
+	s.apiKeysMu.Lock()
+	defer s.apiKeysMu.Unlock()
+
+	if err := s.loadAPIKeysLocked(); err != nil {
+		return err
+	}
+	key, exists := s.apiKeys[id]
+	if !exists {
+		return nil
+	}
+	key.LastUsedAt = &usedAt
+	s.apiKeys[id] = key
+	s.apiKeysDirty = true
+	if time.Since(s.apiKeysFlushed) < apiKeysFlushInterval {
+		return nil
+	}
+	return s.rewriteAPIKeysLocked()
+}
+
+// apiKeysPath возвращает путь к файлу API-ключей.
+func (s *FileStorage) apiKeysPath() string {
+	return s.filePath + ".apikeys"
+}
+
+// ensureAPIKeys загружает API-ключи, если они ещё не загружены, чтобы чтение
+// дальше шло под apiKeysMu.RLock.
+func (s *FileStorage) ensureAPIKeys() error {
+	s.apiKeysMu.RLock()
+	loaded := s.apiKeys != nil
+	s.apiKeysMu.RUnlock()
+	if loaded {
+		return nil
+	}
+
+	s.apiKeysMu.Lock()
+	defer s.apiKeysMu.Unlock()
+	return s.loadAPIKeysLocked()
+}
+
+// loadAPIKeysLocked загружает API-ключи из файла, если они ещё не загружены,
+// и строит индекс по хешу. Вызывающий должен удерживать s.apiKeysMu.
+func (s *FileStorage) loadAPIKeysLocked() error {
+	if s.apiKeys != nil {
+		return nil
+	}
+
+	keys := make(map[string]models.APIKeyModel)
+	file, err := os.Open(s.apiKeysPath())
+	if err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	if err == nil {
+		defer file.Close()
+		if keys, err = s.fileStorage.LoadAPIKeyRecords(file); err != nil {
+			return fmt.Errorf("failed to load API keys: %w", err)
 		}
 	}
 
-	s.data = data
+	s.apiKeyHashes = make(map[string]string, len(keys))
+	for id, key := range keys {
+		s.apiKeyHashes[key.KeyHash] = id
+	}
+	s.apiKeys = keys
 	return nil
 }
 
-// Ping проверяет соединение с базой данных (для файлового хранилища всегда возвращает nil).
-func (s *FileStorage) Ping(ctx context.Context) error {
+// rewriteAPIKeysLocked атомарно перезаписывает файл API-ключей:
+// ключи пишутся во временный файл, который сбрасывается на диск и затем
+// переименовывается. Вызывающий должен удерживать s.apiKeysMu.
+func (s *FileStorage) rewriteAPIKeysLocked() error {
+	keys := make([]models.APIKeyModel, 0, len(s.apiKeys))
+	for _, key := range s.apiKeys {
+		keys = append(keys, key)
+	}
+	storage.SortAPIKeys(keys)
+
+	tmpPath := s.apiKeysPath() + ".tmp"
+	file, err := os.OpenFile(tmpPath, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0600)
+	if err != nil {
+		return err
+	}
+	committed := false
+	defer func() {
+		if !committed {
+			file.Close()
+			os.Remove(tmpPath)
+		}
+	}()
+
+	if err := s.fileStorage.SaveAPIKeyRecords(file, keys); err != nil {
+		return err
+	}
+	if err := file.Sync(); err != nil {
+		return err
+	}
+	if err := file.Close(); err != nil {
+		return err
+	}
+	if err := os.Rename(tmpPath, s.apiKeysPath()); err != nil {
+		return err
+	}
+	committed = true
+	syncDir(s.apiKeysPath())
+
+	s.apiKeysDirty = false
+	s.apiKeysFlushed = time.Now()
 	return nil
 }
 
-// Close закрывает файловое хранилище.
-func (s *FileStorage) DeleteUserURLs(ctx context.Context, userID string, shortURLs []string) error {
-	// Загружаем все записи из файла
-	if err := s.LoadFromFile(); err != nil {
+// isClosed сообщает, закрыто ли хранилище. Используется методами, которые
+// работают под собственными блокировками и не держат s.mu.
+func (s *FileStorage) isClosed() bool {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.closed
+}
+
+// CreateUser дописывает пользователя в файл <path>.users.
+// Если логин занят, возвращает storage.ErrConflict.
+func (s *FileStorage) CreateUser(ctx context.Context, user models.UserModel) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.closed {
+		return ErrStorageClosed
+	}
+	if err := s.loadUsersLocked(); err != nil {
 		return err
 	}
+	if _, exists := s.users[user.Login]; exists {
+		return storage.ErrConflict
+	}
+
+	file, err := os.OpenFile(s.usersPath(), os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0600)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	info, err := file.Stat()
+	if err != nil {
+		return err
+	}
+	// Запись сбрасывается на диск сразу: потерянный после сбоя пользователь
+	// лишился бы доступа к уже перенесённым в аккаунт ссылкам
+	err = s.fileStorage.SaveUserRecord(file, user)
+	if err == nil {
+		err = file.Sync()
+	}
+	if err != nil {
+		// Возвращаем файл к последней целой записи, иначе следующая запись
+		// окажется после недописанной
+		if truncErr := file.Truncate(info.Size()); truncErr != nil {
+			s.users = nil
+			return errors.Join(err, truncErr)
+		}
+		return err
+	}
+	if info.Size() == 0 {
+		syncDir(s.usersPath())
+	}
+	s.users[user.Login] = user
+	return nil
+}
 
+// GetUserByLogin возвращает пользователя по логину.
+func (s *FileStorage) GetUserByLogin(ctx context.Context, login string) (models.UserModel, bool) {
 	s.mu.Lock()
 	defer s.mu.Unlock()
 
-	for _, shortURL := range shortURLs {
-		if urlModel, exists := s.data[shortURL]; exists && urlModel.UserID == userID {
-			urlModel.Deleted = true
-			s.data[shortURL] = urlModel
+	if err := s.loadUsersLocked(); err != nil {
+		return models.UserModel{}, false
+	}
+	user, exists := s.users[login]
+	return user, exists
+}
+
+// GetUserByID возвращает пользователя по идентификатору.
+func (s *FileStorage) GetUserByID(ctx context.Context, id string) (models.UserModel, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if err := s.loadUsersLocked(); err != nil {
+		return models.UserModel{}, false
+	}
+	for _, user := range s.users {
+		if user.ID == id {
+			return user, true
 		}
 	}
+	return models.UserModel{}, false
+}
 
-	// Открываем файл для записи и очищаем его перед записью
-	file, err := os.OpenFile(s.filePath, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0644)
-	if err != nil {
+// ReassignUserURLs передаёт URL и API-ключи пользователя fromUserID пользователю toUserID.
+// Смена владельца URL дописывается в журнал, затем перезаписывается файл API-ключей.
+func (s *FileStorage) ReassignUserURLs(ctx context.Context, fromUserID, toUserID string) (int, error) {
+	if err := s.ensureLoaded(); err != nil {
+		return 0, err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.closed {
+		return 0, ErrStorageClosed
+	}
+
+	ids := s.userData[fromUserID]
+	events := make([]fileutils.Event, 0, len(ids))
+	for _, id := range ids {
+		events = append(events, fileutils.Event{Op: fileutils.OpOwner, URL: models.URLModel{ID: id, UserID: toUserID}})
+	}
+
+	if err := s.appendLocked(events); err != nil {
+		return 0, err
+	}
+
+	s.apiKeysMu.Lock()
+	defer s.apiKeysMu.Unlock()
+	if err := s.loadAPIKeysLocked(); err != nil {
+		return 0, err
+	}
+	keysMoved := false
+	for id, key := range s.apiKeys {
+		if key.UserID == fromUserID {
+			key.UserID = toUserID
+			s.apiKeys[id] = key
+			keysMoved = true
+		}
+	}
+	if keysMoved {
+		if err := s.rewriteAPIKeysLocked(); err != nil {
+			return 0, fmt.Errorf("failed to reassign API keys: %w", err)
+		}
+	}
+	return len(events), nil
+}
+
+// usersPath возвращает путь к файлу пользователей.
+func (s *FileStorage) usersPath() string {
+	return s.filePath + ".users"
+}
+
+// loadUsersLocked загружает пользователей из файла, если они ещё не загружены.
+// Оборванный хвост файла обрезается, как и у журнала URL.
+// Вызывающий должен удерживать s.mu.
+func (s *FileStorage) loadUsersLocked() error {
+	if s.users != nil {
+		return nil
+	}
+
+	file, err := os.OpenFile(s.usersPath(), os.O_RDWR, 0)
+	if os.IsNotExist(err) {
+		s.users = make(map[string]models.UserModel)
+		return nil
+	} else if err != nil {
 		return err
 	}
 	defer file.Close()
 
-	// Записываем все обновленные записи обратно в файл
-	for _, urlModel := range s.data {
-		if err := s.fileStorage.SaveRecord(file, urlModel); err != nil {
+	users, report, err := s.fileStorage.LoadUserRecords(file)
+	if err != nil {
+		return fmt.Errorf("failed to load users: %w", err)
+	}
+	if report.TailSize > 0 {
+		if err := file.Truncate(report.ValidSize); err != nil {
+			return fmt.Errorf("failed to truncate torn tail of %s: %w", s.usersPath(), err)
+		}
+		logger.L().Warn("Truncated torn tail of users file",
+			zap.String("path", s.usersPath()), zap.Int64("size", report.TailSize), zap.Int64("offset", report.ValidSize))
+	}
+	if report.Unterminated {
+		if _, err := file.WriteAt([]byte("\n"), report.ValidSize); err != nil {
 			return err
 		}
 	}
-
+	s.users = users
 	return nil
 }
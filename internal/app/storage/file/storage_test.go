@@ -1,13 +1,23 @@
 package file
 
 import (
+	"bytes"
 	"context"
 	"encoding/json"
+	"errors"
+	"fmt"
 	"os"
+	"path/filepath"
+	"strings"
 	"testing"
+	"time"
 
+	"github.com/alexuryumtsev/go-shortener/internal/app/fileutils"
 	"github.com/alexuryumtsev/go-shortener/internal/app/models"
+	appstorage "github.com/alexuryumtsev/go-shortener/internal/app/storage"
+	"github.com/alexuryumtsev/go-shortener/internal/app/storage/storagetest"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
 func TestStorage_SaveAndLoad(t *testing.T) {
@@ -71,9 +81,15 @@ func TestStorage_SaveToFileFormat(t *testing.T) {
 	err := storage.Save(ctx, urlModel)
 	assert.NoError(t, err)
 
-	file, err := os.Open(filePath)
-	assert.NoError(t, err)
-	defer file.Close()
+	content, err := os.ReadFile(filePath)
+	require.NoError(t, err)
+
+	// Запись: "<длина, 8 hex> <CRC-32C, 8 hex> <JSON>\n"
+	line := strings.TrimSuffix(string(content), "\n")
+	require.Greater(t, len(line), 18)
+	assert.Equal(t, fmt.Sprintf("%08x", len(line)-18), line[:8])
+	assert.Equal(t, " ", line[8:9])
+	assert.Equal(t, " ", line[17:18])
 
 	var record struct {
 		UUID        string `json:"uuid"`
@@ -82,11 +98,512 @@ func TestStorage_SaveToFileFormat(t *testing.T) {
 		Deleted     bool   `json:"is_deleted"`
 	}
 
-	decoder := json.NewDecoder(file)
-	err = decoder.Decode(&record)
+	err = json.Unmarshal([]byte(line[18:]), &record)
 	assert.NoError(t, err)
 
 	assert.Equal(t, "1", record.UUID)
 	assert.Equal(t, "4rSPg8ap", record.ShortURL)
 	assert.Equal(t, "http://yandex.ru", record.OriginalURL)
 }
+
+func TestStorage_DeleteExpired(t *testing.T) {
+	filePath := "test_storage_expired.json"
+	defer os.Remove(filePath)
+	defer os.Remove(filePath + ".archive")
+
+	storage := NewFileStorage(filePath)
+	ctx := context.Background()
+	now := time.Now().UTC().Truncate(time.Second)
+
+	expired := models.URLModel{ID: "expired", URL: "https://old.com", UserID: "user", ExpiresAt: now.Add(-time.Minute)}
+	active := models.URLModel{ID: "active", URL: "https://new.com", UserID: "user", ExpiresAt: now.Add(time.Hour)}
+	assert.NoError(t, storage.Save(ctx, expired))
+	assert.NoError(t, storage.Save(ctx, active))
+
+	removed, err := storage.DeleteExpired(ctx, now, true)
+	assert.NoError(t, err)
+	assert.Equal(t, 1, removed)
+
+	// Данные из файла загружаются заново
+	newStorage := NewFileStorage(filePath)
+	assert.NoError(t, newStorage.LoadFromFile())
+
+	_, exists := newStorage.Get(ctx, "expired")
+	assert.False(t, exists)
+
+	result, exists := newStorage.Get(ctx, "active")
+	assert.True(t, exists)
+	assert.True(t, active.ExpiresAt.Equal(result.ExpiresAt))
+
+	// Истёкшая запись перенесена в архив
+	archive, err := os.ReadFile(filePath + ".archive")
+	assert.NoError(t, err)
+	assert.Contains(t, string(archive), `"short_url":"expired"`)
+}
+
+func TestStorage_Clicks(t *testing.T) {
+	filePath := filepath.Join(t.TempDir(), "storage.json")
+	ctx := context.Background()
+	day := time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC)
+
+	storage := NewFileStorage(filePath)
+	stats, err := storage.GetClickStats(ctx, "abc")
+	require.NoError(t, err)
+	assert.Zero(t, stats.TotalClicks)
+
+	require.NoError(t, storage.SaveClicks(ctx, []models.ClickEvent{
+		{ShortID: "abc", Timestamp: day, IPHash: "a"},
+		{ShortID: "abc", Timestamp: day.Add(time.Hour), IPHash: "a"},
+		{ShortID: "other", Timestamp: day, IPHash: "b"},
+	}))
+	// Статистика обновляется в памяти без повторного чтения файла
+	require.NoError(t, storage.SaveClicks(ctx, []models.ClickEvent{
+		{ShortID: "abc", Timestamp: day.Add(24 * time.Hour), IPHash: "c"},
+	}))
+
+	want := models.LinkStatsModel{
+		TotalClicks:    3,
+		UniqueVisitors: 2,
+		Daily: []models.DailyStatsModel{
+			{Date: "2024-03-01", Clicks: 2, UniqueVisitors: 1},
+			{Date: "2024-03-02", Clicks: 1, UniqueVisitors: 1},
+		},
+	}
+	stats, err = storage.GetClickStats(ctx, "abc")
+	require.NoError(t, err)
+	assert.Equal(t, want, stats)
+
+	// Статистика загружается из файла заново
+	stats, err = NewFileStorage(filePath).GetClickStats(ctx, "abc")
+	require.NoError(t, err)
+	assert.Equal(t, want, stats)
+
+	// Недописанная при сбое запись обрезается, и новые записи читаются
+	clicks, err := os.OpenFile(filePath+".clicks", os.O_APPEND|os.O_WRONLY, 0)
+	require.NoError(t, err)
+	_, err = clicks.WriteString(`{"short_url":"abc","ts":`)
+	require.NoError(t, err)
+	require.NoError(t, clicks.Close())
+
+	storage = NewFileStorage(filePath)
+	require.NoError(t, storage.SaveClicks(ctx, []models.ClickEvent{{ShortID: "abc", Timestamp: day, IPHash: "d"}}))
+	stats, err = NewFileStorage(filePath).GetClickStats(ctx, "abc")
+	require.NoError(t, err)
+	assert.Equal(t, 4, stats.TotalClicks)
+}
+
+func TestStorage_APIKeys(t *testing.T) {
+	filePath := filepath.Join(t.TempDir(), "storage.json")
+	ctx := context.Background()
+	createdAt := time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC)
+
+	storage := NewFileStorage(filePath)
+	key := models.APIKeyModel{ID: "key1", UserID: "user", Name: "ci", KeyHash: "hash", Scopes: []string{"urls:read"}, CreatedAt: createdAt}
+	assert.NoError(t, storage.SaveAPIKey(ctx, key))
+	assert.NoError(t, storage.TouchAPIKey(ctx, "key1", createdAt.Add(time.Hour)))
+
+	// Время использования сразу после записи ключа хранится только в памяти
+	loaded, exists := storage.GetAPIKeyByHash(ctx, "hash")
+	assert.True(t, exists)
+	assert.NotNil(t, loaded.LastUsedAt)
+	loaded, _ = NewFileStorage(filePath).GetAPIKeyByHash(ctx, "hash")
+	assert.Nil(t, loaded.LastUsedAt)
+	_, err := os.Stat(filePath + ".apikeys.tmp")
+	assert.ErrorIs(t, err, os.ErrNotExist)
+
+	// и записывается в файл при закрытии хранилища
+	require.NoError(t, storage.Close())
+
+	// Ключи загружаются из файла заново
+	newStorage := NewFileStorage(filePath)
+	loaded, exists = newStorage.GetAPIKeyByHash(ctx, "hash")
+	assert.True(t, exists)
+	assert.Equal(t, "ci", loaded.Name)
+	if assert.NotNil(t, loaded.LastUsedAt) {
+		assert.True(t, createdAt.Add(time.Hour).Equal(*loaded.LastUsedAt))
+	}
+
+	// Проверка ключа не ждёт записи URL, удерживающей основную блокировку
+	found := make(chan bool)
+	newStorage.mu.Lock()
+	go func() {
+		_, exists := newStorage.GetAPIKeyByHash(ctx, "hash")
+		found <- exists
+	}()
+	select {
+	case exists = <-found:
+		assert.True(t, exists)
+	case <-time.After(time.Second):
+		t.Error("GetAPIKeyByHash is blocked by the URL log lock")
+	}
+	newStorage.mu.Unlock()
+
+	// Индекс по хешу следует за заменой ключа
+	rotated := key
+	rotated.KeyHash = "rotated"
+	require.NoError(t, newStorage.SaveAPIKey(ctx, rotated))
+	_, exists = newStorage.GetAPIKeyByHash(ctx, "hash")
+	assert.False(t, exists)
+	_, exists = newStorage.GetAPIKeyByHash(ctx, "rotated")
+	assert.True(t, exists)
+
+	revoked, err := newStorage.RevokeAPIKey(ctx, "other", "key1")
+	assert.NoError(t, err)
+	assert.False(t, revoked)
+
+	revoked, err = newStorage.RevokeAPIKey(ctx, "user", "key1")
+	assert.NoError(t, err)
+	assert.True(t, revoked)
+	_, exists = newStorage.GetAPIKeyByHash(ctx, "rotated")
+	assert.False(t, exists)
+
+	keys, err := NewFileStorage(filePath).GetUserAPIKeys(ctx, "user")
+	assert.NoError(t, err)
+	assert.Empty(t, keys)
+}
+
+func TestStorage_Users(t *testing.T) {
+	filePath := filepath.Join(t.TempDir(), "storage.json")
+	ctx := context.Background()
+
+	storage := NewFileStorage(filePath)
+	assert.NoError(t, storage.Save(ctx, models.URLModel{ID: "anon", URL: "https://example.com", UserID: "anonymous"}))
+	assert.NoError(t, storage.CreateUser(ctx, models.UserModel{ID: "account", Login: "alice", PasswordHash: "hash"}))
+	assert.ErrorIs(t, storage.CreateUser(ctx, models.UserModel{ID: "other", Login: "alice"}), appstorage.ErrConflict)
+
+	merged, err := storage.ReassignUserURLs(ctx, "anonymous", "account")
+	assert.NoError(t, err)
+	assert.Equal(t, 1, merged)
+
+	// Пользователи и владельцы ссылок загружаются из файлов заново
+	newStorage := NewFileStorage(filePath)
+	user, exists := newStorage.GetUserByLogin(ctx, "alice")
+	assert.True(t, exists)
+	assert.Equal(t, "account", user.ID)
+
+	_, exists = newStorage.GetUserByID(ctx, "account")
+	assert.True(t, exists)
+
+	urlModel, exists := newStorage.Get(ctx, "anon")
+	assert.True(t, exists)
+	assert.Equal(t, "account", urlModel.UserID)
+
+	// Недописанная при сбое запись обрезается, и новые пользователи читаются
+	users, err := os.OpenFile(filePath+".users", os.O_APPEND|os.O_WRONLY, 0)
+	require.NoError(t, err)
+	_, err = users.WriteString(`0000002a 1234abcd {"id":"torn"`)
+	require.NoError(t, err)
+	require.NoError(t, users.Close())
+
+	assert.NoError(t, NewFileStorage(filePath).CreateUser(ctx, models.UserModel{ID: "bob-id", Login: "bob", PasswordHash: "hash"}))
+	newStorage = NewFileStorage(filePath)
+	_, exists = newStorage.GetUserByLogin(ctx, "alice")
+	assert.True(t, exists)
+	_, exists = newStorage.GetUserByLogin(ctx, "bob")
+	assert.True(t, exists)
+}
+
+func TestStorage_EventLog(t *testing.T) {
+	filePath := filepath.Join(t.TempDir(), "storage.json")
+	ctx := context.Background()
+
+	// Записи в старом формате, без типа события, читаются как создание
+	legacy := `{"uuid":"user","short_url":"legacy","original_url":"https://legacy.com","is_deleted":false}` + "\n"
+	assert.NoError(t, os.WriteFile(filePath, []byte(legacy), 0644))
+
+	storage := NewFileStorage(filePath)
+	_, err := storage.SaveBatch(ctx, []models.URLModel{
+		{ID: "a", URL: "https://a.com", UserID: "user"},
+		{ID: "b", URL: "https://b.com", UserID: "user"},
+		{ID: "a", URL: "https://dup.com", UserID: "user"},
+	})
+	assert.NoError(t, err)
+	assert.ErrorIs(t, storage.Save(ctx, models.URLModel{ID: "legacy", URL: "https://c.com", UserID: "other"}), appstorage.ErrConflict)
+	assert.NoError(t, storage.DeleteUserURLs(ctx, "user", []string{"a", "legacy"}))
+	assert.NoError(t, storage.DeleteUserURLs(ctx, "other", []string{"b"}))
+
+	// Удаление дописывает события, а не переписывает файл
+	content, err := os.ReadFile(filePath)
+	assert.NoError(t, err)
+	assert.True(t, strings.HasPrefix(string(content), legacy))
+	assert.Contains(t, string(content), `"op":"delete"`)
+
+	// Индексы восстанавливаются из журнала
+	newStorage := NewFileStorage(filePath)
+	urls, err := newStorage.GetUserURLs(ctx, "user")
+	assert.NoError(t, err)
+	if assert.Len(t, urls, 3) {
+		assert.Equal(t, []string{"legacy", "a", "b"}, []string{urls[0].ID, urls[1].ID, urls[2].ID})
+		assert.True(t, urls[0].Deleted)
+		assert.True(t, urls[1].Deleted)
+		assert.False(t, urls[2].Deleted)
+	}
+
+	result, exists := newStorage.Get(ctx, "a")
+	assert.True(t, exists)
+	assert.True(t, result.Deleted)
+
+	_, exists = newStorage.GetByOriginalURL(ctx, "https://dup.com")
+	assert.False(t, exists, "duplicate short ID is not saved")
+	_, exists = newStorage.GetByOriginalURL(ctx, "https://c.com")
+	assert.False(t, exists, "duplicate short ID is not saved")
+
+	result, exists = newStorage.GetByOriginalURL(ctx, "https://b.com")
+	assert.True(t, exists)
+	assert.Equal(t, "b", result.ID)
+}
+
+func TestStorage_Compact(t *testing.T) {
+	filePath := filepath.Join(t.TempDir(), "storage.json")
+	ctx := context.Background()
+
+	storage := NewFileStorage(filePath)
+	defer storage.Close()
+	for _, id := range []string{"a", "b", "c"} {
+		assert.NoError(t, storage.Save(ctx, models.URLModel{ID: id, URL: "https://" + id + ".com", UserID: "anonymous"}))
+	}
+	assert.NoError(t, storage.DeleteUserURLs(ctx, "anonymous", []string{"b"}))
+	_, err := storage.ReassignUserURLs(ctx, "anonymous", "account")
+	assert.NoError(t, err)
+	assert.NoError(t, storage.Save(ctx, models.URLModel{ID: "d", URL: "https://d.com", UserID: "account", ExpiresAt: time.Now().Add(-time.Hour)}))
+	_, err = storage.DeleteExpired(ctx, time.Now(), false)
+	assert.NoError(t, err)
+
+	before, err := os.Stat(filePath)
+	assert.NoError(t, err)
+	want, err := storage.GetUserURLs(ctx, "account")
+	assert.NoError(t, err)
+
+	assert.NoError(t, storage.Compact())
+
+	// В сжатом журнале по одному событию на каждый URL
+	after, err := os.Stat(filePath)
+	assert.NoError(t, err)
+	assert.Less(t, after.Size(), before.Size())
+	content, err := os.ReadFile(filePath)
+	assert.NoError(t, err)
+	assert.Equal(t, 3, strings.Count(string(content), "\n"))
+	_, err = os.Stat(filePath + ".compact")
+	assert.True(t, os.IsNotExist(err), "temporary file is removed")
+
+	// Запись после сжатия попадает в новый журнал
+	assert.NoError(t, storage.Save(ctx, models.URLModel{ID: "e", URL: "https://e.com", UserID: "account"}))
+	want = append(want, models.URLModel{ID: "e", URL: "https://e.com", UserID: "account"})
+
+	got, err := NewFileStorage(filePath).GetUserURLs(ctx, "account")
+	assert.NoError(t, err)
+	assert.Equal(t, want, got)
+
+	assert.NoError(t, storage.Close())
+	assert.ErrorIs(t, storage.Save(ctx, models.URLModel{ID: "f", URL: "https://f.com"}), ErrStorageClosed)
+	assert.ErrorIs(t, storage.Compact(), ErrStorageClosed)
+}
+
+func TestStorage_SyncInterval(t *testing.T) {
+	filePath := filepath.Join(t.TempDir(), "storage.json")
+	ctx := context.Background()
+
+	policy, err := ParseSyncPolicy("interval")
+	assert.NoError(t, err)
+	_, err = ParseSyncPolicy("sometimes")
+	assert.Error(t, err)
+
+	storage := NewFileStorage(filePath, WithSync(policy, time.Millisecond), WithCompaction(time.Millisecond))
+	assert.NoError(t, storage.Save(ctx, models.URLModel{ID: "a", URL: "https://a.com"}))
+	assert.Eventually(t, func() bool {
+		storage.mu.RLock()
+		defer storage.mu.RUnlock()
+		return !storage.dirty
+	}, time.Second, time.Millisecond)
+	assert.NoError(t, storage.Close())
+
+	_, exists := NewFileStorage(filePath).Get(ctx, "a")
+	assert.True(t, exists)
+}
+
+func TestStorage_TornTail(t *testing.T) {
+	filePath := filepath.Join(t.TempDir(), "storage.json")
+	ctx := context.Background()
+
+	storage := NewFileStorage(filePath)
+	require.NoError(t, storage.Save(ctx, models.URLModel{ID: "a", URL: "https://a.com", UserID: "user"}))
+	require.NoError(t, storage.Close())
+
+	// Имитируем сбой во время записи: в конце журнала недописанная запись
+	content, err := os.ReadFile(filePath)
+	require.NoError(t, err)
+	torn := append(bytes.Clone(content), content[:len(content)/2]...)
+	require.NoError(t, os.WriteFile(filePath, torn, 0644))
+
+	// Хвост обрезается при загрузке, новые записи дописываются после целых
+	storage = NewFileStorage(filePath)
+	require.NoError(t, storage.Save(ctx, models.URLModel{ID: "b", URL: "https://b.com", UserID: "user"}))
+	require.NoError(t, storage.Close())
+
+	storage = NewFileStorage(filePath)
+	defer storage.Close()
+	urls, err := storage.GetUserURLs(ctx, "user")
+	require.NoError(t, err)
+	assert.Len(t, urls, 2)
+}
+
+func TestStorage_CorruptedRecord(t *testing.T) {
+	filePath := filepath.Join(t.TempDir(), "storage.json")
+	ctx := context.Background()
+
+	storage := NewFileStorage(filePath)
+	_, err := storage.SaveBatch(ctx, []models.URLModel{
+		{ID: "a", URL: "https://a.com", UserID: "user"},
+		{ID: "b", URL: "https://b.com", UserID: "user"},
+		{ID: "c", URL: "https://c.com", UserID: "user"},
+	})
+	require.NoError(t, err)
+	require.NoError(t, storage.Close())
+
+	// Портим запись в середине журнала
+	content, err := os.ReadFile(filePath)
+	require.NoError(t, err)
+	second := bytes.IndexByte(content, '\n') + 1
+	content[second+bytes.Index(content[second:], []byte("b.com"))] = 'x'
+	require.NoError(t, os.WriteFile(filePath, content, 0644))
+
+	// Хранилище не загружается молча с потерей данных
+	storage = NewFileStorage(filePath)
+	_, err = storage.GetUserURLs(ctx, "user")
+	assert.ErrorIs(t, err, fileutils.ErrCorruptedRecord)
+	assert.ErrorContains(t, err, "fsck")
+	require.NoError(t, storage.Close())
+
+	// Проверка без восстановления не меняет файл
+	result, err := Check(filePath, false)
+	require.NoError(t, err)
+	assert.True(t, result.Damaged())
+	assert.False(t, result.Repaired)
+	assert.Equal(t, 2, result.URLs)
+	if assert.Len(t, result.Skipped, 1) {
+		assert.Equal(t, int64(second), result.Skipped[0].Offset)
+	}
+	unchanged, err := os.ReadFile(filePath)
+	require.NoError(t, err)
+	assert.Equal(t, content, unchanged)
+
+	// Восстановление отбрасывает повреждённую запись
+	result, err = Check(filePath, true)
+	require.NoError(t, err)
+	assert.True(t, result.Repaired)
+
+	result, err = Check(filePath, false)
+	require.NoError(t, err)
+	assert.False(t, result.Damaged())
+
+	storage = NewFileStorage(filePath)
+	defer storage.Close()
+	urls, err := storage.GetUserURLs(ctx, "user")
+	require.NoError(t, err)
+	if assert.Len(t, urls, 2) {
+		assert.Equal(t, []string{"a", "c"}, []string{urls[0].ID, urls[1].ID})
+	}
+}
+
+func TestStorage_FailedWrite(t *testing.T) {
+	filePath := filepath.Join(t.TempDir(), "storage.json")
+	ctx := context.Background()
+
+	storage := NewFileStorage(filePath)
+	defer storage.Close()
+	require.NoError(t, storage.Save(ctx, models.URLModel{ID: "a", URL: "https://a.com", UserID: "user"}))
+
+	// Подменяем журнал дескриптором только для чтения: запись и обрезка завершаются ошибкой
+	readOnly, err := os.Open(filePath)
+	require.NoError(t, err)
+	storage.mu.Lock()
+	writable := storage.log
+	storage.log = readOnly
+	storage.mu.Unlock()
+
+	err = storage.Save(ctx, models.URLModel{ID: "b", URL: "https://b.com", UserID: "user"})
+	require.Error(t, err)
+	_, exists := storage.Get(ctx, "b")
+	assert.False(t, exists, "failed write must not reach the index")
+
+	// Журнал не удалось вернуть к целой записи: записи отклоняются до перезагрузки
+	err = storage.Save(ctx, models.URLModel{ID: "c", URL: "https://c.com", UserID: "user"})
+	assert.ErrorContains(t, err, "inconsistent after failed write")
+
+	storage.mu.Lock()
+	storage.log = writable
+	storage.mu.Unlock()
+	require.NoError(t, storage.LoadFromFile())
+	require.NoError(t, storage.Save(ctx, models.URLModel{ID: "c", URL: "https://c.com", UserID: "user"}))
+
+	// Некорректное событие отклоняется до записи и не делает журнал незагружаемым
+	assert.Error(t, storage.Save(ctx, models.URLModel{ID: "d", UserID: "user"}))
+	reopened := NewFileStorage(filePath)
+	defer reopened.Close()
+	urls, err := reopened.GetUserURLs(ctx, "user")
+	require.NoError(t, err)
+	assert.Len(t, urls, 2)
+}
+
+func TestStorage_LoadErrorCached(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "missing")
+	ctx := context.Background()
+
+	storage := NewFileStorage(filepath.Join(dir, "storage.json"))
+	defer storage.Close()
+	_, err := storage.GetUserURLs(ctx, "user")
+	require.Error(t, err)
+
+	// Ошибка загрузки запоминается, журнал не перечитывается при каждом обращении
+	require.NoError(t, os.MkdirAll(dir, 0755))
+	_, err = storage.GetUserURLs(ctx, "user")
+	assert.Error(t, err)
+
+	// Явная загрузка сбрасывает ошибку
+	require.NoError(t, storage.LoadFromFile())
+	_, err = storage.GetUserURLs(ctx, "user")
+	assert.NoError(t, err)
+}
+
+func TestStorage_CheckHealth(t *testing.T) {
+	filePath := filepath.Join(t.TempDir(), "storage.json")
+	ctx := context.Background()
+
+	storage := NewFileStorage(filePath)
+	require.NoError(t, storage.Save(ctx, models.URLModel{ID: "a", URL: "https://a.com", UserID: "user"}))
+	health := storage.CheckHealth(ctx)
+	assert.Equal(t, appstorage.HealthUp, health["file"].Status)
+	assert.Equal(t, 1, health["file"].Details["events"])
+	assert.NotEqual(t, appstorage.HealthDown, health["disk"].Status)
+
+	// Журнал, не возвращённый к целой записи после сбоя, отклоняет запись
+	storage.mu.Lock()
+	storage.writeErr = errors.New("truncate failed")
+	storage.mu.Unlock()
+	health = storage.CheckHealth(ctx)
+	assert.Equal(t, appstorage.HealthDown, health["file"].Status)
+	assert.Equal(t, "truncate failed", health["file"].Error)
+	storage.mu.Lock()
+	storage.writeErr = nil
+	storage.mu.Unlock()
+
+	// Закрытое хранилище не принимает записи
+	require.NoError(t, storage.Close())
+	health = storage.CheckHealth(ctx)
+	assert.Equal(t, appstorage.HealthDown, health["file"].Status)
+	assert.Equal(t, ErrStorageClosed.Error(), health["file"].Error)
+
+	// Повреждённый журнал не загружается
+	require.NoError(t, os.WriteFile(filePath, []byte("garbage\n{\"short_url\":\"b\"}\n"), 0644))
+	storage = NewFileStorage(filePath)
+	defer storage.Close()
+	health = storage.CheckHealth(ctx)
+	assert.Equal(t, appstorage.HealthDown, health["file"].Status)
+	assert.Contains(t, health["file"].Error, "fsck")
+}
+
+func TestConformance(t *testing.T) {
+	storagetest.Run(t, func(t *testing.T) appstorage.URLStorage {
+		return NewFileStorage(filepath.Join(t.TempDir(), "storage.json"))
+	})
+}
@@ -0,0 +1,75 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+
+	"github.com/alexuryumtsev/go-shortener/internal/app/fileutils"
+)
+
+// HealthStatus — состояние компонента хранилища.
+type HealthStatus string
+
+// Состояния компонентов.
+const (
+	// HealthUp — компонент работает
+	HealthUp HealthStatus = "up"
+	// HealthDegraded — компонент работает, но близок к отказу;
+	// сервис при этом остаётся готовым принимать запросы
+	HealthDegraded HealthStatus = "degraded"
+	// HealthDown — компонент не работает, сервис не готов принимать запросы
+	HealthDown HealthStatus = "down"
+)
+
+// ComponentHealth — результат проверки компонента хранилища.
+type ComponentHealth struct {
+	Status  HealthStatus   `json:"status"`
+	Error   string         `json:"error,omitempty"`
+	Details map[string]any `json:"details,omitempty"`
+}
+
+// HealthChecker определяет проверку состояния хранилища.
+type HealthChecker interface {
+	// CheckHealth проверяет компоненты хранилища (соединение с базой, файл,
+	// диск) и возвращает их состояние по именам компонентов.
+	CheckHealth(ctx context.Context) map[string]ComponentHealth
+}
+
+// MinFreeDiskSpace — свободное место на диске, при котором хранилище
+// считается неработающим: следующие записи могут не поместиться.
+const MinFreeDiskSpace = 64 << 20
+
+// lowDiskSpaceRatio — доля свободного места, ниже которой диск отмечается HealthDegraded.
+const lowDiskSpaceRatio = 0.1
+
+// CheckDirectory проверяет, что в каталог dir можно записать файл
+// и на его разделе есть свободное место.
+func CheckDirectory(dir string) ComponentHealth {
+	details := map[string]any{"path": dir}
+
+	probe, err := os.CreateTemp(dir, ".healthcheck-*")
+	if err != nil {
+		return ComponentHealth{Status: HealthDown, Error: fmt.Sprintf("directory is not writable: %v", err), Details: details}
+	}
+	probe.Close()
+	os.Remove(probe.Name())
+
+	free, total, err := fileutils.DiskSpace(dir)
+	if errors.Is(err, errors.ErrUnsupported) {
+		return ComponentHealth{Status: HealthUp, Details: details}
+	} else if err != nil {
+		return ComponentHealth{Status: HealthDown, Error: fmt.Sprintf("failed to get disk space: %v", err), Details: details}
+	}
+	details["free_bytes"] = free
+	details["total_bytes"] = total
+
+	switch {
+	case free < MinFreeDiskSpace:
+		return ComponentHealth{Status: HealthDown, Error: "not enough disk space", Details: details}
+	case float64(free) < float64(total)*lowDiskSpaceRatio:
+		return ComponentHealth{Status: HealthDegraded, Error: "low disk space", Details: details}
+	}
+	return ComponentHealth{Status: HealthUp, Details: details}
+}
@@ -3,67 +3,131 @@ package memory
 
 import (
 	"context"
+	"fmt"
 	"sync"
+	"time"
 
 	"github.com/alexuryumtsev/go-shortener/internal/app/models"
+	"github.com/alexuryumtsev/go-shortener/internal/app/storage"
 )
 
 // InMemoryStorage управляет сохранением и получением данных в памяти.
+// data — основное хранилище URL; byURL и userData — индексы коротких ID
+// по оригинальному URL и по пользователю (в порядке создания).
 type InMemoryStorage struct {
 	mu       sync.RWMutex
-	data     map[string]string
-	userData map[string][]models.URLModel
+	data     map[string]models.URLModel
+	byURL    map[string][]string
+	userData map[string][]string
+	clicks   map[string][]models.ClickEvent
+	apiKeys  map[string]models.APIKeyModel
+	users    map[string]models.UserModel
 }
 
 // NewInMemoryStorage создаёт новое хранилище в памяти.
 func NewInMemoryStorage() *InMemoryStorage {
 	return &InMemoryStorage{
-		data:     make(map[string]string),
-		userData: make(map[string][]models.URLModel),
+		data:     make(map[string]models.URLModel),
+		byURL:    make(map[string][]string),
+		userData: make(map[string][]string),
+		clicks:   make(map[string][]models.ClickEvent),
+		apiKeys:  make(map[string]models.APIKeyModel),
+		users:    make(map[string]models.UserModel),
 	}
 }
 
 // Save сохраняет URL в памяти.
+// Если короткий ID уже занят, возвращает storage.ErrConflict.
 func (s *InMemoryStorage) Save(ctx context.Context, urlModel models.URLModel) error {
 	s.mu.Lock()
 	defer s.mu.Unlock()
-	s.data[urlModel.ID] = urlModel.URL
-	userID := urlModel.UserID
-	s.userData[userID] = append(s.userData[userID], urlModel)
+	if _, exists := s.data[urlModel.ID]; exists {
+		return fmt.Errorf("%w: short URL %q already exists", storage.ErrConflict, urlModel.ID)
+	}
+	s.addLocked(urlModel)
 	return nil
 }
 
 // SaveBatch сохраняет множество URL в памяти.
-func (s *InMemoryStorage) SaveBatch(ctx context.Context, urlModels []models.URLModel) error {
+// URL с уже занятыми короткими ID пропускаются, их ID возвращаются в skipped.
+func (s *InMemoryStorage) SaveBatch(ctx context.Context, urlModels []models.URLModel) (skipped []string, err error) {
 	s.mu.Lock()
 	defer s.mu.Unlock()
 	for _, urlModel := range urlModels {
-		userID := urlModel.UserID
-		s.data[urlModel.ID] = urlModel.URL
-		s.userData[userID] = append(s.userData[userID], urlModel)
+		if _, exists := s.data[urlModel.ID]; exists {
+			skipped = append(skipped, urlModel.ID)
+			continue
+		}
+		s.addLocked(urlModel)
 	}
-	return nil
+	return skipped, nil
 }
 
 // Get возвращает оригинальный URL по идентификатору из памяти.
 func (s *InMemoryStorage) Get(ctx context.Context, id string) (models.URLModel, bool) {
 	s.mu.RLock()
 	defer s.mu.RUnlock()
-	url, exists := s.data[id]
-	return models.URLModel{ID: id, URL: url}, exists
+	urlModel, exists := s.data[id]
+	return urlModel, exists
 }
 
-// GetUserURLs возвращает все URL, сокращённые пользователем.
+// GetByOriginalURL возвращает сокращённый URL по оригинальному URL из памяти.
+// Если у URL несколько коротких ссылок, выбирается предпочтительная (см. storage.PreferURL).
+func (s *InMemoryStorage) GetByOriginalURL(ctx context.Context, originalURL string) (models.URLModel, bool) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	var (
+		best   models.URLModel
+		exists bool
+	)
+	for _, id := range s.byURL[originalURL] {
+		if urlModel := s.data[id]; !exists || storage.PreferURL(urlModel, best) {
+			best, exists = urlModel, true
+		}
+	}
+	return best, exists
+}
+
+// GetUserURLs возвращает все URL, сокращённые пользователем, в порядке создания.
 func (s *InMemoryStorage) GetUserURLs(ctx context.Context, userID string) ([]models.URLModel, error) {
 	s.mu.RLock()
 	defer s.mu.RUnlock()
-	urls, exists := s.userData[userID]
-	if !exists {
+	ids := s.userData[userID]
+	if len(ids) == 0 {
 		return nil, nil
 	}
+	urls := make([]models.URLModel, 0, len(ids))
+	for _, id := range ids {
+		urls = append(urls, s.data[id])
+	}
 	return urls, nil
 }
 
+// GetStats возвращает сводную статистику хранилища.
+func (s *InMemoryStorage) GetStats(ctx context.Context) (models.StatsModel, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	urls := make([]models.URLModel, 0, len(s.data))
+	for _, urlModel := range s.data {
+		urls = append(urls, urlModel)
+	}
+	return storage.CountStats(urls, time.Now()), nil
+}
+
+// ForEachURL вызывает fn для URL с коротким ID больше afterID в порядке возрастания ID.
+func (s *InMemoryStorage) ForEachURL(ctx context.Context, afterID string, fn func(models.URLModel) error) error {
+	s.mu.RLock()
+	urls := make([]models.URLModel, 0, len(s.data))
+	for id, urlModel := range s.data {
+		if id > afterID {
+			urls = append(urls, urlModel)
+		}
+	}
+	s.mu.RUnlock()
+	return storage.ForEachSorted(ctx, urls, fn)
+}
+
 // LoadFromFile загружает данные из памяти (не требуется для памяти).
 func (s *InMemoryStorage) LoadFromFile() error {
 	return nil
@@ -74,22 +138,222 @@ func (s *InMemoryStorage) Ping(ctx context.Context) error {
 	return nil
 }
 
-// DeleteUserURLs удаляет URL, сокращённые пользователем.
+// CheckHealth возвращает состояние хранилища в памяти: оно работает всегда.
+func (s *InMemoryStorage) CheckHealth(ctx context.Context) map[string]storage.ComponentHealth {
+	s.mu.RLock()
+	urls := len(s.data)
+	s.mu.RUnlock()
+
+	return map[string]storage.ComponentHealth{
+		"memory": {Status: storage.HealthUp, Details: map[string]any{"urls": urls}},
+	}
+}
+
+// Close закрывает хранилище в памяти (всегда возвращает nil).
+func (s *InMemoryStorage) Close() error {
+	return nil
+}
+
+// DeleteUserURLs помечает URL пользователя как удалённые.
+// Чужие и несуществующие URL пропускаются.
 func (s *InMemoryStorage) DeleteUserURLs(ctx context.Context, userID string, shortURLs []string) error {
 	s.mu.Lock()
 	defer s.mu.Unlock()
 
 	for _, shortURL := range shortURLs {
-		if url, exists := s.data[shortURL]; exists {
-			for i, userURL := range s.userData[userID] {
-				if userURL.ID == shortURL {
-					s.userData[userID][i].Deleted = true
-					break
-				}
-			}
-			s.data[shortURL] = url
+		if urlModel, exists := s.data[shortURL]; exists && urlModel.UserID == userID {
+			urlModel.Deleted = true
+			s.data[shortURL] = urlModel
+		}
+	}
+
+	return nil
+}
+
+// DeleteExpired удаляет из памяти URL, срок действия которых истёк к моменту now.
+// Вместе с URL удаляется статистика переходов по нему. Параметр archive игнорируется:
+// архив в памяти не переживёт перезапуск и только расходовал бы память.
+func (s *InMemoryStorage) DeleteExpired(ctx context.Context, now time.Time, archive bool) (int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	removed := 0
+	for id, urlModel := range s.data {
+		if !urlModel.Expired(now) {
+			continue
 		}
+
+		s.removeLocked(id)
+		delete(s.clicks, id)
+		removed++
+	}
+
+	return removed, nil
+}
+
+// SaveClicks сохраняет события переходов в памяти.
+func (s *InMemoryStorage) SaveClicks(ctx context.Context, events []models.ClickEvent) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, event := range events {
+		s.clicks[event.ShortID] = append(s.clicks[event.ShortID], event)
 	}
+	return nil
+}
+
+// GetClickStats возвращает статистику переходов по короткой ссылке.
+func (s *InMemoryStorage) GetClickStats(ctx context.Context, shortID string) (models.LinkStatsModel, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return storage.AggregateClicks(s.clicks[shortID]), nil
+}
 
+// SaveAPIKey сохраняет API-ключ в памяти.
+func (s *InMemoryStorage) SaveAPIKey(ctx context.Context, key models.APIKeyModel) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.apiKeys[key.ID] = key
 	return nil
 }
+
+// GetAPIKeyByHash возвращает API-ключ по хешу.
+func (s *InMemoryStorage) GetAPIKeyByHash(ctx context.Context, keyHash string) (models.APIKeyModel, bool) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	for _, key := range s.apiKeys {
+		if key.KeyHash == keyHash {
+			return key, true
+		}
+	}
+	return models.APIKeyModel{}, false
+}
+
+// GetUserAPIKeys возвращает API-ключи пользователя в порядке создания.
+func (s *InMemoryStorage) GetUserAPIKeys(ctx context.Context, userID string) ([]models.APIKeyModel, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	var keys []models.APIKeyModel
+	for _, key := range s.apiKeys {
+		if key.UserID == userID {
+			keys = append(keys, key)
+		}
+	}
+	storage.SortAPIKeys(keys)
+	return keys, nil
+}
+
+// RevokeAPIKey удаляет API-ключ пользователя.
+func (s *InMemoryStorage) RevokeAPIKey(ctx context.Context, userID, id string) (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	key, exists := s.apiKeys[id]
+	if !exists || key.UserID != userID {
+		return false, nil
+	}
+	delete(s.apiKeys, id)
+	return true, nil
+}
+
+// TouchAPIKey обновляет время последнего использования API-ключа.
+func (s *InMemoryStorage) TouchAPIKey(ctx context.Context, id string, usedAt time.Time) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if key, exists := s.apiKeys[id]; exists {
+		key.LastUsedAt = &usedAt
+		s.apiKeys[id] = key
+	}
+	return nil
+}
+
+// CreateUser сохраняет пользователя в памяти.
+func (s *InMemoryStorage) CreateUser(ctx context.Context, user models.UserModel) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, exists := s.users[user.Login]; exists {
+		return storage.ErrConflict
+	}
+	s.users[user.Login] = user
+	return nil
+}
+
+// GetUserByLogin возвращает пользователя по логину.
+func (s *InMemoryStorage) GetUserByLogin(ctx context.Context, login string) (models.UserModel, bool) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	user, exists := s.users[login]
+	return user, exists
+}
+
+// GetUserByID возвращает пользователя по идентификатору.
+func (s *InMemoryStorage) GetUserByID(ctx context.Context, id string) (models.UserModel, bool) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	for _, user := range s.users {
+		if user.ID == id {
+			return user, true
+		}
+	}
+	return models.UserModel{}, false
+}
+
+// ReassignUserURLs передаёт URL и API-ключи пользователя fromUserID пользователю toUserID.
+// URL добавляются в конец списка toUserID в прежнем порядке.
+func (s *InMemoryStorage) ReassignUserURLs(ctx context.Context, fromUserID, toUserID string) (int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	ids := s.userData[fromUserID]
+	if fromUserID == toUserID {
+		return len(ids), nil
+	}
+	for _, id := range ids {
+		urlModel := s.data[id]
+		urlModel.UserID = toUserID
+		s.data[id] = urlModel
+	}
+	for id, key := range s.apiKeys {
+		if key.UserID == fromUserID {
+			key.UserID = toUserID
+			s.apiKeys[id] = key
+		}
+	}
+	s.userData[toUserID] = append(s.userData[toUserID], ids...)
+	delete(s.userData, fromUserID)
+	return len(ids), nil
+}
+
+// addLocked сохраняет URL и добавляет его в индексы.
+// Вызывающий должен удерживать s.mu.
+func (s *InMemoryStorage) addLocked(urlModel models.URLModel) {
+	s.data[urlModel.ID] = urlModel
+	s.byURL[urlModel.URL] = append(s.byURL[urlModel.URL], urlModel.ID)
+	s.userData[urlModel.UserID] = append(s.userData[urlModel.UserID], urlModel.ID)
+}
+
+// removeLocked удаляет URL из хранилища и индексов.
+// Вызывающий должен удерживать s.mu.
+func (s *InMemoryStorage) removeLocked(id string) {
+	urlModel, exists := s.data[id]
+	if !exists {
+		return
+	}
+	delete(s.data, id)
+	removeID(s.byURL, urlModel.URL, id)
+	removeID(s.userData, urlModel.UserID, id)
+}
+
+// removeID удаляет id из списка index[key]; пустой список удаляется целиком.
+func removeID(index map[string][]string, key, id string) {
+	ids := index[key]
+	for i := range ids {
+		if ids[i] == id {
+			ids = append(ids[:i], ids[i+1:]...)
+			break
+		}
+	}
+	if len(ids) == 0 {
+		delete(index, key)
+	} else {
+		index[key] = ids
+	}
+}
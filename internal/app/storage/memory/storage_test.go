@@ -3,8 +3,11 @@ package memory
 import (
 	"context"
 	"testing"
+	"time"
 
 	"github.com/alexuryumtsev/go-shortener/internal/app/models"
+	"github.com/alexuryumtsev/go-shortener/internal/app/storage"
+	"github.com/alexuryumtsev/go-shortener/internal/app/storage/storagetest"
 	"github.com/stretchr/testify/assert"
 )
 
@@ -43,3 +46,87 @@ func TestInMemoryStorage_LoadFromFile(t *testing.T) {
 	err := storage.LoadFromFile()
 	assert.NoError(t, err, "LoadFromFile should not return an error")
 }
+
+func TestInMemoryStorage_DeleteExpired(t *testing.T) {
+	storage := NewInMemoryStorage()
+	ctx := context.Background()
+	now := time.Now()
+
+	expired := models.URLModel{ID: "expired", URL: "https://old.com", UserID: "user", ExpiresAt: now.Add(-time.Minute)}
+	active := models.URLModel{ID: "active", URL: "https://new.com", UserID: "user", ExpiresAt: now.Add(time.Hour)}
+	_, err := storage.SaveBatch(ctx, []models.URLModel{expired, active})
+	assert.NoError(t, err)
+	assert.NoError(t, storage.SaveClicks(ctx, []models.ClickEvent{
+		{ShortID: "expired", Timestamp: now, IPHash: "a"},
+		{ShortID: "active", Timestamp: now, IPHash: "a"},
+	}))
+
+	removed, err := storage.DeleteExpired(ctx, now, true)
+	assert.NoError(t, err)
+	assert.Equal(t, 1, removed)
+
+	_, exists := storage.Get(ctx, "expired")
+	assert.False(t, exists, "Expired URL should be removed")
+	assert.NotContains(t, storage.clicks, "expired", "Clicks should be removed with the URL")
+	assert.Contains(t, storage.clicks, "active")
+
+	_, exists = storage.Get(ctx, "active")
+	assert.True(t, exists, "Active URL should be kept")
+
+	userURLs, err := storage.GetUserURLs(ctx, "user")
+	assert.NoError(t, err)
+	assert.Len(t, userURLs, 1)
+}
+
+func TestInMemoryStorage_Clicks(t *testing.T) {
+	storage := NewInMemoryStorage()
+	ctx := context.Background()
+	day := time.Date(2024, 3, 1, 23, 30, 0, 0, time.UTC)
+
+	err := storage.SaveClicks(ctx, []models.ClickEvent{
+		{ShortID: "link", Timestamp: day, IPHash: "a"},
+		{ShortID: "link", Timestamp: day.Add(time.Hour), IPHash: "a"},
+		{ShortID: "link", Timestamp: day.Add(2 * time.Hour), IPHash: "b"},
+		{ShortID: "other", Timestamp: day, IPHash: "c"},
+	})
+	assert.NoError(t, err)
+
+	stats, err := storage.GetClickStats(ctx, "link")
+	assert.NoError(t, err)
+	assert.Equal(t, 3, stats.TotalClicks)
+	assert.Equal(t, 2, stats.UniqueVisitors)
+	assert.Equal(t, []models.DailyStatsModel{
+		{Date: "2024-03-01", Clicks: 1, UniqueVisitors: 1},
+		{Date: "2024-03-02", Clicks: 2, UniqueVisitors: 2},
+	}, stats.Daily)
+
+	stats, err = storage.GetClickStats(ctx, "missing")
+	assert.NoError(t, err)
+	assert.Zero(t, stats.TotalClicks)
+	assert.Empty(t, stats.Daily)
+}
+
+func TestInMemoryStorage_GetStats(t *testing.T) {
+	storage := NewInMemoryStorage()
+	ctx := context.Background()
+	now := time.Now()
+
+	_, err := storage.SaveBatch(ctx, []models.URLModel{
+		{ID: "a", URL: "https://a.com", UserID: "user1"},
+		{ID: "b", URL: "https://b.com", UserID: "user1"},
+		{ID: "c", URL: "https://c.com", UserID: "user2"},
+		{ID: "expired", URL: "https://old.com", UserID: "user3", ExpiresAt: now.Add(-time.Minute)},
+	})
+	assert.NoError(t, err)
+	assert.NoError(t, storage.DeleteUserURLs(ctx, "user1", []string{"b"}))
+
+	stats, err := storage.GetStats(ctx)
+	assert.NoError(t, err)
+	assert.Equal(t, models.StatsModel{URLs: 2, DeletedURLs: 1, Users: 3}, stats)
+}
+
+func TestConformance(t *testing.T) {
+	storagetest.Run(t, func(t *testing.T) storage.URLStorage {
+		return NewInMemoryStorage()
+	})
+}
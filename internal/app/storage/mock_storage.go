@@ -3,18 +3,31 @@ package storage
 
 import (
 	"context"
+	"sync"
+	"time"
 
 	"github.com/alexuryumtsev/go-shortener/internal/app/models"
 )
 
 // MockStorage реализует интерфейс URLStorage для тестирования.
+// События переходов защищены мьютексом, так как записываются асинхронно.
 type MockStorage struct {
 	data map[string]models.URLModel
+
+	clicksMu sync.Mutex
+	clicks   []models.ClickEvent
+
+	apiKeys map[string]models.APIKeyModel
+	users   map[string]models.UserModel
 }
 
 // NewMockStorage создает новое моковое хранилище.
 func NewMockStorage() *MockStorage {
-	return &MockStorage{data: make(map[string]models.URLModel)}
+	return &MockStorage{
+		data:    make(map[string]models.URLModel),
+		apiKeys: make(map[string]models.APIKeyModel),
+		users:   make(map[string]models.UserModel),
+	}
 }
 
 // Save сохраняет URLModel в моковом хранилище.
@@ -24,11 +37,16 @@ func (m *MockStorage) Save(ctx context.Context, urlModel models.URLModel) error
 }
 
 // SaveBatch сохраняет пакет URLModel в моковом хранилище.
-func (m *MockStorage) SaveBatch(ctx context.Context, urlModels []models.URLModel) error {
+// URLModel с уже занятыми ID пропускаются, их ID возвращаются в skipped.
+func (m *MockStorage) SaveBatch(ctx context.Context, urlModels []models.URLModel) (skipped []string, err error) {
 	for _, urlModel := range urlModels {
+		if _, exists := m.data[urlModel.ID]; exists {
+			skipped = append(skipped, urlModel.ID)
+			continue
+		}
 		m.data[urlModel.ID] = urlModel
 	}
-	return nil
+	return skipped, nil
 }
 
 // Get извлекает URLModel по ID из мокового хранилища.
@@ -37,6 +55,20 @@ func (m *MockStorage) Get(ctx context.Context, id string) (models.URLModel, bool
 	return urlModel, exists
 }
 
+// GetByOriginalURL извлекает URLModel по оригинальному URL из мокового хранилища.
+func (m *MockStorage) GetByOriginalURL(ctx context.Context, originalURL string) (models.URLModel, bool) {
+	var (
+		best   models.URLModel
+		exists bool
+	)
+	for _, urlModel := range m.data {
+		if urlModel.URL == originalURL && (!exists || PreferURL(urlModel, best)) {
+			best, exists = urlModel, true
+		}
+	}
+	return best, exists
+}
+
 // GetUserURLs извлекает все URLModel для данного userID из мокового хранилища.
 func (m *MockStorage) GetUserURLs(ctx context.Context, userID string) ([]models.URLModel, error) {
 	var userURLs []models.URLModel
@@ -48,6 +80,26 @@ func (m *MockStorage) GetUserURLs(ctx context.Context, userID string) ([]models.
 	return userURLs, nil
 }
 
+// GetStats возвращает сводную статистику мокового хранилища.
+func (m *MockStorage) GetStats(ctx context.Context) (models.StatsModel, error) {
+	urls := make([]models.URLModel, 0, len(m.data))
+	for _, urlModel := range m.data {
+		urls = append(urls, urlModel)
+	}
+	return CountStats(urls, time.Now()), nil
+}
+
+// ForEachURL вызывает fn для URL с коротким ID больше afterID в порядке возрастания ID.
+func (m *MockStorage) ForEachURL(ctx context.Context, afterID string, fn func(models.URLModel) error) error {
+	var urls []models.URLModel
+	for id, urlModel := range m.data {
+		if id > afterID {
+			urls = append(urls, urlModel)
+		}
+	}
+	return ForEachSorted(ctx, urls, fn)
+}
+
 // LoadFromFile имитирует загрузку данных из файла.
 func (m *MockStorage) LoadFromFile() error {
 	// Можно имитировать ошибку или инициализировать данными для тестов.
@@ -70,3 +122,143 @@ func (m *MockStorage) DeleteUserURLs(ctx context.Context, userID string, shortUR
 	}
 	return nil
 }
+
+// DeleteExpired удаляет из мокового хранилища URL с истёкшим сроком действия
+// вместе с переходами по ним.
+func (m *MockStorage) DeleteExpired(ctx context.Context, now time.Time, archive bool) (int, error) {
+	expired := make(map[string]struct{})
+	for id, urlModel := range m.data {
+		if urlModel.Expired(now) {
+			delete(m.data, id)
+			expired[id] = struct{}{}
+		}
+	}
+
+	m.clicksMu.Lock()
+	defer m.clicksMu.Unlock()
+	kept := m.clicks[:0]
+	for _, event := range m.clicks {
+		if _, ok := expired[event.ShortID]; !ok {
+			kept = append(kept, event)
+		}
+	}
+	m.clicks = kept
+	return len(expired), nil
+}
+
+// SaveClicks сохраняет события переходов в моковом хранилище.
+func (m *MockStorage) SaveClicks(ctx context.Context, events []models.ClickEvent) error {
+	m.clicksMu.Lock()
+	defer m.clicksMu.Unlock()
+	m.clicks = append(m.clicks, events...)
+	return nil
+}
+
+// GetClickStats возвращает статистику переходов по короткой ссылке из мокового хранилища.
+func (m *MockStorage) GetClickStats(ctx context.Context, shortID string) (models.LinkStatsModel, error) {
+	m.clicksMu.Lock()
+	defer m.clicksMu.Unlock()
+	var events []models.ClickEvent
+	for _, event := range m.clicks {
+		if event.ShortID == shortID {
+			events = append(events, event)
+		}
+	}
+	return AggregateClicks(events), nil
+}
+
+// SaveAPIKey сохраняет API-ключ в моковом хранилище.
+func (m *MockStorage) SaveAPIKey(ctx context.Context, key models.APIKeyModel) error {
+	m.apiKeys[key.ID] = key
+	return nil
+}
+
+// GetAPIKeyByHash возвращает API-ключ по хешу.
+func (m *MockStorage) GetAPIKeyByHash(ctx context.Context, keyHash string) (models.APIKeyModel, bool) {
+	for _, key := range m.apiKeys {
+		if key.KeyHash == keyHash {
+			return key, true
+		}
+	}
+	return models.APIKeyModel{}, false
+}
+
+// GetUserAPIKeys возвращает API-ключи пользователя.
+func (m *MockStorage) GetUserAPIKeys(ctx context.Context, userID string) ([]models.APIKeyModel, error) {
+	var keys []models.APIKeyModel
+	for _, key := range m.apiKeys {
+		if key.UserID == userID {
+			keys = append(keys, key)
+		}
+	}
+	SortAPIKeys(keys)
+	return keys, nil
+}
+
+// RevokeAPIKey удаляет API-ключ пользователя.
+func (m *MockStorage) RevokeAPIKey(ctx context.Context, userID, id string) (bool, error) {
+	key, exists := m.apiKeys[id]
+	if !exists || key.UserID != userID {
+		return false, nil
+	}
+	delete(m.apiKeys, id)
+	return true, nil
+}
+
+// TouchAPIKey обновляет время последнего использования API-ключа.
+func (m *MockStorage) TouchAPIKey(ctx context.Context, id string, usedAt time.Time) error {
+	if key, exists := m.apiKeys[id]; exists {
+		key.LastUsedAt = &usedAt
+		m.apiKeys[id] = key
+	}
+	return nil
+}
+
+// CreateUser сохраняет пользователя в моковом хранилище.
+func (m *MockStorage) CreateUser(ctx context.Context, user models.UserModel) error {
+	if _, exists := m.users[user.Login]; exists {
+		return ErrConflict
+	}
+	m.users[user.Login] = user
+	return nil
+}
+
+// GetUserByLogin возвращает пользователя по логину.
+func (m *MockStorage) GetUserByLogin(ctx context.Context, login string) (models.UserModel, bool) {
+	user, exists := m.users[login]
+	return user, exists
+}
+
+// GetUserByID возвращает пользователя по идентификатору.
+func (m *MockStorage) GetUserByID(ctx context.Context, id string) (models.UserModel, bool) {
+	for _, user := range m.users {
+		if user.ID == id {
+			return user, true
+		}
+	}
+	return models.UserModel{}, false
+}
+
+// ReassignUserURLs передаёт URL и API-ключи пользователя fromUserID пользователю toUserID.
+func (m *MockStorage) ReassignUserURLs(ctx context.Context, fromUserID, toUserID string) (int, error) {
+	reassigned := 0
+	for id, urlModel := range m.data {
+		if urlModel.UserID == fromUserID {
+			urlModel.UserID = toUserID
+			m.data[id] = urlModel
+			reassigned++
+		}
+	}
+	for id, key := range m.apiKeys {
+		if key.UserID == fromUserID {
+			key.UserID = toUserID
+			m.apiKeys[id] = key
+		}
+	}
+	return reassigned, nil
+}
+
+// CheckHealth возвращает исправное состояние мокового хранилища.
+func (m *MockStorage) CheckHealth(ctx context.Context) map[string]ComponentHealth {
+	return map[string]ComponentHealth{"mock": {Status: HealthUp}}
+}
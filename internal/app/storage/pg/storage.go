@@ -3,14 +3,25 @@ package pg
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"time"
 
 	"github.com/alexuryumtsev/go-shortener/internal/app/db"
 	"github.com/alexuryumtsev/go-shortener/internal/app/models"
+	"github.com/alexuryumtsev/go-shortener/internal/app/storage"
 	"github.com/jackc/pgerrcode"
+	"github.com/jackc/pgx/v5"
 	"github.com/jackc/pgx/v5/pgconn"
 )
 
+// pageSize — число URL, читаемых ForEachURL одним запросом.
+const pageSize = 1000
+
+// poolSaturationThreshold — доля занятых соединений пула, при которой
+// пул отмечается перегруженным: новые запросы начнут ждать соединения.
+const poolSaturationThreshold = 0.9
+
 // DatabaseStorage управляет сохранением и получением данных в базе данных.
 type DatabaseStorage struct {
 	db *db.Database
@@ -23,12 +34,12 @@ func NewDatabaseStorage(db *db.Database) *DatabaseStorage {
 
 // Save сохраняет URL в базе данных.
 func (s *DatabaseStorage) Save(ctx context.Context, urlModel models.URLModel) error {
-	query := `INSERT INTO urls (user_id, short_url, original_url) VALUES ($1, $2, $3)`
-	_, err := s.db.Pool.Exec(ctx, query, urlModel.UserID, urlModel.ID, urlModel.URL)
+	query := `INSERT INTO urls (user_id, short_url, original_url, is_deleted, expires_at) VALUES ($1, $2, $3, $4, $5)`
+	_, err := s.db.Pool.Exec(ctx, query, urlModel.UserID, urlModel.ID, urlModel.URL, urlModel.Deleted, nullTime(urlModel.ExpiresAt))
 
 	if err != nil {
 		if pgErr, ok := err.(*pgconn.PgError); ok && pgErr.Code == pgerrcode.UniqueViolation {
-			return fmt.Errorf("conflict: %w", err)
+			return fmt.Errorf("%w: %w", storage.ErrConflict, err)
 		}
 		return fmt.Errorf("failed to save URL: %w", err)
 	}
@@ -36,54 +47,78 @@ func (s *DatabaseStorage) Save(ctx context.Context, urlModel models.URLModel) er
 }
 
 // SaveBatch сохраняет множество URL в базе данных.
-func (s *DatabaseStorage) SaveBatch(ctx context.Context, urlModels []models.URLModel) error {
+// URL с уже занятыми короткими ID пропускаются, их ID возвращаются в skipped.
+func (s *DatabaseStorage) SaveBatch(ctx context.Context, urlModels []models.URLModel) (skipped []string, err error) {
 	tx, err := s.db.Pool.Begin(ctx)
 	if err != nil {
-		return fmt.Errorf("failed to begin transaction: %w", err)
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
 	}
+	// После Commit откат ничего не делает
+	defer tx.Rollback(ctx)
 
-	defer func() {
-		if rollbackErr := tx.Rollback(ctx); rollbackErr != nil {
-			// Если произошла ошибка при rollback, добавляем её к основной ошибке
-			if err != nil {
-				err = fmt.Errorf("rollback error: %v: %w", rollbackErr, err)
-			} else {
-				err = rollbackErr
-			}
-		}
-	}()
-
+	query := `INSERT INTO urls (user_id, short_url, original_url, is_deleted, expires_at) VALUES ($1, $2, $3, $4, $5)
+		ON CONFLICT (short_url) DO NOTHING RETURNING short_url`
 	for _, urlModel := range urlModels {
-		query := `INSERT INTO urls (user_id, short_url, original_url) VALUES ($1, $2, $3) ON CONFLICT (short_url) DO NOTHING`
-		_, err := tx.Exec(ctx, query, urlModel.UserID, urlModel.ID, urlModel.URL)
+		var id string
+		err := tx.QueryRow(ctx, query, urlModel.UserID, urlModel.ID, urlModel.URL, urlModel.Deleted, nullTime(urlModel.ExpiresAt)).Scan(&id)
+		if errors.Is(err, pgx.ErrNoRows) {
+			// ON CONFLICT DO NOTHING не возвращает строку: ID уже занят
+			skipped = append(skipped, urlModel.ID)
+			continue
+		}
 		if err != nil {
-			return fmt.Errorf("failed to save URL: %w", err)
+			return nil, fmt.Errorf("failed to save URL: %w", err)
 		}
 	}
 
 	if err := tx.Commit(ctx); err != nil {
-		return fmt.Errorf("failed to commit transaction: %w", err)
+		return nil, fmt.Errorf("failed to commit transaction: %w", err)
 	}
-	return nil
+	return skipped, nil
 }
 
 // Get возвращает оригинальный URL по идентификатору из базы данных.
 func (s *DatabaseStorage) Get(ctx context.Context, id string) (models.URLModel, bool) {
-	query := `SELECT original_url, is_deleted FROM urls WHERE short_url = $1`
+	query := `SELECT original_url, user_id, is_deleted, expires_at FROM urls WHERE short_url = $1`
 	row := s.db.Pool.QueryRow(ctx, query, id)
 
 	var urlModel models.URLModel
+	var expiresAt *time.Time
 	urlModel.ID = id
-	err := row.Scan(&urlModel.URL, &urlModel.Deleted)
+	err := row.Scan(&urlModel.URL, &urlModel.UserID, &urlModel.Deleted, &expiresAt)
+	if err != nil {
+		return models.URLModel{}, false
+	}
+	if expiresAt != nil {
+		urlModel.ExpiresAt = *expiresAt
+	}
+	return urlModel, true
+}
+
+// GetByOriginalURL возвращает сокращённый URL по оригинальному URL из базы данных.
+func (s *DatabaseStorage) GetByOriginalURL(ctx context.Context, originalURL string) (models.URLModel, bool) {
+	// Порядок совпадает с storage.PreferURL: неудалённые ссылки предпочтительнее,
+	// затем бессрочные, затем — с самым поздним сроком действия
+	query := `SELECT short_url, user_id, is_deleted, expires_at FROM urls WHERE original_url = $1
+		ORDER BY is_deleted, expires_at DESC NULLS FIRST LIMIT 1`
+	row := s.db.Pool.QueryRow(ctx, query, originalURL)
+
+	urlModel := models.URLModel{URL: originalURL}
+	var expiresAt *time.Time
+	err := row.Scan(&urlModel.ID, &urlModel.UserID, &urlModel.Deleted, &expiresAt)
 	if err != nil {
 		return models.URLModel{}, false
 	}
+	if expiresAt != nil {
+		urlModel.ExpiresAt = *expiresAt
+	}
 	return urlModel, true
 }
 
-// GetUserURLs возвращает все URL, сокращённые пользователем.
+// GetUserURLs возвращает все URL, сокращённые пользователем, в порядке создания.
+// Удалённые URL возвращаются с признаком Deleted, как и в остальных хранилищах.
 func (s *DatabaseStorage) GetUserURLs(ctx context.Context, userID string) ([]models.URLModel, error) {
-	query := `SELECT short_url, original_url FROM urls WHERE is_deleted IS FALSE AND user_id = $1`
+	query := `SELECT short_url, original_url, is_deleted, expires_at FROM urls WHERE user_id = $1 ORDER BY id`
 	rows, err := s.db.Pool.Query(ctx, query, userID)
 	if err != nil {
 		return nil, fmt.Errorf("failed to get user URLs: %w", err)
@@ -92,16 +127,79 @@ func (s *DatabaseStorage) GetUserURLs(ctx context.Context, userID string) ([]mod
 
 	var urls []models.URLModel
 	for rows.Next() {
-		var urlModel models.URLModel
-		if err := rows.Scan(&urlModel.ID, &urlModel.URL); err != nil {
+		urlModel := models.URLModel{UserID: userID}
+		var expiresAt *time.Time
+		if err := rows.Scan(&urlModel.ID, &urlModel.URL, &urlModel.Deleted, &expiresAt); err != nil {
 			return nil, fmt.Errorf("failed to scan row: %w", err)
 		}
+		if expiresAt != nil {
+			urlModel.ExpiresAt = *expiresAt
+		}
 		urls = append(urls, urlModel)
 	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to get user URLs: %w", err)
+	}
 
 	return urls, nil
 }
 
+// GetStats возвращает сводную статистику, вычисленную одним запросом к базе данных.
+func (s *DatabaseStorage) GetStats(ctx context.Context) (models.StatsModel, error) {
+	query := `
+		SELECT
+			count(*) FILTER (WHERE NOT is_deleted AND (expires_at IS NULL OR expires_at > now())),
+			count(*) FILTER (WHERE is_deleted),
+			count(DISTINCT NULLIF(user_id, ''))
+		FROM urls`
+
+	var stats models.StatsModel
+	if err := s.db.Pool.QueryRow(ctx, query).Scan(&stats.URLs, &stats.DeletedURLs, &stats.Users); err != nil {
+		return models.StatsModel{}, fmt.Errorf("failed to get stats: %w", err)
+	}
+	return stats, nil
+}
+
+// ForEachURL вызывает fn для URL с коротким ID больше afterID в порядке возрастания ID.
+// URL читаются страницами по уникальному индексу short_url, поэтому fn вызывается
+// без открытого запроса и может писать в базу. ID сравниваются по правилам сортировки
+// базы, которые для ID в разном регистре могут отличаться от побайтового порядка;
+// продолжению обхода это не мешает.
+func (s *DatabaseStorage) ForEachURL(ctx context.Context, afterID string, fn func(models.URLModel) error) error {
+	query := `SELECT short_url, original_url, user_id, is_deleted, expires_at FROM urls
+		WHERE short_url > $1 ORDER BY short_url LIMIT $2`
+	for {
+		rows, err := s.db.Pool.Query(ctx, query, afterID, pageSize)
+		if err != nil {
+			return fmt.Errorf("failed to read URLs: %w", err)
+		}
+		page, err := pgx.CollectRows(rows, func(row pgx.CollectableRow) (models.URLModel, error) {
+			var urlModel models.URLModel
+			var expiresAt *time.Time
+			if err := row.Scan(&urlModel.ID, &urlModel.URL, &urlModel.UserID, &urlModel.Deleted, &expiresAt); err != nil {
+				return models.URLModel{}, err
+			}
+			if expiresAt != nil {
+				urlModel.ExpiresAt = *expiresAt
+			}
+			return urlModel, nil
+		})
+		if err != nil {
+			return fmt.Errorf("failed to read URLs: %w", err)
+		}
+
+		for _, urlModel := range page {
+			if err := fn(urlModel); err != nil {
+				return err
+			}
+		}
+		if len(page) < pageSize {
+			return nil
+		}
+		afterID = page[len(page)-1].ID
+	}
+}
+
 // LoadFromFile загружает данные из базы данных (не требуется для базы данных).
 func (s *DatabaseStorage) LoadFromFile() error {
 	return nil
@@ -112,6 +210,39 @@ func (s *DatabaseStorage) Ping(ctx context.Context) error {
 	return s.db.Ping(ctx)
 }
 
+// CheckHealth проверяет соединение с базой и загрузку пула соединений.
+// Перегруженный пул отмечается storage.HealthDegraded: запросы выполняются,
+// но ожидают свободного соединения.
+func (s *DatabaseStorage) CheckHealth(ctx context.Context) map[string]storage.ComponentHealth {
+	database := storage.ComponentHealth{Status: storage.HealthUp}
+	if err := s.db.Ping(ctx); err != nil {
+		database = storage.ComponentHealth{Status: storage.HealthDown, Error: err.Error()}
+	}
+
+	stat := s.db.Pool.Stat()
+	pool := storage.ComponentHealth{
+		Status: storage.HealthUp,
+		Details: map[string]any{
+			"acquired_conns": stat.AcquiredConns(),
+			"idle_conns":     stat.IdleConns(),
+			"total_conns":    stat.TotalConns(),
+			"max_conns":      stat.MaxConns(),
+		},
+	}
+	if stat.MaxConns() > 0 && float64(stat.AcquiredConns()) >= float64(stat.MaxConns())*poolSaturationThreshold {
+		pool.Status = storage.HealthDegraded
+		pool.Error = "connection pool is saturated"
+	}
+
+	return map[string]storage.ComponentHealth{"postgres": database, "pgpool": pool}
+}
+
+// Close закрывает пул соединений с базой данных.
+func (s *DatabaseStorage) Close() error {
+	s.db.Close()
+	return nil
+}
+
 // DeleteUserURLs удаляет URL для данного userID из базы данных.
 func (s *DatabaseStorage) DeleteUserURLs(ctx context.Context, userID string, shortURLs []string) error {
 	tx, err := s.db.Pool.Begin(ctx)
@@ -146,3 +277,216 @@ func (s *DatabaseStorage) DeleteUserURLs(ctx context.Context, userID string, sho
 	}
 	return nil
 }
+
+// DeleteExpired удаляет из базы данных URL с истёкшим сроком действия.
+// При archive = true удалённые строки переносятся в таблицу urls_archive.
+// У таблицы clicks нет внешнего ключа на urls, поэтому переходы по удалённым
+// ссылкам удаляются тем же запросом.
+func (s *DatabaseStorage) DeleteExpired(ctx context.Context, now time.Time, archive bool) (int, error) {
+	archiveQuery := ""
+	if archive {
+		archiveQuery = `, archived AS (
+			INSERT INTO urls_archive (user_id, short_url, original_url, is_deleted, expires_at)
+			SELECT user_id, short_url, original_url, is_deleted, expires_at FROM moved
+		)`
+	}
+	query := `
+		WITH moved AS (
+			DELETE FROM urls WHERE expires_at <= $1
+			RETURNING user_id, short_url, original_url, is_deleted, expires_at
+		), purged AS (
+			DELETE FROM clicks WHERE short_url IN (SELECT short_url FROM moved)
+		)` + archiveQuery + `
+		SELECT count(*) FROM moved`
+
+	var removed int
+	if err := s.db.Pool.QueryRow(ctx, query, now).Scan(&removed); err != nil {
+		return 0, fmt.Errorf("failed to delete expired URLs: %w", err)
+	}
+	return removed, nil
+}
+
+// SaveClicks сохраняет события переходов в таблицу clicks с помощью COPY.
+func (s *DatabaseStorage) SaveClicks(ctx context.Context, events []models.ClickEvent) error {
+	rows := make([][]any, 0, len(events))
+	for _, event := range events {
+		rows = append(rows, []any{event.ShortID, event.Timestamp, event.Referrer, event.UserAgent, event.IPHash})
+	}
+
+	_, err := s.db.Pool.CopyFrom(
+		ctx,
+		pgx.Identifier{"clicks"},
+		[]string{"short_url", "clicked_at", "referrer", "user_agent", "ip_hash"},
+		pgx.CopyFromRows(rows),
+	)
+	if err != nil {
+		return fmt.Errorf("failed to save clicks: %w", err)
+	}
+	return nil
+}
+
+// GetClickStats возвращает статистику переходов по короткой ссылке.
+// Агрегация выполняется на стороне базы данных.
+func (s *DatabaseStorage) GetClickStats(ctx context.Context, shortID string) (models.LinkStatsModel, error) {
+	stats := models.LinkStatsModel{Daily: []models.DailyStatsModel{}}
+
+	totalQuery := `SELECT count(*), count(DISTINCT ip_hash) FROM clicks WHERE short_url = $1`
+	if err := s.db.Pool.QueryRow(ctx, totalQuery, shortID).Scan(&stats.TotalClicks, &stats.UniqueVisitors); err != nil {
+		return models.LinkStatsModel{}, fmt.Errorf("failed to get click totals: %w", err)
+	}
+
+	dailyQuery := `
+		SELECT to_char(clicked_at AT TIME ZONE 'UTC', 'YYYY-MM-DD') AS day, count(*), count(DISTINCT ip_hash)
+		FROM clicks
+		WHERE short_url = $1
+		GROUP BY day
+		ORDER BY day`
+	rows, err := s.db.Pool.Query(ctx, dailyQuery, shortID)
+	if err != nil {
+		return models.LinkStatsModel{}, fmt.Errorf("failed to get daily clicks: %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var day models.DailyStatsModel
+		if err := rows.Scan(&day.Date, &day.Clicks, &day.UniqueVisitors); err != nil {
+			return models.LinkStatsModel{}, fmt.Errorf("failed to scan row: %w", err)
+		}
+		stats.Daily = append(stats.Daily, day)
+	}
+	if err := rows.Err(); err != nil {
+		return models.LinkStatsModel{}, fmt.Errorf("failed to get daily clicks: %w", err)
+	}
+
+	return stats, nil
+}
+
+// SaveAPIKey сохраняет API-ключ в таблицу api_keys.
+func (s *DatabaseStorage) SaveAPIKey(ctx context.Context, key models.APIKeyModel) error {
+	query := `INSERT INTO api_keys (id, user_id, name, key_hash, scopes, created_at) VALUES ($1, $2, $3, $4, $5, $6)`
+	_, err := s.db.Pool.Exec(ctx, query, key.ID, key.UserID, key.Name, key.KeyHash, key.Scopes, key.CreatedAt)
+	if err != nil {
+		return fmt.Errorf("failed to save API key: %w", err)
+	}
+	return nil
+}
+
+// GetAPIKeyByHash возвращает API-ключ по хешу.
+func (s *DatabaseStorage) GetAPIKeyByHash(ctx context.Context, keyHash string) (models.APIKeyModel, bool) {
+	query := `SELECT id, user_id, name, scopes, created_at, last_used_at FROM api_keys WHERE key_hash = $1`
+	key := models.APIKeyModel{KeyHash: keyHash}
+	err := s.db.Pool.QueryRow(ctx, query, keyHash).
+		Scan(&key.ID, &key.UserID, &key.Name, &key.Scopes, &key.CreatedAt, &key.LastUsedAt)
+	if err != nil {
+		return models.APIKeyModel{}, false
+	}
+	return key, true
+}
+
+// GetUserAPIKeys возвращает API-ключи пользователя в порядке создания.
+func (s *DatabaseStorage) GetUserAPIKeys(ctx context.Context, userID string) ([]models.APIKeyModel, error) {
+	query := `SELECT id, name, key_hash, scopes, created_at, last_used_at FROM api_keys WHERE user_id = $1 ORDER BY created_at`
+	rows, err := s.db.Pool.Query(ctx, query, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get API keys: %w", err)
+	}
+	defer rows.Close()
+
+	var keys []models.APIKeyModel
+	for rows.Next() {
+		key := models.APIKeyModel{UserID: userID}
+		if err := rows.Scan(&key.ID, &key.Name, &key.KeyHash, &key.Scopes, &key.CreatedAt, &key.LastUsedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan row: %w", err)
+		}
+		keys = append(keys, key)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to get API keys: %w", err)
+	}
+	return keys, nil
+}
+
+// RevokeAPIKey удаляет API-ключ пользователя.
+func (s *DatabaseStorage) RevokeAPIKey(ctx context.Context, userID, id string) (bool, error) {
+	tag, err := s.db.Pool.Exec(ctx, `DELETE FROM api_keys WHERE id = $1 AND user_id = $2`, id, userID)
+	if err != nil {
+		return false, fmt.Errorf("failed to revoke API key: %w", err)
+	}
+	return tag.RowsAffected() > 0, nil
+}
+
+// TouchAPIKey обновляет время последнего использования API-ключа.
+func (s *DatabaseStorage) TouchAPIKey(ctx context.Context, id string, usedAt time.Time) error {
+	_, err := s.db.Pool.Exec(ctx, `UPDATE api_keys SET last_used_at = $2 WHERE id = $1`, id, usedAt)
+	if err != nil {
+		return fmt.Errorf("failed to update API key: %w", err)
+	}
+	return nil
+}
+
+// CreateUser сохраняет пользователя в таблицу users.
+// Если логин занят, возвращает storage.ErrConflict.
+func (s *DatabaseStorage) CreateUser(ctx context.Context, user models.UserModel) error {
+	query := `INSERT INTO users (id, login, password_hash, created_at) VALUES ($1, $2, $3, $4)`
+	_, err := s.db.Pool.Exec(ctx, query, user.ID, user.Login, user.PasswordHash, user.CreatedAt)
+	if err != nil {
+		var pgErr *pgconn.PgError
+		if errors.As(err, &pgErr) && pgErr.Code == pgerrcode.UniqueViolation {
+			return storage.ErrConflict
+		}
+		return fmt.Errorf("failed to create user: %w", err)
+	}
+	return nil
+}
+
+// GetUserByLogin возвращает пользователя по логину.
+func (s *DatabaseStorage) GetUserByLogin(ctx context.Context, login string) (models.UserModel, bool) {
+	query := `SELECT id, password_hash, created_at FROM users WHERE login = $1`
+	user := models.UserModel{Login: login}
+	if err := s.db.Pool.QueryRow(ctx, query, login).Scan(&user.ID, &user.PasswordHash, &user.CreatedAt); err != nil {
+		return models.UserModel{}, false
+	}
+	return user, true
+}
+
+// GetUserByID возвращает пользователя по идентификатору.
+func (s *DatabaseStorage) GetUserByID(ctx context.Context, id string) (models.UserModel, bool) {
+	query := `SELECT login, password_hash, created_at FROM users WHERE id = $1`
+	user := models.UserModel{ID: id}
+	if err := s.db.Pool.QueryRow(ctx, query, id).Scan(&user.Login, &user.PasswordHash, &user.CreatedAt); err != nil {
+		return models.UserModel{}, false
+	}
+	return user, true
+}
+
+// ReassignUserURLs передаёт URL и API-ключи пользователя fromUserID пользователю
+// toUserID в одной транзакции.
+func (s *DatabaseStorage) ReassignUserURLs(ctx context.Context, fromUserID, toUserID string) (int, error) {
+	tx, err := s.db.Pool.Begin(ctx)
+	if err != nil {
+		return 0, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	// После Commit откат ничего не делает
+	defer tx.Rollback(ctx)
+
+	tag, err := tx.Exec(ctx, `UPDATE urls SET user_id = $2 WHERE user_id = $1`, fromUserID, toUserID)
+	if err != nil {
+		return 0, fmt.Errorf("failed to reassign URLs: %w", err)
+	}
+	if _, err := tx.Exec(ctx, `UPDATE api_keys SET user_id = $2 WHERE user_id = $1`, fromUserID, toUserID); err != nil {
+		return 0, fmt.Errorf("failed to reassign API keys: %w", err)
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return 0, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+	return int(tag.RowsAffected()), nil
+}
+
+// nullTime преобразует нулевое время в NULL для базы данных.
+func nullTime(t time.Time) *time.Time {
+	if t.IsZero() {
+		return nil
+	}
+	return &t
+}
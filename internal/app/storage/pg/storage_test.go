@@ -0,0 +1,34 @@
+package pg
+
+import (
+	"context"
+	"os"
+	"testing"
+
+	"github.com/alexuryumtsev/go-shortener/internal/app/db"
+	"github.com/alexuryumtsev/go-shortener/internal/app/storage"
+	"github.com/alexuryumtsev/go-shortener/internal/app/storage/storagetest"
+	"github.com/stretchr/testify/require"
+)
+
+// TestConformance требует PostgreSQL: строка подключения задаётся переменной
+// окружения TEST_DATABASE_DSN. Таблицы базы очищаются перед каждым тестом.
+func TestConformance(t *testing.T) {
+	dsn := os.Getenv("TEST_DATABASE_DSN")
+	if dsn == "" {
+		t.Skip("TEST_DATABASE_DSN is not set")
+	}
+
+	storagetest.Run(t, func(t *testing.T) storage.URLStorage {
+		ctx := context.Background()
+		database, err := db.NewDatabaseConnection(ctx, dsn)
+		require.NoError(t, err)
+
+		_, err = database.Pool.Exec(ctx, `TRUNCATE urls, urls_archive, clicks, api_keys, users RESTART IDENTITY`)
+		if err != nil {
+			database.Close()
+			require.NoError(t, err)
+		}
+		return NewDatabaseStorage(database)
+	})
+}
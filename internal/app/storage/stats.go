@@ -0,0 +1,28 @@
+package storage
+
+import (
+	"time"
+
+	"github.com/alexuryumtsev/go-shortener/internal/app/models"
+)
+
+// CountStats считает сводную статистику по списку URL на момент now.
+// Ссылки с истёкшим сроком действия не считаются ни действующими, ни удалёнными;
+// пользователи без идентификатора не учитываются.
+func CountStats(urls []models.URLModel, now time.Time) models.StatsModel {
+	var stats models.StatsModel
+	users := make(map[string]struct{})
+	for _, urlModel := range urls {
+		switch {
+		case urlModel.Deleted:
+			stats.DeletedURLs++
+		case !urlModel.Expired(now):
+			stats.URLs++
+		}
+		if urlModel.UserID != "" {
+			users[urlModel.UserID] = struct{}{}
+		}
+	}
+	stats.Users = len(users)
+	return stats
+}
@@ -3,26 +3,85 @@ package storage
 
 import (
 	"context"
+	"errors"
+	"time"
 
 	"github.com/alexuryumtsev/go-shortener/internal/app/models"
 )
 
+// ErrConflict возвращается, если короткий идентификатор или оригинальный URL уже заняты.
+var ErrConflict = errors.New("conflict")
+
 // URLReader определяет методы для чтения URL.
 type URLReader interface {
 	Get(ctx context.Context, id string) (models.URLModel, bool)
+	GetByOriginalURL(ctx context.Context, originalURL string) (models.URLModel, bool)
 	GetUserURLs(ctx context.Context, userID string) ([]models.URLModel, error)
+	GetStats(ctx context.Context) (models.StatsModel, error)
 	LoadFromFile() error
+
+	// ForEachURL вызывает fn для каждого URL с коротким ID больше afterID
+	// (для всех URL, если afterID пуст) в порядке возрастания ID, включая удалённые.
+	// Порядок позволяет продолжить прерванный обход с последнего обработанного ID.
+	// fn вызывается без блокировок хранилища и может писать в него;
+	// ошибка fn прерывает обход и возвращается как есть.
+	ForEachURL(ctx context.Context, afterID string, fn func(models.URLModel) error) error
 }
 
 // URLWriter определяет методы для записи URL.
 type URLWriter interface {
 	Save(ctx context.Context, urlModel models.URLModel) error
-	SaveBatch(ctx context.Context, urlModels []models.URLModel) error
+	// SaveBatch сохраняет пакет URL, пропуская уже занятые короткие ID
+	// (в том числе повторяющиеся в пакете), и возвращает пропущенные ID.
+	SaveBatch(ctx context.Context, urlModels []models.URLModel) (skipped []string, err error)
 	DeleteUserURLs(ctx context.Context, userID string, shortURLs []string) error
+
+	// DeleteExpired удаляет URL, срок действия которых истёк к моменту now,
+	// и возвращает их количество. При archive = true записи переносятся в архив.
+	// Переходы по удалённым URL удаляются вместе с ними, чтобы не достаться
+	// новой ссылке с тем же ID.
+	DeleteExpired(ctx context.Context, now time.Time, archive bool) (int, error)
+}
+
+// ClickStorage определяет методы для хранения статистики переходов.
+type ClickStorage interface {
+	SaveClicks(ctx context.Context, events []models.ClickEvent) error
+	GetClickStats(ctx context.Context, shortID string) (models.LinkStatsModel, error)
+}
+
+// APIKeyStorage определяет методы для хранения API-ключей пользователей.
+type APIKeyStorage interface {
+	SaveAPIKey(ctx context.Context, key models.APIKeyModel) error
+	GetAPIKeyByHash(ctx context.Context, keyHash string) (models.APIKeyModel, bool)
+	GetUserAPIKeys(ctx context.Context, userID string) ([]models.APIKeyModel, error)
+
+	// RevokeAPIKey удаляет ключ id пользователя userID.
+	// Возвращает false, если такого ключа у пользователя нет.
+	RevokeAPIKey(ctx context.Context, userID, id string) (bool, error)
+
+	// TouchAPIKey обновляет время последнего использования ключа.
+	TouchAPIKey(ctx context.Context, id string, usedAt time.Time) error
+}
+
+// UserStorage определяет методы для хранения зарегистрированных пользователей.
+type UserStorage interface {
+	// CreateUser сохраняет пользователя; если логин занят, возвращает ErrConflict.
+	CreateUser(ctx context.Context, user models.UserModel) error
+	GetUserByLogin(ctx context.Context, login string) (models.UserModel, bool)
+	GetUserByID(ctx context.Context, id string) (models.UserModel, bool)
+
+	// ReassignUserURLs передаёт все URL и API-ключи пользователя fromUserID
+	// пользователю toUserID и возвращает количество перенесённых URL.
+	ReassignUserURLs(ctx context.Context, fromUserID, toUserID string) (int, error)
 }
 
-// URLStorage объединяет интерфейсы URLReader и URLWriter.
+// URLStorage объединяет интерфейсы URLReader, URLWriter, ClickStorage, APIKeyStorage,
+// UserStorage и HealthChecker.
 type URLStorage interface {
 	URLReader
 	URLWriter
+	ClickStorage
+	APIKeyStorage
+	UserStorage
+	HealthChecker
 }
@@ -0,0 +1,573 @@
+// Package storagetest содержит общий набор тестов поведения хранилищ URL.
+//
+// Каждая реализация storage.URLStorage запускает набор из своих тестов:
+//
+//	func TestConformance(t *testing.T) {
+//		storagetest.Run(t, func(t *testing.T) storage.URLStorage {
+//			return NewInMemoryStorage()
+//		})
+//	}
+//
+// Контракт хранилища:
+//   - короткий ID уникален: Save возвращает storage.ErrConflict, SaveBatch пропускает
+//     занятые ID, в том числе повторяющиеся в пакете (сохраняется первый), и возвращает их;
+//   - у одного оригинального URL может быть несколько коротких ID (алиасы);
+//     GetByOriginalURL выбирает предпочтительный по storage.PreferURL, так что
+//     удалённая ссылка возвращается, только если других нет;
+//   - DeleteUserURLs помечает удалёнными только URL владельца, а Get и GetUserURLs
+//     возвращают их с признаком Deleted;
+//   - GetUserURLs возвращает URL пользователя в порядке создания;
+//   - DeleteExpired удаляет истёкшие URL вместе с переходами по ним;
+//   - LoadFromFile не теряет и не дублирует данные;
+//   - ForEachURL обходит все URL, включая удалённые, в порядке возрастания ID,
+//     а SaveBatch сохраняет признак Deleted, так что обход переносится без потерь;
+//   - CheckHealth исправного хранилища возвращает хотя бы один компонент
+//     и ни одного неработающего;
+//   - методы безопасны для одновременного вызова.
+package storagetest
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/alexuryumtsev/go-shortener/internal/app/models"
+	"github.com/alexuryumtsev/go-shortener/internal/app/storage"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// Factory создаёт пустое хранилище для одного теста.
+// Освобождение ресурсов регистрируется через t.Cleanup; хранилища,
+// реализующие io.Closer, закрываются автоматически.
+type Factory func(t *testing.T) storage.URLStorage
+
+// Run проверяет хранилища, создаваемые newStorage, на соответствие контракту.
+func Run(t *testing.T, newStorage Factory) {
+	tests := []struct {
+		name string
+		test func(t *testing.T, s storage.URLStorage)
+	}{
+		{"SaveAndGet", testSaveAndGet},
+		{"SaveConflict", testSaveConflict},
+		{"SaveBatch", testSaveBatch},
+		{"SameOriginalURL", testSameOriginalURL},
+		{"DeleteUserURLs", testDeleteUserURLs},
+		{"GetUserURLs", testGetUserURLs},
+		{"GetStats", testGetStats},
+		{"DeleteExpired", testDeleteExpired},
+		{"ReassignUserURLs", testReassignUserURLs},
+		{"LoadFromFile", testLoadFromFile},
+		{"ForEachURL", testForEachURL},
+		{"CheckHealth", testCheckHealth},
+		{"Concurrency", testConcurrency},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := newStorage(t)
+			if closer, ok := s.(io.Closer); ok {
+				t.Cleanup(func() { closer.Close() })
+			}
+			tt.test(t, s)
+		})
+	}
+}
+
+// expiresIn возвращает момент истечения через d, округлённый до секунды:
+// не все хранилища сохраняют наносекунды.
+func expiresIn(d time.Duration) time.Time {
+	return time.Now().Add(d).UTC().Truncate(time.Second)
+}
+
+// assertURL сравнивает URL, учитывая, что хранилища могут вернуть время в другой зоне.
+func assertURL(t *testing.T, want, got models.URLModel) {
+	t.Helper()
+	assert.Equal(t, want.ID, got.ID)
+	assert.Equal(t, want.URL, got.URL)
+	assert.Equal(t, want.UserID, got.UserID)
+	assert.Equal(t, want.Deleted, got.Deleted)
+	assert.True(t, want.ExpiresAt.Equal(got.ExpiresAt), "expires at: want %v, got %v", want.ExpiresAt, got.ExpiresAt)
+}
+
+// userURLIDs возвращает короткие ID URL пользователя в порядке, возвращённом хранилищем.
+func userURLIDs(t *testing.T, s storage.URLStorage, userID string) []string {
+	t.Helper()
+	urls, err := s.GetUserURLs(context.Background(), userID)
+	require.NoError(t, err)
+	ids := make([]string, 0, len(urls))
+	for _, urlModel := range urls {
+		ids = append(ids, urlModel.ID)
+	}
+	return ids
+}
+
+func testSaveAndGet(t *testing.T, s storage.URLStorage) {
+	ctx := context.Background()
+
+	permanent := models.URLModel{ID: "permanent", URL: "https://a.com", UserID: "user"}
+	temporary := models.URLModel{ID: "temporary", URL: "https://b.com", UserID: "user", ExpiresAt: expiresIn(time.Hour)}
+	require.NoError(t, s.Save(ctx, permanent))
+	require.NoError(t, s.Save(ctx, temporary))
+
+	got, exists := s.Get(ctx, "permanent")
+	require.True(t, exists)
+	assertURL(t, permanent, got)
+
+	got, exists = s.Get(ctx, "temporary")
+	require.True(t, exists)
+	assertURL(t, temporary, got)
+
+	got, exists = s.GetByOriginalURL(ctx, "https://b.com")
+	require.True(t, exists)
+	assertURL(t, temporary, got)
+
+	_, exists = s.Get(ctx, "missing")
+	assert.False(t, exists)
+	_, exists = s.GetByOriginalURL(ctx, "https://missing.com")
+	assert.False(t, exists)
+}
+
+func testSaveConflict(t *testing.T, s storage.URLStorage) {
+	ctx := context.Background()
+
+	original := models.URLModel{ID: "id", URL: "https://a.com", UserID: "user"}
+	require.NoError(t, s.Save(ctx, original))
+
+	err := s.Save(ctx, models.URLModel{ID: "id", URL: "https://b.com", UserID: "other"})
+	assert.ErrorIs(t, err, storage.ErrConflict)
+
+	// Конфликтующая запись ничего не меняет
+	got, exists := s.Get(ctx, "id")
+	require.True(t, exists)
+	assertURL(t, original, got)
+	_, exists = s.GetByOriginalURL(ctx, "https://b.com")
+	assert.False(t, exists)
+	assert.Empty(t, userURLIDs(t, s, "other"))
+}
+
+func testSaveBatch(t *testing.T, s storage.URLStorage) {
+	ctx := context.Background()
+
+	existing := models.URLModel{ID: "existing", URL: "https://existing.com", UserID: "user"}
+	require.NoError(t, s.Save(ctx, existing))
+
+	skipped, err := s.SaveBatch(ctx, []models.URLModel{
+		{ID: "a", URL: "https://a.com", UserID: "user"},
+		{ID: "existing", URL: "https://replaced.com", UserID: "user"},
+		{ID: "b", URL: "https://b.com", UserID: "user", ExpiresAt: expiresIn(time.Hour)},
+		{ID: "a", URL: "https://repeated.com", UserID: "user"},
+	})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"existing", "a"}, skipped, "taken and repeated IDs are reported")
+
+	got, exists := s.Get(ctx, "existing")
+	require.True(t, exists)
+	assertURL(t, existing, got)
+
+	got, exists = s.Get(ctx, "a")
+	require.True(t, exists)
+	assert.Equal(t, "https://a.com", got.URL, "first URL with a repeated ID is saved")
+
+	for _, url := range []string{"https://replaced.com", "https://repeated.com"} {
+		_, exists = s.GetByOriginalURL(ctx, url)
+		assert.False(t, exists, url)
+	}
+	assert.Equal(t, []string{"existing", "a", "b"}, userURLIDs(t, s, "user"))
+
+	// Пустой пакет — не ошибка
+	skipped, err = s.SaveBatch(ctx, nil)
+	assert.NoError(t, err)
+	assert.Empty(t, skipped)
+}
+
+func testSameOriginalURL(t *testing.T, s storage.URLStorage) {
+	ctx := context.Background()
+
+	_, err := s.SaveBatch(ctx, []models.URLModel{
+		{ID: "soon", URL: "https://a.com", UserID: "user", ExpiresAt: expiresIn(time.Minute)},
+		{ID: "later", URL: "https://a.com", UserID: "user", ExpiresAt: expiresIn(time.Hour)},
+		{ID: "prefix", URL: "https://a.com/path", UserID: "user"},
+	})
+	require.NoError(t, err)
+
+	// Несколько коротких ID одного URL сохраняются независимо
+	for _, id := range []string{"soon", "later"} {
+		got, exists := s.Get(ctx, id)
+		require.True(t, exists, id)
+		assert.Equal(t, "https://a.com", got.URL)
+	}
+
+	got, exists := s.GetByOriginalURL(ctx, "https://a.com")
+	require.True(t, exists)
+	assert.Equal(t, "later", got.ID, "the latest expiry is preferred")
+
+	require.NoError(t, s.Save(ctx, models.URLModel{ID: "alias", URL: "https://a.com", UserID: "other"}))
+	got, exists = s.GetByOriginalURL(ctx, "https://a.com")
+	require.True(t, exists)
+	assert.Equal(t, "alias", got.ID, "a permanent URL is preferred")
+
+	got, exists = s.GetByOriginalURL(ctx, "https://a.com/path")
+	require.True(t, exists)
+	assert.Equal(t, "prefix", got.ID)
+
+	// Удалённая бессрочная ссылка уступает действующей, а истёкшая — бессрочной
+	_, err = s.SaveBatch(ctx, []models.URLModel{
+		{ID: "expired", URL: "https://b.com", UserID: "user", ExpiresAt: expiresIn(-time.Minute)},
+		{ID: "deleted", URL: "https://b.com", UserID: "user"},
+		{ID: "live", URL: "https://b.com", UserID: "user", ExpiresAt: expiresIn(time.Hour)},
+	})
+	require.NoError(t, err)
+	require.NoError(t, s.DeleteUserURLs(ctx, "user", []string{"deleted"}))
+	got, exists = s.GetByOriginalURL(ctx, "https://b.com")
+	require.True(t, exists)
+	assert.Equal(t, "live", got.ID, "an active URL is preferred over a deleted one")
+
+	require.NoError(t, s.DeleteUserURLs(ctx, "user", []string{"live"}))
+	got, exists = s.GetByOriginalURL(ctx, "https://b.com")
+	require.True(t, exists)
+	assert.Equal(t, "expired", got.ID, "a not deleted URL is preferred over a deleted one")
+}
+
+func testDeleteUserURLs(t *testing.T, s storage.URLStorage) {
+	ctx := context.Background()
+
+	_, err := s.SaveBatch(ctx, []models.URLModel{
+		{ID: "a", URL: "https://a.com", UserID: "user"},
+		{ID: "b", URL: "https://b.com", UserID: "user"},
+		{ID: "c", URL: "https://c.com", UserID: "other"},
+	})
+	require.NoError(t, err)
+
+	// Чужие и несуществующие URL пропускаются
+	require.NoError(t, s.DeleteUserURLs(ctx, "user", []string{"a", "c", "missing"}))
+	// Повторное удаление — не ошибка
+	require.NoError(t, s.DeleteUserURLs(ctx, "user", []string{"a"}))
+
+	for id, deleted := range map[string]bool{"a": true, "b": false, "c": false} {
+		got, exists := s.Get(ctx, id)
+		require.True(t, exists, id)
+		assert.Equal(t, deleted, got.Deleted, id)
+	}
+
+	got, exists := s.GetByOriginalURL(ctx, "https://a.com")
+	require.True(t, exists)
+	assert.True(t, got.Deleted)
+
+	urls, err := s.GetUserURLs(ctx, "user")
+	require.NoError(t, err)
+	if assert.Len(t, urls, 2) {
+		assert.True(t, urls[0].Deleted)
+		assert.False(t, urls[1].Deleted)
+	}
+}
+
+func testGetUserURLs(t *testing.T, s storage.URLStorage) {
+	ctx := context.Background()
+
+	require.NoError(t, s.Save(ctx, models.URLModel{ID: "z", URL: "https://z.com", UserID: "user"}))
+	require.NoError(t, s.Save(ctx, models.URLModel{ID: "other", URL: "https://other.com", UserID: "other"}))
+	_, err := s.SaveBatch(ctx, []models.URLModel{
+		{ID: "m", URL: "https://m.com", UserID: "user"},
+		{ID: "a", URL: "https://a.com", UserID: "user", ExpiresAt: expiresIn(time.Hour)},
+	})
+	require.NoError(t, err)
+
+	// Порядок создания, а не порядок ID
+	assert.Equal(t, []string{"z", "m", "a"}, userURLIDs(t, s, "user"))
+	assert.Equal(t, []string{"other"}, userURLIDs(t, s, "other"))
+
+	urls, err := s.GetUserURLs(ctx, "user")
+	require.NoError(t, err)
+	assertURL(t, models.URLModel{ID: "z", URL: "https://z.com", UserID: "user"}, urls[0])
+	assert.False(t, urls[2].ExpiresAt.IsZero())
+
+	urls, err = s.GetUserURLs(ctx, "nobody")
+	require.NoError(t, err)
+	assert.Empty(t, urls)
+}
+
+func testGetStats(t *testing.T, s storage.URLStorage) {
+	ctx := context.Background()
+
+	stats, err := s.GetStats(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, models.StatsModel{}, stats)
+
+	_, err = s.SaveBatch(ctx, []models.URLModel{
+		{ID: "a", URL: "https://a.com", UserID: "user"},
+		{ID: "b", URL: "https://b.com", UserID: "user"},
+		{ID: "c", URL: "https://c.com", UserID: "other"},
+		{ID: "d", URL: "https://d.com"},
+	})
+	require.NoError(t, err)
+	require.NoError(t, s.DeleteUserURLs(ctx, "user", []string{"b"}))
+
+	stats, err = s.GetStats(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, models.StatsModel{URLs: 3, DeletedURLs: 1, Users: 2}, stats)
+}
+
+func testDeleteExpired(t *testing.T, s storage.URLStorage) {
+	ctx := context.Background()
+	now := time.Now().UTC().Truncate(time.Second)
+
+	_, err := s.SaveBatch(ctx, []models.URLModel{
+		{ID: "expired", URL: "https://a.com", UserID: "user", ExpiresAt: now.Add(-time.Minute)},
+		{ID: "active", URL: "https://a.com", UserID: "user", ExpiresAt: now.Add(time.Hour)},
+		{ID: "permanent", URL: "https://b.com", UserID: "user"},
+	})
+	require.NoError(t, err)
+	require.NoError(t, s.SaveClicks(ctx, []models.ClickEvent{
+		{ShortID: "expired", Timestamp: now.Add(-time.Hour), IPHash: "ip"},
+		{ShortID: "permanent", Timestamp: now.Add(-time.Hour), IPHash: "ip"},
+	}))
+
+	removed, err := s.DeleteExpired(ctx, now, false)
+	require.NoError(t, err)
+	assert.Equal(t, 1, removed)
+
+	// Переходы удаляются вместе со ссылкой
+	linkStats, err := s.GetClickStats(ctx, "expired")
+	require.NoError(t, err)
+	assert.Zero(t, linkStats.TotalClicks)
+	linkStats, err = s.GetClickStats(ctx, "permanent")
+	require.NoError(t, err)
+	assert.Equal(t, 1, linkStats.TotalClicks)
+
+	_, exists := s.Get(ctx, "expired")
+	assert.False(t, exists)
+	got, exists := s.GetByOriginalURL(ctx, "https://a.com")
+	require.True(t, exists)
+	assert.Equal(t, "active", got.ID)
+	assert.Equal(t, []string{"active", "permanent"}, userURLIDs(t, s, "user"))
+
+	removed, err = s.DeleteExpired(ctx, now.Add(2*time.Hour), true)
+	require.NoError(t, err)
+	assert.Equal(t, 1, removed)
+	_, exists = s.GetByOriginalURL(ctx, "https://a.com")
+	assert.False(t, exists)
+	assert.Equal(t, []string{"permanent"}, userURLIDs(t, s, "user"))
+
+	// Новая ссылка с освободившимся ID не получает чужих переходов
+	require.NoError(t, s.Save(ctx, models.URLModel{ID: "expired", URL: "https://c.com", UserID: "other"}))
+	linkStats, err = s.GetClickStats(ctx, "expired")
+	require.NoError(t, err)
+	assert.Zero(t, linkStats.TotalClicks)
+}
+
+func testReassignUserURLs(t *testing.T, s storage.URLStorage) {
+	ctx := context.Background()
+
+	_, err := s.SaveBatch(ctx, []models.URLModel{
+		{ID: "a", URL: "https://a.com", UserID: "account"},
+		{ID: "b", URL: "https://b.com", UserID: "anonymous"},
+		{ID: "c", URL: "https://c.com", UserID: "anonymous"},
+	})
+	require.NoError(t, err)
+	require.NoError(t, s.DeleteUserURLs(ctx, "anonymous", []string{"c"}))
+	require.NoError(t, s.SaveAPIKey(ctx, models.APIKeyModel{ID: "key", UserID: "anonymous", KeyHash: "hash", CreatedAt: time.Now().UTC()}))
+
+	moved, err := s.ReassignUserURLs(ctx, "anonymous", "account")
+	require.NoError(t, err)
+	assert.Equal(t, 2, moved)
+
+	// API-ключи переносятся вместе с URL
+	key, exists := s.GetAPIKeyByHash(ctx, "hash")
+	require.True(t, exists)
+	assert.Equal(t, "account", key.UserID)
+	keys, err := s.GetUserAPIKeys(ctx, "anonymous")
+	require.NoError(t, err)
+	assert.Empty(t, keys)
+
+	assert.ElementsMatch(t, []string{"a", "b", "c"}, userURLIDs(t, s, "account"))
+	assert.Empty(t, userURLIDs(t, s, "anonymous"))
+
+	got, exists := s.Get(ctx, "c")
+	require.True(t, exists)
+	assert.Equal(t, "account", got.UserID)
+	assert.True(t, got.Deleted, "deletion survives reassignment")
+
+	// Новый владелец может удалять перенесённые URL
+	require.NoError(t, s.DeleteUserURLs(ctx, "account", []string{"b"}))
+	got, _ = s.Get(ctx, "b")
+	assert.True(t, got.Deleted)
+
+	moved, err = s.ReassignUserURLs(ctx, "nobody", "account")
+	require.NoError(t, err)
+	assert.Zero(t, moved)
+}
+
+func testLoadFromFile(t *testing.T, s storage.URLStorage) {
+	ctx := context.Background()
+
+	_, err := s.SaveBatch(ctx, []models.URLModel{
+		{ID: "a", URL: "https://a.com", UserID: "user"},
+		{ID: "b", URL: "https://b.com", UserID: "user"},
+	})
+	require.NoError(t, err)
+	require.NoError(t, s.DeleteUserURLs(ctx, "user", []string{"a"}))
+
+	// Повторная загрузка перестраивает индексы, не теряя и не дублируя записи
+	require.NoError(t, s.LoadFromFile())
+	require.NoError(t, s.LoadFromFile())
+
+	assert.Equal(t, []string{"a", "b"}, userURLIDs(t, s, "user"))
+	got, exists := s.Get(ctx, "a")
+	require.True(t, exists)
+	assert.True(t, got.Deleted)
+	got, exists = s.GetByOriginalURL(ctx, "https://b.com")
+	require.True(t, exists)
+	assert.Equal(t, "b", got.ID)
+
+	assert.ErrorIs(t, s.Save(ctx, models.URLModel{ID: "a", URL: "https://c.com"}), storage.ErrConflict)
+}
+
+func testForEachURL(t *testing.T, s storage.URLStorage) {
+	ctx := context.Background()
+
+	collect := func(afterID string) []models.URLModel {
+		t.Helper()
+		var urls []models.URLModel
+		require.NoError(t, s.ForEachURL(ctx, afterID, func(urlModel models.URLModel) error {
+			urls = append(urls, urlModel)
+			return nil
+		}))
+		return urls
+	}
+
+	assert.Empty(t, collect(""))
+
+	expiresAt := expiresIn(time.Hour)
+	_, err := s.SaveBatch(ctx, []models.URLModel{
+		{ID: "c", URL: "https://c.com", UserID: "other"},
+		{ID: "a", URL: "https://a.com", UserID: "user", ExpiresAt: expiresAt},
+		{ID: "b", URL: "https://b.com", UserID: "user"},
+	})
+	require.NoError(t, err)
+	require.NoError(t, s.DeleteUserURLs(ctx, "user", []string{"b"}))
+
+	urls := collect("")
+	require.Len(t, urls, 3)
+	assertURL(t, models.URLModel{ID: "a", URL: "https://a.com", UserID: "user", ExpiresAt: expiresAt}, urls[0])
+	assertURL(t, models.URLModel{ID: "b", URL: "https://b.com", UserID: "user", Deleted: true}, urls[1])
+	assertURL(t, models.URLModel{ID: "c", URL: "https://c.com", UserID: "other"}, urls[2])
+
+	// Обход продолжается после указанного ID, даже если его уже нет
+	urls = collect("a")
+	require.Len(t, urls, 2)
+	assert.Equal(t, "b", urls[0].ID)
+	urls = collect("aa")
+	require.Len(t, urls, 2)
+	assert.Empty(t, collect("c"))
+
+	// Ошибка fn прерывает обход
+	errStop := errors.New("stop")
+	visited := 0
+	err = s.ForEachURL(ctx, "", func(models.URLModel) error {
+		visited++
+		return errStop
+	})
+	assert.ErrorIs(t, err, errStop)
+	assert.Equal(t, 1, visited)
+
+	// fn может писать в хранилище
+	require.NoError(t, s.ForEachURL(ctx, "", func(urlModel models.URLModel) error {
+		copied := urlModel
+		copied.ID = "copy-" + urlModel.ID
+		_, err := s.SaveBatch(ctx, []models.URLModel{copied})
+		return err
+	}))
+	assert.Len(t, collect(""), 6)
+
+	// SaveBatch сохраняет флаг удаления, чтобы обход можно было перенести в другое хранилище
+	_, err = s.SaveBatch(ctx, []models.URLModel{{ID: "d", URL: "https://d.com", UserID: "user", Deleted: true}})
+	require.NoError(t, err)
+	urls = collect("copy-c")
+	require.NotEmpty(t, urls)
+	assertURL(t, models.URLModel{ID: "d", URL: "https://d.com", UserID: "user", Deleted: true}, urls[0])
+}
+
+func testCheckHealth(t *testing.T, s storage.URLStorage) {
+	ctx := context.Background()
+	require.NoError(t, s.Save(ctx, models.URLModel{ID: "a", URL: "https://a.com", UserID: "user"}))
+
+	components := s.CheckHealth(ctx)
+	require.NotEmpty(t, components)
+	for name, component := range components {
+		// Диск может быть почти заполнен на машине с тестами, поэтому допускается HealthDegraded
+		assert.NotEqual(t, storage.HealthDown, component.Status, "component %s: %s", name, component.Error)
+	}
+}
+
+func testConcurrency(t *testing.T, s storage.URLStorage) {
+	ctx := context.Background()
+	const (
+		writers = 8
+		perUser = 25
+		racers  = 8
+	)
+
+	var (
+		wg        sync.WaitGroup
+		mu        sync.Mutex
+		succeeded int
+	)
+	for w := 0; w < writers; w++ {
+		wg.Add(1)
+		go func(w int) {
+			defer wg.Done()
+			userID := fmt.Sprintf("user-%d", w)
+			for i := 0; i < perUser; i++ {
+				id := fmt.Sprintf("%d-%d", w, i)
+				assert.NoError(t, s.Save(ctx, models.URLModel{ID: id, URL: "https://" + id + ".com", UserID: userID}))
+				s.Get(ctx, id)
+				if i%5 == 4 {
+					assert.NoError(t, s.DeleteUserURLs(ctx, userID, []string{id}))
+				}
+			}
+		}(w)
+	}
+
+	// Из одновременных записей одного ID успешна ровно одна
+	for r := 0; r < racers; r++ {
+		wg.Add(1)
+		go func(r int) {
+			defer wg.Done()
+			err := s.Save(ctx, models.URLModel{ID: "race", URL: fmt.Sprintf("https://race-%d.com", r), UserID: "racer"})
+			if err == nil {
+				mu.Lock()
+				succeeded++
+				mu.Unlock()
+				return
+			}
+			assert.ErrorIs(t, err, storage.ErrConflict)
+		}(r)
+	}
+	wg.Wait()
+
+	assert.Equal(t, 1, succeeded)
+	assert.Len(t, userURLIDs(t, s, "racer"), 1)
+
+	for w := 0; w < writers; w++ {
+		urls, err := s.GetUserURLs(ctx, fmt.Sprintf("user-%d", w))
+		require.NoError(t, err)
+		require.Len(t, urls, perUser)
+		for i, urlModel := range urls {
+			assert.Equal(t, fmt.Sprintf("%d-%d", w, i), urlModel.ID)
+			assert.Equal(t, i%5 == 4, urlModel.Deleted, urlModel.ID)
+		}
+	}
+
+	stats, err := s.GetStats(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, models.StatsModel{
+		URLs:        writers*perUser*4/5 + 1,
+		DeletedURLs: writers * perUser / 5,
+		Users:       writers + 1,
+	}, stats)
+}
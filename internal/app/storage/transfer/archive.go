@@ -0,0 +1,253 @@
+package transfer
+
+import (
+	"bufio"
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/alexuryumtsev/go-shortener/internal/app/models"
+)
+
+// Format — формат архива.
+type Format string
+
+// Форматы архива.
+const (
+	// FormatNDJSON — по одному JSON-объекту на строку
+	FormatNDJSON Format = "ndjson"
+	// FormatCSV — CSV с заголовком csvHeader
+	FormatCSV Format = "csv"
+)
+
+// ParseFormat разбирает название формата архива.
+func ParseFormat(name string) (Format, error) {
+	switch format := Format(strings.ToLower(name)); format {
+	case FormatNDJSON, FormatCSV:
+		return format, nil
+	case "jsonl":
+		return FormatNDJSON, nil
+	default:
+		return "", fmt.Errorf("unknown archive format %q: expected ndjson or csv", name)
+	}
+}
+
+// FormatFromPath определяет формат архива по расширению файла.
+func FormatFromPath(path string) (Format, bool) {
+	format, err := ParseFormat(strings.TrimPrefix(filepath.Ext(path), "."))
+	return format, err == nil
+}
+
+// csvHeader — столбцы CSV-архива.
+var csvHeader = []string{"short_url", "original_url", "user_id", "is_deleted", "expires_at"}
+
+// record — запись NDJSON-архива.
+type record struct {
+	ShortURL    string     `json:"short_url"`
+	OriginalURL string     `json:"original_url"`
+	UserID      string     `json:"user_id"`
+	Deleted     bool       `json:"is_deleted"`
+	ExpiresAt   *time.Time `json:"expires_at,omitempty"`
+}
+
+// Writer записывает URL в архив. Реализует Sink.
+type Writer struct {
+	w      *bufio.Writer
+	csv    *csv.Writer
+	format Format
+	header bool
+}
+
+// NewWriter создаёт Writer. Заголовок CSV записывается перед первым пакетом,
+// если header выставлен: при дозаписи в существующий архив он не нужен.
+func NewWriter(w io.Writer, format Format, header bool) *Writer {
+	buffered := bufio.NewWriter(w)
+	writer := &Writer{w: buffered, format: format, header: header}
+	if format == FormatCSV {
+		writer.csv = csv.NewWriter(buffered)
+	}
+	return writer
+}
+
+// SaveBatch записывает URL в архив и сбрасывает буфер, чтобы после возврата
+// пакет целиком оказался в нижележащем io.Writer. Архив ничего не пропускает.
+func (w *Writer) SaveBatch(_ context.Context, urlModels []models.URLModel) ([]string, error) {
+	if w.format == FormatCSV {
+		return nil, w.saveCSV(urlModels)
+	}
+
+	encoder := json.NewEncoder(w.w)
+	for _, urlModel := range urlModels {
+		rec := record{ShortURL: urlModel.ID, OriginalURL: urlModel.URL, UserID: urlModel.UserID, Deleted: urlModel.Deleted}
+		if !urlModel.ExpiresAt.IsZero() {
+			expiresAt := urlModel.ExpiresAt.UTC()
+			rec.ExpiresAt = &expiresAt
+		}
+		if err := encoder.Encode(rec); err != nil {
+			return nil, err
+		}
+	}
+	return nil, w.w.Flush()
+}
+
+// saveCSV записывает URL в CSV-архив.
+func (w *Writer) saveCSV(urlModels []models.URLModel) error {
+	if w.header {
+		if err := w.csv.Write(csvHeader); err != nil {
+			return err
+		}
+		w.header = false
+	}
+	for _, urlModel := range urlModels {
+		expiresAt := ""
+		if !urlModel.ExpiresAt.IsZero() {
+			expiresAt = urlModel.ExpiresAt.UTC().Format(time.RFC3339Nano)
+		}
+		row := []string{urlModel.ID, urlModel.URL, urlModel.UserID, strconv.FormatBool(urlModel.Deleted), expiresAt}
+		if err := w.csv.Write(row); err != nil {
+			return err
+		}
+	}
+	w.csv.Flush()
+	if err := w.csv.Error(); err != nil {
+		return err
+	}
+	return w.w.Flush()
+}
+
+// Reader читает URL из архива. Реализует Source; архив читается один раз.
+type Reader struct {
+	r      io.Reader
+	format Format
+}
+
+// NewReader создаёт Reader.
+func NewReader(r io.Reader, format Format) *Reader {
+	return &Reader{r: r, format: format}
+}
+
+// Each вызывает fn для URL архива, пропуская первые from.Count записей.
+func (r *Reader) Each(ctx context.Context, from Progress, fn func(models.URLModel) error) error {
+	n := 0
+	return r.each(func(urlModel models.URLModel) error {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		n++
+		if n <= from.Count {
+			return nil
+		}
+		return fn(urlModel)
+	})
+}
+
+// each разбирает записи архива по порядку.
+func (r *Reader) each(fn func(models.URLModel) error) error {
+	if r.format == FormatCSV {
+		return r.eachCSV(fn)
+	}
+
+	scanner := bufio.NewScanner(r.r)
+	scanner.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+	for line := 1; scanner.Scan(); line++ {
+		if len(strings.TrimSpace(scanner.Text())) == 0 {
+			continue
+		}
+		var rec record
+		if err := json.Unmarshal(scanner.Bytes(), &rec); err != nil {
+			return fmt.Errorf("%w at line %d: %w", ErrInvalidRecord, line, err)
+		}
+		urlModel := models.URLModel{ID: rec.ShortURL, URL: rec.OriginalURL, UserID: rec.UserID, Deleted: rec.Deleted}
+		if rec.ExpiresAt != nil {
+			urlModel.ExpiresAt = rec.ExpiresAt.UTC()
+		}
+		if err := validate(urlModel, line); err != nil {
+			return err
+		}
+		if err := fn(urlModel); err != nil {
+			return err
+		}
+	}
+	return scanner.Err()
+}
+
+// eachCSV разбирает CSV-архив. Столбцы определяются по заголовку,
+// поэтому их порядок может отличаться от csvHeader, а лишние столбцы пропускаются.
+func (r *Reader) eachCSV(fn func(models.URLModel) error) error {
+	reader := csv.NewReader(r.r)
+	reader.FieldsPerRecord = -1
+
+	header, err := reader.Read()
+	if errors.Is(err, io.EOF) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("%w: failed to read CSV header: %w", ErrInvalidRecord, err)
+	}
+	columns := make(map[string]int, len(header))
+	for i, name := range header {
+		columns[strings.TrimSpace(name)] = i
+	}
+	for _, name := range csvHeader[:2] {
+		if _, ok := columns[name]; !ok {
+			return fmt.Errorf("%w: CSV header has no %q column", ErrInvalidRecord, name)
+		}
+	}
+	field := func(row []string, name string) string {
+		if i, ok := columns[name]; ok && i < len(row) {
+			return row[i]
+		}
+		return ""
+	}
+
+	for {
+		row, err := reader.Read()
+		if errors.Is(err, io.EOF) {
+			return nil
+		}
+		line, _ := reader.FieldPos(0)
+		if err != nil {
+			return fmt.Errorf("%w: %w", ErrInvalidRecord, err)
+		}
+
+		urlModel := models.URLModel{
+			ID:     field(row, "short_url"),
+			URL:    field(row, "original_url"),
+			UserID: field(row, "user_id"),
+		}
+		if deleted := field(row, "is_deleted"); deleted != "" {
+			urlModel.Deleted, err = strconv.ParseBool(deleted)
+			if err != nil {
+				return fmt.Errorf("%w at line %d: is_deleted: %w", ErrInvalidRecord, line, err)
+			}
+		}
+		if expiresAt := field(row, "expires_at"); expiresAt != "" {
+			urlModel.ExpiresAt, err = time.Parse(time.RFC3339Nano, expiresAt)
+			if err != nil {
+				return fmt.Errorf("%w at line %d: expires_at: %w", ErrInvalidRecord, line, err)
+			}
+			urlModel.ExpiresAt = urlModel.ExpiresAt.UTC()
+		}
+		if err := validate(urlModel, line); err != nil {
+			return err
+		}
+		if err := fn(urlModel); err != nil {
+			return err
+		}
+	}
+}
+
+// validate проверяет обязательные поля записи архива.
+func validate(urlModel models.URLModel, line int) error {
+	if urlModel.ID == "" || urlModel.URL == "" {
+		return fmt.Errorf("%w at line %d: short_url and original_url are required", ErrInvalidRecord, line)
+	}
+	return nil
+}
@@ -0,0 +1,111 @@
+// Package transfer переносит URL между хранилищами и переносимыми архивами.
+//
+// Источник (Source) отдаёт URL по одному, приёмник (Sink) сохраняет их пакетами
+// через SaveBatch. После каждого сохранённого пакета вызывается Options.Checkpoint
+// с текущей позицией (Progress): сохранив её, прерванный перенос можно продолжить.
+// Хранилища пропускают уже занятые короткие ID, поэтому повторный перенос
+// пакета в хранилище безопасен.
+package transfer
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/alexuryumtsev/go-shortener/internal/app/models"
+	"github.com/alexuryumtsev/go-shortener/internal/app/storage"
+)
+
+// DefaultBatchSize — размер пакета по умолчанию.
+const DefaultBatchSize = 500
+
+// ErrInvalidRecord возвращается для записи архива, которую нельзя перенести.
+var ErrInvalidRecord = errors.New("invalid record")
+
+// Progress — позиция переноса.
+type Progress struct {
+	// LastID — короткий ID последнего сохранённого URL;
+	// по нему продолжается обход хранилища
+	LastID string `json:"last_id"`
+	// Count — число сохранённых URL; по нему продолжается чтение архива
+	Count int `json:"count"`
+	// Offset — размер архива-приёмника после последнего пакета;
+	// заполняется вызывающим, чтобы отбросить недописанный пакет
+	Offset int64 `json:"offset,omitempty"`
+}
+
+// Source — источник URL.
+type Source interface {
+	// Each вызывает fn для каждого URL после позиции from.
+	Each(ctx context.Context, from Progress, fn func(models.URLModel) error) error
+}
+
+// Sink — приёмник URL. Хранилища (storage.URLWriter) реализуют его без адаптеров.
+// SaveBatch возвращает ID, которые уже заняты в приёмнике и поэтому пропущены.
+type Sink interface {
+	SaveBatch(ctx context.Context, urlModels []models.URLModel) (skipped []string, err error)
+}
+
+// Options — параметры переноса.
+type Options struct {
+	// BatchSize — число URL в одном вызове SaveBatch; по умолчанию DefaultBatchSize
+	BatchSize int
+	// Checkpoint вызывается после каждого сохранённого пакета; ошибка прерывает перенос
+	Checkpoint func(Progress) error
+}
+
+// FromStorage возвращает источник, обходящий хранилище в порядке коротких ID.
+func FromStorage(r storage.URLReader) Source {
+	return storageSource{r}
+}
+
+// storageSource обходит хранилище с помощью ForEachURL.
+type storageSource struct {
+	reader storage.URLReader
+}
+
+// Each обходит URL хранилища с ID больше from.LastID.
+func (s storageSource) Each(ctx context.Context, from Progress, fn func(models.URLModel) error) error {
+	return s.reader.ForEachURL(ctx, from.LastID, fn)
+}
+
+// Copy переносит URL из src в dst, начиная с позиции from, и возвращает
+// позицию после последнего сохранённого пакета — и при успехе, и при ошибке.
+func Copy(ctx context.Context, src Source, dst Sink, from Progress, opts Options) (Progress, error) {
+	batchSize := opts.BatchSize
+	if batchSize <= 0 {
+		batchSize = DefaultBatchSize
+	}
+
+	progress := from
+	batch := make([]models.URLModel, 0, batchSize)
+	flush := func() error {
+		if len(batch) == 0 {
+			return nil
+		}
+		// Уже перенесённые ID пропускаются: так повторный запуск после сбоя
+		// не дублирует записи
+		if _, err := dst.SaveBatch(ctx, batch); err != nil {
+			return fmt.Errorf("failed to save batch after %d URLs: %w", progress.Count, err)
+		}
+		progress.LastID = batch[len(batch)-1].ID
+		progress.Count += len(batch)
+		batch = batch[:0]
+		if opts.Checkpoint != nil {
+			return opts.Checkpoint(progress)
+		}
+		return nil
+	}
+
+	err := src.Each(ctx, from, func(urlModel models.URLModel) error {
+		batch = append(batch, urlModel)
+		if len(batch) < batchSize {
+			return nil
+		}
+		return flush()
+	})
+	if err != nil {
+		return progress, err
+	}
+	return progress, flush()
+}
@@ -0,0 +1,177 @@
+package transfer
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/alexuryumtsev/go-shortener/internal/app/models"
+	"github.com/alexuryumtsev/go-shortener/internal/app/storage/memory"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func testURLs() []models.URLModel {
+	expiresAt := time.Date(2030, 1, 2, 3, 4, 5, 0, time.UTC)
+	return []models.URLModel{
+		{ID: "a", URL: "https://a.com", UserID: "user", ExpiresAt: expiresAt},
+		{ID: "b", URL: "https://b.com/?q=1,2", UserID: "user", Deleted: true},
+		{ID: "c", URL: "https://c.com", UserID: ""},
+		{ID: "d", URL: "https://d.com", UserID: "other"},
+		{ID: "e", URL: "https://e.com", UserID: "other"},
+	}
+}
+
+// failingSink отказывает на пакете с номером failAt.
+type failingSink struct {
+	saved  []models.URLModel
+	calls  int
+	failAt int
+}
+
+func (s *failingSink) SaveBatch(_ context.Context, urlModels []models.URLModel) ([]string, error) {
+	s.calls++
+	if s.calls == s.failAt {
+		return nil, errors.New("disk full")
+	}
+	s.saved = append(s.saved, urlModels...)
+	return nil, nil
+}
+
+func TestCopy_StorageToStorage(t *testing.T) {
+	ctx := context.Background()
+	src := memory.NewInMemoryStorage()
+	_, err := src.SaveBatch(ctx, testURLs())
+	require.NoError(t, err)
+	dst := memory.NewInMemoryStorage()
+
+	var checkpoints []Progress
+	progress, err := Copy(ctx, FromStorage(src), dst, Progress{}, Options{
+		BatchSize:  2,
+		Checkpoint: func(p Progress) error { checkpoints = append(checkpoints, p); return nil },
+	})
+	require.NoError(t, err)
+	assert.Equal(t, Progress{LastID: "e", Count: 5}, progress)
+	assert.Equal(t, []Progress{{LastID: "b", Count: 2}, {LastID: "d", Count: 4}, {LastID: "e", Count: 5}}, checkpoints)
+
+	for _, want := range testURLs() {
+		got, exists := dst.Get(ctx, want.ID)
+		assert.True(t, exists)
+		assert.Equal(t, want, got)
+	}
+}
+
+func TestCopy_Resume(t *testing.T) {
+	ctx := context.Background()
+	src := memory.NewInMemoryStorage()
+	_, err := src.SaveBatch(ctx, testURLs())
+	require.NoError(t, err)
+
+	// Второй пакет не сохраняется: позиция остаётся после первого
+	sink := &failingSink{failAt: 2}
+	progress, err := Copy(ctx, FromStorage(src), sink, Progress{}, Options{BatchSize: 2})
+	require.Error(t, err)
+	assert.Equal(t, Progress{LastID: "b", Count: 2}, progress)
+
+	sink.failAt = 0
+	progress, err = Copy(ctx, FromStorage(src), sink, progress, Options{BatchSize: 2})
+	require.NoError(t, err)
+	assert.Equal(t, Progress{LastID: "e", Count: 5}, progress)
+	assert.Equal(t, testURLs(), sink.saved)
+}
+
+func TestArchive_RoundTrip(t *testing.T) {
+	for _, format := range []Format{FormatNDJSON, FormatCSV} {
+		t.Run(string(format), func(t *testing.T) {
+			ctx := context.Background()
+			src := memory.NewInMemoryStorage()
+			_, err := src.SaveBatch(ctx, testURLs())
+			require.NoError(t, err)
+
+			var buf bytes.Buffer
+			_, err = Copy(ctx, FromStorage(src), NewWriter(&buf, format, true), Progress{}, Options{BatchSize: 2})
+			require.NoError(t, err)
+
+			dst := memory.NewInMemoryStorage()
+			progress, err := Copy(ctx, NewReader(&buf, format), dst, Progress{}, Options{})
+			require.NoError(t, err)
+			assert.Equal(t, 5, progress.Count)
+
+			for _, want := range testURLs() {
+				got, exists := dst.Get(ctx, want.ID)
+				assert.True(t, exists)
+				assert.Equal(t, want, got)
+			}
+		})
+	}
+}
+
+func TestArchive_ResumeByCount(t *testing.T) {
+	ctx := context.Background()
+	var buf bytes.Buffer
+	_, err := NewWriter(&buf, FormatCSV, true).SaveBatch(ctx, testURLs())
+	require.NoError(t, err)
+
+	sink := &failingSink{}
+	progress, err := Copy(ctx, NewReader(&buf, FormatCSV), sink, Progress{LastID: "c", Count: 3}, Options{})
+	require.NoError(t, err)
+	assert.Equal(t, Progress{LastID: "e", Count: 5}, progress)
+	assert.Equal(t, testURLs()[3:], sink.saved)
+}
+
+func TestArchive_Formats(t *testing.T) {
+	ctx := context.Background()
+	var buf bytes.Buffer
+	_, err := NewWriter(&buf, FormatNDJSON, true).SaveBatch(ctx, testURLs()[:2])
+	require.NoError(t, err)
+	assert.Equal(t, `{"short_url":"a","original_url":"https://a.com","user_id":"user","is_deleted":false,"expires_at":"2030-01-02T03:04:05Z"}
+{"short_url":"b","original_url":"https://b.com/?q=1,2","user_id":"user","is_deleted":true}
+`, buf.String())
+
+	buf.Reset()
+	_, err = NewWriter(&buf, FormatCSV, true).SaveBatch(ctx, testURLs()[:2])
+	require.NoError(t, err)
+	assert.Equal(t, `short_url,original_url,user_id,is_deleted,expires_at
+a,https://a.com,user,false,2030-01-02T03:04:05Z
+b,"https://b.com/?q=1,2",user,true,
+`, buf.String())
+
+	// Столбцы CSV определяются по заголовку
+	sink := &failingSink{}
+	_, err = Copy(ctx, NewReader(strings.NewReader("original_url,short_url,extra\nhttps://x.com,x,1\n"), FormatCSV), sink, Progress{}, Options{})
+	require.NoError(t, err)
+	assert.Equal(t, []models.URLModel{{ID: "x", URL: "https://x.com"}}, sink.saved)
+}
+
+func TestArchive_InvalidRecords(t *testing.T) {
+	tests := []struct {
+		name   string
+		format Format
+		input  string
+	}{
+		{"broken json", FormatNDJSON, "{\"short_url\":\"a\"\n"},
+		{"missing url", FormatNDJSON, "{\"short_url\":\"a\"}\n"},
+		{"missing column", FormatCSV, "short_url,user_id\na,user\n"},
+		{"bad flag", FormatCSV, "short_url,original_url,is_deleted\na,https://a.com,maybe\n"},
+		{"bad time", FormatCSV, "short_url,original_url,expires_at\na,https://a.com,tomorrow\n"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := Copy(context.Background(), NewReader(strings.NewReader(tt.input), tt.format), &failingSink{}, Progress{}, Options{})
+			assert.ErrorIs(t, err, ErrInvalidRecord)
+		})
+	}
+}
+
+func TestFormatFromPath(t *testing.T) {
+	for path, want := range map[string]Format{"dump.ndjson": FormatNDJSON, "dump.JSONL": FormatNDJSON, "dump.csv": FormatCSV} {
+		format, ok := FormatFromPath(path)
+		assert.True(t, ok, path)
+		assert.Equal(t, want, format, path)
+	}
+	_, ok := FormatFromPath("storage.db")
+	assert.False(t, ok)
+}
@@ -0,0 +1,41 @@
+package storage
+
+import (
+	"context"
+	"sort"
+
+	"github.com/alexuryumtsev/go-shortener/internal/app/models"
+)
+
+// PreferURL сообщает, предпочтительнее ли короткая ссылка candidate ссылки current
+// на тот же оригинальный URL: неудалённая лучше удалённой, бессрочная лучше срочной,
+// из срочных — с более поздним сроком, поэтому действующая ссылка лучше истёкшей.
+// Используется хранилищами в GetByOriginalURL, если у URL несколько коротких ссылок.
+func PreferURL(candidate, current models.URLModel) bool {
+	switch {
+	case candidate.Deleted != current.Deleted:
+		return current.Deleted
+	case current.ExpiresAt.IsZero():
+		return false
+	case candidate.ExpiresAt.IsZero():
+		return true
+	default:
+		return candidate.ExpiresAt.After(current.ExpiresAt)
+	}
+}
+
+// ForEachSorted вызывает fn для URL из urls в порядке возрастания короткого ID.
+// Используется хранилищами, которые держат URL в памяти: они передают снимок,
+// сделанный под блокировкой, и fn вызывается уже без неё.
+func ForEachSorted(ctx context.Context, urls []models.URLModel, fn func(models.URLModel) error) error {
+	sort.Slice(urls, func(i, j int) bool { return urls[i].ID < urls[j].ID })
+	for _, urlModel := range urls {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		if err := fn(urlModel); err != nil {
+			return err
+		}
+	}
+	return nil
+}
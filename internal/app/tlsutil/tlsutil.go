@@ -0,0 +1,187 @@
+// Package tlsutil предоставляет сертификаты для HTTPS-сервера
+// и обработчик перенаправления HTTP-запросов на HTTPS.
+package tlsutil
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"fmt"
+	"math/big"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// Параметры самоподписанного сертификата.
+const (
+	selfSignedValidity = 365 * 24 * time.Hour
+	// selfSignedRenewBefore — запас, при котором кешированный сертификат перевыпускается.
+	selfSignedRenewBefore = 7 * 24 * time.Hour
+
+	cachedCertFile = "cert.pem"
+	cachedKeyFile  = "key.pem"
+)
+
+// LoadCertificate возвращает сертификат для HTTPS-сервера.
+// Если заданы certFile и keyFile, сертификат читается из них.
+// Иначе используется самоподписанный сертификат из cacheDir; он создаётся
+// для hosts при первом запуске и перевыпускается незадолго до истечения
+// или при смене hosts.
+func LoadCertificate(certFile, keyFile, cacheDir string, hosts []string) (tls.Certificate, error) {
+	if certFile != "" || keyFile != "" {
+		if certFile == "" || keyFile == "" {
+			return tls.Certificate{}, fmt.Errorf("both TLS certificate and key files must be set")
+		}
+		cert, err := tls.LoadX509KeyPair(certFile, keyFile)
+		if err != nil {
+			return tls.Certificate{}, fmt.Errorf("failed to load TLS certificate: %w", err)
+		}
+		return cert, nil
+	}
+
+	return selfSigned(cacheDir, hosts, time.Now())
+}
+
+// selfSigned загружает кешированный самоподписанный сертификат или создаёт новый.
+// Кешированный сертификат используется, только если он выпущен для тех же hosts.
+func selfSigned(cacheDir string, hosts []string, now time.Time) (tls.Certificate, error) {
+	certPath := filepath.Join(cacheDir, cachedCertFile)
+	keyPath := filepath.Join(cacheDir, cachedKeyFile)
+
+	if cert, err := tls.LoadX509KeyPair(certPath, keyPath); err == nil {
+		leaf, err := x509.ParseCertificate(cert.Certificate[0])
+		if err == nil && now.Add(selfSignedRenewBefore).Before(leaf.NotAfter) && issuedFor(leaf, hosts) {
+			return cert, nil
+		}
+	}
+
+	certPEM, keyPEM, err := generateSelfSigned(hosts, now)
+	if err != nil {
+		return tls.Certificate{}, err
+	}
+
+	if err := os.MkdirAll(cacheDir, 0700); err != nil {
+		return tls.Certificate{}, fmt.Errorf("failed to create certificate cache: %w", err)
+	}
+	if err := os.WriteFile(certPath, certPEM, 0600); err != nil {
+		return tls.Certificate{}, fmt.Errorf("failed to cache certificate: %w", err)
+	}
+	if err := os.WriteFile(keyPath, keyPEM, 0600); err != nil {
+		return tls.Certificate{}, fmt.Errorf("failed to cache certificate key: %w", err)
+	}
+
+	return tls.X509KeyPair(certPEM, keyPEM)
+}
+
+// generateSelfSigned создаёт самоподписанный сертификат ECDSA P-256 для hosts.
+// localhost и адреса loopback добавляются всегда.
+func generateSelfSigned(hosts []string, now time.Time) (certPEM, keyPEM []byte, err error) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to generate key: %w", err)
+	}
+
+	serial, err := rand.Int(rand.Reader, new(big.Int).Lsh(big.NewInt(1), 128))
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to generate serial number: %w", err)
+	}
+
+	template := &x509.Certificate{
+		SerialNumber:          serial,
+		Subject:               pkix.Name{Organization: []string{"go-shortener"}, CommonName: "localhost"},
+		NotBefore:             now.Add(-time.Hour),
+		NotAfter:              now.Add(selfSignedValidity),
+		KeyUsage:              x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:           []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+		BasicConstraintsValid: true,
+	}
+	template.DNSNames, template.IPAddresses = subjectAltNames(hosts)
+
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to create certificate: %w", err)
+	}
+
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to marshal key: %w", err)
+	}
+
+	certPEM = pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der})
+	keyPEM = pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER})
+	return certPEM, keyPEM, nil
+}
+
+// subjectAltNames возвращает имена и адреса, для которых выпускается сертификат:
+// hosts, а также localhost и адреса loopback.
+func subjectAltNames(hosts []string) (dnsNames []string, ips []net.IP) {
+	dnsNames = []string{"localhost"}
+	ips = []net.IP{net.IPv4(127, 0, 0, 1), net.IPv6loopback}
+	for _, host := range hosts {
+		if host == "" || host == "localhost" {
+			continue
+		}
+		if ip := net.ParseIP(host); ip != nil {
+			ips = append(ips, ip)
+		} else {
+			dnsNames = append(dnsNames, host)
+		}
+	}
+	return dnsNames, ips
+}
+
+// issuedFor сообщает, выпущен ли сертификат leaf ровно для hosts.
+func issuedFor(leaf *x509.Certificate, hosts []string) bool {
+	dnsNames, ips := subjectAltNames(hosts)
+
+	want := make(map[string]struct{}, len(dnsNames)+len(ips))
+	for _, name := range dnsNames {
+		want["dns:"+name] = struct{}{}
+	}
+	for _, ip := range ips {
+		want["ip:"+ip.String()] = struct{}{}
+	}
+
+	got := make(map[string]struct{}, len(leaf.DNSNames)+len(leaf.IPAddresses))
+	for _, name := range leaf.DNSNames {
+		got["dns:"+name] = struct{}{}
+	}
+	for _, ip := range leaf.IPAddresses {
+		got["ip:"+ip.String()] = struct{}{}
+	}
+
+	if len(got) != len(want) {
+		return false
+	}
+	for name := range want {
+		if _, ok := got[name]; !ok {
+			return false
+		}
+	}
+	return true
+}
+
+// RedirectHandler перенаправляет все запросы на HTTPS-сервер, слушающий httpsAddr.
+// Хост берётся из запроса, порт — из httpsAddr; стандартный порт 443 опускается.
+func RedirectHandler(httpsAddr string) http.Handler {
+	_, port, _ := net.SplitHostPort(httpsAddr)
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		host := r.Host
+		if h, _, err := net.SplitHostPort(r.Host); err == nil {
+			host = h
+		}
+		if port != "" && port != "443" {
+			host = net.JoinHostPort(host, port)
+		}
+
+		http.Redirect(w, r, "https://"+host+r.URL.RequestURI(), http.StatusPermanentRedirect)
+	})
+}
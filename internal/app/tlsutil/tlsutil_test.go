@@ -0,0 +1,100 @@
+package tlsutil
+
+import (
+	"crypto/x509"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLoadCertificate_SelfSigned(t *testing.T) {
+	cacheDir := t.TempDir()
+
+	cert, err := LoadCertificate("", "", cacheDir, []string{"short.example.com", "10.0.0.1"})
+	require.NoError(t, err)
+
+	leaf, err := x509.ParseCertificate(cert.Certificate[0])
+	require.NoError(t, err)
+	assert.NoError(t, leaf.VerifyHostname("localhost"))
+	assert.NoError(t, leaf.VerifyHostname("short.example.com"))
+	assert.NoError(t, leaf.VerifyHostname("10.0.0.1"))
+
+	// Повторная загрузка для тех же хостов использует кешированный сертификат
+	cached, err := LoadCertificate("", "", cacheDir, []string{"10.0.0.1", "short.example.com"})
+	require.NoError(t, err)
+	assert.Equal(t, cert.Certificate[0], cached.Certificate[0])
+
+	// Смена хостов перевыпускает сертификат
+	moved, err := LoadCertificate("", "", cacheDir, []string{"new.example.com"})
+	require.NoError(t, err)
+	assert.NotEqual(t, cert.Certificate[0], moved.Certificate[0])
+	leaf, err = x509.ParseCertificate(moved.Certificate[0])
+	require.NoError(t, err)
+	assert.NoError(t, leaf.VerifyHostname("new.example.com"))
+	assert.Error(t, leaf.VerifyHostname("short.example.com"))
+
+	// Сертификат, срок которого скоро истечёт, перевыпускается
+	renewed, err := selfSigned(cacheDir, []string{"new.example.com"}, time.Now().Add(selfSignedValidity))
+	require.NoError(t, err)
+	assert.NotEqual(t, moved.Certificate[0], renewed.Certificate[0])
+}
+
+func TestLoadCertificate_Files(t *testing.T) {
+	dir := t.TempDir()
+	certPEM, keyPEM, err := generateSelfSigned(nil, time.Now())
+	require.NoError(t, err)
+
+	certFile := filepath.Join(dir, "server.crt")
+	keyFile := filepath.Join(dir, "server.key")
+	require.NoError(t, os.WriteFile(certFile, certPEM, 0600))
+	require.NoError(t, os.WriteFile(keyFile, keyPEM, 0600))
+
+	_, err = LoadCertificate(certFile, keyFile, "", nil)
+	assert.NoError(t, err)
+
+	_, err = LoadCertificate(certFile, "", "", nil)
+	assert.Error(t, err, "key file is required")
+
+	_, err = LoadCertificate(filepath.Join(dir, "missing.crt"), keyFile, "", nil)
+	assert.Error(t, err)
+}
+
+func TestRedirectHandler(t *testing.T) {
+	tests := []struct {
+		name      string
+		httpsAddr string
+		target    string
+		want      string
+	}{
+		{
+			name:      "Custom port",
+			httpsAddr: ":8443",
+			target:    "http://localhost:8080/abc?x=1",
+			want:      "https://localhost:8443/abc?x=1",
+		},
+		{
+			name:      "Default port",
+			httpsAddr: ":443",
+			target:    "http://short.example.com/abc",
+			want:      "https://short.example.com/abc",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, tt.target, nil)
+			rec := httptest.NewRecorder()
+
+			RedirectHandler(tt.httpsAddr).ServeHTTP(rec, req)
+
+			assert.Equal(t, http.StatusPermanentRedirect, rec.Code)
+			assert.Equal(t, tt.want, rec.Header().Get("Location"))
+		})
+	}
+}
@@ -0,0 +1,148 @@
+// Package tracing настраивает трассировку OpenTelemetry.
+//
+// Setup устанавливает глобальные TracerProvider и пропагатор W3C Trace Context,
+// поэтому остальные пакеты получают трассировщик через otel.Tracer и не зависят
+// от выбранного экспортёра. При отключённой трассировке спаны не записываются,
+// но заголовок traceparent входящих запросов всё равно разбирается.
+package tracing
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracegrpc"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
+	"go.opentelemetry.io/otel/exporters/stdout/stdouttrace"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	semconv "go.opentelemetry.io/otel/semconv/v1.26.0"
+)
+
+// Экспортёры спанов.
+const (
+	// ExporterNone отключает запись спанов
+	ExporterNone = "none"
+	// ExporterOTLP отправляет спаны коллектору по OTLP/gRPC
+	ExporterOTLP = "otlp"
+	// ExporterOTLPHTTP отправляет спаны коллектору по OTLP/HTTP
+	ExporterOTLPHTTP = "otlp-http"
+	// ExporterStdout пишет спаны в стандартный вывод в формате JSON
+	ExporterStdout = "stdout"
+	// ExporterFile дописывает спаны в файл в формате JSON
+	ExporterFile = "file"
+)
+
+// serviceName — имя сервиса в ресурсе трассировки.
+const serviceName = "shortener"
+
+// Options — параметры трассировки.
+type Options struct {
+	// Exporter — один из Exporter*; пустое значение равносильно ExporterNone
+	Exporter string
+	// Endpoint — URL коллектора OTLP, например "http://localhost:4317";
+	// схема http отключает TLS. Если пуст, используются переменные
+	// OTEL_EXPORTER_OTLP_ENDPOINT и значения по умолчанию экспортёра
+	Endpoint string
+	// File — путь к файлу для ExporterFile
+	File string
+	// ServiceVersion — версия сервиса в ресурсе трассировки
+	ServiceVersion string
+}
+
+// Setup настраивает трассировку и возвращает функцию, которая отправляет
+// накопленные спаны и останавливает экспортёр.
+func Setup(ctx context.Context, opts Options) (func(context.Context) error, error) {
+	otel.SetTextMapPropagator(propagation.NewCompositeTextMapPropagator(propagation.TraceContext{}, propagation.Baggage{}))
+
+	exporter, closeOutput, err := newExporter(ctx, opts)
+	if err != nil || exporter == nil {
+		return func(context.Context) error { return nil }, err
+	}
+
+	attrs := []attribute.KeyValue{semconv.ServiceName(serviceName)}
+	if opts.ServiceVersion != "" {
+		attrs = append(attrs, semconv.ServiceVersion(opts.ServiceVersion))
+	}
+	res, err := resource.New(ctx,
+		resource.WithSchemaURL(semconv.SchemaURL),
+		resource.WithAttributes(attrs...),
+		resource.WithFromEnv(),
+		resource.WithTelemetrySDK(),
+	)
+	if err != nil {
+		return nil, errors.Join(fmt.Errorf("failed to create trace resource: %w", err), exporter.Shutdown(ctx), closeOutput())
+	}
+
+	provider := sdktrace.NewTracerProvider(
+		sdktrace.WithBatcher(exporter),
+		sdktrace.WithResource(res),
+	)
+	otel.SetTracerProvider(provider)
+
+	return func(ctx context.Context) error {
+		return errors.Join(provider.Shutdown(ctx), closeOutput())
+	}, nil
+}
+
+// newExporter создаёт экспортёр спанов и функцию закрытия его вывода.
+// Для ExporterNone возвращает nil.
+func newExporter(ctx context.Context, opts Options) (sdktrace.SpanExporter, func() error, error) {
+	noClose := func() error { return nil }
+
+	switch opts.Exporter {
+	case "", ExporterNone:
+		return nil, noClose, nil
+	case ExporterOTLP:
+		var clientOpts []otlptracegrpc.Option
+		if opts.Endpoint != "" {
+			clientOpts = append(clientOpts, otlptracegrpc.WithEndpointURL(opts.Endpoint))
+		}
+		exporter, err := otlptracegrpc.New(ctx, clientOpts...)
+		return exporter, noClose, err
+	case ExporterOTLPHTTP:
+		var clientOpts []otlptracehttp.Option
+		if opts.Endpoint != "" {
+			clientOpts = append(clientOpts, otlptracehttp.WithEndpointURL(opts.Endpoint))
+		}
+		exporter, err := otlptracehttp.New(ctx, clientOpts...)
+		return exporter, noClose, err
+	case ExporterStdout:
+		exporter, err := stdouttrace.New(stdouttrace.WithWriter(os.Stdout))
+		return exporter, noClose, err
+	case ExporterFile:
+		if opts.File == "" {
+			return nil, nil, errors.New("trace file path is required for the file exporter")
+		}
+		f, err := openFile(opts.File)
+		if err != nil {
+			return nil, nil, err
+		}
+		exporter, err := stdouttrace.New(stdouttrace.WithWriter(f))
+		if err != nil {
+			f.Close()
+			return nil, nil, err
+		}
+		return exporter, f.Close, nil
+	default:
+		return nil, nil, fmt.Errorf("unknown trace exporter %q: expected none, otlp, otlp-http, stdout or file", opts.Exporter)
+	}
+}
+
+// openFile открывает файл трассировки для дозаписи, создавая директорию.
+func openFile(path string) (io.WriteCloser, error) {
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return nil, fmt.Errorf("failed to create trace file directory: %w", err)
+	}
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0o644)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open trace file: %w", err)
+	}
+	return f, nil
+}
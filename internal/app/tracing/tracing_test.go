@@ -0,0 +1,107 @@
+package tracing
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	semconv "go.opentelemetry.io/otel/semconv/v1.26.0"
+)
+
+// recordSpans устанавливает глобальный TracerProvider, сохраняющий спаны в памяти.
+func recordSpans(t *testing.T) *tracetest.SpanRecorder {
+	t.Helper()
+	recorder := tracetest.NewSpanRecorder()
+	previous := otel.GetTracerProvider()
+	otel.SetTracerProvider(sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder)))
+	t.Cleanup(func() { otel.SetTracerProvider(previous) })
+	return recorder
+}
+
+func TestSetup_Exporters(t *testing.T) {
+	ctx := context.Background()
+
+	shutdown, err := Setup(ctx, Options{Exporter: ExporterNone})
+	require.NoError(t, err)
+	assert.NoError(t, shutdown(ctx))
+
+	_, err = Setup(ctx, Options{Exporter: "jaeger"})
+	assert.ErrorContains(t, err, "unknown trace exporter")
+
+	_, err = Setup(ctx, Options{Exporter: ExporterFile})
+	assert.ErrorContains(t, err, "trace file path is required")
+}
+
+func TestSetup_FileExporter(t *testing.T) {
+	previous := otel.GetTracerProvider()
+	t.Cleanup(func() { otel.SetTracerProvider(previous) })
+
+	ctx := context.Background()
+	path := filepath.Join(t.TempDir(), "traces", "spans.json")
+	shutdown, err := Setup(ctx, Options{Exporter: ExporterFile, File: path, ServiceVersion: "v1.2.3"})
+	require.NoError(t, err)
+
+	_, span := otel.Tracer("test").Start(ctx, "operation")
+	span.End()
+	require.NoError(t, shutdown(ctx))
+
+	data, err := os.ReadFile(path)
+	require.NoError(t, err)
+	assert.Contains(t, string(data), `"Name":"operation"`)
+	assert.Contains(t, string(data), `"Value":"shortener"`)
+	assert.Contains(t, string(data), `"Value":"v1.2.3"`)
+}
+
+func TestMiddleware(t *testing.T) {
+	recorder := recordSpans(t)
+	_, err := Setup(context.Background(), Options{})
+	require.NoError(t, err)
+
+	r := chi.NewRouter()
+	r.Use(Middleware)
+	r.Route("/", func(r chi.Router) {
+		r.Get("/{id}", func(w http.ResponseWriter, r *http.Request) {
+			// Обработчик получает контекст со спаном запроса
+			_, child := otel.Tracer("test").Start(r.Context(), "child")
+			child.End()
+			w.WriteHeader(http.StatusTemporaryRedirect)
+		})
+		r.Post("/", func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(http.StatusInternalServerError)
+		})
+	})
+
+	const traceID = "4bf92f3577b34da6a3ce929d0e0e4736"
+	req := httptest.NewRequest(http.MethodGet, "/abc", nil)
+	req.Header.Set("traceparent", "00-"+traceID+"-00f067aa0ba902b7-01")
+	r.ServeHTTP(httptest.NewRecorder(), req)
+	r.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodPost, "/", nil))
+
+	spans := recorder.Ended()
+	require.Len(t, spans, 3)
+	child, server, failed := spans[0], spans[1], spans[2]
+
+	// Спан продолжает трассу клиента из traceparent
+	assert.Equal(t, "GET /{id}", server.Name())
+	assert.Equal(t, traceID, server.SpanContext().TraceID().String())
+	assert.Equal(t, "00f067aa0ba902b7", server.Parent().SpanID().String())
+	assert.Equal(t, server.SpanContext().SpanID(), child.Parent().SpanID())
+	assert.Contains(t, server.Attributes(), semconv.HTTPRoute("/{id}"))
+	assert.Contains(t, server.Attributes(), attribute.Int("http.response.status_code", http.StatusTemporaryRedirect))
+	assert.Equal(t, codes.Unset, server.Status().Code)
+
+	assert.Equal(t, "POST /", failed.Name())
+	assert.False(t, failed.Parent().IsValid())
+	assert.Equal(t, codes.Error, failed.Status().Code)
+}
@@ -2,9 +2,12 @@
 package validator
 
 import (
+	"errors"
 	"fmt"
+	"net"
 	"net/url"
 	"regexp"
+	"strings"
 )
 
 // ValidateServerAddress проверяет формат host:port.
@@ -25,3 +28,82 @@ func ValidateBaseURL(baseURL string) error {
 	}
 	return nil
 }
+
+// ValidateBaseURLScheme проверяет, что базовый URL использует схему scheme.
+func ValidateBaseURLScheme(baseURL, scheme string) error {
+	u, err := url.Parse(baseURL)
+	if err != nil {
+		return fmt.Errorf("invalid base URL: %v", err)
+	}
+	if !strings.EqualFold(u.Scheme, scheme) {
+		return fmt.Errorf("base URL must use %s scheme, got %q", scheme, u.Scheme)
+	}
+	return nil
+}
+
+// ErrInvalidAlias возвращается, если пользовательский алиас не прошёл проверку.
+var ErrInvalidAlias = errors.New("invalid alias")
+
+// Ограничения на пользовательский алиас.
+const (
+	aliasMinLength = 3
+	aliasMaxLength = 64
+)
+
+// aliasPattern допускает латинские буквы, цифры, дефис и подчёркивание.
+var aliasPattern = regexp.MustCompile(`^[A-Za-z0-9_-]+$`)
+
+// reservedAliases содержит имена, совпадающие с маршрутами сервиса.
+var reservedAliases = map[string]bool{
+	"api":      true,
+	"ping":     true,
+	"debug":    true,
+	"healthz":  true,
+	"readyz":   true,
+	"metrics":  true,
+	"admin":    true,
+	"static":   true,
+	"login":    true,
+	"logout":   true,
+	"register": true,
+}
+
+// ValidateAlias проверяет длину, набор символов и зарезервированность алиаса.
+func ValidateAlias(alias string) error {
+	if len(alias) < aliasMinLength || len(alias) > aliasMaxLength {
+		return fmt.Errorf("%w: length must be between %d and %d", ErrInvalidAlias, aliasMinLength, aliasMaxLength)
+	}
+	if !aliasPattern.MatchString(alias) {
+		return fmt.Errorf("%w: only latin letters, digits, '-' and '_' are allowed", ErrInvalidAlias)
+	}
+	if reservedAliases[strings.ToLower(alias)] {
+		return fmt.Errorf("%w: %q is reserved", ErrInvalidAlias, alias)
+	}
+	return nil
+}
+
+// ParseSubnets разбирает список подсетей CIDR или отдельных IP-адресов,
+// перечисленных через запятую. Адрес без маски считается подсетью из одного адреса.
+func ParseSubnets(list string) ([]*net.IPNet, error) {
+	var subnets []*net.IPNet
+	for _, item := range strings.Split(list, ",") {
+		item = strings.TrimSpace(item)
+		if item == "" {
+			continue
+		}
+		if ip := net.ParseIP(item); ip != nil {
+			bits := 8 * net.IPv6len
+			if ip4 := ip.To4(); ip4 != nil {
+				ip, bits = ip4, 8*net.IPv4len
+			}
+			subnets = append(subnets, &net.IPNet{IP: ip, Mask: net.CIDRMask(bits, bits)})
+			continue
+		}
+		_, subnet, err := net.ParseCIDR(item)
+		if err != nil {
+			return nil, fmt.Errorf("invalid subnet %q: expected CIDR or IP address", item)
+		}
+		subnets = append(subnets, subnet)
+	}
+	return subnets, nil
+}
@@ -1,11 +1,19 @@
 package validator
 
 import (
+	"strings"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
 )
 
+func TestValidateBaseURLScheme(t *testing.T) {
+	assert.NoError(t, ValidateBaseURLScheme("https://localhost:8443/", "https"))
+	assert.NoError(t, ValidateBaseURLScheme("HTTPS://short.example.com", "https"))
+	assert.Error(t, ValidateBaseURLScheme("http://localhost:8080/", "https"))
+	assert.Error(t, ValidateBaseURLScheme("localhost:8080", "https"))
+}
+
 func TestValidateServerAddress(t *testing.T) {
 	tests := []struct {
 		name    string
@@ -115,3 +123,47 @@ func TestValidateBaseURL(t *testing.T) {
 		})
 	}
 }
+
+func TestValidateAlias(t *testing.T) {
+	tests := []struct {
+		name    string
+		alias   string
+		wantErr bool
+	}{
+		{name: "Valid alias", alias: "spring-sale", wantErr: false},
+		{name: "Valid alias with underscore", alias: "Spring_2025", wantErr: false},
+		{name: "Invalid: too short", alias: "ab", wantErr: true},
+		{name: "Invalid: too long", alias: strings.Repeat("a", 65), wantErr: true},
+		{name: "Invalid: forbidden characters", alias: "spring/sale", wantErr: true},
+		{name: "Invalid: reserved word", alias: "ping", wantErr: true},
+		{name: "Invalid: reserved word in upper case", alias: "API", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := ValidateAlias(tt.alias)
+			if tt.wantErr {
+				assert.ErrorIs(t, err, ErrInvalidAlias)
+			} else {
+				assert.NoError(t, err)
+			}
+		})
+	}
+}
+
+func TestParseSubnets(t *testing.T) {
+	subnets, err := ParseSubnets(" 10.0.0.0/8, 192.168.0.1 ,, ::1")
+	assert.NoError(t, err)
+	if assert.Len(t, subnets, 3) {
+		assert.Equal(t, "10.0.0.0/8", subnets[0].String())
+		assert.Equal(t, "192.168.0.1/32", subnets[1].String())
+		assert.Equal(t, "::1/128", subnets[2].String())
+	}
+
+	subnets, err = ParseSubnets("")
+	assert.NoError(t, err)
+	assert.Empty(t, subnets)
+
+	_, err = ParseSubnets("10.0.0.0/8,proxy.local")
+	assert.ErrorContains(t, err, `invalid subnet "proxy.local"`)
+}